@@ -2,16 +2,25 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 )
 
+// ToolVersion is the deployer build version, set by main at startup
+var ToolVersion = "dev"
+
 // Config represents the application configuration stored in ./config.json (current working directory)
 type Config struct {
-	// Image sources (up to 10)
+	// Image sources (up to SourceLimit())
 	ImageSources []ImageSource `json:"image_sources,omitempty"`
+	// Maximum number of image sources (0 = DefaultMaxImageSources)
+	MaxImageSources int `json:"max_image_sources,omitempty"`
 
 	// Custom image overrides per component
 	CustomImages map[string]string `json:"custom_images,omitempty"`
@@ -19,13 +28,40 @@ type Config struct {
 	// Last used settings for convenience
 	LastProxmoxHost     string `json:"last_proxmox_host,omitempty"`
 	LastProxmoxUser     string `json:"last_proxmox_user,omitempty"`
-	LastProxmoxPassword string `json:"last_proxmox_password,omitempty"`
+	LastProxmoxPassword string `json:"last_proxmox_password,omitempty" secret:"true"`
 	LastStorage         string `json:"last_storage,omitempty"`
 	LastSSHKeyPath      string `json:"last_ssh_key_path,omitempty"`
 
 	// Director connection info (saved after successful deployment)
 	DirectorIP       string `json:"director_ip,omitempty"`
 	DirectorUsername string `json:"director_username,omitempty"`
+
+	// Go text/template for VM notes (empty = built-in markdown template)
+	DescriptionTemplate string `json:"description_template,omitempty"`
+
+	// Preferred serial console tool, one of ConsoleTools (empty = default chain)
+	ConsoleTool string `json:"console_tool,omitempty"`
+
+	// Record serial console output to ConsoleLogDir()
+	ConsoleLog bool `json:"console_log,omitempty"`
+	// Delete console logs older than this many days (0 = keep forever)
+	ConsoleLogRetentionDays int `json:"console_log_retention_days,omitempty"`
+
+	// PEM CA certificates trusted for outbound TLS, e.g. a TLS inspection
+	// proxy's CA (--ca-bundle overrides)
+	CABundle string `json:"ca_bundle,omitempty"`
+
+	// Concurrent ISO downloads from any one image source (0 = default of 2)
+	DownloadsPerSource int `json:"downloads_per_source,omitempty"`
+
+	// Where missing ISO checksums come from, most preferred first: "sidecar"
+	// (.sha256/.md5 file), "header" (Content-MD5 or S3 ETag) and "computed"
+	// (hashed after download). Empty = all three in that order.
+	ChecksumSources []string `json:"checksum_sources,omitempty"`
+
+	// Oldest image version per feature and component, e.g.
+	// {"cloud-init": {"director": "22.1.1"}}, merged over FeatureMinVersions
+	FeatureMinVersions map[Feature]map[ComponentType]string `json:"feature_min_versions,omitempty"`
 }
 
 // ImageSource represents a source for Versa ISO images
@@ -34,7 +70,23 @@ type ImageSource struct {
 	Type     string `json:"type"` // dropbox, http, sftp, local
 	Name     string `json:"name,omitempty"`
 	SSHKey   string `json:"ssh_key,omitempty"`   // For SFTP sources
-	Password string `json:"password,omitempty"` // For SFTP sources (not recommended)
+	Password string `json:"password,omitempty"` // For SFTP sources (not recommended); for HTTP, Basic Auth with Username or else a bearer token
+	Username string `json:"username,omitempty"` // For HTTP Basic Auth
+	Priority int    `json:"priority,omitempty"` // Lower is tried first; ImageSources is kept in this order
+
+	// Extra request headers for HTTP sources, e.g. {"X-JFrog-Art-Api": "<key>"}
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Outcome of the most recent scan (nil = never scanned)
+	Scan *SourceScanStatus `json:"scan,omitempty"`
+}
+
+// SourceScanStatus records the latest scan of an image source
+type SourceScanStatus struct {
+	LastAttempt time.Time  `json:"last_attempt"`
+	LastSuccess *time.Time `json:"last_success,omitempty"`
+	LastError   string     `json:"last_error,omitempty"` // Empty when the last attempt succeeded
+	ISOCount    int        `json:"iso_count"`            // ISOs found by the last successful scan
 }
 
 // ConfigDir returns the configuration directory path (current working directory)
@@ -56,6 +108,16 @@ func CacheDir() string {
 	return filepath.Join(ConfigDir(), "images")
 }
 
+// DeployLogDir returns the directory the web UI writes deployment logs to
+func DeployLogDir() string {
+	return filepath.Join(ConfigDir(), "logs")
+}
+
+// ConsoleLogDir returns the directory serial console recordings are written to
+func ConsoleLogDir() string {
+	return filepath.Join(ConfigDir(), "console-logs")
+}
+
 // Load reads the configuration from disk
 func Load() (*Config, error) {
 	cfg := &Config{
@@ -83,6 +145,7 @@ func Load() (*Config, error) {
 	if cfg.ImageSources == nil {
 		cfg.ImageSources = []ImageSource{}
 	}
+	cfg.sortImageSources()
 
 	return cfg, nil
 }
@@ -106,28 +169,125 @@ func (c *Config) Save() error {
 	return nil
 }
 
-// AddImageSource adds a new image source to the config
-func (c *Config) AddImageSource(source ImageSource) error {
-	if len(c.ImageSources) >= 10 {
-		return fmt.Errorf("maximum of 10 image sources allowed")
+// DefaultMaxImageSources is the image source limit when max_image_sources is unset
+const DefaultMaxImageSources = 10
+
+var (
+	// ErrSourceLimit is wrapped by AddImageSource when the limit is reached
+	ErrSourceLimit = errors.New("image source limit reached")
+
+	// ErrSourceExists is wrapped by AddImageSource for a duplicate URL
+	ErrSourceExists = errors.New("source already exists")
+)
+
+// SourceLimit returns how many image sources may be configured
+func (c *Config) SourceLimit() int {
+	if c.MaxImageSources > 0 {
+		return c.MaxImageSources
 	}
+	return DefaultMaxImageSources
+}
 
-	// Check for duplicate URLs
+// CanAddImageSource reports whether a source with url could be added,
+// wrapping ErrSourceLimit or ErrSourceExists if not
+func (c *Config) CanAddImageSource(url string) error {
 	for _, existing := range c.ImageSources {
-		if existing.URL == source.URL {
-			return fmt.Errorf("source already exists: %s", source.URL)
+		if existing.URL == url {
+			return fmt.Errorf("%w: %s", ErrSourceExists, url)
 		}
 	}
+	if limit := c.SourceLimit(); len(c.ImageSources) >= limit {
+		return fmt.Errorf("%w: %d of %d sources configured; remove one or raise max_image_sources", ErrSourceLimit, len(c.ImageSources), limit)
+	}
+	return nil
+}
 
+// AddImageSource adds a new image source to the config
+func (c *Config) AddImageSource(source ImageSource) error {
+	if err := c.CanAddImageSource(source.URL); err != nil {
+		return err
+	}
+
+	if source.Priority <= 0 {
+		source.Priority = len(c.ImageSources) + 1
+	}
 	c.ImageSources = append(c.ImageSources, source)
+	c.sortImageSources()
+	return nil
+}
+
+// ReorderImageSources puts the sources in the given URL order and renumbers
+// their priorities. urls must list every configured source exactly once.
+func (c *Config) ReorderImageSources(urls []string) error {
+	if len(urls) != len(c.ImageSources) {
+		return fmt.Errorf("expected %d sources in new order, got %d", len(c.ImageSources), len(urls))
+	}
+
+	byURL := make(map[string]ImageSource, len(c.ImageSources))
+	for _, src := range c.ImageSources {
+		byURL[src.URL] = src
+	}
+
+	reordered := make([]ImageSource, 0, len(urls))
+	for _, url := range urls {
+		src, ok := byURL[url]
+		if !ok {
+			return fmt.Errorf("unknown or duplicate source: %s", url)
+		}
+		delete(byURL, url)
+		reordered = append(reordered, src)
+	}
+
+	c.ImageSources = reordered
+	c.renumberImageSources()
 	return nil
 }
 
+// sortImageSources orders sources by priority, keeping list order for ties
+// (configs written before priorities existed), then renumbers them 1..n
+func (c *Config) sortImageSources() {
+	sort.SliceStable(c.ImageSources, func(i, j int) bool {
+		return c.ImageSources[i].Priority < c.ImageSources[j].Priority
+	})
+	c.renumberImageSources()
+}
+
+func (c *Config) renumberImageSources() {
+	for i := range c.ImageSources {
+		c.ImageSources[i].Priority = i + 1
+	}
+}
+
+// RecordSourceScan stores a scan result for the source with the given URL.
+// A failed scan keeps the previous success time and ISO count. Returns false
+// if no such source is configured.
+func (c *Config) RecordSourceScan(url string, isoCount int, scanErr string, at time.Time) bool {
+	for i := range c.ImageSources {
+		src := &c.ImageSources[i]
+		if src.URL != url {
+			continue
+		}
+		if src.Scan == nil {
+			src.Scan = &SourceScanStatus{}
+		}
+		src.Scan.LastAttempt = at
+		src.Scan.LastError = scanErr
+		if scanErr == "" {
+			success := at
+			src.Scan.LastSuccess = &success
+			src.Scan.ISOCount = isoCount
+		}
+		return true
+	}
+	return false
+}
+
 // RemoveImageSource removes an image source by URL or name
 func (c *Config) RemoveImageSource(url string) bool {
 	for i, source := range c.ImageSources {
 		if source.URL == url || source.Name == url {
 			c.ImageSources = append(c.ImageSources[:i], c.ImageSources[i+1:]...)
+			c.renumberImageSources()
 			return true
 		}
 	}
@@ -171,6 +331,206 @@ type DeploymentConfig struct {
 
 	// IP configuration
 	IPConfig IPConfig
+
+	// Startup behaviour
+	StartAfterCreate   bool // Start VMs once created (false leaves them stopped for review)
+	StartRetries       int  // Extra qm start attempts for VMs that fail to come up
+	SnapshotBeforeBoot bool // Take a clean-install snapshot of each VM before first boot
+	GuestAgent         bool // Enable the QEMU guest agent, needed to trim disks after install
+	SetTagColors       bool // Give component tags distinct colors in the Proxmox UI (PVE 7.3+)
+
+	// Pause between VM starts to smooth host I/O and CPU spikes (0 = none).
+	// Dependents wait longer after the Director (see deployer.startVMs).
+	StartDelay time.Duration
+
+	// Create VMs with only the management NIC; the rest are recorded and
+	// added later (deployer.AddRemainingNetworks) once management is verified
+	ManagementOnlyFirst bool
+
+	// What to clean up when the deployment fails ("" = full rollback)
+	RollbackPolicy RollbackPolicy
+
+	// How components without an ISO get one (zero = newest version)
+	ISOPolicy ISOPolicy
+
+	// Node scoring for the auto_balance strategy (zero = DefaultBalanceWeights)
+	BalanceWeights BalanceWeights
+
+	// Director registration check for new Analytics/Controller VMs
+	Registration RegistrationConfig
+
+	// Directory of per-component cloud-init user-data templates, named
+	// <component>.yaml (empty = no cloud-init). See deployer.CloudInitVars.
+	CloudInitDir string
+	// Storage the rendered user-data is uploaded to ("" = first snippets storage)
+	SnippetsStorage string
+
+	// Local PEM CA bundle that downloads on the Proxmox host verify TLS
+	// against ("" = skip verification there, for TLS inspection proxies)
+	CABundle string
+
+	// Concurrent ISO downloads from any one image source (0 = downloader default)
+	DownloadsPerSource int
+	// Checksum origin preference, see config.Config.ChecksumSources
+	ChecksumSources []string
+	// Feature minimums merged over FeatureMinVersions, see
+	// config.Config.FeatureMinVersions
+	FeatureMinVersions map[Feature]map[ComponentType]string
+
+	// Extra qm create arguments appended verbatim (shell-escaped) to every VM.
+	// An escape hatch for Proxmox features the tool doesn't model; use with care.
+	ExtraVMArgs []string
+
+	// VM notes metadata
+	Operator            string // Who ran the deployment
+	Ticket              string // Change/ticket reference
+	Environment         string // e.g. lab, staging, prod; tagged as versa-env-<name>
+	DescriptionTemplate string // Go text/template for VM notes (empty = default)
+}
+
+// RegistrationConfig holds the Director credentials used to confirm new
+// components joined the HeadEnd. The check is skipped when DirectorIP or
+// Password is empty.
+type RegistrationConfig struct {
+	DirectorIP string
+	Username   string
+	Password   string
+	Timeout    time.Duration // How long to wait for every component to register
+}
+
+// DefaultRegistrationTimeout bounds the post-deploy Director registration wait
+const DefaultRegistrationTimeout = 15 * time.Minute
+
+// RollbackPolicy controls which created VMs are removed after a failure
+type RollbackPolicy string
+
+const (
+	RollbackFull       RollbackPolicy = "full"        // Destroy every VM created in the run
+	RollbackNone       RollbackPolicy = "none"        // Leave everything in place and just report
+	RollbackFailedOnly RollbackPolicy = "failed-only" // Destroy only VMs that failed to create or start
+)
+
+// ParseRollbackPolicy validates a rollback policy name; empty means full
+func ParseRollbackPolicy(s string) (RollbackPolicy, error) {
+	switch p := RollbackPolicy(s); p {
+	case "":
+		return RollbackFull, nil
+	case RollbackFull, RollbackNone, RollbackFailedOnly:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown rollback policy %q (expected full, none or failed-only)", s)
+	}
+}
+
+// ISOPolicyMode names how ISOPolicy picks among a component's images
+type ISOPolicyMode string
+
+const (
+	ISOLatest        ISOPolicyMode = "latest"          // Newest version, pre-releases included
+	ISOLatestStable  ISOPolicyMode = "latest-stable"   // Newest version without a beta/rc suffix
+	ISOLatestInMajor ISOPolicyMode = "latest-in-major" // Newest stable version of one major release
+)
+
+// ISOPolicy picks the image for components deployed without an explicit ISO
+type ISOPolicy struct {
+	Mode  ISOPolicyMode
+	Major int // Major version for ISOLatestInMajor, e.g. 22
+}
+
+// ParseISOPolicy parses latest, latest-stable or latest-in-major:<N>; empty means latest
+func ParseISOPolicy(s string) (ISOPolicy, error) {
+	mode, arg, hasArg := strings.Cut(s, ":")
+	switch p := ISOPolicyMode(mode); p {
+	case "":
+		if !hasArg {
+			return ISOPolicy{Mode: ISOLatest}, nil
+		}
+	case ISOLatest, ISOLatestStable:
+		if !hasArg {
+			return ISOPolicy{Mode: p}, nil
+		}
+	case ISOLatestInMajor:
+		major, err := strconv.Atoi(arg)
+		if err != nil || major <= 0 {
+			return ISOPolicy{}, fmt.Errorf("ISO policy %q needs a major version, e.g. latest-in-major:22", s)
+		}
+		return ISOPolicy{Mode: p, Major: major}, nil
+	}
+	return ISOPolicy{}, fmt.Errorf("unknown ISO policy %q (expected latest, latest-stable or latest-in-major:<major>)", s)
+}
+
+// String returns the policy in the form ParseISOPolicy accepts
+func (p ISOPolicy) String() string {
+	switch p.Mode {
+	case "":
+		return string(ISOLatest)
+	case ISOLatestInMajor:
+		return fmt.Sprintf("%s:%d", p.Mode, p.Major)
+	}
+	return string(p.Mode)
+}
+
+// BalanceWeights weigh a node's free CPU and RAM share when auto_balance
+// picks a node, minus VMPenalty points per VM already placed there
+type BalanceWeights struct {
+	CPU       float64
+	RAM       float64
+	VMPenalty float64
+}
+
+// DefaultBalanceWeights favour RAM, usually the tighter resource for Versa
+// images: 40% CPU, 60% RAM and 5 points per VM already on the node
+var DefaultBalanceWeights = BalanceWeights{CPU: 0.4, RAM: 0.6, VMPenalty: 5}
+
+// Normalized returns the weights with CPU and RAM clamped to be non-negative
+// and scaled to sum to 1. All-zero CPU and RAM weights mean the defaults.
+func (w BalanceWeights) Normalized() BalanceWeights {
+	if w == (BalanceWeights{}) {
+		return DefaultBalanceWeights
+	}
+	w.CPU = max(w.CPU, 0)
+	w.RAM = max(w.RAM, 0)
+	w.VMPenalty = max(w.VMPenalty, 0)
+	sum := w.CPU + w.RAM
+	if sum == 0 {
+		w.CPU, w.RAM = DefaultBalanceWeights.CPU, DefaultBalanceWeights.RAM
+		return w
+	}
+	w.CPU /= sum
+	w.RAM /= sum
+	return w
+}
+
+// ParseBalanceWeights reads weights from cpu=, ram= and vm-penalty= values.
+// When only one of cpu and ram is given the other makes up the rest of 1;
+// unset values keep their defaults.
+func ParseBalanceWeights(values map[string]string) (BalanceWeights, error) {
+	w := DefaultBalanceWeights
+	_, hasCPU := values["cpu"]
+	_, hasRAM := values["ram"]
+	for key, raw := range values {
+		v, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil || v < 0 {
+			return BalanceWeights{}, fmt.Errorf("invalid balance weight %s=%q (expected a non-negative number)", key, raw)
+		}
+		switch key {
+		case "cpu":
+			w.CPU = v
+			if !hasRAM {
+				w.RAM = max(1-v, 0)
+			}
+		case "ram":
+			w.RAM = v
+			if !hasCPU {
+				w.CPU = max(1-v, 0)
+			}
+		case "vm-penalty":
+			w.VMPenalty = v
+		default:
+			return BalanceWeights{}, fmt.Errorf("unknown balance weight %q (expected cpu, ram or vm-penalty)", key)
+		}
+	}
+	return w.Normalized(), nil
 }
 
 // ComponentConfig holds configuration for a single component deployment
@@ -183,6 +543,33 @@ type ComponentConfig struct {
 	Node     string // Target Proxmox node
 	ISOPath  string // Path to ISO on Proxmox
 	Version  string // ISO version string
+
+	StoragePool string // Disk storage, overrides DeploymentConfig.StoragePool when set
+	DataDiskGB  int    // Secondary data disk on the same storage, Analytics only (0 = none)
+
+	// Data-plane tuning (off by default)
+	Hugepages string // Hugepage size in MB: "2", "1024" or "any"; also enables NUMA
+	Affinity  string // Host CPUs to pin vCPUs to, e.g. "0-3,8-11"
+
+	DiskBus DiskBus // Boot disk controller, overrides the VMSpec default when set
+	BIOS    BIOS    // Firmware, overrides the VMSpec default when set
+	Machine Machine // Machine type, overrides the VMSpec default when set
+
+	// Proxmox cloud-init drive for unattended first boot (nil = none)
+	CloudInit *CloudInitConfig
+
+	// Pre-installed VM template to full-clone instead of installing from ISO (0 = none)
+	TemplateVMID int
+}
+
+// CloudInitConfig is the Proxmox-generated cloud-init data for a component,
+// for images that run cloud-init on first boot
+type CloudInitConfig struct {
+	User     string   // Default user (empty = image default)
+	Password string   // Password for User
+	SSHKeys  []string // Authorized public keys, OpenSSH format
+	IP       string   // Static IPv4 for net0 in CIDR form, e.g. 10.0.0.10/24 (empty = DHCP); HA instances take the following addresses
+	Gateway  string   // Default gateway for a static IP
 }
 
 // NetworkConfig holds network bridge and VLAN configuration
@@ -203,6 +590,10 @@ type NetworkConfig struct {
 	ControllerWANBridges []string
 	ControllerWANVLANs   []int
 
+	// Analytics southbound network (optional, falls back to Director-Router)
+	AnalyticsSouthboundBridge string
+	AnalyticsSouthboundVLAN   int
+
 	// Analytics cluster network (optional)
 	AnalyticsClusterBridge string
 	AnalyticsClusterVLAN   int
@@ -215,6 +606,20 @@ type NetworkConfig struct {
 	// Values are ordered lists of interface IDs (e.g. ["base:0", "base:1", "wan:0"]).
 	// When set, BuildNetworksForComponent uses this to reorder the network interfaces.
 	InterfaceOrder map[string][]string
+
+	// Per-interface MTU. Keys are network purposes (e.g. "controller-wan",
+	// "router-ha") or a specific interface (e.g. "controller-wan-2", which wins
+	// over its purpose). 1 inherits the bridge MTU; unset keeps the default.
+	MTU map[string]int
+}
+
+// AnalyticsSouthbound returns the bridge and VLAN for the Analytics southbound
+// interface, falling back to the Director-Router link when not set explicitly
+func (n NetworkConfig) AnalyticsSouthbound() (string, int) {
+	if n.AnalyticsSouthboundBridge != "" {
+		return n.AnalyticsSouthboundBridge, n.AnalyticsSouthboundVLAN
+	}
+	return n.DirectorRouterBridge, n.DirectorRouterVLAN
 }
 
 // VLANPurpose represents the purpose/name of a VLAN configuration
@@ -227,6 +632,7 @@ const (
 	VLANControllerWAN1   VLANPurpose = "Controller-WAN-1"
 	VLANControllerWAN2   VLANPurpose = "Controller-WAN-2"
 	VLANControllerWAN3   VLANPurpose = "Controller-WAN-3"
+	VLANAnalyticsSouth   VLANPurpose = "Analytics-Southbound"
 	VLANAnalyticsCluster VLANPurpose = "Analytics-Cluster"
 	VLANRouterHA         VLANPurpose = "Router-HA-Sync"
 )
@@ -240,6 +646,7 @@ func GetVLANDescription(purpose VLANPurpose) string {
 		VLANControllerWAN1:   "Controller WAN interface 1 (Internet/MPLS)",
 		VLANControllerWAN2:   "Controller WAN interface 2 (backup link)",
 		VLANControllerWAN3:   "Controller WAN interface 3 (tertiary link)",
+		VLANAnalyticsSouth:   "Analytics southbound (log collection from branches)",
 		VLANAnalyticsCluster: "Analytics cluster synchronization",
 		VLANRouterHA:         "Router HA pair synchronization",
 	}
@@ -268,6 +675,12 @@ func NewDeploymentConfig() *DeploymentConfig {
 		IPConfig: IPConfig{
 			ManualIPs: make(map[string]string),
 		},
+		StartAfterCreate: true,
+		StartRetries:     2,
+		RollbackPolicy:   RollbackFull,
+		Registration: RegistrationConfig{
+			Timeout: DefaultRegistrationTimeout,
+		},
 	}
 }
 
@@ -280,11 +693,28 @@ func (dc *DeploymentConfig) GetTotalResources() (cpu int, ramGB int, diskGB int)
 		}
 		cpu += comp.CPU * count
 		ramGB += comp.RAMGB * count
-		diskGB += comp.DiskGB * count
+		diskGB += (comp.DiskGB + comp.DataDisk()) * count
 	}
 	return
 }
 
+// DataDisk returns the size of the data disk a component's VMs get, or 0.
+// Only Analytics keeps its data on a separate disk.
+func (c ComponentConfig) DataDisk() int {
+	if c.Type != ComponentAnalytics {
+		return 0
+	}
+	return c.DataDiskGB
+}
+
+// StorageFor returns the disk storage pool for a component
+func (dc *DeploymentConfig) StorageFor(comp ComponentConfig) string {
+	if comp.StoragePool != "" {
+		return comp.StoragePool
+	}
+	return dc.StoragePool
+}
+
 // VMCount returns the total number of VMs to be created
 func (dc *DeploymentConfig) VMCount() int {
 	count := 0
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestReorderImageSources(t *testing.T) {
+	newConfig := func() *Config {
+		return &Config{ImageSources: []ImageSource{
+			{URL: "https://a.example/isos", Priority: 1},
+			{URL: "https://b.example/isos", Priority: 2},
+			{URL: "/srv/isos", Priority: 3},
+		}}
+	}
+
+	// The web UI builds the order from the url keys of GET /api/sources
+	data, err := json.Marshal(newConfig().ImageSources)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var listed []map[string]any
+	if err := json.Unmarshal(data, &listed); err != nil {
+		t.Fatal(err)
+	}
+	var fromAPI []string
+	for _, src := range listed {
+		url, _ := src["url"].(string)
+		fromAPI = append(fromAPI, url)
+	}
+	fromAPI[0], fromAPI[2] = fromAPI[2], fromAPI[0]
+
+	tests := []struct {
+		name    string
+		order   []string
+		want    []string
+		wantErr bool
+	}{
+		{"order from API keys", fromAPI, []string{"/srv/isos", "https://b.example/isos", "https://a.example/isos"}, false},
+		{"missing urls", []string{"", "", ""}, nil, true},
+		{"duplicate", []string{"/srv/isos", "/srv/isos", "https://a.example/isos"}, nil, true},
+		{"too short", []string{"/srv/isos"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newConfig()
+			err := c.ReorderImageSources(tt.order)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("ReorderImageSources(%q) succeeded, want an error", tt.order)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, src := range c.ImageSources {
+				if src.URL != tt.want[i] || src.Priority != i+1 {
+					t.Errorf("source %d = %s (priority %d), want %s (priority %d)", i, src.URL, src.Priority, tt.want[i], i+1)
+				}
+			}
+		})
+	}
+}
@@ -1,5 +1,10 @@
 package config
 
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
 
 // ComponentType represents the type of Versa component
 type ComponentType string
@@ -13,17 +18,90 @@ const (
 	ComponentFlexVNF    ComponentType = "flexvnf"
 )
 
-// VMSpec defines the default resource specifications for a VM
+// VMSpec defines the default resource specifications for a VM. The json
+// names are the fields a specs file may override (see LoadVMSpecs).
 type VMSpec struct {
-	MinCPU         int    // Minimum vCPU cores
-	DefaultCPU     int    // Default vCPU cores
-	MinRAMGB       int    // Minimum RAM in GB
-	DefaultRAMGB   int    // Default RAM in GB
-	MinDiskGB      int    // Minimum disk in GB
-	DefaultDiskGB  int    // Default disk in GB
-	NetworkCount   int    // Number of network interfaces
-	ISOPattern     string // Pattern to match ISO filename
-	Description    string // Human-readable description
+	MinCPU        int    `json:"min_cpu,omitempty"`     // Minimum vCPU cores
+	DefaultCPU    int    `json:"cpu,omitempty"`         // Default vCPU cores
+	MinRAMGB      int    `json:"min_ram_gb,omitempty"`  // Minimum RAM in GB
+	DefaultRAMGB  int    `json:"ram_gb,omitempty"`      // Default RAM in GB
+	MinDiskGB     int    `json:"min_disk_gb,omitempty"` // Minimum disk in GB
+	DefaultDiskGB int    `json:"disk_gb,omitempty"`     // Default disk in GB
+	NetworkCount  int    `json:"-"`                     // Number of network interfaces
+	ISOPattern    string `json:"-"`                     // Pattern to match ISO filename
+	Description   string `json:"description,omitempty"` // Human-readable description
+
+	RecommendHugepages bool `json:"-"` // Data-plane component that benefits from hugepages + NUMA
+
+	// Boot disk controller the installer expects (empty = scsi). VOS-based
+	// images (Controller, Router, FlexVNF) install onto virtio-blk.
+	DiskBus DiskBus `json:"disk_bus,omitempty"`
+
+	// Firmware and machine type (empty = seabios and i440fx). Releases
+	// that only boot under UEFI need ovmf, usually with q35.
+	BIOS    BIOS    `json:"bios,omitempty"`
+	Machine Machine `json:"machine,omitempty"`
+
+	// Host boot sequencing: Proxmox starts onboot VMs in ascending order,
+	// waiting StartupDelay seconds after each before starting the next
+	StartupOrder int `json:"startup_order,omitempty"`
+	StartupDelay int `json:"startup_delay,omitempty"`
+}
+
+// DiskBus is the controller a VM's boot disk is attached to
+type DiskBus string
+
+const (
+	DiskBusSCSI   DiskBus = "scsi"   // virtio-scsi-pci, scsi0
+	DiskBusVirtio DiskBus = "virtio" // virtio-blk, virtio0
+	DiskBusSATA   DiskBus = "sata"   // sata0
+	DiskBusIDE    DiskBus = "ide"    // ide0 (ide2 is the install CD-ROM)
+)
+
+// ParseDiskBus validates a disk bus name
+func ParseDiskBus(s string) (DiskBus, error) {
+	switch b := DiskBus(strings.ToLower(s)); b {
+	case DiskBusSCSI, DiskBusVirtio, DiskBusSATA, DiskBusIDE:
+		return b, nil
+	default:
+		return "", fmt.Errorf("unknown disk bus %q (expected scsi, virtio, sata or ide)", s)
+	}
+}
+
+// BIOS is a VM's firmware
+type BIOS string
+
+const (
+	BIOSSeaBIOS BIOS = "seabios" // Legacy BIOS, the Proxmox default
+	BIOSOVMF    BIOS = "ovmf"    // UEFI, with an EFI vars disk
+)
+
+// ParseBIOS validates a firmware name
+func ParseBIOS(s string) (BIOS, error) {
+	switch b := BIOS(strings.ToLower(s)); b {
+	case BIOSSeaBIOS, BIOSOVMF:
+		return b, nil
+	default:
+		return "", fmt.Errorf("unknown BIOS %q (expected seabios or ovmf)", s)
+	}
+}
+
+// Machine is a VM's QEMU machine type
+type Machine string
+
+const (
+	MachineI440FX Machine = "i440fx" // The Proxmox default
+	MachineQ35    Machine = "q35"    // PCIe chipset
+)
+
+// ParseMachine validates a machine type name
+func ParseMachine(s string) (Machine, error) {
+	switch m := Machine(strings.ToLower(s)); m {
+	case MachineI440FX, MachineQ35:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown machine type %q (expected i440fx or q35)", s)
+	}
 }
 
 // DefaultVMSpecs contains the default specifications for each Versa component
@@ -38,6 +116,10 @@ var DefaultVMSpecs = map[ComponentType]VMSpec{
 		NetworkCount:  2, // eth0 (northbound), eth1 (southbound/router)
 		ISOPattern:    "versa-director",
 		Description:   "Versa Director - Central management and orchestration",
+
+		StartupOrder: 1,
+		StartupDelay: 120, // Let Director services come up before the rest
+
 	},
 	ComponentAnalytics: {
 		MinCPU:        4,
@@ -49,6 +131,9 @@ var DefaultVMSpecs = map[ComponentType]VMSpec{
 		NetworkCount:  3, // eth0 (northbound), eth1 (southbound), eth2 (cluster - optional)
 		ISOPattern:    "versa-analytics",
 		Description:   "Versa Analytics - Log collection and reporting",
+
+		StartupOrder: 2,
+		StartupDelay: 30,
 	},
 	ComponentController: {
 		MinCPU:        4,
@@ -60,6 +145,10 @@ var DefaultVMSpecs = map[ComponentType]VMSpec{
 		NetworkCount:  5, // eth0 (northbound), eth1 (router), eth2-4 (WAN 1-3)
 		ISOPattern:    "versa-flexvnf",
 		Description:   "Versa Controller - SD-WAN controller",
+
+		DiskBus:      DiskBusVirtio,
+		StartupOrder: 2,
+		StartupDelay: 30,
 	},
 	ComponentConcerto: {
 		MinCPU:        4,
@@ -71,6 +160,9 @@ var DefaultVMSpecs = map[ComponentType]VMSpec{
 		NetworkCount:  2, // eth0 (northbound), eth1 (southbound)
 		ISOPattern:    "concerto",
 		Description:   "Versa Concerto - Multi-tenant orchestration",
+
+		StartupOrder: 2,
+		StartupDelay: 30,
 	},
 	ComponentRouter: {
 		MinCPU:        4,
@@ -82,6 +174,10 @@ var DefaultVMSpecs = map[ComponentType]VMSpec{
 		NetworkCount:  3, // eth0 (northbound), eth1 (to-director), eth2 (to-controller)
 		ISOPattern:    "versa-flexvnf",
 		Description:   "Versa Router - HeadEnd router component",
+
+		RecommendHugepages: true,
+		DiskBus:            DiskBusVirtio,
+		StartupOrder:       3,
 	},
 	ComponentFlexVNF: {
 		MinCPU:        4,
@@ -93,9 +189,50 @@ var DefaultVMSpecs = map[ComponentType]VMSpec{
 		NetworkCount:  3, // eth0 (mgmt), eth1 (wan), eth2 (lan)
 		ISOPattern:    "versa-flexvnf",
 		Description:   "Versa FlexVNF - Branch CPE device",
+
+		DiskBus:      DiskBusVirtio,
+		StartupOrder: 3,
 	},
 }
 
+// ConsoleTools are the serial console tools in their default fallback order
+var ConsoleTools = []string{"socat", "miniterm", "qm"}
+
+// ValidateConsoleTool checks a preferred console tool; empty and "auto" keep
+// the default chain
+func ValidateConsoleTool(tool string) error {
+	tool = strings.ToLower(strings.TrimSpace(tool))
+	if tool == "" || tool == "auto" || slices.Contains(ConsoleTools, tool) {
+		return nil
+	}
+	return fmt.Errorf("unknown console tool %q (expected %s)", tool, strings.Join(ConsoleTools, ", "))
+}
+
+// Feature is an optional VM setting that only works on images that support it
+type Feature string
+
+const (
+	FeatureCloudInit  Feature = "cloud-init"  // User-data from --cloud-init-dir or a cloud-init drive
+	FeatureGuestAgent Feature = "guest-agent" // QEMU guest agent (--guest-agent)
+)
+
+// FeatureMinVersions is the oldest image version, per component, on which a
+// feature works. Older builds boot but ignore or misapply the setting.
+// Components without an entry have no known minimum. Only add a minimum
+// documented in Versa's release notes, and cite them next to the entry;
+// sites that know their own minimums set feature_min_versions in config.json.
+var FeatureMinVersions = map[Feature]map[ComponentType]string{}
+
+// FeatureMinVersion returns the oldest image version of a component that
+// supports a feature, from overrides if it has an entry, else from
+// FeatureMinVersions. Empty means no known minimum.
+func FeatureMinVersion(overrides map[Feature]map[ComponentType]string, f Feature, t ComponentType) string {
+	if v, ok := overrides[f][t]; ok {
+		return v
+	}
+	return FeatureMinVersions[f][t]
+}
+
 // VMTags for deployment tracking
 const (
 	TagVersaDeployer   = "versa-deployer"
@@ -105,8 +242,102 @@ const (
 	TagVersaConcerto   = "versa-concerto"
 	TagVersaRouter     = "versa-router"
 	TagVersaFlexVNF    = "versa-flexvnf"
+
+	// TagVersionPrefix prefixes the deployed ISO version, e.g. versa-version-22.1.4-b
+	TagVersionPrefix = "versa-version-"
+
+	// TagEnvironmentPrefix prefixes the deployment environment, e.g. versa-env-prod
+	TagEnvironmentPrefix = "versa-env-"
+
+	// TagToolVersionPrefix prefixes the deployer build that created a VM, e.g. versa-deployer-v1.4.0
+	TagToolVersionPrefix = "versa-deployer-v"
 )
 
+// TagColors are the Proxmox UI background colors (hex RGB) for component
+// tags, applied by deploy --set-tag-colors
+var TagColors = map[string]string{
+	TagVersaDeployer:   "5a6270",
+	TagVersaDirector:   "1f6feb",
+	TagVersaAnalytics:  "8250df",
+	TagVersaController: "1a7f37",
+	TagVersaConcerto:   "bf3989",
+	TagVersaRouter:     "d4a72c",
+	TagVersaFlexVNF:    "cf4d27",
+}
+
+// VersionTag returns the tag recording a deployed version. Characters Proxmox
+// does not allow in tags are replaced with underscores.
+func VersionTag(version string) string {
+	return TagVersionPrefix + tagValue(version)
+}
+
+// EnvironmentTag returns the tag recording a deployment's environment
+func EnvironmentTag(env string) string {
+	return TagEnvironmentPrefix + tagValue(env)
+}
+
+// EnvironmentFromTags returns the environment recorded by EnvironmentTag, or "" if absent
+func EnvironmentFromTags(tags []string) string {
+	for _, tag := range tags {
+		if env, ok := strings.CutPrefix(tag, TagEnvironmentPrefix); ok && env != "" {
+			return env
+		}
+	}
+	return ""
+}
+
+// ToolVersionTag returns the tag recording the deployer version that created a VM
+func ToolVersionTag(version string) string {
+	return TagToolVersionPrefix + tagValue(strings.TrimPrefix(version, "v"))
+}
+
+// ToolVersionFromTags returns the deployer version recorded by ToolVersionTag, or "" if absent
+func ToolVersionFromTags(tags []string) string {
+	for _, tag := range tags {
+		if v, ok := strings.CutPrefix(tag, TagToolVersionPrefix); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// tagValue lowercases s and replaces characters Proxmox does not allow in
+// tags with underscores
+func tagValue(s string) string {
+	var sb strings.Builder
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '.', r == '_', r == '+':
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune('_')
+		}
+	}
+	return sb.String()
+}
+
+// VersionFromTags returns the version recorded by VersionTag, or "" if absent
+func VersionFromTags(tags []string) string {
+	for _, tag := range tags {
+		if v, ok := strings.CutPrefix(tag, TagVersionPrefix); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// ComponentFromTags returns the component type recorded in a VM's tags
+func ComponentFromTags(tags []string) ComponentType {
+	for _, tag := range tags {
+		for _, ct := range AllComponents() {
+			if tag == GetComponentTag(ct) {
+				return ct
+			}
+		}
+	}
+	return ""
+}
+
 // GetComponentTag returns the tag for a component type
 func GetComponentTag(ct ComponentType) string {
 	switch ct {
@@ -0,0 +1,65 @@
+package config
+
+import "testing"
+
+func TestVersionTagRoundTrip(t *testing.T) {
+	tests := []struct {
+		version string
+		tag     string
+		want    string // version read back from the tag
+	}{
+		{"22.1.4", "versa-version-22.1.4", "22.1.4"},
+		{"22.1.4-b", "versa-version-22.1.4-b", "22.1.4-b"},
+		{"21.2.3+hotfix_1", "versa-version-21.2.3+hotfix_1", "21.2.3+hotfix_1"},
+		// Proxmox lowercases tags, and rejects spaces and slashes
+		{"22.1.4-B", "versa-version-22.1.4-b", "22.1.4-b"},
+		{"22.1 rc/2", "versa-version-22.1_rc_2", "22.1_rc_2"},
+	}
+	for _, tt := range tests {
+		tag := VersionTag(tt.version)
+		if tag != tt.tag {
+			t.Errorf("VersionTag(%q) = %q, want %q", tt.version, tag, tt.tag)
+		}
+		tags := []string{TagVersaDeployer, TagVersaDirector, ToolVersionTag("v1.4.0"), EnvironmentTag("lab"), tag}
+		if got := VersionFromTags(tags); got != tt.want {
+			t.Errorf("VersionFromTags(%q) = %q, want %q", tags, got, tt.want)
+		}
+	}
+
+	for _, tags := range [][]string{nil, {TagVersaDeployer}, {TagVersionPrefix}} {
+		if got := VersionFromTags(tags); got != "" {
+			t.Errorf("VersionFromTags(%q) = %q, want none", tags, got)
+		}
+	}
+	if got := ToolVersionFromTags([]string{VersionTag("22.1.4"), ToolVersionTag("v1.4.0")}); got != "1.4.0" {
+		t.Errorf("ToolVersionFromTags = %q, want 1.4.0", got)
+	}
+}
+
+func TestFeatureMinVersion(t *testing.T) {
+	defaults := FeatureMinVersions
+	defer func() { FeatureMinVersions = defaults }()
+	FeatureMinVersions = map[Feature]map[ComponentType]string{
+		FeatureCloudInit: {ComponentDirector: "21.1.0", ComponentAnalytics: "21.2.0"},
+	}
+	overrides := map[Feature]map[ComponentType]string{
+		FeatureCloudInit:  {ComponentDirector: "22.1.1"},
+		FeatureGuestAgent: {ComponentRouter: "21.3.0"},
+	}
+
+	tests := []struct {
+		feature Feature
+		comp    ComponentType
+		want    string
+	}{
+		{FeatureCloudInit, ComponentDirector, "22.1.1"},
+		{FeatureCloudInit, ComponentAnalytics, "21.2.0"},
+		{FeatureGuestAgent, ComponentRouter, "21.3.0"},
+		{FeatureGuestAgent, ComponentDirector, ""},
+	}
+	for _, tt := range tests {
+		if got := FeatureMinVersion(overrides, tt.feature, tt.comp); got != tt.want {
+			t.Errorf("FeatureMinVersion(%s, %s) = %q, want %q", tt.feature, tt.comp, got, tt.want)
+		}
+	}
+}
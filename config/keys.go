@@ -0,0 +1,165 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// redacted replaces secrets in Redacted output
+const redacted = "********"
+
+// Redacted returns a copy of the config with passwords and tokens masked
+func (c *Config) Redacted() *Config {
+	out := *c
+	if out.LastProxmoxPassword != "" {
+		out.LastProxmoxPassword = redacted
+	}
+	out.ImageSources = RedactSources(c.ImageSources)
+	return &out
+}
+
+// RedactSources returns a copy of image sources with passwords, tokens and
+// custom header values masked, e.g. to show them in the web UI
+func RedactSources(srcs []ImageSource) []ImageSource {
+	out := make([]ImageSource, len(srcs))
+	for i, src := range srcs {
+		if src.Password != "" {
+			src.Password = redacted
+		}
+		if len(src.Headers) > 0 {
+			headers := make(map[string]string, len(src.Headers))
+			for k := range src.Headers {
+				headers[k] = redacted
+			}
+			src.Headers = headers
+		}
+		out[i] = src
+	}
+	return out
+}
+
+// Keys lists the keys accepted by Get and Set: the JSON names of the
+// config's scalar settings, plus custom_images.<component>
+func Keys() []string {
+	var keys []string
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		if name, ok := scalarKey(t.Field(i)); ok {
+			keys = append(keys, name)
+		}
+	}
+	keys = append(keys, "custom_images.<component>")
+	sort.Strings(keys)
+	return keys
+}
+
+// ErrSecret is returned by Get for a secret setting unless showSecret is set
+var ErrSecret = errors.New("setting is a secret")
+
+// keyValidators check settings with a fixed set of values before Set stores
+// them, so a typo fails now rather than at the next connect or console
+var keyValidators = map[string]func(string) error{
+	"console_tool": ValidateConsoleTool,
+}
+
+// Get returns one setting by its config.json key. Secrets, such as the saved
+// Proxmox password, are refused unless showSecret is set.
+func (c *Config) Get(key string, showSecret bool) (string, error) {
+	if comp, ok := strings.CutPrefix(key, "custom_images."); ok {
+		return c.CustomImages[comp], nil
+	}
+
+	v, f, err := c.field(key)
+	if err != nil {
+		return "", err
+	}
+	if f.Tag.Get("secret") == "true" && !showSecret && v.String() != "" {
+		return "", fmt.Errorf("%s: %w", key, ErrSecret)
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int:
+		return strconv.FormatInt(v.Int(), 10), nil
+	default:
+		return v.String(), nil
+	}
+}
+
+// Set changes one setting by its config.json key; an empty value clears it
+func (c *Config) Set(key, value string) error {
+	if comp, ok := strings.CutPrefix(key, "custom_images."); ok {
+		if _, ok := DefaultVMSpecs[ComponentType(comp)]; !ok {
+			return fmt.Errorf("unknown component %q", comp)
+		}
+		if c.CustomImages == nil {
+			c.CustomImages = make(map[string]string)
+		}
+		if value == "" {
+			delete(c.CustomImages, comp)
+		} else {
+			c.CustomImages[comp] = value
+		}
+		return nil
+	}
+
+	v, _, err := c.field(key)
+	if err != nil {
+		return err
+	}
+	if validate := keyValidators[key]; validate != nil && value != "" {
+		if err := validate(value); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		b := false
+		if value != "" {
+			if b, err = strconv.ParseBool(value); err != nil {
+				return fmt.Errorf("%s must be true or false, got %q", key, value)
+			}
+		}
+		v.SetBool(b)
+	case reflect.Int:
+		n := 0
+		if value != "" {
+			if n, err = strconv.Atoi(value); err != nil || n < 0 {
+				return fmt.Errorf("%s must be a number of 0 or more, got %q", key, value)
+			}
+		}
+		v.SetInt(int64(n))
+	default:
+		v.SetString(value)
+	}
+	return nil
+}
+
+// field finds the scalar field whose JSON name is key
+func (c *Config) field(key string) (reflect.Value, reflect.StructField, error) {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if name, ok := scalarKey(t.Field(i)); ok && name == key {
+			return v.Field(i), t.Field(i), nil
+		}
+	}
+	return reflect.Value{}, reflect.StructField{}, fmt.Errorf("unknown config key %q (expected one of: %s)", key, strings.Join(Keys(), ", "))
+}
+
+// scalarKey returns a field's JSON name if it holds a string, bool or int
+func scalarKey(f reflect.StructField) (string, bool) {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return "", false
+	}
+	switch f.Type.Kind() {
+	case reflect.String, reflect.Bool, reflect.Int:
+		return name, true
+	}
+	return "", false
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGetSecret(t *testing.T) {
+	c := &Config{LastProxmoxPassword: "s3cret", LastProxmoxUser: "root"}
+
+	if _, err := c.Get("last_proxmox_password", false); !errors.Is(err, ErrSecret) {
+		t.Errorf("Get(last_proxmox_password) error = %v, want ErrSecret", err)
+	}
+	if got, err := c.Get("last_proxmox_password", true); err != nil || got != "s3cret" {
+		t.Errorf("Get(last_proxmox_password, showSecret) = %q, %v; want the password", got, err)
+	}
+	if got, err := c.Get("last_proxmox_user", false); err != nil || got != "root" {
+		t.Errorf("Get(last_proxmox_user) = %q, %v; want root", got, err)
+	}
+	if got, err := (&Config{}).Get("last_proxmox_password", false); err != nil || got != "" {
+		t.Errorf("Get(unset last_proxmox_password) = %q, %v; want empty", got, err)
+	}
+}
+
+func TestSetValidates(t *testing.T) {
+	tests := []struct {
+		key, value string
+		wantErr    bool
+	}{
+		{"console_tool", "socat", false},
+		{"console_tool", "auto", false},
+		{"console_tool", "", false},
+		{"console_tool", "screen", true},
+		{"downloads_per_source", "3", false},
+		{"downloads_per_source", "-1", true},
+		{"console_log", "yes", true},
+		{"no_such_key", "1", true},
+	}
+	for _, tt := range tests {
+		c := &Config{ConsoleTool: "qm"}
+		err := c.Set(tt.key, tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Set(%s=%q) error = %v, want error %v", tt.key, tt.value, err, tt.wantErr)
+		}
+		if tt.wantErr && tt.key == "console_tool" && c.ConsoleTool != "qm" {
+			t.Errorf("rejected Set(%s=%q) still changed it to %q", tt.key, tt.value, c.ConsoleTool)
+		}
+	}
+}
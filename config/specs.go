@@ -0,0 +1,111 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// LoadVMSpecs reads a specs file, a JSON object of per-component VMSpec
+// overrides such as {"director": {"cpu": 16, "ram_gb": 32}}, and returns
+// DefaultVMSpecs with the overrides merged in
+func LoadVMSpecs(path string) (map[ComponentType]VMSpec, error) {
+	data, err := os.ReadFile(ExpandPath(path))
+	if err != nil {
+		return nil, fmt.Errorf("reading specs file: %w", err)
+	}
+
+	var overrides map[ComponentType]VMSpec
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&overrides); err != nil {
+		return nil, fmt.Errorf("parsing specs file %s: %w", path, err)
+	}
+
+	specs, err := MergeVMSpecs(overrides)
+	if err != nil {
+		return nil, fmt.Errorf("specs file %s: %w", path, err)
+	}
+	return specs, nil
+}
+
+// MergeVMSpecs returns a copy of DefaultVMSpecs with the non-zero fields of
+// each override applied. Minimums may only be raised, and every default must
+// meet its component's minimum.
+func MergeVMSpecs(overrides map[ComponentType]VMSpec) (map[ComponentType]VMSpec, error) {
+	specs := make(map[ComponentType]VMSpec, len(DefaultVMSpecs))
+	for ct, spec := range DefaultVMSpecs {
+		specs[ct] = spec
+	}
+
+	for ct, o := range overrides {
+		spec, ok := specs[ct]
+		if !ok {
+			return nil, fmt.Errorf("unknown component type %q", ct)
+		}
+		builtin := spec
+
+		overrideInt(&spec.MinCPU, o.MinCPU)
+		overrideInt(&spec.DefaultCPU, o.DefaultCPU)
+		overrideInt(&spec.MinRAMGB, o.MinRAMGB)
+		overrideInt(&spec.DefaultRAMGB, o.DefaultRAMGB)
+		overrideInt(&spec.MinDiskGB, o.MinDiskGB)
+		overrideInt(&spec.DefaultDiskGB, o.DefaultDiskGB)
+		overrideInt(&spec.StartupOrder, o.StartupOrder)
+		overrideInt(&spec.StartupDelay, o.StartupDelay)
+		if o.Description != "" {
+			spec.Description = o.Description
+		}
+		if o.DiskBus != "" {
+			bus, err := ParseDiskBus(string(o.DiskBus))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", ct, err)
+			}
+			spec.DiskBus = bus
+		}
+		if o.BIOS != "" {
+			bios, err := ParseBIOS(string(o.BIOS))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", ct, err)
+			}
+			spec.BIOS = bios
+		}
+		if o.Machine != "" {
+			machine, err := ParseMachine(string(o.Machine))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", ct, err)
+			}
+			spec.Machine = machine
+		}
+
+		if spec.MinCPU < builtin.MinCPU || spec.MinRAMGB < builtin.MinRAMGB || spec.MinDiskGB < builtin.MinDiskGB {
+			return nil, fmt.Errorf("%s: minimums cannot go below %d vCPU, %d GB RAM, %d GB disk",
+				ct, builtin.MinCPU, builtin.MinRAMGB, builtin.MinDiskGB)
+		}
+		if spec.DefaultCPU < spec.MinCPU {
+			return nil, fmt.Errorf("%s: cpu %d is below the minimum of %d", ct, spec.DefaultCPU, spec.MinCPU)
+		}
+		if spec.DefaultRAMGB < spec.MinRAMGB {
+			return nil, fmt.Errorf("%s: ram_gb %d is below the minimum of %d", ct, spec.DefaultRAMGB, spec.MinRAMGB)
+		}
+		if spec.DefaultDiskGB < spec.MinDiskGB {
+			return nil, fmt.Errorf("%s: disk_gb %d is below the minimum of %d", ct, spec.DefaultDiskGB, spec.MinDiskGB)
+		}
+
+		if spec.StartupOrder < 0 || spec.StartupDelay < 0 {
+			return nil, fmt.Errorf("%s: startup_order and startup_delay must not be negative", ct)
+		}
+
+		specs[ct] = spec
+	}
+	return specs, nil
+}
+
+// overrideInt replaces *dst with v when v is set; negative values are kept
+// so validation rejects them
+func overrideInt(dst *int, v int) {
+	if v != 0 {
+		*dst = v
+	}
+}
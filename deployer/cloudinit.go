@@ -0,0 +1,206 @@
+package deployer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/config"
+	"github.com/mihailvovk/versa-proxmox-deployer/proxmox"
+)
+
+// CloudInitVars are the values a cloud-init user-data template can use
+type CloudInitVars struct {
+	Name       string // VM name, e.g. lab-analytics-2
+	Component  config.ComponentType
+	Prefix     string
+	Index      int    // 1-based instance number within the component
+	IP         string // Manual IP assigned to this VM, empty if none
+	DirectorIP string
+}
+
+// loadCloudInitTemplates parses <dir>/<component>.yaml for every component
+// being deployed. Components without a template get no cloud-init.
+func loadCloudInitTemplates(dir string, components []config.ComponentConfig) (map[config.ComponentType]*template.Template, error) {
+	templates := make(map[config.ComponentType]*template.Template)
+	for _, comp := range components {
+		if _, seen := templates[comp.Type]; seen {
+			continue
+		}
+		path := filepath.Join(dir, string(comp.Type)+".yaml")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("reading cloud-init template: %w", err)
+		}
+		tmpl, err := template.New(filepath.Base(path)).Parse(string(data))
+		if err != nil {
+			return nil, fmt.Errorf("parsing cloud-init template: %w", err)
+		}
+		templates[comp.Type] = tmpl
+	}
+	return templates, nil
+}
+
+// cloudInitVars returns the template values for one instance of a component
+func (d *Deployer) cloudInitVars(comp config.ComponentConfig, index int) CloudInitVars {
+	name := proxmox.VMName(d.config.Prefix, comp, index)
+	return CloudInitVars{
+		Name:       name,
+		Component:  comp.Type,
+		Prefix:     d.config.Prefix,
+		Index:      index + 1,
+		IP:         d.config.IPConfig.ManualIPs[name],
+		DirectorIP: d.directorIP(),
+	}
+}
+
+// directorIP is the registration Director address, or else the manual IP of
+// the first Director being deployed
+func (d *Deployer) directorIP() string {
+	if d.config.Registration.DirectorIP != "" {
+		return d.config.Registration.DirectorIP
+	}
+	for _, comp := range d.config.Components {
+		if comp.Type == config.ComponentDirector {
+			return d.config.IPConfig.ManualIPs[proxmox.VMName(d.config.Prefix, comp, 0)]
+		}
+	}
+	return ""
+}
+
+// renderCloudInit executes a user-data template and validates the result
+func renderCloudInit(tmpl *template.Template, vars CloudInitVars) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("rendering cloud-init template %s: %w", tmpl.Name(), err)
+	}
+	if err := validateUserData(buf.String()); err != nil {
+		return "", fmt.Errorf("cloud-init template %s for %s: %w", tmpl.Name(), vars.Name, err)
+	}
+	return buf.String(), nil
+}
+
+// validateUserData catches the mistakes that make cloud-init ignore
+// user-data: a missing #cloud-config header, tab indentation, top-level
+// lines that aren't keys, and variables that rendered to nothing. It is not
+// a full YAML parser. Script user-data (#!) is passed through unchecked.
+func validateUserData(data string) error {
+	lines := strings.Split(data, "\n")
+	header := strings.TrimSpace(lines[0])
+	if strings.HasPrefix(header, "#!") {
+		return nil
+	}
+	if header != "#cloud-config" {
+		return fmt.Errorf("user-data must start with #cloud-config, got %q", header)
+	}
+
+	for i, line := range lines[1:] {
+		lineNo := i + 2
+		trimmed := strings.TrimLeft(line, " \t")
+		if strings.Contains(line[:len(line)-len(trimmed)], "\t") {
+			return fmt.Errorf("line %d: tabs are not allowed in YAML indentation", lineNo)
+		}
+		if strings.Contains(line, "<no value>") {
+			return fmt.Errorf("line %d: template variable has no value", lineNo)
+		}
+		trimmed = strings.TrimSpace(trimmed)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || trimmed == "---" {
+			continue
+		}
+		if line[0] != ' ' && !strings.Contains(trimmed, ":") {
+			return fmt.Errorf("line %d: expected a top-level key, got %q", lineNo, trimmed)
+		}
+	}
+	return nil
+}
+
+// validateCloudInit loads the cloud-init templates, renders every VM's
+// user-data and checks a snippets storage is available to hold it
+func (d *Deployer) validateCloudInit(report *ValidationReport) {
+	d.cloudInitTemplates = nil
+	if d.config.CloudInitDir == "" {
+		return
+	}
+
+	templates, err := loadCloudInitTemplates(d.config.CloudInitDir, d.config.Components)
+	if err != nil {
+		report.Errorf("%v", err)
+		return
+	}
+	if len(templates) == 0 {
+		report.Warnf("no cloud-init templates for the selected components in %s", d.config.CloudInitDir)
+		return
+	}
+
+	for _, comp := range d.config.Components {
+		tmpl := templates[comp.Type]
+		if tmpl == nil {
+			continue
+		}
+		usesIP := strings.Contains(tmpl.Tree.Root.String(), ".IP")
+		count := comp.Count
+		if count == 0 {
+			count = 1
+		}
+		for i := 0; i < count; i++ {
+			vars := d.cloudInitVars(comp, i)
+			if _, err := renderCloudInit(tmpl, vars); err != nil {
+				report.Errorf("%v", err)
+				continue
+			}
+			if usesIP && vars.IP == "" {
+				report.Warnf("cloud-init template %s uses .IP but %s has no manual IP", tmpl.Name(), vars.Name)
+			}
+		}
+	}
+
+	storage, err := d.discoverer.SelectSnippetsStorage(d.config.SnippetsStorage)
+	if err != nil {
+		report.Errorf("cloud-init: %v", err)
+		return
+	}
+	nodes := make(map[string]bool)
+	for _, comp := range d.config.Components {
+		nodes[comp.Node] = true
+	}
+	if !storage.Shared && len(nodes) > 1 {
+		report.Warnf("snippets storage %s is not shared; user-data is only written on the connected node", storage.Name)
+	}
+
+	d.cloudInitTemplates = templates
+	d.snippetsStorage = storage.Name
+}
+
+// applyCloudInit uploads a VM's rendered user-data to snippets storage and
+// attaches a cloud-init drive that uses it
+func (d *Deployer) applyCloudInit(tmpl *template.Template, comp config.ComponentConfig, index int, vmConfig proxmox.VMConfig) error {
+	userData, err := renderCloudInit(tmpl, d.cloudInitVars(comp, index))
+	if err != nil {
+		return err
+	}
+
+	filename := fmt.Sprintf("vm-%d-user.yaml", vmConfig.VMID)
+	volume, err := d.storage.UploadSnippet(d.snippetsStorage, filename, []byte(userData))
+	if err != nil {
+		return fmt.Errorf("uploading cloud-init user-data: %w", err)
+	}
+	if vmConfig.CloudInit != nil {
+		// qm create already added the drive; the template replaces only
+		// the generated user-data, so the IP config still applies
+		err = d.vmCreator.SetCICustom(vmConfig.VMID, volume)
+	} else {
+		err = d.vmCreator.SetCloudInit(vmConfig.VMID, vmConfig.Storage, volume)
+	}
+	if err != nil {
+		return fmt.Errorf("attaching cloud-init drive: %w", err)
+	}
+
+	d.log(fmt.Sprintf("%s: cloud-init user-data %s", vmConfig.Name, volume))
+	return nil
+}
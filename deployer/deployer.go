@@ -1,11 +1,20 @@
 package deployer
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"text/template"
 	"time"
 
 	"github.com/mihailvovk/versa-proxmox-deployer/config"
+	"github.com/mihailvovk/versa-proxmox-deployer/director"
 	"github.com/mihailvovk/versa-proxmox-deployer/downloader"
 	"github.com/mihailvovk/versa-proxmox-deployer/proxmox"
 	"github.com/mihailvovk/versa-proxmox-deployer/sources"
@@ -22,6 +31,7 @@ type Deployer struct {
 	config     *config.DeploymentConfig
 	proxmoxInfo *proxmox.ProxmoxInfo
 	knownImages []sources.ISOFile
+	dryRun      bool // Log planned changes instead of making them
 
 	// Rollback tracking
 	createdVMIDs []int
@@ -29,10 +39,46 @@ type Deployer struct {
 	// ISO storage tracking: maps requested ISO filename → resolved location
 	isoResolvedMap map[string]resolvedISO
 
-	// Progress callbacks
+	// ISOs attached to existing VMs: filename → storage, set by prepareImages
+	attachedISOs map[string]string
+
+	// Cloud-init user-data templates and their snippets storage, set by Preflight
+	cloudInitTemplates map[config.ComponentType]*template.Template
+	snippetsStorage    string
+
+	// Director nodes registered before this deployment, set by
+	// snapshotRegistrations
+	knownNodes director.KnownNodes
+
+	// ISOs prepared at once (0 = DefaultConcurrentDownloads)
+	MaxConcurrentDownloads int
+
+	// Cancels remote tasks on shutdown (set by SetContext), and the running
+	// Proxmox-side downloads, keyed by UPID
+	ctx           context.Context
+	downloadsMu   sync.Mutex
+	downloadTasks map[string]*downloadTask
+
+	// Progress callbacks, never called concurrently
 	OnProgress    func(stage string, current, total int)
 	OnLog         func(message string)
 	OnError       func(err error)
+	OnTransfer    func(t TransferProgress)
+	// Called with the running Proxmox-side downloads whenever they change
+	OnDownloadTasks func(tasks []DownloadTask)
+	callbackMu    sync.Mutex
+}
+
+// DefaultConcurrentDownloads is how many ISOs prepareImages fetches at once
+const DefaultConcurrentDownloads = 2
+
+// TransferProgress is byte-level progress of one ISO download or upload
+type TransferProgress struct {
+	Action      string  `json:"action"` // "download" or "upload"
+	Filename    string  `json:"filename"`
+	Done        int64   `json:"done"`
+	Total       int64   `json:"total"`
+	BytesPerSec float64 `json:"bytesPerSec"`
 }
 
 // resolvedISO tracks where an ISO actually lives on Proxmox.
@@ -52,10 +98,14 @@ const (
 	StageVMCreation   DeploymentStage = "vm_creation"
 	StageNetworking   DeploymentStage = "networking"
 	StageStartup      DeploymentStage = "startup"
+	StageRegistration DeploymentStage = "registration"
 	StageRollback     DeploymentStage = "rollback"
 	StageComplete     DeploymentStage = "complete"
 )
 
+// ErrValidation is wrapped by Deploy when the pre-flight validation fails
+var ErrValidation = errors.New("validation failed")
+
 // DeploymentResult holds the result of a deployment
 type DeploymentResult struct {
 	Success      bool
@@ -64,6 +114,13 @@ type DeploymentResult struct {
 	Duration     time.Duration
 	RolledBack   bool
 	ConsoleURLs  map[string]string
+	NotStarted   bool   // VMs were created but intentionally left stopped
+	RemovedVMIDs []int  // VMs destroyed by failed-only cleanup
+	Preserved    bool   // Created VMs were kept for inspection after a failure
+	Status       string // StatusPlanned for a dry run, empty otherwise
+
+	// Director registration of new Analytics/Controller VMs (nil if not checked)
+	Registrations []director.Registration
 }
 
 // VMResult holds the result of a single VM creation
@@ -75,6 +132,14 @@ type VMResult struct {
 	Status      string
 	IP          string
 	ConsoleURL  string
+	Error       string // Why creation or startup failed, if it did
+	Snapshot    string // Pre-boot snapshot name, if one was taken
+
+	// Interfaces held back by ManagementOnlyFirst, added as net1 onwards
+	PendingNetworks []proxmox.VMNetwork
+
+	// As-built NIC map, including any pending interfaces
+	Interfaces []VMInterface
 }
 
 // NewDeployer creates a new deployer
@@ -92,6 +157,10 @@ func NewDeployer(client *ssh.Client, srcs []sources.ImageSource) *Deployer {
 // SetConfig sets the deployment configuration
 func (d *Deployer) SetConfig(cfg *config.DeploymentConfig) {
 	d.config = cfg
+	d.downloader.SetDownloadsPerSource(cfg.DownloadsPerSource)
+	// An invalid order keeps the default; Preflight reports it
+	order, _ := sources.ParseChecksumOrder(cfg.ChecksumSources)
+	d.downloader.SetChecksumOrder(order)
 }
 
 // SetKnownImages sets the scanned ISO images available from sources
@@ -109,17 +178,40 @@ func (d *Deployer) Discover() (*proxmox.ProxmoxInfo, error) {
 	}
 
 	d.proxmoxInfo = info
+	d.vmCreator.SetLocalNode(d.localNode())
 	return info, nil
 }
 
-// Validate validates the deployment configuration against available resources
+// localNode is the name of the node the SSH client is connected to, empty
+// if discovery couldn't tell
+func (d *Deployer) localNode() string {
+	for _, n := range d.proxmoxInfo.Nodes {
+		if n.IsLocal {
+			return n.Name
+		}
+	}
+	return ""
+}
+
+// Validate validates the deployment configuration against available
+// resources, returning every error found as one error
 func (d *Deployer) Validate() error {
+	return d.Preflight().Err()
+}
+
+// Preflight checks the deployment configuration against available resources
+// and collects every problem instead of stopping at the first
+func (d *Deployer) Preflight() *ValidationReport {
+	report := &ValidationReport{}
+
 	if d.config == nil {
-		return fmt.Errorf("no deployment configuration set")
+		report.Errorf("no deployment configuration set")
+		return report
 	}
 
 	if d.proxmoxInfo == nil {
-		return fmt.Errorf("discovery not performed")
+		report.Errorf("discovery not performed")
+		return report
 	}
 
 	d.log("Validating deployment configuration...")
@@ -127,30 +219,134 @@ func (d *Deployer) Validate() error {
 	// Check total resources required
 	totalCPU, totalRAM, totalDisk := d.config.GetTotalResources()
 
-	// Find target storage
-	var targetStorage *proxmox.StorageInfo
-	for _, s := range d.proxmoxInfo.Storage {
-		if s.Name == d.config.StoragePool {
-			targetStorage = &s
-			break
+	// Sum disk needs per target storage (components may override the global pool)
+	diskByStorage := make(map[string]int)
+	var storageOrder []string
+	for _, comp := range d.config.Components {
+		count := comp.Count
+		if count == 0 {
+			count = 1
+		}
+		pool := d.config.StorageFor(comp)
+		if _, seen := diskByStorage[pool]; !seen {
+			storageOrder = append(storageOrder, pool)
+		}
+		diskByStorage[pool] += (comp.DiskGB + comp.DataDisk()) * count
+	}
+
+	for _, pool := range storageOrder {
+		var targetStorage *proxmox.StorageInfo
+		for _, s := range d.proxmoxInfo.Storage {
+			if s.Name == pool {
+				targetStorage = &s
+				break
+			}
+		}
+
+		if targetStorage == nil {
+			report.Errorf("storage pool '%s' not found", pool)
+			continue
+		}
+
+		if !storageHasContent(targetStorage, "images") {
+			report.Errorf("storage pool '%s' does not support VM disk images", pool)
+		}
+
+		if targetStorage.AvailableGB < diskByStorage[pool] {
+			report.Errorf("insufficient storage on '%s': need %dGB but only %dGB available",
+				pool, diskByStorage[pool], targetStorage.AvailableGB)
+		}
+
+		for _, comp := range d.config.Components {
+			if comp.Node != "" && d.config.StorageFor(comp) == pool && !targetStorage.AvailableOn(comp.Node) {
+				report.Errorf("storage pool '%s' is not available on node %s (%s); it is limited to %s",
+					pool, comp.Node, comp.Type, strings.Join(targetStorage.Nodes, ", "))
+			}
+		}
+	}
+
+	// Every component needs an install image or a template to clone
+	for _, comp := range d.config.Components {
+		if comp.ISOPath == "" && comp.TemplateVMID == 0 {
+			report.Errorf("no ISO selected for %s (no source provides a matching image)", comp.Type)
+		}
+	}
+
+	if v := d.proxmoxInfo.PVEVersion(); !v.Supported() {
+		report.Warnf("Proxmox VE %s is older than the minimum supported %d.%d; some features may not work",
+			v, proxmox.MinSupportedVersion.Major, proxmox.MinSupportedVersion.Minor)
+	}
+
+	d.validateHugepages(report)
+	if err := d.assignIPs(); err != nil {
+		report.Errorf("%v", err)
+	}
+	d.validateCloudInit(report)
+	d.validateTemplates(report)
+	d.validateFeatureVersions(report)
+
+	if d.config.CABundle != "" {
+		if _, err := sources.LoadCABundle(d.config.CABundle); err != nil {
+			report.Errorf("%v", err)
 		}
 	}
 
-	if targetStorage == nil {
-		return fmt.Errorf("storage pool '%s' not found", d.config.StoragePool)
+	if err := proxmox.ValidateExtraArgs(d.config.ExtraVMArgs); err != nil {
+		report.Errorf("%v", err)
+	} else if len(d.config.ExtraVMArgs) > 0 {
+		report.Warnf("extra qm create arguments in use (%s); they are passed through unchecked by the deployer",
+			strings.Join(d.config.ExtraVMArgs, " "))
 	}
 
-	if targetStorage.AvailableGB < totalDisk {
-		return fmt.Errorf("insufficient storage: need %dGB but only %dGB available", totalDisk, targetStorage.AvailableGB)
+	for _, comp := range d.config.Components {
+		if comp.DiskBus != "" {
+			if _, err := config.ParseDiskBus(string(comp.DiskBus)); err != nil {
+				report.Errorf("%s: %v", comp.Type, err)
+			}
+		}
+		if comp.BIOS != "" {
+			if _, err := config.ParseBIOS(string(comp.BIOS)); err != nil {
+				report.Errorf("%s: %v", comp.Type, err)
+			}
+		}
+		if comp.Machine != "" {
+			if _, err := config.ParseMachine(string(comp.Machine)); err != nil {
+				report.Errorf("%s: %v", comp.Type, err)
+			}
+		}
+		if comp.DataDiskGB < 0 {
+			report.Errorf("%s: data disk size must not be negative", comp.Type)
+		} else if comp.DataDiskGB > 0 && comp.DataDisk() == 0 {
+			report.Warnf("%s: data disks are only created for Analytics, ignoring %dGB", comp.Type, comp.DataDiskGB)
+		}
+	}
+
+	mtuKeys := make([]string, 0, len(d.config.Networks.MTU))
+	for purpose := range d.config.Networks.MTU {
+		mtuKeys = append(mtuKeys, purpose)
+	}
+	sort.Strings(mtuKeys)
+	for _, purpose := range mtuKeys {
+		if err := proxmox.ValidateMTU(d.config.Networks.MTU[purpose]); err != nil {
+			report.Errorf("network %s: %v", purpose, err)
+		}
 	}
 
-	// Check each target node has enough resources
+	// Sum RAM per target node, then check each node can hold it
+	ramByNode := make(map[string]int)
+	var nodeOrder []string
 	for _, comp := range d.config.Components {
 		node := comp.Node
 		if node == "" && len(d.proxmoxInfo.Nodes) > 0 {
 			node = d.proxmoxInfo.Nodes[0].Name
 		}
+		if _, seen := ramByNode[node]; !seen {
+			nodeOrder = append(nodeOrder, node)
+		}
+		ramByNode[node] += comp.RAMGB * comp.Count
+	}
 
+	for _, node := range nodeOrder {
 		var targetNode *proxmox.NodeInfo
 		for _, n := range d.proxmoxInfo.Nodes {
 			if n.Name == node {
@@ -160,22 +356,125 @@ func (d *Deployer) Validate() error {
 		}
 
 		if targetNode == nil {
-			return fmt.Errorf("node '%s' not found", node)
+			report.Errorf("node '%s' not found", node)
+			continue
 		}
 
 		if targetNode.Status != "online" {
-			return fmt.Errorf("node '%s' is not online", node)
+			report.Errorf("node '%s' is not online", node)
+			continue
 		}
 
 		availableRAM := targetNode.RAMGB - targetNode.RAMUsedGB
-		if comp.RAMGB*comp.Count > availableRAM {
-			return fmt.Errorf("insufficient RAM on node '%s': need %dGB but only %dGB available",
-				node, comp.RAMGB*comp.Count, availableRAM)
+		if ramByNode[node] > availableRAM {
+			report.Errorf("insufficient RAM on node '%s': need %dGB but only %dGB available",
+				node, ramByNode[node], availableRAM)
 		}
 	}
 
-	d.log(fmt.Sprintf("Validation passed: %d vCPU, %dGB RAM, %dGB disk required", totalCPU, totalRAM, totalDisk))
-	return nil
+	if _, err := sources.ParseChecksumOrder(d.config.ChecksumSources); err != nil {
+		report.Errorf("%v", err)
+	}
+
+	// Bridges may still be created before deploy (the web UI does so), so
+	// network problems only warn
+	for _, issue := range ValidateNetworkConfig(d.config.Networks, d.proxmoxInfo.Networks) {
+		report.Warnf("%s", issue)
+	}
+
+	if report.OK() {
+		d.log(fmt.Sprintf("Validation passed: %d vCPU, %dGB RAM, %dGB disk required", totalCPU, totalRAM, totalDisk))
+	}
+	return report
+}
+
+// validateFeatureVersions checks each component's image version meets the
+// minimum for the features enabled on it. An unknown version is only warned
+// about, since custom images carry no version.
+func (d *Deployer) validateFeatureVersions(report *ValidationReport) {
+	for _, comp := range d.config.Components {
+		var features []config.Feature
+		if d.cloudInitTemplates[comp.Type] != nil || comp.CloudInit != nil {
+			features = append(features, config.FeatureCloudInit)
+		}
+		if d.config.GuestAgent {
+			features = append(features, config.FeatureGuestAgent)
+		}
+
+		// Version is only set for images picked from sources; an explicit
+		// iso= names the version in its filename
+		version := comp.Version
+		if version == "" && comp.ISOPath != "" {
+			version = sources.ExtractVersion(filepath.Base(comp.ISOPath))
+		}
+
+		for _, f := range features {
+			minVersion := config.FeatureMinVersion(d.config.FeatureMinVersions, f, comp.Type)
+			if minVersion == "" {
+				continue
+			}
+			if version == "" {
+				report.Warnf("%s needs %s %s or later, but the image version is unknown", f, comp.Type, minVersion)
+				continue
+			}
+			if sources.CompareVersions(version, minVersion) < 0 {
+				report.Errorf("%s requires %s %s or later, but %s is selected", f, comp.Type, minVersion, version)
+			}
+		}
+	}
+}
+
+// validateHugepages checks the host has enough hugepages reserved for every
+// component that requests them, and hints where they are recommended
+func (d *Deployer) validateHugepages(report *ValidationReport) {
+	neededMB := make(map[string]int)
+	for _, comp := range d.config.Components {
+		if comp.Hugepages == "" {
+			if config.DefaultVMSpecs[comp.Type].RecommendHugepages {
+				report.Warnf("%s benefits from hugepages for line-rate forwarding (--component %s:hugepages=2)", comp.Type, comp.Type)
+			}
+			continue
+		}
+		switch comp.Hugepages {
+		case "2", "1024", "any":
+		default:
+			report.Errorf("invalid hugepages %q for %s (expected 2, 1024 or any)", comp.Hugepages, comp.Type)
+			continue
+		}
+		neededMB[comp.Hugepages] += comp.RAMGB * 1024 * comp.Count
+	}
+
+	for size, need := range neededMB {
+		status, err := d.discoverer.GetHugepages(size)
+		if err != nil {
+			report.Errorf("checking hugepages: %v", err)
+			continue
+		}
+		if status.Total == 0 {
+			report.Errorf("hugepages (%sMB) requested but none are configured on the host; "+
+				"reserve them first, e.g. add 'hugepagesz=%sM hugepages=<count>' to the kernel command line and reboot, "+
+				"or write a page count to /sys/kernel/mm/hugepages/hugepages-<size>kB/nr_hugepages", size, size)
+			continue
+		}
+		if status.FreeMB() < need {
+			report.Errorf("insufficient hugepages (%sMB): need %dMB but only %dMB free (%d of %d pages)",
+				size, need, status.FreeMB(), status.Free, status.Total)
+		}
+	}
+}
+
+// storageHasContent reports whether a storage advertises a content type.
+// Storages with unknown content are assumed capable.
+func storageHasContent(s *proxmox.StorageInfo, content string) bool {
+	if len(s.Content) == 0 {
+		return true
+	}
+	for _, c := range s.Content {
+		if c == content {
+			return true
+		}
+	}
+	return false
 }
 
 // Deploy executes the full deployment
@@ -189,73 +488,208 @@ func (d *Deployer) Deploy() (*DeploymentResult, error) {
 		result.Duration = time.Since(startTime)
 	}()
 
-	// Validate first
-	if err := d.Validate(); err != nil {
-		result.Errors = append(result.Errors, err.Error())
-		return result, err
+	// Validate first, reporting every problem at once
+	report := d.Preflight()
+	for _, w := range report.Warnings {
+		d.log("WARNING: " + w)
+	}
+	if !report.OK() {
+		result.Errors = append(result.Errors, report.Errors...)
+		return result, fmt.Errorf("%w: %w", ErrValidation, report.Err())
 	}
 
 	// Prepare images
 	d.progress(StageImagePrep, 0, len(d.config.Components))
 	if err := d.prepareImages(); err != nil {
 		result.Errors = append(result.Errors, err.Error())
-		d.rollback()
-		result.RolledBack = true
+		d.cleanupAfterFailure(result)
 		return result, err
 	}
 
+	if d.config.StartAfterCreate && !d.dryRun {
+		d.snapshotRegistrations()
+	}
+
 	// Create VMs
 	d.progress(StageVMCreation, 0, d.config.VMCount())
 	vmResults, err := d.createVMs()
+	result.VMs = vmResults
 	if err != nil {
 		result.Errors = append(result.Errors, err.Error())
-		d.rollback()
-		result.RolledBack = true
+		d.cleanupAfterFailure(result)
 		return result, err
 	}
-	result.VMs = vmResults
 
-	// Start VMs
-	d.progress(StageStartup, 0, len(vmResults))
-	for i, vm := range vmResults {
-		d.log(fmt.Sprintf("Starting %s...", vm.Name))
-		if err := d.vmCreator.StartVM(vm.VMID); err != nil {
-			d.log(fmt.Sprintf("WARNING: Failed to start %s: %v", vm.Name, err))
-			result.Errors = append(result.Errors, fmt.Sprintf("failed to start %s: %v", vm.Name, err))
-			result.VMs[i].Status = "stopped"
-		} else {
-			// Verify it's actually running
-			status, err := d.vmCreator.GetVMStatus(vm.VMID)
-			if err == nil && status == "running" {
-				result.VMs[i].Status = "running"
-				d.log(fmt.Sprintf("VM %s is running", vm.Name))
-			} else {
-				result.VMs[i].Status = status
-				d.log(fmt.Sprintf("WARNING: VM %s status is '%s' after start (expected 'running')", vm.Name, status))
-			}
+	if d.dryRun {
+		result.Status = StatusPlanned
+		result.Success = true
+		d.log(fmt.Sprintf("Dry run complete: %d VM(s) planned, nothing was changed", len(result.VMs)))
+		d.progress(StageComplete, 1, 1)
+		return result, nil
+	}
+
+	if d.config.SetTagColors {
+		d.applyTagColors()
+	}
+
+	if d.config.SnapshotBeforeBoot {
+		d.snapshotVMs(result)
+	}
+
+	// Start VMs, unless the operator wants to review them before boot
+	if d.config.StartAfterCreate {
+		d.startVMs(result)
+		if d.config.RollbackPolicy == config.RollbackFailedOnly {
+			d.removeFailedVMs(result)
 		}
-		d.progress(StageStartup, i+1, len(vmResults))
+	} else {
+		d.log("Skipping startup: VMs were created but not started")
+		result.NotStarted = true
 	}
 
 	// Generate console URLs
 	for _, vm := range result.VMs {
+		if vm.Status == "removed" {
+			continue
+		}
 		url := d.vmCreator.GetConsoleURL(vm.VMID, d.sshClient.Host())
 		result.ConsoleURLs[vm.Name] = url
 		result.VMs[findVMIndex(result.VMs, vm.VMID)].ConsoleURL = url
 	}
 
+	if d.config.ManagementOnlyFirst {
+		if err := d.recordPendingNetworks(result); err != nil {
+			d.log(fmt.Sprintf("WARNING: could not record pending interfaces: %v", err))
+		}
+	}
+
+	if d.config.StartAfterCreate {
+		d.waitForRegistration(result)
+	}
+
 	result.Success = len(result.Errors) == 0
 	d.progress(StageComplete, 1, 1)
 
 	return result, nil
 }
 
+// directorStartDelayFactor stretches StartDelay after the Director so it is
+// up before the components that register with it
+const directorStartDelayFactor = 3
+
+// startVMs starts every created VM, recording failures in the result.
+// Cancelling the deployment stops it between VMs.
+func (d *Deployer) startVMs(result *DeploymentResult) {
+	d.progress(StageStartup, 0, len(result.VMs))
+	for i, vm := range result.VMs {
+		if i > 0 && d.config.StartDelay > 0 {
+			delay := d.config.StartDelay
+			if result.VMs[i-1].Component == config.ComponentDirector && vm.Component != config.ComponentDirector {
+				delay *= directorStartDelayFactor
+			}
+			d.log(fmt.Sprintf("Waiting %s before starting %s", delay, vm.Name))
+			if err := d.wait(delay); err != nil {
+				d.log(fmt.Sprintf("WARNING: Not starting %s or later VMs: %v", vm.Name, err))
+				result.Errors = append(result.Errors, fmt.Sprintf("startup stopped before %s: %v", vm.Name, err))
+				return
+			}
+		}
+		d.log(fmt.Sprintf("Starting %s...", vm.Name))
+		status, err := d.startVMWithRetry(vm)
+		result.VMs[i].Status = status
+		if err != nil {
+			result.VMs[i].Error = err.Error()
+			d.log(fmt.Sprintf("WARNING: Failed to start %s: %v", vm.Name, err))
+			result.Errors = append(result.Errors, fmt.Sprintf("failed to start %s: %v", vm.Name, err))
+		} else {
+			d.log(fmt.Sprintf("VM %s is running", vm.Name))
+		}
+		d.progress(StageStartup, i+1, len(result.VMs))
+	}
+}
+
+// applyTagColors colors the component tags in the Proxmox UI. It only adds
+// colors for tags that have none, and failure is not fatal.
+func (d *Deployer) applyTagColors() {
+	if v := d.proxmoxInfo.PVEVersion(); !v.SupportsTagStyle() {
+		d.log(fmt.Sprintf("Skipping tag colors: they need Proxmox VE 7.3+ (found %s)", v))
+		return
+	}
+	added, err := d.discoverer.EnsureTagColors(config.TagColors)
+	if err != nil {
+		d.log(fmt.Sprintf("WARNING: could not set tag colors: %v", err))
+		return
+	}
+	if len(added) > 0 {
+		d.log(fmt.Sprintf("Set Proxmox tag colors for %s", strings.Join(added, ", ")))
+	}
+}
+
+// snapshotVMs takes a clean-install snapshot of every created VM so a botched
+// install can be rolled back without recreating the VM. Storage without
+// snapshot support only produces a warning.
+func (d *Deployer) snapshotVMs(result *DeploymentResult) {
+	for i, vm := range result.VMs {
+		if vm.Status != "created" {
+			continue
+		}
+		if err := d.vmCreator.Snapshot(vm.VMID, proxmox.CleanInstallSnapshot); err != nil {
+			d.log(fmt.Sprintf("WARNING: Could not snapshot %s: %v", vm.Name, err))
+			continue
+		}
+		result.VMs[i].Snapshot = proxmox.CleanInstallSnapshot
+		d.log(fmt.Sprintf("Snapshot %q taken of %s", proxmox.CleanInstallSnapshot, vm.Name))
+	}
+}
+
+// startRetryDelay is the pause between start attempts for a VM
+const startRetryDelay = 10 * time.Second
+
+// startVMWithRetry starts a VM and confirms it is running, retrying up to
+// StartRetries times, or until the deployment is cancelled. The returned error
+// carries the Proxmox-reported reason.
+func (d *Deployer) startVMWithRetry(vm VMResult) (string, error) {
+	attempts := d.config.StartRetries + 1
+	var lastErr error
+
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err := d.vmCreator.StartVM(vm.VMID); err != nil {
+			lastErr = err
+		} else {
+			status, err := d.vmCreator.GetVMStatus(vm.VMID)
+			if err == nil && status == "running" {
+				return "running", nil
+			}
+			if reason := d.vmCreator.GetStartFailureReason(vm.VMID); reason != "" {
+				lastErr = fmt.Errorf("%s", reason)
+			} else {
+				lastErr = fmt.Errorf("status is '%s' after start (expected 'running')", status)
+			}
+		}
+
+		if attempt < attempts {
+			d.log(fmt.Sprintf("WARNING: %s did not start (attempt %d/%d): %v, retrying in %s",
+				vm.Name, attempt, attempts, lastErr, startRetryDelay))
+			if err := d.wait(startRetryDelay); err != nil {
+				lastErr = fmt.Errorf("%w (retries stopped: %w)", lastErr, err)
+				break
+			}
+		}
+	}
+
+	status, err := d.vmCreator.GetVMStatus(vm.VMID)
+	if err != nil || status == "" {
+		status = "stopped"
+	}
+	return status, lastErr
+}
+
 // prepareImages ensures all required ISOs are available
 func (d *Deployer) prepareImages() error {
 	// Get unique ISOs needed
 	isoNeeded := make(map[string]bool)
 	for _, comp := range d.config.Components {
-		if comp.ISOPath != "" {
+		if comp.ISOPath != "" && comp.TemplateVMID == 0 {
 			isoNeeded[comp.ISOPath] = true
 		}
 	}
@@ -268,116 +702,314 @@ func (d *Deployer) prepareImages() error {
 	// Preferred upload target is the first ISO storage
 	uploadStorName := isoStorages[0].Name
 
+	// ISOs existing VMs boot from are already on the node
+	d.attachedISOs, err = d.discoverer.GetAttachedISOs()
+	if err != nil {
+		d.log(fmt.Sprintf("WARNING: could not read ISOs attached to existing VMs: %v", err))
+	}
+
+	if d.dryRun {
+		return d.planImages(isoNeeded, isoStorages, uploadStorName)
+	}
+
+	// Downloads on Proxmox verify TLS against the CA bundle when one is set
+	if d.config.CABundle != "" {
+		pem, err := os.ReadFile(d.config.CABundle)
+		if err != nil {
+			return fmt.Errorf("reading CA bundle: %w", err)
+		}
+		if err := d.storage.SetCABundle(pem); err != nil {
+			return err
+		}
+	}
+
 	// Track which storage and filename each ISO resolves to on Proxmox
 	d.isoResolvedMap = make(map[string]resolvedISO)
 
-	// Check/upload each ISO
-	i := 0
-	for isoFile := range isoNeeded {
-		d.progress(StageImagePrep, i, len(isoNeeded))
-		d.log(fmt.Sprintf("Checking ISO: %s", isoFile))
+	// Check/upload the ISOs, a few at a time. Each worker resolves whole
+	// ISOs, so direct downloads are still tried first per ISO.
+	names := make([]string, 0, len(isoNeeded))
+	for name := range isoNeeded {
+		names = append(names, name)
+	}
+	sort.Strings(names)
 
-		// 1. Check if ISO already exists by exact filename on any storage
-		foundOn, _ := d.storage.ISOExistsOnAny(isoStorages, isoFile)
-		if foundOn != "" {
-			d.log(fmt.Sprintf("ISO already on Proxmox (%s): %s", foundOn, isoFile))
-			d.isoResolvedMap[isoFile] = resolvedISO{Storage: foundOn, Filename: isoFile}
-			i++
-			continue
-		}
+	workers := d.MaxConcurrentDownloads
+	if workers <= 0 {
+		workers = DefaultConcurrentDownloads
+	}
+	if workers > len(names) {
+		workers = len(names)
+	}
 
-		// Find the ISOFile metadata for this filename
-		var isoMeta *sources.ISOFile
-		for idx := range d.knownImages {
-			if d.knownImages[idx].Filename == isoFile {
-				isoMeta = &d.knownImages[idx]
-				break
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+		done     int
+	)
+	jobs := make(chan string)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for isoFile := range jobs {
+				resolved, err := d.prepareImage(isoFile, isoStorages, uploadStorName)
+				mu.Lock()
+				if err != nil {
+					if firstErr == nil {
+						firstErr = err
+					}
+				} else {
+					d.isoResolvedMap[isoFile] = resolved
+				}
+				done++
+				d.progress(StageImagePrep, done, len(names))
+				mu.Unlock()
 			}
+		}()
+	}
+	for _, isoFile := range names {
+		// Stop handing out ISOs after a failure; ones in progress finish
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			break
 		}
+		jobs <- isoFile
+	}
+	close(jobs)
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+
+	return d.distributeISOs(isoStorages)
+}
+
+// attachedISO returns the storage of an ISO an existing VM references, if
+// the volume is still there. A VM config can outlive the ISO it names, and
+// trusting it would skip the download and fail the attach check later.
+func (d *Deployer) attachedISO(isoFile string) (string, bool) {
+	storage, ok := d.attachedISOs[isoFile]
+	if !ok {
+		return "", false
+	}
+	exists, err := d.storage.ISOExists(storage, isoFile)
+	if err != nil || !exists {
+		d.log(fmt.Sprintf("Existing VM references %s:iso/%s, but it isn't on the storage; not reusing it", storage, isoFile))
+		return "", false
+	}
+	return storage, true
+}
+
+// prepareImage makes one ISO available on Proxmox storage and returns where
+// it lives. prepareImages runs it for several ISOs at once.
+func (d *Deployer) prepareImage(isoFile string, isoStorages []proxmox.StorageInfo, uploadStorName string) (resolvedISO, error) {
+	d.log(fmt.Sprintf("Checking ISO: %s", isoFile))
 
-		if isoMeta == nil {
-			return fmt.Errorf("ISO metadata not found for %s — ensure image sources are configured", isoFile)
+	if storage, ok := d.attachedISO(isoFile); ok {
+		d.log(fmt.Sprintf("ISO already attached to an existing VM (%s): %s", storage, isoFile))
+		return resolvedISO{Storage: storage, Filename: isoFile}, nil
+	}
+
+	// 1. Check if ISO already exists on any storage, tolerating cosmetic
+	// filename differences (case, whitespace, URL encoding)
+	foundOn, foundFile, _ := d.storage.FindISOTolerant(isoStorages, isoFile)
+	if foundOn != "" {
+		if foundFile != isoFile {
+			d.log(fmt.Sprintf("ISO already on Proxmox (%s) as %q, matching %q", foundOn, foundFile, isoFile))
+		} else {
+			d.log(fmt.Sprintf("ISO already on Proxmox (%s): %s", foundOn, isoFile))
 		}
+		return resolvedISO{Storage: foundOn, Filename: foundFile}, nil
+	}
 
-		// 2. Check if same content exists under a different filename (MD5 match)
-		if isoMeta.MD5 != "" {
-			d.log(fmt.Sprintf("Checking for existing ISO by MD5 (%s)...", isoMeta.MD5[:8]))
-			stor, existingFile, err := d.storage.FindISOByMD5(isoStorages, isoMeta.MD5)
-			if err == nil {
-				d.log(fmt.Sprintf("Found matching ISO by MD5 on %s: %s (reusing for %s)", stor, existingFile, isoFile))
-				d.isoResolvedMap[isoFile] = resolvedISO{Storage: stor, Filename: existingFile}
-				i++
-				continue
-			}
+	// Find the ISOFile metadata for this filename
+	isoMeta := d.findKnownImage(isoFile)
+
+	if isoMeta == nil {
+		return resolvedISO{}, fmt.Errorf("ISO metadata not found for %s — ensure image sources are configured", isoFile)
+	}
+
+	// Companion .sha256/.md5 files are only flagged by the scan for remote
+	// sources; fetch their values now that this image is needed
+	d.downloader.FillChecksums(isoMeta)
+
+	// 2. Check if same content exists under a different filename,
+	// preferring a SHA256 match over MD5
+	if isoMeta.SHA256 != "" {
+		d.log(fmt.Sprintf("Checking for existing ISO by SHA256 (%s)...", isoMeta.SHA256[:8]))
+		stor, existingFile, err := d.storage.FindISOBySHA256(isoStorages, isoMeta.SHA256)
+		if err == nil {
+			d.log(fmt.Sprintf("Found matching ISO by SHA256 on %s: %s (reusing for %s)", stor, existingFile, isoFile))
+			return resolvedISO{Storage: stor, Filename: existingFile}, nil
+		}
+	}
+	if isoMeta.MD5 != "" {
+		d.log(fmt.Sprintf("Checking for existing ISO by MD5 (%s)...", isoMeta.MD5[:8]))
+		stor, existingFile, err := d.storage.FindISOByMD5(isoStorages, isoMeta.MD5)
+		if err == nil {
+			d.log(fmt.Sprintf("Found matching ISO by MD5 on %s: %s (reusing for %s)", stor, existingFile, isoFile))
+			return resolvedISO{Storage: stor, Filename: existingFile}, nil
 		}
+	}
 
-		// 3. Try direct download to Proxmox (skips local download + SCP)
-		if sources.SupportsDirectDownload(*isoMeta) {
-			node := d.proxmoxInfo.Nodes[0].Name
-			directOK := false
+	// 3. Try direct download to Proxmox (skips local download + SCP)
+	if sources.SupportsDirectDownload(*isoMeta) {
+		node := d.proxmoxInfo.Nodes[0].Name
+		directOK := false
 
-			// Try 3a: Proxmox native download-url API (pvesh)
+		// Try 3a: Proxmox native download-url API (pvesh, PVE 7.0+)
+		var err error
+		if pveVersion := d.proxmoxInfo.PVEVersion(); pveVersion.SupportsDownloadURL() {
 			d.log(fmt.Sprintf("Attempting direct download on Proxmox (pvesh): %s", isoFile))
-			err := d.storage.DownloadISOFromURL(node, uploadStorName, isoFile, isoMeta.SourceURL, d.log)
+			// Task output doesn't name the ISO, which matters when several download at once
+			taskLog := func(msg string) { d.log(isoFile + ": " + msg) }
+			err = d.downloadOnProxmox(node, uploadStorName, isoFile, isoMeta.SourceURL, taskLog)
+			if ctxErr := d.context().Err(); ctxErr != nil {
+				return resolvedISO{}, fmt.Errorf("downloading ISO %s: %w", isoFile, ctxErr)
+			}
+			if errors.Is(err, context.Canceled) {
+				// Stopped with CancelDownload: the mirror is likely stalled,
+				// so skip the wget/curl fallback, which would use it too
+				d.log(fmt.Sprintf("Download of %s on Proxmox cancelled, falling back to local download + upload", isoFile))
+			} else if err != nil {
+				d.log(fmt.Sprintf("pvesh download-url failed for %s: %s", isoFile, err.Error()))
+			}
+		} else {
+			err = fmt.Errorf("download-url requires PVE 7.0+")
+			d.log(fmt.Sprintf("Skipping pvesh download-url on PVE %s (requires 7.0+)", pveVersion))
+		}
+		if err == nil {
+			directOK = true
+		} else if !errors.Is(err, context.Canceled) {
+			// Try 3b: wget/curl fallback
+			d.log(fmt.Sprintf("Trying wget/curl fallback for %s...", isoFile))
+			err = d.storage.DownloadISODirect(uploadStorName, isoFile, isoMeta.SourceURL, isoMeta.Size)
 			if err == nil {
 				directOK = true
 			} else {
-				d.log(fmt.Sprintf("pvesh download-url failed: %s", err.Error()))
-
-				// Try 3b: wget/curl fallback
-				d.log("Trying wget/curl fallback...")
-				err = d.storage.DownloadISODirect(uploadStorName, isoFile, isoMeta.SourceURL, isoMeta.Size)
-				if err == nil {
-					directOK = true
-				} else {
-					d.log(fmt.Sprintf("Direct download failed, falling back to local download + upload: %s", err.Error()))
-				}
+				d.log(fmt.Sprintf("Direct download of %s failed, falling back to local download + upload: %s", isoFile, err.Error()))
 			}
+		}
 
-			// Verify the ISO actually landed on storage before moving on
-			if directOK {
-				found, verifyErr := d.storage.ISOExists(uploadStorName, isoFile)
-				if verifyErr == nil && found {
-					d.log(fmt.Sprintf("Direct download successful: %s", isoFile))
-					d.isoResolvedMap[isoFile] = resolvedISO{Storage: uploadStorName, Filename: isoFile}
-					i++
-					continue
-				}
-				d.log("Direct download reported success but ISO not found on storage, falling back to SCP")
+		// Verify the ISO actually landed on storage before moving on
+		if directOK {
+			found, verifyErr := d.storage.ISOExists(uploadStorName, isoFile)
+			if verifyErr == nil && found {
+				verifyErr = d.verifyISOChecksum(uploadStorName, isoFile, isoMeta)
+			}
+			if verifyErr != nil {
+				d.log(fmt.Sprintf("Direct download of %s failed verification, falling back to SCP: %s", isoFile, verifyErr.Error()))
+				d.storage.DeleteISO(uploadStorName, isoFile)
+			} else if found {
+				d.log(fmt.Sprintf("Direct download successful: %s", isoFile))
+				return resolvedISO{Storage: uploadStorName, Filename: isoFile}, nil
+			} else {
+				d.log(fmt.Sprintf("Direct download of %s reported success but the ISO is not on storage, falling back to SCP", isoFile))
 			}
 		}
+	}
 
-		// 4. Fallback: download locally then upload via SCP
-		d.log(fmt.Sprintf("Downloading ISO: %s (source: %s, size: %s)", isoFile, isoMeta.SourceName, formatBytes(isoMeta.Size)))
-		dlResult, err := d.downloader.EnsureISO(*isoMeta, makeThrottledProgress(d, "Download", isoFile))
-		if err != nil {
-			return fmt.Errorf("downloading ISO %s: %w", isoFile, err)
-		}
+	// 4. Fallback: download locally then upload via SCP
+	d.log(fmt.Sprintf("Downloading ISO: %s (source: %s, size: %s)", isoFile, isoMeta.SourceName, formatBytes(isoMeta.Size)))
+	dlResult, err := d.downloader.EnsureISO(*isoMeta, makeThrottledProgress(d, "Download", isoFile))
+	if err != nil {
+		return resolvedISO{}, fmt.Errorf("downloading ISO %s: %w", isoFile, err)
+	}
 
-		if dlResult.WasCached {
-			d.log(fmt.Sprintf("ISO already cached locally: %s (size: %s, MD5 verified: %v)", isoFile, formatBytes(dlResult.Size), dlResult.MD5Verified))
-		} else {
-			d.log(fmt.Sprintf("ISO downloaded: %s (size: %s, MD5 verified: %v)", isoFile, formatBytes(dlResult.Size), dlResult.MD5Verified))
+	if dlResult.WasCached {
+		d.log(fmt.Sprintf("ISO already cached locally: %s (size: %s, %s)", isoFile, formatBytes(dlResult.Size), checksumStatus(dlResult)))
+	} else {
+		d.log(fmt.Sprintf("ISO downloaded: %s from %s (size: %s, %s)", isoFile, dlResult.SourceName, formatBytes(dlResult.Size), checksumStatus(dlResult)))
+		if dlResult.Resumed > 0 {
+			d.log(fmt.Sprintf("Resumed an interrupted download of %s (%s already on disk)", isoFile, formatBytes(dlResult.Resumed)))
 		}
+	}
 
-		// Upload to Proxmox via SCP
-		d.log(fmt.Sprintf("Uploading to Proxmox storage '%s': %s (%s)", uploadStorName, isoFile, formatBytes(dlResult.Size)))
-		if err := d.storage.UploadISO(dlResult.LocalPath, uploadStorName, makeThrottledProgress(d, "Upload", isoFile)); err != nil {
-			return fmt.Errorf("uploading ISO %s: %w", isoFile, err)
-		}
-		d.log(fmt.Sprintf("Upload complete: %s", isoFile))
-		d.isoResolvedMap[isoFile] = resolvedISO{Storage: uploadStorName, Filename: isoFile}
+	if isoMeta.MD5 == "" && isoMeta.SHA256 == "" {
+		isoMeta.MD5 = dlResult.MD5 // Computed: lets the description record it
+	}
 
-		i++
+	// Upload to Proxmox via SCP
+	d.log(fmt.Sprintf("Uploading to Proxmox storage '%s': %s (%s)", uploadStorName, isoFile, formatBytes(dlResult.Size)))
+	if err := d.storage.UploadISO(dlResult.LocalPath, uploadStorName, makeThrottledProgress(d, "Upload", isoFile)); err != nil {
+		return resolvedISO{}, fmt.Errorf("uploading ISO %s: %w", isoFile, err)
 	}
+	// The upload keeps the local file's name, which a tolerant match may
+	// have made differ from isoFile
+	uploadedFile := filepath.Base(dlResult.LocalPath)
+	d.log(fmt.Sprintf("Upload complete: %s", uploadedFile))
+	return resolvedISO{Storage: uploadStorName, Filename: uploadedFile}, nil
+}
 
+// verifyISOChecksum checks an ISO on Proxmox storage against the image's
+// SHA256, or its MD5 when no SHA256 is known
+func (d *Deployer) verifyISOChecksum(storage, filename string, iso *sources.ISOFile) error {
+	var ok bool
+	var err error
+	switch {
+	case iso.SHA256 != "":
+		d.log(fmt.Sprintf("Verifying SHA256 of %s on Proxmox...", filename))
+		ok, err = d.storage.VerifyISOSHA256(storage, filename, iso.SHA256)
+	case iso.MD5 != "":
+		d.log(fmt.Sprintf("Verifying MD5 of %s on Proxmox...", filename))
+		ok, err = d.storage.VerifyISOMD5(storage, filename, iso.MD5)
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("verifying checksum: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("checksum mismatch for %s", filename)
+	}
 	return nil
 }
 
-// makeThrottledProgress returns a progress callback that logs at most every 10 seconds
+// checksumStatus describes which checksum a download was verified against
+func checksumStatus(r *downloader.DownloadResult) string {
+	switch {
+	case r.SHA256Verified:
+		return "SHA256 verified"
+	case r.MD5Verified:
+		return "MD5 verified"
+	case r.MD5 != "":
+		return "MD5 computed"
+	}
+	return "no checksum"
+}
+
+// findKnownImage returns the scanned image for a filename, falling back to
+// a tolerant match (see proxmox.NormalizeISOName) when no name is exact
+func (d *Deployer) findKnownImage(filename string) *sources.ISOFile {
+	for i := range d.knownImages {
+		if d.knownImages[i].Filename == filename {
+			return &d.knownImages[i]
+		}
+	}
+	want := proxmox.NormalizeISOName(filename)
+	for i := range d.knownImages {
+		if proxmox.NormalizeISOName(d.knownImages[i].Filename) == want {
+			d.log(fmt.Sprintf("Using source image %q for %q (filenames differ only cosmetically)", d.knownImages[i].Filename, filename))
+			return &d.knownImages[i]
+		}
+	}
+	return nil
+}
+
+// transferEventInterval throttles OnTransfer updates for one file
+const transferEventInterval = time.Second
+
+// makeThrottledProgress returns a progress callback that logs at most every
+// 20 seconds and reports OnTransfer at most every transferEventInterval
 func makeThrottledProgress(d *Deployer, action, filename string) func(done, total int64) {
 	var mu sync.Mutex
 	lastLog := time.Time{}
+	lastEvent := time.Now()
+	var lastDone int64
 	return func(done, total int64) {
 		if total <= 0 {
 			return
@@ -390,7 +1022,96 @@ func makeThrottledProgress(d *Deployer, action, filename string) func(done, tota
 			d.log(fmt.Sprintf("  %s %s: %d%% (%s / %s)", action, filename, pct, formatBytes(done), formatBytes(total)))
 			lastLog = now
 		}
+		if d.OnTransfer != nil {
+			if elapsed := now.Sub(lastEvent); elapsed >= transferEventInterval || done >= total {
+				t := TransferProgress{
+					Action:   strings.ToLower(action),
+					Filename: filename,
+					Done:     done,
+					Total:    total,
+				}
+				// Two callbacks in the same instant have no measurable rate
+				if elapsed > 0 {
+					t.BytesPerSec = float64(done-lastDone) / elapsed.Seconds()
+				}
+				d.callbackMu.Lock()
+				d.OnTransfer(t)
+				d.callbackMu.Unlock()
+				lastEvent = now
+				lastDone = done
+			}
+		}
+	}
+}
+
+// distributeISOs makes each resolved ISO reachable from every node that
+// boots a VM from it. An ISO on a node-local storage is copied once to a
+// shared ISO storage when there is one, otherwise to each node's own copy
+// of the storage.
+func (d *Deployer) distributeISOs(isoStorages []proxmox.StorageInfo) error {
+	if !d.proxmoxInfo.IsCluster {
+		return nil
+	}
+	local := d.localNode()
+	if local == "" {
+		d.log("WARNING: connected node unknown, not copying ISOs to other nodes")
+		return nil
+	}
+
+	byName := make(map[string]proxmox.StorageInfo)
+	var shared *proxmox.StorageInfo
+	for i, s := range isoStorages {
+		byName[s.Name] = s
+		if s.Shared && shared == nil {
+			shared = &isoStorages[i]
+		}
+	}
+
+	// Nodes other than the connected one that need each ISO
+	needed := make(map[string][]string)
+	seen := make(map[string]bool)
+	for _, comp := range d.config.Components {
+		if comp.ISOPath == "" || comp.TemplateVMID != 0 || comp.Node == "" || comp.Node == local || seen[comp.ISOPath+"@"+comp.Node] {
+			continue
+		}
+		seen[comp.ISOPath+"@"+comp.Node] = true
+		needed[comp.ISOPath] = append(needed[comp.ISOPath], comp.Node)
+	}
+
+	for isoFile, nodes := range needed {
+		resolved, ok := d.isoResolvedMap[isoFile]
+		if !ok || byName[resolved.Storage].Shared {
+			continue
+		}
+
+		if shared != nil {
+			d.log(fmt.Sprintf("Copying ISO %s from node-local %s to shared storage %s", resolved.Filename, resolved.Storage, shared.Name))
+			if err := d.storage.CopyISO(resolved.Storage, shared.Name, resolved.Filename); err != nil {
+				return fmt.Errorf("copying ISO %s: %w", resolved.Filename, err)
+			}
+			d.isoResolvedMap[isoFile] = resolvedISO{Storage: shared.Name, Filename: resolved.Filename}
+			continue
+		}
+
+		for _, node := range nodes {
+			if !byName[resolved.Storage].AvailableOn(node) {
+				return fmt.Errorf("ISO %s is on storage %s, which is not available on node %s", resolved.Filename, resolved.Storage, node)
+			}
+			found, err := d.storage.ISOExistsOnNode(node, resolved.Storage, resolved.Filename)
+			if err != nil {
+				return err
+			}
+			if found {
+				d.log(fmt.Sprintf("ISO already on node %s (%s): %s", node, resolved.Storage, resolved.Filename))
+				continue
+			}
+			d.log(fmt.Sprintf("Copying ISO %s to node %s (%s is not shared)", resolved.Filename, node, resolved.Storage))
+			if err := d.storage.CopyISOToNode(resolved.Storage, resolved.Filename, node); err != nil {
+				return fmt.Errorf("copying ISO %s to node %s: %w", resolved.Filename, node, err)
+			}
+		}
 	}
+	return nil
 }
 
 func formatBytes(b int64) string {
@@ -426,7 +1147,7 @@ func (d *Deployer) createVMs() ([]VMResult, error) {
 				isoFilename = resolved.Filename
 			}
 		}
-		if isoStorName == "" {
+		if isoStorName == "" && comp.TemplateVMID == 0 {
 			// Fallback: pick first ISO-capable storage (most available space)
 			isoStorage, err := d.discoverer.GetISOStorage()
 			if err != nil || len(isoStorage) == 0 {
@@ -447,6 +1168,17 @@ func (d *Deployer) createVMs() ([]VMResult, error) {
 			// Build network configuration
 			networks := proxmox.BuildNetworksForComponent(comp.Type, d.config.Networks, d.config.HAMode)
 
+			// Phased bring-up: create with the management NIC only
+			var pendingNets []proxmox.VMNetwork
+			if d.config.ManagementOnlyFirst && len(networks) > 1 {
+				if networks[0].Name == string(proxmox.NetworkNorthbound) {
+					pendingNets = networks[1:]
+					networks = networks[:1]
+				} else {
+					d.log(fmt.Sprintf("WARNING: %s interface order does not start with management, creating all interfaces", comp.Type))
+				}
+			}
+
 			// Add HA network for Router if in HA mode
 			if comp.Type == config.ComponentRouter && d.config.HAMode && i > 0 {
 				// This is the second router in HA pair, needs HA sync interface
@@ -458,16 +1190,32 @@ func (d *Deployer) createVMs() ([]VMResult, error) {
 				comp,
 				d.config.Prefix,
 				i,
-				d.config.StoragePool,
+				d.config.StorageFor(comp),
 				isoStorName,
 				networks,
 				vmid,
 			)
+			vmConfig.ExtraArgs = d.config.ExtraVMArgs
+			vmConfig.GuestAgent = d.config.GuestAgent
+			d.applyAssignedIP(&vmConfig)
+			if d.config.Environment != "" {
+				vmConfig.Tags = append(vmConfig.Tags, config.EnvironmentTag(d.config.Environment))
+			}
 
 			// Override ISO filename if resolved to a different name (e.g. MD5 match)
 			if isoFilename != comp.ISOPath {
 				vmConfig.ISOFile = isoFilename
 			}
+			if comp.TemplateVMID != 0 {
+				vmConfig.ISOFile = ""
+			}
+
+			// Write deployment metadata and image provenance into the VM notes
+			if desc, err := d.buildDescription(comp, vmConfig); err != nil {
+				d.log(fmt.Sprintf("WARNING: %v, using default description", err))
+			} else {
+				vmConfig.Description = desc
+			}
 
 			// Set target node
 			if comp.Node != "" {
@@ -476,29 +1224,98 @@ func (d *Deployer) createVMs() ([]VMResult, error) {
 				vmConfig.Node = d.proxmoxInfo.Nodes[0].Name
 			}
 
-			d.log(fmt.Sprintf("Creating VM: %s (VMID %d) on %s", vmConfig.Name, vmid, vmConfig.Node))
+			if d.dryRun {
+				result, err := d.planVM(comp, vmConfig, pendingNets)
+				if err != nil {
+					return results, err
+				}
+				results = append(results, result)
+				vmIndex++
+				continue
+			}
+
+			// /cluster/nextid can hand the same ID to racing deployments, and
+			// VMs placed on other nodes aren't always visible to it yet
+			if free, err := d.freeVMID(vmid); err != nil {
+				return results, err
+			} else if free != vmid {
+				vmid = free
+				vmConfig.VMID = vmid
+			}
+
+			create := d.vmCreator.CreateVM
+			if comp.TemplateVMID != 0 {
+				d.log(fmt.Sprintf("Cloning VM: %s (VMID %d) from template %d on %s", vmConfig.Name, vmid, comp.TemplateVMID, vmConfig.Node))
+				create = func(cfg proxmox.VMConfig) error { return d.cloneVM(comp, cfg) }
+			} else {
+				d.log(fmt.Sprintf("Creating VM: %s (VMID %d) on %s", vmConfig.Name, vmid, vmConfig.Node))
+			}
 
-			// Create the VM
-			if err := d.vmCreator.CreateVM(vmConfig); err != nil {
+			if err := create(vmConfig); err != nil {
+				d.discoverer.ReleaseVMID(vmid)
+				results = append(results, VMResult{
+					VMID:      vmid,
+					Name:      vmConfig.Name,
+					Component: comp.Type,
+					Node:      vmConfig.Node,
+					Status:    "failed",
+					Error:     err.Error(),
+				})
 				return results, fmt.Errorf("creating VM %s: %w", vmConfig.Name, err)
 			}
 
 			// Track for rollback
 			d.createdVMIDs = append(d.createdVMIDs, vmid)
 
+			// Catch a bad storage/filename resolution now rather than as a VM
+			// that boots to no media
+			if vmConfig.ISOFile != "" {
+				if err := d.verifyAttachedISO(vmid, vmConfig); err != nil {
+					results = append(results, VMResult{
+						VMID:      vmid,
+						Name:      vmConfig.Name,
+						Component: comp.Type,
+						Node:      vmConfig.Node,
+						Status:    "failed",
+						Error:     err.Error(),
+					})
+					return results, fmt.Errorf("VM %s: %w", vmConfig.Name, err)
+				}
+			}
+
+			if tmpl := d.cloudInitTemplates[comp.Type]; tmpl != nil {
+				if err := d.applyCloudInit(tmpl, comp, i, vmConfig); err != nil {
+					results = append(results, VMResult{
+						VMID:      vmid,
+						Name:      vmConfig.Name,
+						Component: comp.Type,
+						Node:      vmConfig.Node,
+						Status:    "failed",
+						Error:     err.Error(),
+					})
+					return results, fmt.Errorf("VM %s: %w", vmConfig.Name, err)
+				}
+			}
+
 			// Get assigned IP if configured
 			ip := ""
 			if d.config.IPConfig.ManualIPs != nil {
 				ip = d.config.IPConfig.ManualIPs[vmConfig.Name]
 			}
 
+			if len(pendingNets) > 0 {
+				d.log(fmt.Sprintf("%s created with management interface only, %d interface(s) pending", vmConfig.Name, len(pendingNets)))
+			}
+
 			results = append(results, VMResult{
-				VMID:      vmid,
-				Name:      vmConfig.Name,
-				Component: comp.Type,
-				Node:      vmConfig.Node,
-				Status:    "created",
-				IP:        ip,
+				VMID:            vmid,
+				Name:            vmConfig.Name,
+				Component:       comp.Type,
+				Node:            vmConfig.Node,
+				Status:          "created",
+				IP:              ip,
+				PendingNetworks: pendingNets,
+				Interfaces:      vmInterfaces(vmConfig.Networks, pendingNets),
 			})
 
 			vmIndex++
@@ -508,6 +1325,109 @@ func (d *Deployer) createVMs() ([]VMResult, error) {
 	return results, nil
 }
 
+// maxVMIDConflicts bounds how many taken VMIDs freeVMID skips
+const maxVMIDConflicts = 10
+
+// freeVMID verifies an allocated VMID is unused across the cluster right
+// before it is created, moving on to the next allocation while it is taken.
+// This is the one place VMID collisions are handled.
+func (d *Deployer) freeVMID(vmid int) (int, error) {
+	for attempt := 0; attempt < maxVMIDConflicts; attempt++ {
+		exists, err := d.vmCreator.VMIDExists(vmid)
+		if err != nil {
+			d.discoverer.ReleaseVMID(vmid)
+			return 0, err
+		}
+		if !exists {
+			return vmid, nil
+		}
+
+		// The taken ID stays reserved until the next one is allocated, so
+		// GetNextVMID can't hand it out again
+		taken := vmid
+		vmid, err = d.discoverer.GetNextVMID()
+		d.discoverer.ReleaseVMID(taken)
+		if err != nil {
+			return 0, fmt.Errorf("getting next VMID: %w", err)
+		}
+		d.log(fmt.Sprintf("VMID %d is already in use on the cluster, trying VMID %d", taken, vmid))
+	}
+	d.discoverer.ReleaseVMID(vmid)
+	return 0, fmt.Errorf("no free VMID found after skipping %d taken IDs", maxVMIDConflicts)
+}
+
+// verifyAttachedISO checks that a created VM's CD-ROM references the ISO it
+// was built with and that the ISO exists on its storage
+func (d *Deployer) verifyAttachedISO(vmid int, vmConfig proxmox.VMConfig) error {
+	storage, filename, err := d.vmCreator.GetAttachedISO(vmid)
+	if err != nil {
+		return fmt.Errorf("verifying ISO attachment: %w", err)
+	}
+	if filename == "" {
+		return fmt.Errorf("no ISO attached to ide2 (expected %s:iso/%s)", vmConfig.ISOStorage, vmConfig.ISOFile)
+	}
+	if storage != vmConfig.ISOStorage || filename != vmConfig.ISOFile {
+		return fmt.Errorf("ide2 references %s:iso/%s, expected %s:iso/%s",
+			storage, filename, vmConfig.ISOStorage, vmConfig.ISOFile)
+	}
+
+	// The VM boots from the ISO as its own node sees the storage
+	var exists bool
+	if local := d.localNode(); local != "" && vmConfig.Node != "" && vmConfig.Node != local {
+		exists, err = d.storage.ISOExistsOnNode(vmConfig.Node, storage, filename)
+	} else {
+		exists, err = d.storage.ISOExists(storage, filename)
+	}
+	if err != nil {
+		return fmt.Errorf("verifying ISO attachment: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("attached ISO %s not found on storage %s", filename, storage)
+	}
+	return nil
+}
+
+// buildDescription renders the VM notes for a component instance
+func (d *Deployer) buildDescription(comp config.ComponentConfig, vmConfig proxmox.VMConfig) (string, error) {
+	operator := d.config.Operator
+	if operator == "" {
+		if u, err := user.Current(); err == nil {
+			operator = u.Username
+		}
+	}
+
+	info := proxmox.DescriptionInfo{
+		Summary:     config.DefaultVMSpecs[comp.Type].Description,
+		Name:        vmConfig.Name,
+		Component:   string(comp.Type),
+		Version:     comp.Version,
+		Prefix:      d.config.Prefix,
+		DeployedAt:  time.Now().UTC().Format(time.RFC3339),
+		ToolVersion: config.ToolVersion,
+		Operator:    operator,
+		Ticket:      d.config.Ticket,
+		ISOFile:     vmConfig.ISOFile,
+	}
+
+	for _, img := range d.knownImages {
+		if img.Filename == comp.ISOPath {
+			info.ISOSource = img.SourceName
+			info.ISOSourceURL = img.SourceURL
+			if img.SHA256 != "" {
+				info.ISOChecksum = "sha256:" + img.SHA256
+			} else if img.MD5 != "" {
+				info.ISOChecksum = "md5:" + img.MD5
+			}
+			if info.Version == "" {
+				info.Version = img.Version
+			}
+			break
+		}
+	}
+
+	return proxmox.RenderDescription(d.config.DescriptionTemplate, info)
+}
+
 // rollback destroys all created VMs
 func (d *Deployer) rollback() {
 	if len(d.createdVMIDs) == 0 {
@@ -533,17 +1453,88 @@ func (d *Deployer) rollback() {
 	d.log("Rollback complete")
 }
 
+// cleanupAfterFailure applies the rollback policy after a failed stage
+func (d *Deployer) cleanupAfterFailure(result *DeploymentResult) {
+	switch d.config.RollbackPolicy {
+	case config.RollbackNone:
+		d.preserveVMs(result)
+	case config.RollbackFailedOnly:
+		d.removeFailedVMs(result)
+	default:
+		d.rollback()
+		result.RolledBack = true
+	}
+}
+
+// preserveVMs leaves every created VM in place after a failure and logs
+// each one's console URL, so the deploy log records what was kept
+func (d *Deployer) preserveVMs(result *DeploymentResult) {
+	if len(d.createdVMIDs) == 0 {
+		return
+	}
+
+	d.log(fmt.Sprintf("Preserving %d created VMs for inspection (rollback policy: none)", len(d.createdVMIDs)))
+	for _, vmid := range d.createdVMIDs {
+		url := d.vmCreator.GetConsoleURL(vmid, d.sshClient.Host())
+		name := fmt.Sprintf("VM %d", vmid)
+		if i := findVMIndex(result.VMs, vmid); i >= 0 {
+			result.VMs[i].ConsoleURL = url
+			name = result.VMs[i].Name
+			result.ConsoleURLs[name] = url
+		}
+		d.log(fmt.Sprintf("Preserved %s (VMID %d): %s", name, vmid, url))
+	}
+	result.Preserved = true
+}
+
+// removeFailedVMs destroys created VMs that failed to start and keeps the
+// rest. VMs whose qm create failed were never tracked: Proxmox removes them.
+func (d *Deployer) removeFailedVMs(result *DeploymentResult) {
+	failed := make(map[int]bool)
+	for _, vm := range result.VMs {
+		if vm.Error != "" {
+			failed[vm.VMID] = true
+		}
+	}
+
+	var kept []int
+	for _, vmid := range d.createdVMIDs {
+		if !failed[vmid] {
+			kept = append(kept, vmid)
+			continue
+		}
+
+		d.log(fmt.Sprintf("Destroying failed VM %d...", vmid))
+		if err := d.vmCreator.DestroyVM(vmid); err != nil {
+			d.log(fmt.Sprintf("Warning: failed to destroy VM %d: %v", vmid, err))
+			kept = append(kept, vmid)
+			continue
+		}
+		result.RemovedVMIDs = append(result.RemovedVMIDs, vmid)
+		result.VMs[findVMIndex(result.VMs, vmid)].Status = "removed"
+	}
+
+	if len(kept) > 0 {
+		d.log(fmt.Sprintf("Keeping %d successfully created VMs (rollback policy: failed-only)", len(kept)))
+	}
+	d.createdVMIDs = kept
+}
+
 // log sends a log message
 func (d *Deployer) log(message string) {
 	if d.OnLog != nil {
+		d.callbackMu.Lock()
 		d.OnLog(message)
+		d.callbackMu.Unlock()
 	}
 }
 
 // progress reports progress
 func (d *Deployer) progress(stage DeploymentStage, current, total int) {
 	if d.OnProgress != nil {
+		d.callbackMu.Lock()
 		d.OnProgress(string(stage), current, total)
+		d.callbackMu.Unlock()
 	}
 }
 
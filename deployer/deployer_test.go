@@ -0,0 +1,238 @@
+package deployer
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/config"
+	"github.com/mihailvovk/versa-proxmox-deployer/proxmox"
+	"github.com/mihailvovk/versa-proxmox-deployer/sources"
+	"github.com/mihailvovk/versa-proxmox-deployer/ssh/sshtest"
+)
+
+func TestPrepareImageFindsRenamedISOByChecksum(t *testing.T) {
+	const (
+		isoFile  = "versa-director-22.1.4-B.iso"
+		onDisk   = "director-golden.iso"
+		diskPath = "/var/lib/vz/template/iso/" + onDisk
+	)
+	sha := func(s string) string { sum := sha256.Sum256([]byte(s)); return hex.EncodeToString(sum[:]) }
+	md := func(s string) string { sum := md5.Sum([]byte(s)); return hex.EncodeToString(sum[:]) }
+
+	tests := []struct {
+		name       string
+		sidecars   map[string]string // companion files served by the image source
+		meta       sources.ISOFile   // as the source scan listed it
+		diskSHA256 string
+		diskMD5    string
+		wantMD5Run bool
+	}{
+		{
+			name:       "only a .sha256 companion",
+			sidecars:   map[string]string{isoFile + ".sha256": sha("director") + "  " + isoFile + "\n"},
+			meta:       sources.ISOFile{HasSHA256File: true},
+			diskSHA256: sha("director"),
+			wantMD5Run: false,
+		},
+		{
+			name:       "SHA256 miss falls back to MD5",
+			meta:       sources.ISOFile{SHA256: sha("rebuilt director"), MD5: md("director")},
+			diskSHA256: sha("director"),
+			diskMD5:    md("director"),
+			wantMD5Run: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			web := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, ok := tt.sidecars[strings.TrimPrefix(r.URL.Path, "/")]
+				if !ok {
+					http.NotFound(w, r)
+					return
+				}
+				w.Write([]byte(body))
+			}))
+			defer web.Close()
+
+			var mu sync.Mutex
+			var md5Run bool
+			client := sshtest.NewClient(t, func(cmd string) (string, int) {
+				switch {
+				case strings.HasPrefix(cmd, "pvesm path "):
+					return "/var/lib/vz/template/iso/test.iso\n", 0
+				case strings.HasPrefix(cmd, "pvesm list "):
+					return `[{"volid":"local:iso/` + onDisk + `","size":1000}]`, 0
+				case strings.HasPrefix(cmd, "sha256sum "):
+					return tt.diskSHA256 + "  " + diskPath + "\n", 0
+				case strings.HasPrefix(cmd, "md5sum "):
+					mu.Lock()
+					md5Run = true
+					mu.Unlock()
+					return tt.diskMD5 + "  " + diskPath + "\n", 0
+				}
+				return "", 1
+			})
+
+			src := sources.NewHTTPSource(web.URL, "artifacts")
+			meta := tt.meta
+			meta.Filename = isoFile
+			meta.Size = 1000
+			meta.SourceName = src.Name()
+			meta.SourceType = src.Type()
+			meta.SourceURL = web.URL + "/" + isoFile
+
+			d := NewDeployer(client, []sources.ImageSource{src})
+			d.SetKnownImages([]sources.ISOFile{meta})
+
+			got, err := d.prepareImage(isoFile, []proxmox.StorageInfo{{Name: "local"}}, "local")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Storage != "local" || got.Filename != onDisk {
+				t.Errorf("prepareImage resolved %s:iso/%s, want local:iso/%s", got.Storage, got.Filename, onDisk)
+			}
+			if md5Run != tt.wantMD5Run {
+				t.Errorf("MD5 lookup ran: %v, want %v", md5Run, tt.wantMD5Run)
+			}
+		})
+	}
+}
+
+func TestThrottledProgressRateIsFinite(t *testing.T) {
+	d := NewDeployer(nil, nil)
+	var events []TransferProgress
+	d.OnTransfer = func(p TransferProgress) { events = append(events, p) }
+
+	progress := makeThrottledProgress(d, "Upload", "director.iso")
+	// Completion reports bypass the throttle, so these arrive back to back
+	progress(100, 100)
+	progress(100, 100)
+
+	if len(events) != 2 {
+		t.Fatalf("got %d transfer events, want 2", len(events))
+	}
+	for _, e := range events {
+		if math.IsInf(e.BytesPerSec, 0) || math.IsNaN(e.BytesPerSec) {
+			t.Errorf("BytesPerSec = %v, want a finite rate", e.BytesPerSec)
+		}
+		if _, err := json.Marshal(e); err != nil {
+			t.Errorf("marshalling %+v: %v", e, err)
+		}
+	}
+}
+
+func TestValidateFeatureVersions(t *testing.T) {
+	minimums := map[config.Feature]map[config.ComponentType]string{
+		config.FeatureCloudInit: {config.ComponentDirector: "22.1.1"},
+	}
+
+	tests := []struct {
+		name         string
+		comp         config.ComponentConfig
+		wantErrors   int
+		wantWarnings int
+	}{
+		{"old version", config.ComponentConfig{Type: config.ComponentDirector, Version: "21.2.3"}, 1, 0},
+		{"old version from iso filename", config.ComponentConfig{Type: config.ComponentDirector, ISOPath: "/isos/versa-director-21.2.3-B.iso"}, 1, 0},
+		{"unknown version", config.ComponentConfig{Type: config.ComponentDirector}, 0, 1},
+		{"new enough", config.ComponentConfig{Type: config.ComponentDirector, Version: "22.1.4"}, 0, 0},
+		{"no minimum for component", config.ComponentConfig{Type: config.ComponentRouter, Version: "16.1.0"}, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.comp.CloudInit = &config.CloudInitConfig{User: "admin"}
+			d := NewDeployer(nil, nil)
+			d.config = &config.DeploymentConfig{
+				Components:         []config.ComponentConfig{tt.comp},
+				FeatureMinVersions: minimums,
+			}
+
+			var report ValidationReport
+			d.validateFeatureVersions(&report)
+			if len(report.Errors) != tt.wantErrors || len(report.Warnings) != tt.wantWarnings {
+				t.Errorf("got errors %q, warnings %q; want %d error(s), %d warning(s)",
+					report.Errors, report.Warnings, tt.wantErrors, tt.wantWarnings)
+			}
+		})
+	}
+}
+
+func TestStartVMsStopsWhenCancelled(t *testing.T) {
+	var mu sync.Mutex
+	var started []string
+	client := sshtest.NewClient(t, func(cmd string) (string, int) {
+		switch {
+		case strings.HasPrefix(cmd, "qm start "):
+			mu.Lock()
+			started = append(started, cmd)
+			mu.Unlock()
+			return "", 0
+		case strings.HasPrefix(cmd, "qm status "):
+			return "status: running\n", 0
+		}
+		return "", 0
+	})
+
+	d := NewDeployer(client, nil)
+	d.config = &config.DeploymentConfig{StartDelay: time.Hour}
+	ctx, cancel := context.WithCancel(context.Background())
+	d.SetContext(ctx)
+	cancel()
+
+	result := &DeploymentResult{VMs: []VMResult{
+		{VMID: 101, Name: "lab-director-1", Component: config.ComponentDirector, Status: "created"},
+		{VMID: 102, Name: "lab-controller-1", Component: config.ComponentController, Status: "created"},
+	}}
+	d.startVMs(result)
+
+	if len(started) != 1 || started[0] != "qm start 101" {
+		t.Errorf("started %q, want only VM 101", started)
+	}
+	if result.VMs[1].Status != "created" || len(result.Errors) != 1 {
+		t.Errorf("VM 102 status %q, errors %q; want it left created with one error", result.VMs[1].Status, result.Errors)
+	}
+}
+
+func TestStartVMWithRetryStopsWhenCancelled(t *testing.T) {
+	var mu sync.Mutex
+	starts := 0
+	client := sshtest.NewClient(t, func(cmd string) (string, int) {
+		switch {
+		case strings.HasPrefix(cmd, "qm start "):
+			mu.Lock()
+			starts++
+			mu.Unlock()
+			return "", 1
+		case strings.HasPrefix(cmd, "qm status "):
+			return "status: stopped\n", 0
+		case strings.HasPrefix(cmd, "pvesh "):
+			return "[]", 0
+		}
+		return "", 0
+	})
+
+	d := NewDeployer(client, nil)
+	d.config = &config.DeploymentConfig{StartRetries: 3}
+	ctx, cancel := context.WithCancel(context.Background())
+	d.SetContext(ctx)
+	cancel()
+
+	status, err := d.startVMWithRetry(VMResult{VMID: 101, Name: "lab-director-1"})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("startVMWithRetry() error = %v, want it to wrap context.Canceled", err)
+	}
+	if starts != 1 || status != "stopped" {
+		t.Errorf("%d start attempt(s), status %q; want 1 attempt, stopped", starts, status)
+	}
+}
@@ -0,0 +1,76 @@
+package deployer
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/proxmox"
+	"github.com/mihailvovk/versa-proxmox-deployer/ssh"
+)
+
+// DestroyResult is the outcome of destroying one VM
+type DestroyResult struct {
+	VMID  int
+	Name  string
+	Error error
+}
+
+// FindDestroyTargets resolves the deployer-managed VMs to destroy: the given
+// VMIDs, or every VM in the deployment when prefix is set. Like the web UI's
+// delete, any VMID without the versa-deployer tag is refused.
+func FindDestroyTargets(client *ssh.Client, prefix string, vmids []int) ([]proxmox.VMInfo, error) {
+	if prefix == "" && len(vmids) == 0 {
+		return nil, fmt.Errorf("no VMs selected")
+	}
+
+	managed, err := proxmox.NewDiscoverer(client).FindVersaDeployments()
+	if err != nil {
+		return nil, fmt.Errorf("finding deployments: %w", err)
+	}
+	byID := make(map[int]proxmox.VMInfo)
+	for _, vm := range managed {
+		byID[vm.VMID] = vm
+	}
+
+	selected := make(map[int]proxmox.VMInfo)
+	for _, vmid := range vmids {
+		vm, ok := byID[vmid]
+		if !ok {
+			return nil, fmt.Errorf("VM %d does not have the versa-deployer tag; refusing to destroy it", vmid)
+		}
+		selected[vmid] = vm
+	}
+	if prefix != "" {
+		found := false
+		for _, vm := range managed {
+			if deploymentPrefix(vm) == prefix {
+				selected[vm.VMID] = vm
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("no deployer-managed VMs in deployment %s", prefix)
+		}
+	}
+
+	targets := make([]proxmox.VMInfo, 0, len(selected))
+	for _, vm := range selected {
+		targets = append(targets, vm)
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].VMID < targets[j].VMID })
+	return targets, nil
+}
+
+// DestroyVMs stops and purges each VM, continuing past failures
+func DestroyVMs(client *ssh.Client, vms []proxmox.VMInfo) []DestroyResult {
+	creator := proxmox.NewVMCreator(client)
+	results := make([]DestroyResult, 0, len(vms))
+	for _, vm := range vms {
+		results = append(results, DestroyResult{
+			VMID:  vm.VMID,
+			Name:  vm.Name,
+			Error: creator.DestroyVM(vm.VMID),
+		})
+	}
+	return results
+}
@@ -1,7 +1,9 @@
 package deployer
 
 import (
+	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/mihailvovk/versa-proxmox-deployer/config"
 	"github.com/mihailvovk/versa-proxmox-deployer/proxmox"
@@ -24,6 +26,121 @@ const (
 	StrategyHASeparate DistributionStrategy = "ha_separate"
 )
 
+// ParseStrategy validates a strategy name. An empty name returns "", meaning
+// the strategy recommended for the cluster.
+func ParseStrategy(name string) (DistributionStrategy, error) {
+	switch strategy := DistributionStrategy(strings.ReplaceAll(strings.TrimSpace(name), "-", "_")); strategy {
+	case "", StrategyAutoBalance, StrategyAllOnOne, StrategyManual, StrategyHASeparate:
+		return strategy, nil
+	default:
+		return "", fmt.Errorf("unknown distribution strategy %q (expected %s, %s, %s or %s)",
+			name, StrategyAutoBalance, StrategyAllOnOne, StrategyHASeparate, StrategyManual)
+	}
+}
+
+// AssignNodes spreads the configured components across the named nodes (all
+// online nodes when names is empty) using the given strategy, or the
+// recommended one when strategy is empty. Every named node must exist and be
+// online. Requires Discover to have run.
+func (d *Deployer) AssignNodes(names []string, strategy DistributionStrategy) error {
+	if d.proxmoxInfo == nil {
+		return fmt.Errorf("discovery not performed")
+	}
+
+	var nodes []proxmox.NodeInfo
+	if len(names) == 0 {
+		for _, n := range d.proxmoxInfo.Nodes {
+			if n.Status == "online" {
+				nodes = append(nodes, n)
+			}
+		}
+		if len(nodes) == 0 {
+			return fmt.Errorf("no online nodes found")
+		}
+	} else {
+		seen := make(map[string]bool)
+		for _, name := range names {
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			var found *proxmox.NodeInfo
+			for i := range d.proxmoxInfo.Nodes {
+				if d.proxmoxInfo.Nodes[i].Name == name {
+					found = &d.proxmoxInfo.Nodes[i]
+					break
+				}
+			}
+			if found == nil {
+				return fmt.Errorf("node %s not found in cluster", name)
+			}
+			if found.Status != "online" {
+				return fmt.Errorf("node %s is %s", name, found.Status)
+			}
+			nodes = append(nodes, *found)
+		}
+	}
+
+	// Only place components on nodes that can reach the storage pools in use
+	usable, excluded := d.nodesWithStorage(nodes)
+	if len(usable) == 0 {
+		return fmt.Errorf("no candidate node has access to every storage pool in use")
+	}
+	for _, name := range excluded {
+		if len(names) > 0 {
+			return fmt.Errorf("node %s cannot use the configured storage pools", name)
+		}
+		d.log(fmt.Sprintf("Skipping node %s: a configured storage pool is restricted to other nodes", name))
+	}
+	nodes = usable
+
+	if strategy == "" {
+		strategy = GetRecommendedStrategy(nodes, d.config.HAMode)
+	}
+
+	dist := NewDistributor(nodes, strategy)
+	dist.SetWeights(d.config.BalanceWeights)
+	d.config.Components = dist.DistributeComponents(d.config.Components, d.config.HAMode)
+
+	for _, comp := range d.config.Components {
+		d.log(fmt.Sprintf("Placing %s on %s (%s)", comp.Type, comp.Node, strategy))
+	}
+	return nil
+}
+
+// nodesWithStorage splits nodes into those where every component's storage
+// pool is available, per its storage.cfg node restriction, and the names of
+// the rest. Pools missing from discovery are left to Preflight to report.
+func (d *Deployer) nodesWithStorage(nodes []proxmox.NodeInfo) (usable []proxmox.NodeInfo, excluded []string) {
+	var pools []proxmox.StorageInfo
+	for _, comp := range d.config.Components {
+		pool := d.config.StorageFor(comp)
+		for _, s := range d.proxmoxInfo.Storage {
+			if s.Name == pool {
+				pools = append(pools, s)
+				break
+			}
+		}
+	}
+
+	for _, n := range nodes {
+		ok := true
+		for _, s := range pools {
+			if !s.AvailableOn(n.Name) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			usable = append(usable, n)
+		} else {
+			excluded = append(excluded, n.Name)
+		}
+	}
+	return usable, excluded
+}
+
 // NodeScore represents a node with its capacity score
 type NodeScore struct {
 	Node           proxmox.NodeInfo
@@ -37,6 +154,7 @@ type NodeScore struct {
 type Distributor struct {
 	nodes    []proxmox.NodeInfo
 	strategy DistributionStrategy
+	weights  config.BalanceWeights
 }
 
 // NewDistributor creates a new distributor
@@ -44,9 +162,15 @@ func NewDistributor(nodes []proxmox.NodeInfo, strategy DistributionStrategy) *Di
 	return &Distributor{
 		nodes:    nodes,
 		strategy: strategy,
+		weights:  config.DefaultBalanceWeights,
 	}
 }
 
+// SetWeights sets the node scoring weights, normalized to sum to 1
+func (d *Distributor) SetWeights(w config.BalanceWeights) {
+	d.weights = w.Normalized()
+}
+
 // DistributeComponents assigns nodes to each component based on strategy
 func (d *Distributor) DistributeComponents(components []config.ComponentConfig, haMode bool) []config.ComponentConfig {
 	if len(d.nodes) == 0 {
@@ -203,14 +327,13 @@ func (d *Distributor) calculateNodeScores() []NodeScore {
 
 // calculateScore calculates a combined resource score
 func (d *Distributor) calculateScore(ns NodeScore) float64 {
-	// Weight: 40% CPU, 60% RAM (RAM is usually more constrained)
 	cpuScore := float64(ns.AvailableCPU) / float64(ns.Node.CPUCores+1) * 100
 	ramScore := float64(ns.AvailableRAMGB) / float64(ns.Node.RAMGB+1) * 100
 
 	// Penalize nodes that already have assigned VMs
-	vmPenalty := float64(ns.AssignedVMs) * 5
+	vmPenalty := float64(ns.AssignedVMs) * d.weights.VMPenalty
 
-	return cpuScore*0.4 + ramScore*0.6 - vmPenalty
+	return cpuScore*d.weights.CPU + ramScore*d.weights.RAM - vmPenalty
 }
 
 // GetRecommendedStrategy recommends a distribution strategy
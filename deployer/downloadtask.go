@@ -0,0 +1,116 @@
+package deployer
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DownloadTask is an ISO download running as a Proxmox task
+type DownloadTask struct {
+	ISO  string `json:"iso"`
+	Node string `json:"node"`
+	UPID string `json:"upid"`
+}
+
+// downloadTask is a running DownloadTask and the function cancelling it
+type downloadTask struct {
+	DownloadTask
+	cancel context.CancelFunc
+}
+
+// SetContext makes cancelling ctx stop the deployment's running Proxmox
+// download tasks and cut short its waits. Run sets it to its own context.
+func (d *Deployer) SetContext(ctx context.Context) {
+	d.ctx = ctx
+}
+
+// context returns the deployment's context, or Background when none is set
+func (d *Deployer) context() context.Context {
+	if d.ctx == nil {
+		return context.Background()
+	}
+	return d.ctx
+}
+
+// wait pauses for delay, returning the context's error early if the
+// deployment is cancelled
+func (d *Deployer) wait(delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-d.context().Done():
+		return d.context().Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// DownloadTasks returns the ISO downloads currently running on Proxmox
+func (d *Deployer) DownloadTasks() []DownloadTask {
+	d.downloadsMu.Lock()
+	defer d.downloadsMu.Unlock()
+	tasks := make([]DownloadTask, 0, len(d.downloadTasks))
+	for _, t := range d.downloadTasks {
+		tasks = append(tasks, t.DownloadTask)
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ISO < tasks[j].ISO })
+	return tasks
+}
+
+// CancelDownload stops a stuck ISO download task on Proxmox. The deployment
+// then downloads the ISO locally and uploads it instead.
+func (d *Deployer) CancelDownload(upid string) error {
+	d.downloadsMu.Lock()
+	t, ok := d.downloadTasks[upid]
+	d.downloadsMu.Unlock()
+	if !ok {
+		return fmt.Errorf("no running download task %s", upid)
+	}
+	d.log(fmt.Sprintf("Cancelling download of %s on Proxmox (UPID: %s)", t.ISO, upid))
+	t.cancel()
+	return nil
+}
+
+// downloadOnProxmox runs a pvesh download-url task for an ISO, tracking it
+// so CancelDownload can stop it
+func (d *Deployer) downloadOnProxmox(node, storage, isoFile, url string, log func(string)) error {
+	ctx, cancel := context.WithCancel(d.context())
+	defer cancel()
+
+	var upid string
+	err := d.storage.DownloadISOFromURL(ctx, node, storage, isoFile, url, log, func(id string) {
+		upid = id
+		d.setDownloadTask(upid, &downloadTask{
+			DownloadTask: DownloadTask{ISO: isoFile, Node: node, UPID: upid},
+			cancel:       cancel,
+		})
+	})
+	if upid != "" {
+		d.setDownloadTask(upid, nil)
+	}
+	return err
+}
+
+// setDownloadTask records a running download task, or removes it when t is
+// nil, and reports the change to OnDownloadTasks
+func (d *Deployer) setDownloadTask(upid string, t *downloadTask) {
+	d.downloadsMu.Lock()
+	if t != nil {
+		if d.downloadTasks == nil {
+			d.downloadTasks = make(map[string]*downloadTask)
+		}
+		d.downloadTasks[upid] = t
+	} else {
+		delete(d.downloadTasks, upid)
+	}
+	d.downloadsMu.Unlock()
+
+	if d.OnDownloadTasks != nil {
+		tasks := d.DownloadTasks()
+		d.callbackMu.Lock()
+		d.OnDownloadTasks(tasks)
+		d.callbackMu.Unlock()
+	}
+}
@@ -0,0 +1,28 @@
+package deployer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWaitReturnsOnCancel(t *testing.T) {
+	d := NewDeployer(nil, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	d.SetContext(ctx)
+	cancel()
+
+	start := time.Now()
+	if err := d.wait(time.Hour); !errors.Is(err, context.Canceled) {
+		t.Errorf("wait() = %v, want context.Canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("cancelled wait took %s", elapsed)
+	}
+
+	d.SetContext(context.Background())
+	if err := d.wait(time.Millisecond); err != nil {
+		t.Errorf("wait() = %v, want nil", err)
+	}
+}
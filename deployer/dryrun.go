@@ -0,0 +1,109 @@
+package deployer
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/config"
+	"github.com/mihailvovk/versa-proxmox-deployer/proxmox"
+	"github.com/mihailvovk/versa-proxmox-deployer/sources"
+)
+
+// StatusPlanned marks the result of a dry run: VMs are listed but not created
+const StatusPlanned = "planned"
+
+// SetDryRun makes Deploy log the ISO transfers and qm create commands it
+// would run, without changing anything on Proxmox. Discovery and pre-flight
+// checks still run, since they only read.
+func (d *Deployer) SetDryRun(dryRun bool) {
+	d.dryRun = dryRun
+}
+
+// planImages logs where each needed ISO would come from and records the
+// storage it would end up on, so planned VMs reference it
+func (d *Deployer) planImages(isoNeeded map[string]bool, isoStorages []proxmox.StorageInfo, uploadStorName string) error {
+	names := make([]string, 0, len(isoNeeded))
+	for name := range isoNeeded {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for i, isoFile := range names {
+		d.progress(StageImagePrep, i, len(names))
+
+		if storage, ok := d.attachedISO(isoFile); ok {
+			d.log(fmt.Sprintf("ISO already attached to an existing VM (%s): %s", storage, isoFile))
+			d.isoResolvedMap[isoFile] = resolvedISO{Storage: storage, Filename: isoFile}
+			continue
+		}
+		if foundOn, foundFile, _ := d.storage.FindISOTolerant(isoStorages, isoFile); foundOn != "" {
+			d.log(fmt.Sprintf("ISO already on Proxmox (%s): %s", foundOn, foundFile))
+			d.isoResolvedMap[isoFile] = resolvedISO{Storage: foundOn, Filename: foundFile}
+			continue
+		}
+
+		isoMeta := d.findKnownImage(isoFile)
+		if isoMeta == nil {
+			return fmt.Errorf("ISO metadata not found for %s — ensure image sources are configured", isoFile)
+		}
+		if sources.SupportsDirectDownload(*isoMeta) {
+			d.log(fmt.Sprintf("Would download %s (%s) on Proxmox into %s from %s",
+				isoFile, formatBytes(isoMeta.Size), uploadStorName, isoMeta.SourceURL))
+		} else {
+			d.log(fmt.Sprintf("Would download %s (%s) from %s and upload it to %s",
+				isoFile, formatBytes(isoMeta.Size), isoMeta.SourceName, uploadStorName))
+		}
+		d.isoResolvedMap[isoFile] = resolvedISO{Storage: uploadStorName, Filename: isoFile}
+	}
+	return nil
+}
+
+// planVM logs the qm create (or qm clone and qm set) commands for one VM and returns its planned result
+func (d *Deployer) planVM(comp config.ComponentConfig, vmConfig proxmox.VMConfig, pendingNets []proxmox.VMNetwork) (VMResult, error) {
+	shown := vmConfig
+	if ci := vmConfig.CloudInit; ci != nil && ci.Password != "" {
+		redacted := *ci
+		redacted.Password = "********"
+		shown.CloudInit = &redacted
+	}
+	if comp.TemplateVMID != 0 {
+		cloneCmd, err := proxmox.CloneVMCommand(comp.TemplateVMID, vmConfig.VMID, vmConfig.Name, vmConfig.Storage, d.cloneTarget(comp, vmConfig))
+		if err != nil {
+			return VMResult{}, fmt.Errorf("VM %s: %w", vmConfig.Name, err)
+		}
+		// The clone inherits the template's interfaces
+		templateNets, err := d.vmCreator.NetSlots(comp.TemplateVMID)
+		if err != nil {
+			return VMResult{}, fmt.Errorf("VM %s: %w", vmConfig.Name, err)
+		}
+		setCmd, err := proxmox.ConfigureCloneCommand(shown, templateNets)
+		if err != nil {
+			return VMResult{}, fmt.Errorf("VM %s: %w", vmConfig.Name, err)
+		}
+		d.log(fmt.Sprintf("Would clone VM: %s (VMID %d) from template %d on %s", vmConfig.Name, vmConfig.VMID, comp.TemplateVMID, vmConfig.Node))
+		d.log("  " + cloneCmd)
+		d.log("  " + setCmd)
+	} else {
+		cmd, err := proxmox.CreateVMCommand(shown)
+		if err != nil {
+			return VMResult{}, fmt.Errorf("VM %s: %w", vmConfig.Name, err)
+		}
+		d.log(fmt.Sprintf("Would create VM: %s (VMID %d) on %s", vmConfig.Name, vmConfig.VMID, vmConfig.Node))
+		d.log("  " + cmd)
+	}
+
+	ip := ""
+	if d.config.IPConfig.ManualIPs != nil {
+		ip = d.config.IPConfig.ManualIPs[vmConfig.Name]
+	}
+	return VMResult{
+		VMID:            vmConfig.VMID,
+		Name:            vmConfig.Name,
+		Component:       comp.Type,
+		Node:            vmConfig.Node,
+		Status:          StatusPlanned,
+		IP:              ip,
+		PendingNetworks: pendingNets,
+		Interfaces:      vmInterfaces(vmConfig.Networks, pendingNets),
+	}, nil
+}
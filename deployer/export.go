@@ -0,0 +1,248 @@
+package deployer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/config"
+	"github.com/mihailvovk/versa-proxmox-deployer/proxmox"
+	"github.com/mihailvovk/versa-proxmox-deployer/ssh"
+)
+
+// ExportedVM is one VM's hand-off details
+type ExportedVM struct {
+	VMID          int                  `json:"vmid"`
+	Name          string               `json:"name"`
+	Prefix        string               `json:"prefix"`
+	Component     config.ComponentType `json:"component"`
+	Version       string               `json:"version,omitempty"`
+	ToolVersion   string               `json:"toolVersion,omitempty"` // Deployer version that created the VM
+	Node          string               `json:"node"`
+	Status        string               `json:"status"`
+	IP            string               `json:"ip,omitempty"`
+	ConsoleURL    string               `json:"consoleUrl"`
+	SerialCommand string               `json:"serialCommand"`
+	DefaultLogin  string               `json:"defaultLogin,omitempty"`
+	PostInstall   []string             `json:"postInstall,omitempty"`
+	Interfaces    []VMInterface        `json:"interfaces,omitempty"`
+}
+
+// DeploymentExport is a hand-off bundle describing deployed VMs
+type DeploymentExport struct {
+	Prefix      string       `json:"prefix,omitempty"`
+	ProxmoxHost string       `json:"proxmoxHost"`
+	GeneratedAt time.Time    `json:"generatedAt"`
+	DirectorIP  string       `json:"directorIp,omitempty"`
+	DirectorURL string       `json:"directorUrl,omitempty"`
+	VMs         []ExportedVM `json:"vms"`
+}
+
+// defaultLogins are the factory credentials Versa images ship with; they
+// must be changed on first login
+var defaultLogins = map[config.ComponentType]string{
+	config.ComponentDirector:   "admin / versa123 (CLI), Administrator / versa123 (UI)",
+	config.ComponentAnalytics:  "admin / versa123",
+	config.ComponentController: "admin / versa123",
+	config.ComponentRouter:     "admin / versa123",
+	config.ComponentFlexVNF:    "admin / versa123",
+}
+
+// postInstallSteps are the manual steps left after the deployer finishes
+var postInstallSteps = map[config.ComponentType][]string{
+	config.ComponentDirector: {
+		"Complete the first-boot setup on the serial console (management IP, hostname)",
+		"Change the default passwords",
+		"Log in to the Director UI and apply licenses",
+	},
+	config.ComponentAnalytics: {
+		"Set the management IP on the serial console",
+		"Add the Analytics cluster in Director",
+	},
+	config.ComponentController: {
+		"Set the management IP on the serial console",
+		"Onboard the Controller from Director (Workflows > Infrastructure > Controllers)",
+	},
+	config.ComponentRouter: {
+		"Set the management IP on the serial console",
+		"Onboard the Router from Director",
+	},
+	config.ComponentConcerto: {
+		"Complete the first-boot setup on the serial console",
+		"Register Concerto with Director",
+	},
+	config.ComponentFlexVNF: {
+		"Stage the device against its Controller",
+	},
+}
+
+// deploymentPrefix returns a VM's deployment prefix from its versa-deploy tag,
+// falling back to parsing the VM name
+func deploymentPrefix(vm proxmox.VMInfo) string {
+	for _, tag := range vm.Tags {
+		if p, ok := strings.CutPrefix(tag, "versa-deploy-"); ok {
+			return p
+		}
+	}
+	prefix, _, _, _ := ParseVMName(vm.Name)
+	return prefix
+}
+
+// ExportDeployment collects hand-off details for every deployer-managed VM,
+// or only those in one deployment when prefix is set. directorIP, when known,
+// is used for the Director's IP and management URL.
+func ExportDeployment(client *ssh.Client, prefix, directorIP string) (*DeploymentExport, error) {
+	vms, err := proxmox.NewDiscoverer(client).FindVersaDeployments()
+	if err != nil {
+		return nil, fmt.Errorf("finding deployments: %w", err)
+	}
+
+	creator := proxmox.NewVMCreator(client)
+	pending, err := loadPendingNetworks()
+	if err != nil {
+		return nil, err
+	}
+	export := &DeploymentExport{
+		Prefix:      prefix,
+		ProxmoxHost: client.Host(),
+		GeneratedAt: time.Now().UTC(),
+		DirectorIP:  directorIP,
+		VMs:         []ExportedVM{},
+	}
+	if directorIP != "" {
+		export.DirectorURL = fmt.Sprintf("https://%s", directorIP)
+	}
+
+	for _, vm := range vms {
+		vmPrefix := deploymentPrefix(vm)
+		if prefix != "" && vmPrefix != prefix {
+			continue
+		}
+
+		comp := config.ComponentFromTags(vm.Tags)
+		if comp == "" {
+			_, comp, _, _ = ParseVMName(vm.Name)
+		}
+
+		ev := ExportedVM{
+			VMID:          vm.VMID,
+			Name:          vm.Name,
+			Prefix:        vmPrefix,
+			Component:     comp,
+			Version:       vm.Version,
+			ToolVersion:   vm.ToolVersion,
+			Node:          vm.Node,
+			Status:        vm.Status,
+			ConsoleURL:    creator.GetConsoleURL(vm.VMID, client.Host()),
+			SerialCommand: fmt.Sprintf("qm terminal %d", vm.VMID),
+			DefaultLogin:  defaultLogins[comp],
+			PostInstall:   postInstallSteps[comp],
+		}
+		if comp == config.ComponentDirector && directorIP != "" {
+			ev.IP = directorIP
+		}
+		if nets, err := creator.GetNetworks(vm.Node, vm.VMID); err == nil {
+			ev.Interfaces = exportInterfaces(nets, pending[pendingKey(client.Host(), vm.VMID)])
+		}
+		export.VMs = append(export.VMs, ev)
+	}
+
+	if prefix != "" && len(export.VMs) == 0 {
+		return nil, fmt.Errorf("no deployer-managed VMs found for deployment %q", prefix)
+	}
+
+	sort.Slice(export.VMs, func(i, j int) bool {
+		if export.VMs[i].Prefix != export.VMs[j].Prefix {
+			return export.VMs[i].Prefix < export.VMs[j].Prefix
+		}
+		return export.VMs[i].VMID < export.VMs[j].VMID
+	})
+
+	return export, nil
+}
+
+// Markdown renders the export as a markdown hand-off document
+func (e *DeploymentExport) Markdown() string {
+	var sb strings.Builder
+
+	title := "Versa HeadEnd deployment"
+	if e.Prefix != "" {
+		title += " " + e.Prefix
+	}
+	fmt.Fprintf(&sb, "# %s\n\n", title)
+	fmt.Fprintf(&sb, "- **Proxmox host:** %s\n", e.ProxmoxHost)
+	fmt.Fprintf(&sb, "- **Generated:** %s\n", e.GeneratedAt.Format(time.RFC3339))
+	if e.DirectorURL != "" {
+		fmt.Fprintf(&sb, "- **Director UI:** %s\n", e.DirectorURL)
+	}
+
+	sb.WriteString("\n| VM | VMID | Component | Node | Status | IP | Console |\n")
+	sb.WriteString("|---|---|---|---|---|---|---|\n")
+	for _, vm := range e.VMs {
+		ip := vm.IP
+		if ip == "" {
+			ip = "-"
+		}
+		fmt.Fprintf(&sb, "| %s | %d | %s | %s | %s | %s | %s |\n",
+			vm.Name, vm.VMID, vm.Component, vm.Node, vm.Status, ip, vm.ConsoleURL)
+	}
+
+	for _, vm := range e.VMs {
+		fmt.Fprintf(&sb, "\n## %s (VMID %d)\n\n", vm.Name, vm.VMID)
+		fmt.Fprintf(&sb, "- **Serial console:** `%s` (on node %s)\n", vm.SerialCommand, vm.Node)
+		if vm.ToolVersion != "" {
+			fmt.Fprintf(&sb, "- **Deployed with:** versa-deployer v%s\n", vm.ToolVersion)
+		}
+		if vm.DefaultLogin != "" {
+			fmt.Fprintf(&sb, "- **Default login:** %s\n", vm.DefaultLogin)
+		}
+		if len(vm.Interfaces) > 0 {
+			sb.WriteString("\nNetwork interfaces:\n\n")
+			for _, iface := range vm.Interfaces {
+				fmt.Fprintf(&sb, "- %s\n", iface)
+			}
+		}
+		if len(vm.PostInstall) > 0 {
+			sb.WriteString("\nPost-install steps:\n\n")
+			for i, step := range vm.PostInstall {
+				fmt.Fprintf(&sb, "%d. %s\n", i+1, step)
+			}
+		}
+	}
+
+	return sb.String()
+}
+
+// exportInterfaces lists a VM's configured interfaces in slot order, then
+// any still pending from a management-only-first deploy. Purposes are only
+// known for pending interfaces; Proxmox doesn't store them.
+func exportInterfaces(nets map[int]proxmox.VMNetwork, pending PendingNetworks) []VMInterface {
+	slots := make([]int, 0, len(nets))
+	for n := range nets {
+		slots = append(slots, n)
+	}
+	sort.Ints(slots)
+
+	var ifaces []VMInterface
+	for _, n := range slots {
+		net := nets[n]
+		ifaces = append(ifaces, VMInterface{
+			Slot:   fmt.Sprintf("net%d", n),
+			Bridge: net.Bridge,
+			VLAN:   net.VLAN,
+			Model:  net.Model,
+		})
+	}
+	for i, net := range pending.Networks {
+		ifaces = append(ifaces, VMInterface{
+			Slot:    fmt.Sprintf("net%d", pending.First+i),
+			Purpose: net.Name,
+			Bridge:  net.Bridge,
+			VLAN:    net.VLAN,
+			Model:   net.Model,
+			Pending: true,
+		})
+	}
+	return ifaces
+}
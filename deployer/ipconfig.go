@@ -3,9 +3,11 @@ package deployer
 import (
 	"fmt"
 	"net"
+	"sort"
 	"strings"
 
 	"github.com/mihailvovk/versa-proxmox-deployer/config"
+	"github.com/mihailvovk/versa-proxmox-deployer/proxmox"
 )
 
 // IPPlan holds IP address assignments for deployment
@@ -39,10 +41,15 @@ func NewIPAllocator(cidr, gateway string) (*IPAllocator, error) {
 		gwIP[len(gwIP)-1] = 1
 	}
 
-	// Start allocating from .10 by default
+	// Start allocating from .10 by default, or right after the network
+	// address in subnets too small for that
 	startIP := make(net.IP, len(subnet.IP))
 	copy(startIP, subnet.IP)
 	startIP[len(startIP)-1] = 10
+	if !subnet.Contains(startIP) {
+		copy(startIP, subnet.IP)
+		startIP[len(startIP)-1]++
+	}
 
 	return &IPAllocator{
 		subnet:    subnet,
@@ -134,12 +141,24 @@ func (a *IPAllocator) isBroadcast(ip net.IP) bool {
 	return true
 }
 
-// GenerateIPPlan creates an IP plan for the deployment
-func GenerateIPPlan(components []config.ComponentConfig, prefix string, subnet, gateway string) (*IPPlan, error) {
+// GenerateIPPlan creates an IP plan for the deployment, giving each VM the
+// next free address of the subnet. Used addresses are skipped.
+func GenerateIPPlan(components []config.ComponentConfig, prefix string, subnet, gateway string, used []string) (*IPPlan, error) {
 	allocator, err := NewIPAllocator(subnet, gateway)
 	if err != nil {
 		return nil, err
 	}
+	for _, ip := range used {
+		allocator.AllocateSpecific(ip) // Addresses outside the subnet don't matter
+	}
+	vmCount := 0
+	for _, comp := range components {
+		if comp.Count == 0 {
+			vmCount++
+		} else {
+			vmCount += comp.Count
+		}
+	}
 
 	plan := &IPPlan{
 		Subnet:   subnet,
@@ -154,15 +173,12 @@ func GenerateIPPlan(components []config.ComponentConfig, prefix string, subnet,
 		}
 
 		for i := 0; i < count; i++ {
-			// Build VM name
-			name := fmt.Sprintf("%s-%s", prefix, comp.Type)
-			if count > 1 {
-				name = fmt.Sprintf("%s-%d", name, i+1)
-			}
+			name := proxmox.VMName(prefix, comp, i)
 
 			ip, err := allocator.Allocate()
 			if err != nil {
-				return nil, fmt.Errorf("allocating IP for %s: %w", name, err)
+				return nil, fmt.Errorf("management subnet %s has only %d free address(es) for %d VMs",
+					allocator.GetSubnet(), len(plan.Assigned), vmCount)
 			}
 
 			plan.Assigned[name] = ip
@@ -172,6 +188,93 @@ func GenerateIPPlan(components []config.ComponentConfig, prefix string, subnet,
 	return plan, nil
 }
 
+// assignIPs fills ManualIPs from the management subnet when no IPs were
+// given, skipping addresses the Proxmox host already sees in use. VMs then
+// carry their IP in cloud-init and in the deployment result.
+func (d *Deployer) assignIPs() error {
+	ipCfg := &d.config.IPConfig
+	if ipCfg.ManagementSubnet == "" || len(ipCfg.ManualIPs) > 0 {
+		return nil
+	}
+	if _, _, err := net.ParseCIDR(ipCfg.ManagementSubnet); err != nil {
+		return fmt.Errorf("invalid management subnet %q: %w", ipCfg.ManagementSubnet, err)
+	}
+
+	plan, err := GenerateIPPlan(d.config.Components, d.config.Prefix, ipCfg.ManagementSubnet, ipCfg.ManagementGateway, d.usedAddresses())
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(plan.Assigned))
+	for name := range plan.Assigned {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if ipCfg.ManagementGateway == "" {
+		ipCfg.ManagementGateway = plan.Gateway // Derived .1, for cloud-init drives
+	}
+	ipCfg.ManualIPs = make(map[string]string, len(plan.Assigned))
+	for _, name := range names {
+		ipCfg.ManualIPs[name] = plan.Assigned[name]
+		d.log(fmt.Sprintf("Assigned %s to %s from %s", plan.Assigned[name], name, plan.Subnet))
+	}
+	return nil
+}
+
+// applyAssignedIP gives a VM's cloud-init drive its manual or assigned
+// management IP, unless the component sets a static IP of its own
+func (d *Deployer) applyAssignedIP(vmConfig *proxmox.VMConfig) {
+	ip := d.config.IPConfig.ManualIPs[vmConfig.Name]
+	if vmConfig.CloudInit == nil || vmConfig.CloudInit.IP != "" || ip == "" || d.config.IPConfig.ManagementSubnet == "" {
+		return
+	}
+	ci := *vmConfig.CloudInit
+	ci.IP = FormatIPWithCIDR(ip, d.config.IPConfig.ManagementSubnet)
+	if ci.Gateway == "" {
+		ci.Gateway = d.config.IPConfig.ManagementGateway
+	}
+	vmConfig.CloudInit = &ci
+}
+
+// usedAddresses lists the IPv4 addresses in the Proxmox host's neighbour
+// table and on its own interfaces. It is best effort: hosts that are down
+// or never talked to the node aren't seen.
+func (d *Deployer) usedAddresses() []string {
+	result, err := d.sshClient.Run("ip -4 neigh show; ip -4 -o addr show")
+	if err != nil {
+		return nil
+	}
+	return parseUsedAddresses(result.Stdout)
+}
+
+// parseUsedAddresses extracts addresses from "ip neigh" lines, which start
+// with the address, and "ip -o addr" lines, which carry it after "inet"
+func parseUsedAddresses(output string) []string {
+	var used []string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if ip := net.ParseIP(fields[0]); ip != nil {
+			// Unanswered neighbour entries don't mean the address is taken
+			if state := fields[len(fields)-1]; state == "FAILED" || state == "INCOMPLETE" {
+				continue
+			}
+			used = append(used, ip.String())
+			continue
+		}
+		for i, f := range fields[:len(fields)-1] {
+			if f == "inet" {
+				if ip, _, err := net.ParseCIDR(fields[i+1]); err == nil {
+					used = append(used, ip.String())
+				}
+			}
+		}
+	}
+	return used
+}
+
 // ValidateIPConfig validates the IP configuration
 func ValidateIPConfig(ipConfig config.IPConfig) []string {
 	var errors []string
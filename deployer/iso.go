@@ -0,0 +1,68 @@
+package deployer
+
+import (
+	"fmt"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/proxmox"
+	"github.com/mihailvovk/versa-proxmox-deployer/ssh"
+)
+
+// AttachISO inserts an ISO into a deployer-managed VM's CD-ROM drive. An
+// empty storage searches every ISO storage for the file. With installer set
+// the VM boots from the ISO next, for a reinstall; otherwise the disk stays
+// first, e.g. for a tools ISO. It returns the storage the ISO was found on.
+func AttachISO(client *ssh.Client, vmid int, storage, filename string, installer bool) (string, error) {
+	vm, err := proxmox.NewDiscoverer(client).FindManagedVM(vmid)
+	if err != nil {
+		return "", err
+	}
+
+	storageMgr := proxmox.NewStorageManager(client)
+	if storage == "" {
+		isoStorages, err := proxmox.NewDiscoverer(client).GetISOStorage()
+		if err != nil {
+			return "", fmt.Errorf("listing ISO storage: %w", err)
+		}
+		if storage, err = storageMgr.ISOExistsOnAny(isoStorages, filename); err != nil {
+			return "", fmt.Errorf("looking for %s: %w", filename, err)
+		}
+		if storage == "" {
+			return "", fmt.Errorf("ISO %s not found on any ISO storage", filename)
+		}
+	} else {
+		found, err := storageMgr.ISOExists(storage, filename)
+		if err != nil {
+			return "", err
+		}
+		if !found {
+			return "", fmt.Errorf("ISO %s not found on storage %s", filename, storage)
+		}
+	}
+
+	vmCreator := proxmox.NewVMCreator(client)
+	if err := vmCreator.AttachISO(vm.VMID, storage, filename); err != nil {
+		return "", fmt.Errorf("attaching %s to VM %d: %w", filename, vm.VMID, err)
+	}
+	if err := vmCreator.SetCDROMBoot(vm.VMID, installer); err != nil {
+		return storage, fmt.Errorf("ISO attached, but setting boot order: %w", err)
+	}
+	return storage, nil
+}
+
+// DetachISO ejects the ISO from a deployer-managed VM and puts its CD-ROM
+// back behind the disk in the boot order
+func DetachISO(client *ssh.Client, vmid int) error {
+	vm, err := proxmox.NewDiscoverer(client).FindManagedVM(vmid)
+	if err != nil {
+		return err
+	}
+
+	vmCreator := proxmox.NewVMCreator(client)
+	if err := vmCreator.DetachISO(vm.VMID); err != nil {
+		return fmt.Errorf("detaching ISO from VM %d: %w", vm.VMID, err)
+	}
+	if err := vmCreator.SetCDROMBoot(vm.VMID, false); err != nil {
+		return fmt.Errorf("ISO detached, but setting boot order: %w", err)
+	}
+	return nil
+}
@@ -25,6 +25,63 @@ type NetworkAssignment struct {
 	Components  []config.ComponentType // Components using this network
 }
 
+// VMInterface is one NIC as a VM was actually built, for the as-built
+// network map in deploy results and exports
+type VMInterface struct {
+	Slot    string `json:"slot"`              // net0, net1, ...
+	Purpose string `json:"purpose,omitempty"` // proxmox.NetworkPurpose, empty if unknown
+	Bridge  string `json:"bridge"`
+	VLAN    int    `json:"vlan,omitempty"`
+	Model   string `json:"model,omitempty"`
+	Pending bool   `json:"pending,omitempty"` // Held back by ManagementOnlyFirst
+}
+
+// String formats the interface for CLI and markdown output
+func (i VMInterface) String() string {
+	s := i.Slot + " " + i.Bridge
+	if i.VLAN > 0 {
+		s += fmt.Sprintf(" vlan %d", i.VLAN)
+	}
+	if i.Purpose != "" {
+		label := NetworkPurposeLabels[i.Purpose]
+		if label == "" {
+			label = i.Purpose
+		}
+		s += " (" + label + ")"
+	}
+	if i.Pending {
+		s += " [pending]"
+	}
+	return s
+}
+
+// vmInterfaces lists the interfaces a VM was created with, then the pending
+// ones that will follow them
+func vmInterfaces(created, pending []proxmox.VMNetwork) []VMInterface {
+	ifaces := make([]VMInterface, 0, len(created)+len(pending))
+	add := func(n proxmox.VMNetwork, isPending bool) {
+		model := n.Model
+		if model == "" {
+			model = "virtio"
+		}
+		ifaces = append(ifaces, VMInterface{
+			Slot:    fmt.Sprintf("net%d", len(ifaces)),
+			Purpose: n.Name,
+			Bridge:  n.Bridge,
+			VLAN:    n.VLAN,
+			Model:   model,
+			Pending: isPending,
+		})
+	}
+	for _, n := range created {
+		add(n, false)
+	}
+	for _, n := range pending {
+		add(n, true)
+	}
+	return ifaces
+}
+
 // NetworkPurposeLabels provides human-readable labels for network purposes
 var NetworkPurposeLabels = map[string]string{
 	"northbound":         "Management (Northbound)",
@@ -59,14 +116,18 @@ func BuildNetworkPlan(netConfig config.NetworkConfig, components []config.Compon
 		})
 	}
 
-	// Director-Router network
+	// Director-Router network (also Analytics southbound unless it has its own)
 	if netConfig.DirectorRouterBridge != "" {
+		users := []config.ComponentType{config.ComponentDirector, config.ComponentRouter}
+		if netConfig.AnalyticsSouthboundBridge == "" {
+			users = append(users, config.ComponentAnalytics)
+		}
 		plan.Networks = append(plan.Networks, NetworkAssignment{
 			Purpose:     "director-router",
 			Bridge:      netConfig.DirectorRouterBridge,
 			VLAN:        netConfig.DirectorRouterVLAN,
 			Description: formatNetworkDescription("Director ↔ Router", netConfig.DirectorRouterBridge, netConfig.DirectorRouterVLAN),
-			Components:  []config.ComponentType{config.ComponentDirector, config.ComponentRouter, config.ComponentAnalytics},
+			Components:  users,
 		})
 	}
 
@@ -96,6 +157,17 @@ func BuildNetworkPlan(netConfig config.NetworkConfig, components []config.Compon
 		})
 	}
 
+	// Analytics southbound network
+	if netConfig.AnalyticsSouthboundBridge != "" {
+		plan.Networks = append(plan.Networks, NetworkAssignment{
+			Purpose:     "analytics-south",
+			Bridge:      netConfig.AnalyticsSouthboundBridge,
+			VLAN:        netConfig.AnalyticsSouthboundVLAN,
+			Description: formatNetworkDescription("Analytics Southbound", netConfig.AnalyticsSouthboundBridge, netConfig.AnalyticsSouthboundVLAN),
+			Components:  []config.ComponentType{config.ComponentAnalytics},
+		})
+	}
+
 	// Analytics cluster network
 	if netConfig.AnalyticsClusterBridge != "" {
 		plan.Networks = append(plan.Networks, NetworkAssignment{
@@ -138,15 +210,42 @@ func ValidateNetworkConfig(netConfig config.NetworkConfig, available []proxmox.N
 	checkBridge("Northbound", netConfig.NorthboundBridge)
 	checkBridge("Director-Router", netConfig.DirectorRouterBridge)
 	checkBridge("Controller-Router", netConfig.ControllerRouterBridge)
+	checkBridge("Analytics Southbound", netConfig.AnalyticsSouthboundBridge)
 	checkBridge("Analytics Cluster", netConfig.AnalyticsClusterBridge)
 
+	// Southbound and cluster sync carry different traffic and must not share a segment
+	southBridge, southVLAN := netConfig.AnalyticsSouthbound()
+	if netConfig.AnalyticsClusterBridge != "" &&
+		netConfig.AnalyticsClusterBridge == southBridge &&
+		netConfig.AnalyticsClusterVLAN == southVLAN {
+		errors = append(errors, fmt.Sprintf("Analytics Cluster: bridge '%s' (VLAN %d) is the same segment as Analytics Southbound", southBridge, southVLAN))
+	}
+
 	for i, bridge := range netConfig.ControllerWANBridges {
 		checkBridge(fmt.Sprintf("Controller WAN %d", i+1), bridge)
 	}
 
+	// VLANs pair with WAN bridges by position; a short list silently leaves
+	// the trailing WANs untagged
+	wans, vlans := len(netConfig.ControllerWANBridges), len(netConfig.ControllerWANVLANs)
+	if vlans > 0 && vlans < wans {
+		errors = append(errors, fmt.Sprintf("Controller WAN %s: no VLAN provided, interface(s) will be untagged (give one VLAN per WAN bridge, 0 for untagged)",
+			interfaceRange(vlans+1, wans)))
+	} else if vlans > wans {
+		errors = append(errors, fmt.Sprintf("Controller WAN: %d VLANs given for %d bridge(s), the extra VLANs are ignored", vlans, wans))
+	}
+
 	return errors
 }
 
+// interfaceRange formats 1-based interface numbers as "2" or "2-3"
+func interfaceRange(first, last int) string {
+	if first == last {
+		return fmt.Sprintf("%d", first)
+	}
+	return fmt.Sprintf("%d-%d", first, last)
+}
+
 // SuggestNetworkConfig suggests a network configuration based on available networks
 func SuggestNetworkConfig(available []proxmox.NetworkInfo) config.NetworkConfig {
 	cfg := config.NetworkConfig{}
@@ -221,7 +320,7 @@ func NetworkRequirements() map[config.ComponentType][]string {
 		},
 		config.ComponentAnalytics: {
 			"eth0: Management (Northbound)",
-			"eth1: Southbound (to Director/Router)",
+			"eth1: Southbound (dedicated, or shared Director/Router link)",
 			"eth2: Cluster Sync (optional)",
 		},
 		config.ComponentController: {
@@ -0,0 +1,132 @@
+package deployer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/config"
+	"github.com/mihailvovk/versa-proxmox-deployer/proxmox"
+	"github.com/mihailvovk/versa-proxmox-deployer/ssh"
+)
+
+// PendingNetworks are the interfaces held back from a VM created with
+// ManagementOnlyFirst, to be added once the management plane is verified
+type PendingNetworks struct {
+	Host     string              `json:"host"`
+	VMID     int                 `json:"vmid"`
+	Name     string              `json:"name"`
+	First    int                 `json:"first"` // Index of the first pending netN
+	Networks []proxmox.VMNetwork `json:"networks"`
+}
+
+// pendingNetworksPath is the sidecar file holding every host's pending interfaces
+func pendingNetworksPath() string {
+	return filepath.Join(config.ConfigDir(), "pending-networks.json")
+}
+
+func pendingKey(host string, vmid int) string {
+	return fmt.Sprintf("%s/%d", host, vmid)
+}
+
+func loadPendingNetworks() (map[string]PendingNetworks, error) {
+	pending := make(map[string]PendingNetworks)
+	data, err := os.ReadFile(pendingNetworksPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return pending, nil
+		}
+		return nil, fmt.Errorf("reading pending networks: %w", err)
+	}
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return nil, fmt.Errorf("parsing pending networks: %w", err)
+	}
+	return pending, nil
+}
+
+func savePendingNetworks(pending map[string]PendingNetworks) error {
+	data, err := json.MarshalIndent(pending, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling pending networks: %w", err)
+	}
+	if err := os.WriteFile(pendingNetworksPath(), data, 0600); err != nil {
+		return fmt.Errorf("writing pending networks: %w", err)
+	}
+	return nil
+}
+
+// recordPendingNetworks saves the held-back interfaces of every created VM
+func (d *Deployer) recordPendingNetworks(result *DeploymentResult) error {
+	pending, err := loadPendingNetworks()
+	if err != nil {
+		return err
+	}
+
+	added := 0
+	host := d.sshClient.Host()
+	for _, vm := range result.VMs {
+		if len(vm.PendingNetworks) == 0 || vm.Status == "removed" {
+			continue
+		}
+		pending[pendingKey(host, vm.VMID)] = PendingNetworks{
+			Host:     host,
+			VMID:     vm.VMID,
+			Name:     vm.Name,
+			First:    1,
+			Networks: vm.PendingNetworks,
+		}
+		added++
+	}
+	if added == 0 {
+		return nil
+	}
+	return savePendingNetworks(pending)
+}
+
+// ListPendingNetworks returns the VMs on host still waiting for their
+// remaining interfaces, ordered by VMID
+func ListPendingNetworks(host string) ([]PendingNetworks, error) {
+	pending, err := loadPendingNetworks()
+	if err != nil {
+		return nil, err
+	}
+
+	var list []PendingNetworks
+	for _, p := range pending {
+		if p.Host == host {
+			list = append(list, p)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].VMID < list[j].VMID })
+	return list, nil
+}
+
+// AddRemainingNetworks attaches the interfaces held back when a
+// deployer-managed VM was created management-only, then forgets them
+func AddRemainingNetworks(client *ssh.Client, vmid int) (*PendingNetworks, error) {
+	pending, err := loadPendingNetworks()
+	if err != nil {
+		return nil, err
+	}
+	key := pendingKey(client.Host(), vmid)
+	p, ok := pending[key]
+	if !ok {
+		return nil, fmt.Errorf("VM %d has no pending interfaces", vmid)
+	}
+
+	if _, err := proxmox.NewDiscoverer(client).FindManagedVM(vmid); err != nil {
+		return nil, err
+	}
+
+	if err := proxmox.NewVMCreator(client).SetNetworks(vmid, p.Networks, p.First); err != nil {
+		return nil, fmt.Errorf("adding interfaces to VM %d: %w", vmid, err)
+	}
+
+	delete(pending, key)
+	if err := savePendingNetworks(pending); err != nil {
+		return &p, fmt.Errorf("interfaces added, but %w", err)
+	}
+	return &p, nil
+}
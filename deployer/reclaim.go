@@ -0,0 +1,110 @@
+package deployer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/config"
+	"github.com/mihailvovk/versa-proxmox-deployer/proxmox"
+	"github.com/mihailvovk/versa-proxmox-deployer/ssh"
+)
+
+// ParseVMName splits a deployer-style VM name ("<prefix>-<component>" or
+// "<prefix>-<component>-<N>") into its parts. haIndex is 0 for unnumbered VMs.
+func ParseVMName(name string) (prefix string, comp config.ComponentType, haIndex int, ok bool) {
+	base := name
+	if i := strings.LastIndex(name, "-"); i > 0 {
+		if n, err := strconv.Atoi(name[i+1:]); err == nil && n > 0 {
+			base = name[:i]
+			haIndex = n
+		}
+	}
+
+	for _, ct := range config.AllComponents() {
+		suffix := "-" + string(ct)
+		if strings.HasSuffix(base, suffix) && len(base) > len(suffix) {
+			return strings.TrimSuffix(base, suffix), ct, haIndex, true
+		}
+	}
+	return "", "", 0, false
+}
+
+// ReclaimResult describes the tags re-applied to a reclaimed VM
+type ReclaimResult struct {
+	VMID      int                  `json:"vmid"`
+	Name      string               `json:"name"`
+	Prefix    string               `json:"prefix"`
+	Component config.ComponentType `json:"component"`
+	Tags      []string             `json:"tags"`
+}
+
+// ReclaimVM brings a VM that lost its versa-deployer tag back under
+// management by re-applying the deployer, component and prefix tags derived
+// from its name. This bypasses the tag safety check on stop/delete, so confirm
+// must equal the VM's exact name. Existing tags are kept.
+func ReclaimVM(client *ssh.Client, vmid int, confirm string) (*ReclaimResult, error) {
+	vms, err := proxmox.NewDiscoverer(client).GetVMs()
+	if err != nil {
+		return nil, fmt.Errorf("listing VMs: %w", err)
+	}
+
+	var vm *proxmox.VMInfo
+	for i := range vms {
+		if vms[i].VMID == vmid {
+			vm = &vms[i]
+			break
+		}
+	}
+	if vm == nil {
+		return nil, fmt.Errorf("VM %d not found", vmid)
+	}
+	if vm.TagsUnknown {
+		// Rewriting tags we couldn't read would drop them
+		return nil, fmt.Errorf("could not read the tags of VM %d; try again", vmid)
+	}
+
+	if confirm != vm.Name {
+		return nil, fmt.Errorf("confirmation does not match: type the VM name %q to reclaim VM %d", vm.Name, vmid)
+	}
+
+	prefix, comp, haIndex, ok := ParseVMName(vm.Name)
+	if !ok {
+		return nil, fmt.Errorf("VM %d name %q does not match <prefix>-<component>[-N]; refusing to reclaim", vmid, vm.Name)
+	}
+
+	// Keep whatever tags remain and add the missing management tags
+	tags := append([]string{}, vm.Tags...)
+	want := []string{
+		config.TagVersaDeployer,
+		config.GetComponentTag(comp),
+		fmt.Sprintf("versa-deploy-%s", prefix),
+	}
+	if haIndex > 0 {
+		want = append(want, fmt.Sprintf("versa-ha-%d", haIndex))
+	}
+	for _, tag := range want {
+		found := false
+		for _, existing := range tags {
+			if existing == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			tags = append(tags, tag)
+		}
+	}
+
+	if err := proxmox.NewVMCreator(client).SetVMTags(vmid, tags); err != nil {
+		return nil, fmt.Errorf("setting tags on VM %d: %w", vmid, err)
+	}
+
+	return &ReclaimResult{
+		VMID:      vmid,
+		Name:      vm.Name,
+		Prefix:    prefix,
+		Component: comp,
+		Tags:      tags,
+	}, nil
+}
@@ -0,0 +1,135 @@
+package deployer
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/config"
+	"github.com/mihailvovk/versa-proxmox-deployer/director"
+)
+
+// registrationPollInterval is the pause between Director registration checks
+const registrationPollInterval = 30 * time.Second
+
+// expectedRegistrations lists the running VMs that should register with the
+// Director: Analytics nodes and Controllers
+func expectedRegistrations(vms []VMResult) []director.ExpectedComponent {
+	var expected []director.ExpectedComponent
+	for _, vm := range vms {
+		if vm.Status != "running" {
+			continue
+		}
+		switch vm.Component {
+		case config.ComponentAnalytics:
+			expected = append(expected, director.ExpectedComponent{Name: vm.Name, Type: "Analytics", IP: vm.IP})
+		case config.ComponentController:
+			expected = append(expected, director.ExpectedComponent{Name: vm.Name, Type: "Controller", IP: vm.IP})
+		}
+	}
+	return expected
+}
+
+// registrationClient returns a Director client for the registration check,
+// or nil when no Director address or credentials are configured
+func (d *Deployer) registrationClient() *director.Client {
+	reg := d.config.Registration
+	if reg.DirectorIP == "" || reg.Password == "" {
+		return nil
+	}
+	return director.NewClient(director.ClientConfig{
+		Host:     reg.DirectorIP,
+		Username: reg.Username,
+		Password: reg.Password,
+		Insecure: true,
+	})
+}
+
+// snapshotRegistrations records the nodes already registered with the
+// Director before any VM is created, so waitForRegistration can tell a new
+// Analytics node without a known IP from an existing one
+func (d *Deployer) snapshotRegistrations() {
+	var wanted bool
+	for _, comp := range d.config.Components {
+		if comp.Type == config.ComponentAnalytics {
+			wanted = true
+		}
+	}
+	client := d.registrationClient()
+	if !wanted || client == nil {
+		return
+	}
+	known, err := client.SnapshotNodes()
+	if err != nil {
+		d.log(fmt.Sprintf("WARNING: could not list nodes registered with Director: %v", err))
+		return
+	}
+	d.knownNodes = known
+}
+
+// waitForRegistration polls the Director until every new Analytics and
+// Controller VM is reported healthy, the timeout passes or the deployment is
+// cancelled. Components that never register are logged as warnings; VMs are
+// left as they are.
+func (d *Deployer) waitForRegistration(result *DeploymentResult) {
+	reg := d.config.Registration
+	expected := expectedRegistrations(result.VMs)
+	if len(expected) == 0 {
+		return
+	}
+	client := d.registrationClient()
+	if client == nil {
+		d.log("Skipping Director registration check: no Director address or credentials")
+		return
+	}
+
+	timeout := reg.Timeout
+	if timeout <= 0 {
+		timeout = config.DefaultRegistrationTimeout
+	}
+
+	d.log(fmt.Sprintf("Waiting up to %s for %d components to register with Director %s...",
+		timeout, len(expected), reg.DirectorIP))
+	deadline := time.Now().Add(timeout)
+
+	var regs []director.Registration
+	var lastErr error
+	for {
+		current, err := client.CheckRegistrations(expected, d.knownNodes)
+		if err != nil {
+			lastErr = err
+		} else {
+			regs, lastErr = current, nil
+			done := 0
+			for _, r := range regs {
+				if r.Registered {
+					done++
+				}
+			}
+			d.progress(StageRegistration, done, len(expected))
+			if done == len(expected) {
+				break
+			}
+		}
+
+		if time.Now().Add(registrationPollInterval).After(deadline) {
+			break
+		}
+		if err := d.wait(registrationPollInterval); err != nil {
+			d.log(fmt.Sprintf("Stopped waiting for Director registration: %v", err))
+			break
+		}
+	}
+
+	if regs == nil {
+		d.log(fmt.Sprintf("WARNING: Could not check Director registration: %v", lastErr))
+		return
+	}
+	result.Registrations = regs
+	for _, r := range regs {
+		if r.Registered {
+			d.log(fmt.Sprintf("%s registered with Director (%s)", r.Name, r.Status))
+		} else {
+			d.log(fmt.Sprintf("WARNING: %s has not registered with Director after %s (status: %s)", r.Name, timeout, r.Status))
+		}
+	}
+}
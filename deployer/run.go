@@ -0,0 +1,233 @@
+// Package deployer deploys Versa HeadEnd components as Proxmox VMs.
+//
+// Run is the library entry point: it connects, discovers, validates and
+// deploys in one call. A program embedding the deployer needs only
+//
+//	import (
+//		"github.com/mihailvovk/versa-proxmox-deployer/config"
+//		"github.com/mihailvovk/versa-proxmox-deployer/deployer"
+//		"github.com/mihailvovk/versa-proxmox-deployer/ssh"
+//	)
+//
+// plus sources, when the deployer should pick ISOs from image sources:
+//
+//	cfg := config.NewDeploymentConfig()
+//	cfg.Prefix = "lab"
+//	cfg.StoragePool = "local-lvm"
+//	cfg.Networks.NorthboundBridge = "vmbr0"
+//	cfg.Components = []config.ComponentConfig{{Type: config.ComponentDirector, Count: 1, CPU: 8, RAMGB: 16, DiskGB: 100, ISOPath: "versa-director.iso"}}
+//
+//	result, err := deployer.Run(ctx, deployer.DeployRequest{
+//		SSH:    ssh.ClientOptions{Host: "pve.example.com", User: "root", KeyPath: "~/.ssh/id_ed25519"},
+//		Config: cfg,
+//		OnLog:  func(msg string) { log.Println(msg) },
+//	})
+//
+// Prepare runs the same steps short of deploying, for callers that preflight
+// first or need the Deployer while it runs, as the web UI does.
+package deployer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/config"
+	"github.com/mihailvovk/versa-proxmox-deployer/sources"
+	"github.com/mihailvovk/versa-proxmox-deployer/ssh"
+)
+
+var (
+	// ErrConnection is wrapped by Run when the SSH connection or discovery fails
+	ErrConnection = errors.New("connection failed")
+
+	// ErrNoISO is wrapped (with ErrValidation) by Run when a component has
+	// no ISOPath and no known image provides one
+	ErrNoISO = errors.New("no ISO available")
+)
+
+// DeployRequest describes one deployment for Run
+type DeployRequest struct {
+	// SSH is used to connect when Client is nil
+	SSH ssh.ClientOptions
+	// Client is an already-connected client; Run does not close it
+	Client *ssh.Client
+
+	Config *config.DeploymentConfig
+
+	// Sources are scanned for the newest ISO of every component without an
+	// ISOPath, and serve as download sources for images missing on Proxmox
+	Sources []sources.ImageSource
+	// KnownImages, if set, are used instead of scanning Sources
+	KnownImages []sources.ISOFile
+
+	// AssignNodes places components across Nodes (empty = all online nodes)
+	// with Strategy after discovery. Leave false when every component's Node is set.
+	AssignNodes bool
+	Nodes       []string
+	Strategy    DistributionStrategy
+
+	// DryRun logs the planned ISO transfers and VMs without creating anything
+	DryRun bool
+	// MaxConcurrentDownloads caps ISOs prepared at once (0 = DefaultConcurrentDownloads)
+	MaxConcurrentDownloads int
+
+	OnLog      func(message string)
+	OnProgress func(stage string, current, total int)
+	OnTransfer func(t TransferProgress)
+	// Called with the running Proxmox-side downloads whenever they change
+	OnDownloadTasks func(tasks []DownloadTask)
+}
+
+// Run connects, discovers, validates and deploys. Errors wrap ErrConnection
+// or ErrValidation when nothing was created; otherwise the result tells
+// whether VMs were rolled back. ctx is checked between phases and stops
+// ISO downloads running on Proxmox; once VM creation has started the
+// deployment runs to completion.
+func Run(ctx context.Context, req DeployRequest) (*DeploymentResult, error) {
+	if req.Config == nil {
+		return nil, fmt.Errorf("%w: no deployment configuration", ErrValidation)
+	}
+	if req.Client == nil {
+		client, err := connect(req.SSH)
+		if err != nil {
+			return nil, err
+		}
+		defer client.Close()
+		req.Client = client
+	}
+
+	d, err := Prepare(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return d.Deploy()
+}
+
+// Prepare does everything Run does before deploying: it resolves each
+// component's ISO, discovers Proxmox and assigns nodes, returning a Deployer
+// ready for Preflight or Deploy. Unlike Run it needs req.Client, which must
+// stay open until the deployment is done.
+func Prepare(ctx context.Context, req DeployRequest) (*Deployer, error) {
+	if req.Client == nil {
+		return nil, fmt.Errorf("%w: no SSH client", ErrConnection)
+	}
+	d, err := newRequestDeployer(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	d.SetDryRun(req.DryRun)
+	d.MaxConcurrentDownloads = req.MaxConcurrentDownloads
+	d.SetContext(ctx)
+	d.OnProgress = req.OnProgress
+	d.OnTransfer = req.OnTransfer
+	d.OnDownloadTasks = req.OnDownloadTasks
+
+	if _, err := d.Discover(); err != nil {
+		return nil, fmt.Errorf("%w: discovery failed: %w", ErrConnection, err)
+	}
+
+	if req.AssignNodes {
+		if err := d.AssignNodes(req.Nodes, req.Strategy); err != nil {
+			return nil, fmt.Errorf("%w: assigning nodes: %w", ErrValidation, err)
+		}
+	}
+	return d, nil
+}
+
+// connect opens the SSH connection for a request without a Client
+func connect(opts ssh.ClientOptions) (*ssh.Client, error) {
+	client, err := ssh.NewClient(opts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConnection, err)
+	}
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrConnection, err)
+	}
+	return client, nil
+}
+
+// newRequestDeployer scans the request's sources unless images are known,
+// resolves each component's ISO and returns a Deployer for req.Client
+func newRequestDeployer(ctx context.Context, req DeployRequest) (*Deployer, error) {
+	if req.Config == nil {
+		return nil, fmt.Errorf("%w: no deployment configuration", ErrValidation)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	images := req.KnownImages
+	if images == nil && len(req.Sources) > 0 {
+		if req.OnLog != nil {
+			req.OnLog("Scanning image sources...")
+		}
+		collection, err := sources.ScanAllSources(req.Sources, sources.DefaultScanOptions)
+		if err != nil {
+			return nil, fmt.Errorf("scanning image sources: %w", err)
+		}
+		images = collection.All()
+	}
+	if err := resolveLatestISOs(req.Config.Components, images, req.Config.ISOPolicy); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	d := NewDeployer(req.Client, req.Sources)
+	d.SetConfig(req.Config)
+	d.SetKnownImages(images)
+	d.OnLog = req.OnLog
+	return d, nil
+}
+
+// resolveLatestISOs picks the image the ISO policy selects for every
+// component without an ISOPath or template
+func resolveLatestISOs(components []config.ComponentConfig, images []sources.ISOFile, policy config.ISOPolicy) error {
+	var eligible []sources.ISOFile
+	for _, img := range images {
+		if isoPolicyAllows(policy, img.Version) {
+			eligible = append(eligible, img)
+		}
+	}
+	collection := sources.NewISOCollection(eligible)
+
+	var missing []string
+	for i := range components {
+		comp := &components[i]
+		if comp.ISOPath != "" || comp.TemplateVMID != 0 {
+			continue
+		}
+		latest := collection.GetLatestISO(comp.Type)
+		if latest == nil {
+			missing = append(missing, string(comp.Type))
+			continue
+		}
+		comp.ISOPath = latest.Filename
+		comp.Version = latest.Version
+	}
+	if len(missing) > 0 {
+		if policy.Mode != "" && policy.Mode != config.ISOLatest {
+			return fmt.Errorf("%w: %w matching ISO policy %s for: %s", ErrValidation, ErrNoISO, policy, strings.Join(missing, ", "))
+		}
+		return fmt.Errorf("%w: %w for: %s", ErrValidation, ErrNoISO, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// isoPolicyAllows reports whether an image version is eligible under policy
+func isoPolicyAllows(policy config.ISOPolicy, version string) bool {
+	switch policy.Mode {
+	case config.ISOLatestStable:
+		return !sources.IsPrerelease(version)
+	case config.ISOLatestInMajor:
+		return !sources.IsPrerelease(version) && sources.VersionMajor(version) == policy.Major
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package deployer
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/config"
+	"github.com/mihailvovk/versa-proxmox-deployer/sources"
+)
+
+func TestResolveLatestISOs(t *testing.T) {
+	images := []sources.ISOFile{
+		{Filename: "versa-director-22.1.3.iso", Component: config.ComponentDirector, Version: "22.1.3"},
+		{Filename: "versa-director-23.1.1-rc1.iso", Component: config.ComponentDirector, Version: "23.1.1-rc1"},
+		{Filename: "versa-director-22.1.4-B.iso", Component: config.ComponentDirector, Version: "22.1.4-B"},
+		{Filename: "versa-flexvnf-22.1.4.iso", Component: config.ComponentFlexVNF, Version: "22.1.4"},
+	}
+
+	tests := []struct {
+		policy  string
+		want    map[config.ComponentType]string
+		wantErr bool
+	}{
+		{"latest", map[config.ComponentType]string{
+			config.ComponentDirector:   "versa-director-23.1.1-rc1.iso",
+			config.ComponentController: "versa-flexvnf-22.1.4.iso",
+		}, false},
+		{"latest-stable", map[config.ComponentType]string{
+			config.ComponentDirector:   "versa-director-22.1.4-B.iso",
+			config.ComponentController: "versa-flexvnf-22.1.4.iso",
+		}, false},
+		{"latest-in-major:23", nil, true},
+	}
+	for _, tt := range tests {
+		policy, err := config.ParseISOPolicy(tt.policy)
+		if err != nil {
+			t.Fatal(err)
+		}
+		components := []config.ComponentConfig{
+			{Type: config.ComponentDirector},
+			{Type: config.ComponentController},
+			{Type: config.ComponentAnalytics, ISOPath: "custom-analytics.iso"},
+		}
+		err = resolveLatestISOs(components, images, policy)
+		if tt.wantErr {
+			if !errors.Is(err, ErrNoISO) {
+				t.Errorf("%s: error %v, want ErrNoISO", tt.policy, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.policy, err)
+			continue
+		}
+		for _, comp := range components {
+			want, ok := tt.want[comp.Type]
+			if !ok {
+				want = "custom-analytics.iso"
+			}
+			if comp.ISOPath != want {
+				t.Errorf("%s: %s resolved to %q, want %q", tt.policy, comp.Type, comp.ISOPath, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package deployer
+
+import (
+	"fmt"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/config"
+	"github.com/mihailvovk/versa-proxmox-deployer/proxmox"
+)
+
+// templateVM returns the existing VM a component clones, or nil when it
+// isn't on the cluster
+func (d *Deployer) templateVM(comp config.ComponentConfig) *proxmox.VMInfo {
+	for i, vm := range d.proxmoxInfo.ExistingVMs {
+		if vm.VMID == comp.TemplateVMID {
+			return &d.proxmoxInfo.ExistingVMs[i]
+		}
+	}
+	return nil
+}
+
+// validateTemplates checks that every component deployed from a template
+// clones an existing template on the connected node, where qm clone runs
+func (d *Deployer) validateTemplates(report *ValidationReport) {
+	local := d.localNode()
+	for _, comp := range d.config.Components {
+		if comp.TemplateVMID == 0 {
+			continue
+		}
+		tmpl := d.templateVM(comp)
+		if tmpl == nil {
+			report.Errorf("template VM %d for %s not found", comp.TemplateVMID, comp.Type)
+			continue
+		}
+		if local != "" && tmpl.Node != local {
+			report.Errorf("template VM %d for %s is on node %s; connect to %s to clone it",
+				comp.TemplateVMID, comp.Type, tmpl.Node, tmpl.Node)
+			continue
+		}
+		if err := d.vmCreator.CheckTemplate(comp.TemplateVMID); err != nil {
+			report.Errorf("%s: %v", comp.Type, err)
+			continue
+		}
+		if comp.ISOPath != "" {
+			report.Warnf("%s clones template VM %d, ignoring ISO %s", comp.Type, comp.TemplateVMID, comp.ISOPath)
+		}
+	}
+}
+
+// cloneTarget returns the node to pass to qm clone --target: empty when the
+// clone stays on the template's node
+func (d *Deployer) cloneTarget(comp config.ComponentConfig, vmConfig proxmox.VMConfig) string {
+	if tmpl := d.templateVM(comp); tmpl != nil && tmpl.Node == vmConfig.Node {
+		return ""
+	}
+	return vmConfig.Node
+}
+
+// cloneVM creates a component's VM as a full clone of its template, then
+// applies the component's CPU, RAM and network settings. A clone that can't
+// be configured is destroyed again.
+func (d *Deployer) cloneVM(comp config.ComponentConfig, vmConfig proxmox.VMConfig) error {
+	if err := d.vmCreator.CloneVM(comp.TemplateVMID, vmConfig.VMID, vmConfig.Name, vmConfig.Storage, d.cloneTarget(comp, vmConfig)); err != nil {
+		return err
+	}
+	if err := d.vmCreator.ConfigureClone(vmConfig); err != nil {
+		if derr := d.vmCreator.DestroyVM(vmConfig.VMID); derr != nil {
+			d.log(fmt.Sprintf("WARNING: failed to remove unconfigured clone %d: %v", vmConfig.VMID, derr))
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package deployer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/proxmox"
+	"github.com/mihailvovk/versa-proxmox-deployer/ssh"
+)
+
+// TrimVM runs fstrim inside a running deployer-managed VM through the guest
+// agent, so thin-provisioned storage reclaims blocks freed since install.
+// Disks are created with discard=on, which the trim needs to reach storage.
+func TrimVM(client *ssh.Client, vmid int) ([]proxmox.FSTrimResult, error) {
+	vm, err := proxmox.NewDiscoverer(client).FindManagedVM(vmid)
+	if err != nil {
+		return nil, err
+	}
+	if vm.Status != "running" {
+		return nil, fmt.Errorf("VM %d is %s; start it to trim its disks", vm.VMID, vm.Status)
+	}
+
+	results, err := proxmox.NewVMCreator(client).TrimDisks(vm.VMID)
+	if errors.Is(err, proxmox.ErrGuestAgentUnavailable) {
+		return nil, fmt.Errorf("%w (deploy with --guest-agent, or run 'fstrim -av' in the VM console)", err)
+	}
+	return results, err
+}
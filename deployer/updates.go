@@ -0,0 +1,53 @@
+package deployer
+
+import (
+	"github.com/mihailvovk/versa-proxmox-deployer/config"
+	"github.com/mihailvovk/versa-proxmox-deployer/proxmox"
+	"github.com/mihailvovk/versa-proxmox-deployer/sources"
+)
+
+// ComponentUpdate compares a deployed VM's version with the newest ISO available
+type ComponentUpdate struct {
+	VMID            int                  `json:"vmid"`
+	Name            string               `json:"name"`
+	Component       config.ComponentType `json:"component"`
+	DeployedVersion string               `json:"deployedVersion"`
+	LatestVersion   string               `json:"latestVersion"`
+	LatestISO       string               `json:"latestIso"`
+}
+
+// CheckForUpdates reports VMs whose versa-version tag is older than the newest
+// ISO for their component. It only uses Proxmox tags and the source scan, so
+// Director doesn't need to be reachable. VMs without a version tag are skipped.
+func CheckForUpdates(vms []proxmox.VMInfo, collection *sources.ISOCollection) []ComponentUpdate {
+	if collection == nil {
+		return nil
+	}
+
+	var updates []ComponentUpdate
+	for _, vm := range vms {
+		version := vm.Version
+		if version == "" {
+			version = config.VersionFromTags(vm.Tags)
+		}
+		comp := config.ComponentFromTags(vm.Tags)
+		if version == "" || comp == "" {
+			continue
+		}
+
+		latest := collection.GetLatestISO(comp)
+		if latest == nil || sources.CompareVersions(latest.Version, version) <= 0 {
+			continue
+		}
+
+		updates = append(updates, ComponentUpdate{
+			VMID:            vm.VMID,
+			Name:            vm.Name,
+			Component:       comp,
+			DeployedVersion: version,
+			LatestVersion:   latest.Version,
+			LatestISO:       latest.Filename,
+		})
+	}
+	return updates
+}
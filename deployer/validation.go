@@ -0,0 +1,41 @@
+package deployer
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ValidationReport collects every problem found before a deployment, so
+// operators can fix them all in one pass
+type ValidationReport struct {
+	Errors   []string `json:"errors"`
+	Warnings []string `json:"warnings"`
+}
+
+// Errorf records a problem that blocks the deployment
+func (r *ValidationReport) Errorf(format string, args ...any) {
+	r.Errors = append(r.Errors, fmt.Sprintf(format, args...))
+}
+
+// Warnf records a problem that does not block the deployment
+func (r *ValidationReport) Warnf(format string, args ...any) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// OK reports whether the deployment may proceed
+func (r *ValidationReport) OK() bool {
+	return len(r.Errors) == 0
+}
+
+// Err returns nil when there are no errors, otherwise one error listing them all
+func (r *ValidationReport) Err() error {
+	switch len(r.Errors) {
+	case 0:
+		return nil
+	case 1:
+		return errors.New(r.Errors[0])
+	default:
+		return fmt.Errorf("%d problems: %s", len(r.Errors), strings.Join(r.Errors, "; "))
+	}
+}
@@ -0,0 +1,129 @@
+package deployer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/sources"
+)
+
+// Image verification outcomes
+const (
+	ImageOK         = "ok"
+	ImageMismatch   = "mismatch"
+	ImageMissing    = "missing"
+	ImageUnverified = "unverified" // No checksum known for the image
+	ImageError      = "error"
+)
+
+// ImageCheck is the result of re-verifying one ISO on Proxmox storage
+type ImageCheck struct {
+	ISO       string `json:"iso"`
+	Storage   string `json:"storage,omitempty"`
+	Filename  string `json:"filename,omitempty"` // Name on storage, if it differs from ISO
+	Algorithm string `json:"algorithm,omitempty"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Passed reports whether the ISO was found and its checksum matched
+func (c ImageCheck) Passed() bool {
+	return c.Status == ImageOK
+}
+
+// VerifyImages resolves the ISO of every configured component on Proxmox
+// storage and re-checks its checksum against the source metadata, without
+// deploying anything. A failed ISO can be fixed by deleting and re-uploading it.
+func (d *Deployer) VerifyImages() ([]ImageCheck, error) {
+	if d.config == nil {
+		return nil, fmt.Errorf("no deployment configuration")
+	}
+	isoStorages, err := d.discoverer.GetISOStorage()
+	if err != nil || len(isoStorages) == 0 {
+		return nil, fmt.Errorf("no ISO storage available")
+	}
+
+	var checks []ImageCheck
+	seen := make(map[string]bool)
+	for _, comp := range d.config.Components {
+		if comp.ISOPath == "" || seen[comp.ISOPath] {
+			continue
+		}
+		seen[comp.ISOPath] = true
+
+		check := ImageCheck{ISO: comp.ISOPath}
+		d.log(fmt.Sprintf("Verifying ISO: %s", comp.ISOPath))
+
+		meta := d.findKnownImage(comp.ISOPath)
+		if meta != nil {
+			d.downloader.FillChecksums(meta)
+		}
+
+		storage, filename, _ := d.storage.FindISOTolerant(isoStorages, comp.ISOPath)
+		if storage == "" && meta != nil {
+			// Deploys reuse same-content ISOs stored under another name
+			if meta.SHA256 != "" {
+				storage, filename, _ = d.storage.FindISOBySHA256(isoStorages, meta.SHA256)
+			}
+			if storage == "" && meta.MD5 != "" {
+				storage, filename, _ = d.storage.FindISOByMD5(isoStorages, meta.MD5)
+			}
+		}
+		if storage == "" {
+			check.Status = ImageMissing
+			checks = append(checks, check)
+			continue
+		}
+		check.Storage = storage
+		if filename != comp.ISOPath {
+			check.Filename = filename
+		}
+
+		var ok bool
+		switch {
+		case meta != nil && meta.SHA256 != "":
+			check.Algorithm = string(sources.ChecksumSHA256)
+			ok, err = d.storage.VerifyISOSHA256(storage, filename, meta.SHA256)
+		case meta != nil && meta.MD5 != "":
+			check.Algorithm = string(sources.ChecksumMD5)
+			ok, err = d.storage.VerifyISOMD5(storage, filename, meta.MD5)
+		default:
+			check.Status = ImageUnverified
+			checks = append(checks, check)
+			continue
+		}
+		switch {
+		case err != nil:
+			check.Status = ImageError
+			check.Error = err.Error()
+		case ok:
+			check.Status = ImageOK
+		default:
+			check.Status = ImageMismatch
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+// VerifyImages connects and scans sources like Run, resolves each
+// component's ISO the same way, then verifies the ISOs on Proxmox
+func VerifyImages(ctx context.Context, req DeployRequest) ([]ImageCheck, error) {
+	if req.Config == nil {
+		return nil, fmt.Errorf("%w: no deployment configuration", ErrValidation)
+	}
+	if req.Client == nil {
+		client, err := connect(req.SSH)
+		if err != nil {
+			return nil, err
+		}
+		defer client.Close()
+		req.Client = client
+	}
+
+	d, err := newRequestDeployer(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return d.VerifyImages()
+}
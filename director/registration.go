@@ -0,0 +1,146 @@
+package director
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ExpectedComponent is a newly deployed component that should register with
+// the Director
+type ExpectedComponent struct {
+	Name string // VM name, matched against the Director's node name
+	Type string // "Analytics" or "Controller"
+	IP   string // Management IP if known; preferred over the name for matching
+}
+
+// Registration is the Director's view of an expected component
+type Registration struct {
+	Name       string
+	Type       string
+	IP         string
+	Status     string // Director-reported status, "not registered" if absent
+	Registered bool
+}
+
+// KnownNodes are the Analytics and Controller nodes a Director reported
+// before a deployment, so nodes registered earlier aren't taken for new ones
+type KnownNodes map[string]bool
+
+// nodeKey identifies a node in KnownNodes
+func nodeKey(n *ComponentStatus) string {
+	return n.Type + "/" + n.Name + "/" + n.IP
+}
+
+// SnapshotNodes records the Analytics and Controller nodes the Director
+// currently reports
+func (c *Client) SnapshotNodes() (KnownNodes, error) {
+	analytics, err := c.getAnalyticsNodes()
+	if err != nil && !errors.Is(err, errNoAnalyticsNodes) {
+		return nil, fmt.Errorf("querying Director Analytics nodes: %w", err)
+	}
+	controllers, err := c.getControllersStatus()
+	if err != nil {
+		return nil, fmt.Errorf("querying Director Controllers: %w", err)
+	}
+	known := make(KnownNodes)
+	for _, n := range append(analytics, controllers...) {
+		known[nodeKey(n)] = true
+	}
+	return known, nil
+}
+
+// CheckRegistrations looks up each expected component in the Director's
+// Analytics and Controller lists. A component counts as registered once the
+// Director reports it healthy. known, from SnapshotNodes before the deploy,
+// tells new Analytics nodes from old ones when a component has no IP; nil
+// leaves such components "unknown".
+func (c *Client) CheckRegistrations(expected []ExpectedComponent, known KnownNodes) ([]Registration, error) {
+	var analytics, controllers []*ComponentStatus
+	var analyticsErr, controllersErr error
+	for _, e := range expected {
+		switch e.Type {
+		case "Analytics":
+			if analytics == nil && analyticsErr == nil {
+				analytics, analyticsErr = c.getAnalyticsNodes()
+			}
+		case "Controller":
+			if controllers == nil && controllersErr == nil {
+				controllers, controllersErr = c.getControllersStatus()
+			}
+		}
+	}
+	// Only lists an expected component needs are queried, so any failure
+	// leaves some component's status unknown
+	if analyticsErr != nil {
+		return nil, fmt.Errorf("querying Director Analytics nodes: %w", analyticsErr)
+	}
+	if controllersErr != nil {
+		return nil, fmt.Errorf("querying Director Controllers: %w", controllersErr)
+	}
+
+	results := make([]Registration, 0, len(expected))
+	for _, e := range expected {
+		reg := Registration{Name: e.Name, Type: e.Type, IP: e.IP, Status: "not registered"}
+
+		nodes := controllers
+		if e.Type == "Analytics" {
+			nodes = analytics
+		}
+		node, ambiguous := matchComponent(nodes, e, known)
+		if ambiguous {
+			reg.Status = "unknown"
+		}
+		if node != nil {
+			reg.Status = node.Status
+			reg.Registered = node.Status == "healthy"
+			if reg.IP == "" {
+				reg.IP = node.IP
+			}
+		}
+		results = append(results, reg)
+	}
+	return results, nil
+}
+
+// matchComponent finds an expected component by IP, then by a name only one
+// node has. Analytics nodes all share one name, so without an IP an
+// Analytics component matches the one node not in known. ambiguous is set
+// when several nodes could be the component.
+func matchComponent(nodes []*ComponentStatus, e ExpectedComponent, known KnownNodes) (node *ComponentStatus, ambiguous bool) {
+	if e.IP != "" {
+		for _, n := range nodes {
+			if n.IP == e.IP {
+				return n, false
+			}
+		}
+		return nil, false
+	}
+
+	var named []*ComponentStatus
+	for _, n := range nodes {
+		if strings.EqualFold(n.Name, e.Name) {
+			named = append(named, n)
+		}
+	}
+	if len(named) == 1 {
+		return named[0], false
+	}
+	if e.Type != "Analytics" {
+		return nil, len(named) > 1
+	}
+
+	if known == nil {
+		return nil, len(nodes) > 0
+	}
+	var added []*ComponentStatus
+	for _, n := range nodes {
+		if !known[nodeKey(n)] {
+			added = append(added, n)
+		}
+	}
+	if len(added) == 1 {
+		return added[0], false
+	}
+	return nil, len(added) > 1
+}
@@ -0,0 +1,36 @@
+package director
+
+import "testing"
+
+func TestMatchComponent(t *testing.T) {
+	old := &ComponentStatus{Name: "Analytics", Type: "Analytics", IP: "10.0.0.20", Status: "healthy"}
+	fresh := &ComponentStatus{Name: "Analytics", Type: "Analytics", IP: "10.0.0.21", Status: "degraded"}
+	ctrl := &ComponentStatus{Name: "lab-controller-1", Type: "Controller", IP: "10.0.0.30", Status: "healthy"}
+	known := KnownNodes{nodeKey(old): true}
+
+	tests := []struct {
+		name          string
+		nodes         []*ComponentStatus
+		expected      ExpectedComponent
+		known         KnownNodes
+		want          *ComponentStatus
+		wantAmbiguous bool
+	}{
+		{"by IP", []*ComponentStatus{old, fresh}, ExpectedComponent{Name: "lab-analytics-1", Type: "Analytics", IP: "10.0.0.21"}, known, fresh, false},
+		{"IP not registered", []*ComponentStatus{old}, ExpectedComponent{Name: "lab-analytics-1", Type: "Analytics", IP: "10.0.0.21"}, known, nil, false},
+		{"only a pre-existing healthy node", []*ComponentStatus{old}, ExpectedComponent{Name: "lab-analytics-1", Type: "Analytics"}, known, nil, false},
+		{"the one node added since the snapshot", []*ComponentStatus{old, fresh}, ExpectedComponent{Name: "lab-analytics-1", Type: "Analytics"}, known, fresh, false},
+		{"no snapshot", []*ComponentStatus{old}, ExpectedComponent{Name: "lab-analytics-1", Type: "Analytics"}, nil, nil, true},
+		{"several nodes added", []*ComponentStatus{old, fresh, {Name: "Analytics", Type: "Analytics", IP: "10.0.0.22"}}, ExpectedComponent{Name: "lab-analytics-1", Type: "Analytics"}, known, nil, true},
+		{"controller by name", []*ComponentStatus{ctrl}, ExpectedComponent{Name: "LAB-controller-1", Type: "Controller"}, nil, ctrl, false},
+		{"controller name shared", []*ComponentStatus{ctrl, {Name: "lab-controller-1", Type: "Controller", IP: "10.0.0.31"}}, ExpectedComponent{Name: "lab-controller-1", Type: "Controller"}, nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ambiguous := matchComponent(tt.nodes, tt.expected, tt.known)
+			if got != tt.want || ambiguous != tt.wantAmbiguous {
+				t.Errorf("matchComponent() = %+v, %v; want %+v, %v", got, ambiguous, tt.want, tt.wantAmbiguous)
+			}
+		})
+	}
+}
@@ -1,7 +1,12 @@
 package director
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
 )
 
 // HeadEndStatus holds the status of the entire HeadEnd deployment
@@ -37,6 +42,9 @@ type BranchStatus struct {
 	OnlineCount   int
 	OfflineCount  int
 	Devices       []BranchDevice
+
+	Truncated  bool     // Some pages could not be fetched
+	PageErrors []string // Errors for the missing pages
 }
 
 // BranchDevice represents a single branch device
@@ -125,6 +133,18 @@ func (c *Client) GetHeadEndStatus() (*HeadEndStatus, error) {
 
 // getAnalyticsStatus retrieves Analytics node status
 func (c *Client) getAnalyticsStatus() (*ComponentStatus, error) {
+	nodes, err := c.getAnalyticsNodes()
+	if err != nil {
+		return nil, err
+	}
+	return nodes[0], nil
+}
+
+// errNoAnalyticsNodes is returned when the Director reports no Analytics nodes
+var errNoAnalyticsNodes = errors.New("no analytics nodes found")
+
+// getAnalyticsNodes retrieves the status of every Analytics node
+func (c *Client) getAnalyticsNodes() ([]*ComponentStatus, error) {
 	var result struct {
 		Nodes []struct {
 			IP      string `json:"ipAddress"`
@@ -142,18 +162,21 @@ func (c *Client) getAnalyticsStatus() (*ComponentStatus, error) {
 	}
 
 	if len(result.Nodes) == 0 {
-		return nil, fmt.Errorf("no analytics nodes found")
-	}
-
-	node := result.Nodes[0]
-	return &ComponentStatus{
-		Name:    "Analytics",
-		Type:    "Analytics",
-		IP:      node.IP,
-		Status:  normalizeStatus(node.Status),
-		Version: node.Version,
-		Uptime:  formatUptime(node.Uptime),
-	}, nil
+		return nil, errNoAnalyticsNodes
+	}
+
+	var nodes []*ComponentStatus
+	for _, node := range result.Nodes {
+		nodes = append(nodes, &ComponentStatus{
+			Name:    "Analytics",
+			Type:    "Analytics",
+			IP:      node.IP,
+			Status:  normalizeStatus(node.Status),
+			Version: node.Version,
+			Uptime:  formatUptime(node.Uptime),
+		})
+	}
+	return nodes, nil
 }
 
 // getControllersStatus retrieves Controller node statuses
@@ -192,51 +215,222 @@ func (c *Client) getControllersStatus() ([]*ComponentStatus, error) {
 	return controllers, nil
 }
 
-// GetBranchStatus retrieves the status of branch devices
+// BranchQuery controls how branch devices are fetched and filtered
+type BranchQuery struct {
+	Organization string // Only devices in this organization (case-insensitive)
+	Status       string // Only devices with this normalized status (healthy, offline, ...)
+	PageSize     int    // Devices per request (default 100)
+	Concurrency  int    // Parallel page fetches when the total is known (default 4)
+	MaxPages     int    // Pages fetched at most; more leave the result Truncated (default 1000)
+}
+
+// defaultMaxBranchPages bounds a walk against a Director that never ends it
+const defaultMaxBranchPages = 1000
+
+// branchPage is a single page of the appliances status endpoint
+type branchPage struct {
+	Devices []struct {
+		Name         string `json:"name"`
+		IP           string `json:"ipAddress"`
+		Status       string `json:"status"`
+		LastSeen     string `json:"lastSeen"`
+		Version      string `json:"softwareVersion"`
+		Template     string `json:"templateName"`
+		Organization string `json:"organizationName"`
+	} `json:"devices"`
+	Total         int    `json:"totalCount"`
+	Online        int    `json:"onlineCount"`
+	Offline       int    `json:"offlineCount"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// GetBranchStatus retrieves the status of all branch devices
 func (c *Client) GetBranchStatus() (*BranchStatus, error) {
-	var result struct {
-		Devices []struct {
-			Name         string `json:"name"`
-			IP           string `json:"ipAddress"`
-			Status       string `json:"status"`
-			LastSeen     string `json:"lastSeen"`
-			Version      string `json:"softwareVersion"`
-			Template     string `json:"templateName"`
-			Organization string `json:"organizationName"`
-		} `json:"devices"`
-		Total   int `json:"totalCount"`
-		Online  int `json:"onlineCount"`
-		Offline int `json:"offlineCount"`
-	}
-
-	if err := c.get("/api/v1/appliances/status", &result); err != nil {
-		// Try alternative
-		if err := c.get("/vnms/appliance/appliances", &result); err != nil {
+	return c.QueryBranchStatus(BranchQuery{})
+}
+
+// QueryBranchStatus retrieves branch devices page by page and applies filters.
+// When the Director reports a total count the remaining pages are fetched
+// concurrently; otherwise page tokens or short pages end the walk.
+func (c *Client) QueryBranchStatus(q BranchQuery) (*BranchStatus, error) {
+	if q.PageSize <= 0 {
+		q.PageSize = 100
+	}
+	if q.Concurrency <= 0 {
+		q.Concurrency = 4
+	}
+	if q.MaxPages <= 0 {
+		q.MaxPages = defaultMaxBranchPages
+	}
+
+	// First page also decides which endpoint this Director supports
+	endpoint := "/api/v1/appliances/status"
+	first, err := c.getBranchPage(endpoint, 0, q.PageSize, "")
+	if err != nil {
+		endpoint = "/vnms/appliance/appliances"
+		first, err = c.getBranchPage(endpoint, 0, q.PageSize, "")
+		if err != nil {
 			return nil, err
 		}
 	}
 
-	status := &BranchStatus{
-		TotalDevices: result.Total,
-		OnlineCount:  result.Online,
-		OfflineCount: result.Offline,
+	pages := []*branchPage{first}
+	var pageErrs []string
+	// Set when the walk stopped early: the page cap was hit, or the
+	// Director repeated a page token or ignored the offset
+	var incomplete bool
+
+	switch {
+	case first.NextPageToken != "":
+		// Token pagination is inherently sequential
+		seen := map[string]bool{}
+		token := first.NextPageToken
+		for token != "" {
+			if seen[token] || len(pages) >= q.MaxPages {
+				incomplete = true
+				break
+			}
+			seen[token] = true
+			page, err := c.getBranchPage(endpoint, 0, q.PageSize, token)
+			if err != nil {
+				pageErrs = append(pageErrs, err.Error())
+				break
+			}
+			pages = append(pages, page)
+			token = page.NextPageToken
+		}
+
+	case first.Total > len(first.Devices) && len(first.Devices) > 0:
+		// Offset pagination with a known total: fetch the rest in parallel
+		var offsets []int
+		for off := len(first.Devices); off < first.Total; off += q.PageSize {
+			if len(offsets)+1 >= q.MaxPages {
+				incomplete = true
+				break
+			}
+			offsets = append(offsets, off)
+		}
+
+		results := make([]*branchPage, len(offsets))
+		errs := make([]error, len(offsets))
+		sem := make(chan struct{}, q.Concurrency)
+		var wg sync.WaitGroup
+		for i, off := range offsets {
+			wg.Add(1)
+			go func(i, off int) {
+				defer wg.Done()
+				sem <- struct{}{}
+				defer func() { <-sem }()
+				results[i], errs[i] = c.getBranchPage(endpoint, off, q.PageSize, "")
+			}(i, off)
+		}
+		wg.Wait()
+
+		for i := range offsets {
+			if errs[i] != nil {
+				pageErrs = append(pageErrs, fmt.Sprintf("offset %d: %v", offsets[i], errs[i]))
+				continue
+			}
+			pages = append(pages, results[i])
+		}
+
+	case first.Total == 0 && len(first.Devices) == q.PageSize:
+		// No total reported: keep going until a short page
+		for off := q.PageSize; ; off += q.PageSize {
+			if len(pages) >= q.MaxPages {
+				incomplete = true
+				break
+			}
+			page, err := c.getBranchPage(endpoint, off, q.PageSize, "")
+			if err != nil {
+				pageErrs = append(pageErrs, err.Error())
+				break
+			}
+			if samePageStart(page, pages[len(pages)-1]) {
+				incomplete = true
+				break
+			}
+			pages = append(pages, page)
+			if len(page.Devices) < q.PageSize {
+				break
+			}
+		}
+	}
+
+	status := &BranchStatus{PageErrors: pageErrs}
+	for _, page := range pages {
+		for _, dev := range page.Devices {
+			device := BranchDevice{
+				Name:         dev.Name,
+				IP:           dev.IP,
+				Status:       normalizeStatus(dev.Status),
+				LastSeen:     dev.LastSeen,
+				Version:      dev.Version,
+				Template:     dev.Template,
+				Organization: dev.Organization,
+			}
+			if q.Organization != "" && !strings.EqualFold(device.Organization, q.Organization) {
+				continue
+			}
+			if q.Status != "" && device.Status != normalizeStatus(q.Status) {
+				continue
+			}
+			status.Devices = append(status.Devices, device)
+		}
 	}
 
-	for _, dev := range result.Devices {
-		status.Devices = append(status.Devices, BranchDevice{
-			Name:        dev.Name,
-			IP:          dev.IP,
-			Status:      normalizeStatus(dev.Status),
-			LastSeen:    dev.LastSeen,
-			Version:     dev.Version,
-			Template:    dev.Template,
-			Organization: dev.Organization,
-		})
+	fetched := 0
+	for _, page := range pages {
+		fetched += len(page.Devices)
+	}
+	status.Truncated = incomplete || len(pageErrs) > 0 || (first.Total > 0 && fetched < first.Total)
+
+	// Use the Director's counters for a complete unfiltered view, otherwise count what we have
+	if q.Organization == "" && q.Status == "" && !status.Truncated && first.Total > 0 {
+		status.TotalDevices = first.Total
+		status.OnlineCount = first.Online
+		status.OfflineCount = first.Offline
+	} else {
+		status.TotalDevices = len(status.Devices)
+		for _, dev := range status.Devices {
+			switch dev.Status {
+			case "healthy":
+				status.OnlineCount++
+			case "offline":
+				status.OfflineCount++
+			}
+		}
 	}
 
 	return status, nil
 }
 
+// samePageStart reports whether two pages begin with the same device, as
+// happens when a Director ignores the offset and returns page one again
+func samePageStart(a, b *branchPage) bool {
+	if len(a.Devices) == 0 || len(b.Devices) == 0 {
+		return false
+	}
+	return a.Devices[0].Name == b.Devices[0].Name && a.Devices[0].IP == b.Devices[0].IP
+}
+
+// getBranchPage fetches one page of branch devices
+func (c *Client) getBranchPage(endpoint string, offset, limit int, token string) (*branchPage, error) {
+	params := url.Values{}
+	params.Set("limit", strconv.Itoa(limit))
+	if token != "" {
+		params.Set("pageToken", token)
+	} else {
+		params.Set("offset", strconv.Itoa(offset))
+	}
+
+	var page branchPage
+	if err := c.get(endpoint+"?"+params.Encode(), &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
 // GetTenants retrieves list of tenants/organizations
 func (c *Client) GetTenants() ([]TenantInfo, error) {
 	var result struct {
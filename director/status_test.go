@@ -0,0 +1,103 @@
+package director
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// branchServer serves /api/v1/appliances/status from page, which gets the
+// request's offset and page token
+func branchServer(t *testing.T, page func(offset int, token string) map[string]any) *Client {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var offset int
+		fmt.Sscan(r.URL.Query().Get("offset"), &offset)
+		json.NewEncoder(w).Encode(page(offset, r.URL.Query().Get("pageToken")))
+	}))
+	t.Cleanup(srv.Close)
+	return &Client{baseURL: srv.URL, httpClient: srv.Client()}
+}
+
+// devices returns n devices named from start
+func devices(start, n int) []map[string]any {
+	var devs []map[string]any
+	for i := start; i < start+n; i++ {
+		devs = append(devs, map[string]any{"name": fmt.Sprintf("branch-%d", i), "status": "up"})
+	}
+	return devs
+}
+
+func TestQueryBranchStatusStopsRunawayPaging(t *testing.T) {
+	tests := []struct {
+		name        string
+		page        func(offset int, token string) map[string]any
+		maxPages    int
+		wantDevices int
+	}{
+		{
+			name: "offset ignored",
+			page: func(int, string) map[string]any {
+				return map[string]any{"devices": devices(0, 10)}
+			},
+			wantDevices: 10,
+		},
+		{
+			name: "token repeated",
+			page: func(offset int, token string) map[string]any {
+				if token == "" {
+					return map[string]any{"devices": devices(0, 10), "nextPageToken": "a"}
+				}
+				return map[string]any{"devices": devices(10, 10), "nextPageToken": "a"}
+			},
+			wantDevices: 20,
+		},
+		{
+			name: "offset pages never end",
+			page: func(offset int, token string) map[string]any {
+				return map[string]any{"devices": devices(offset, 10)}
+			},
+			maxPages:    5,
+			wantDevices: 50,
+		},
+		{
+			name: "token pages never end",
+			page: func(offset int, token string) map[string]any {
+				var n int
+				fmt.Sscan(token, &n)
+				return map[string]any{"devices": devices(n*10, 10), "nextPageToken": fmt.Sprint(n + 1)}
+			},
+			maxPages:    5,
+			wantDevices: 50,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := branchServer(t, tt.page)
+			status, err := c.QueryBranchStatus(BranchQuery{PageSize: 10, MaxPages: tt.maxPages})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(status.Devices) != tt.wantDevices || !status.Truncated {
+				t.Errorf("got %d devices, truncated %v; want %d, truncated", len(status.Devices), status.Truncated, tt.wantDevices)
+			}
+		})
+	}
+}
+
+func TestQueryBranchStatusShortPageEnds(t *testing.T) {
+	c := branchServer(t, func(offset int, token string) map[string]any {
+		if offset >= 20 {
+			return map[string]any{"devices": devices(offset, 3)}
+		}
+		return map[string]any{"devices": devices(offset, 10)}
+	})
+	status, err := c.QueryBranchStatus(BranchQuery{PageSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status.Devices) != 23 || status.Truncated {
+		t.Errorf("got %d devices, truncated %v; want 23, not truncated", len(status.Devices), status.Truncated)
+	}
+}
@@ -2,37 +2,111 @@ package downloader
 
 import (
 	"crypto/md5"
+	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
 
 	"github.com/mihailvovk/versa-proxmox-deployer/config"
 	"github.com/mihailvovk/versa-proxmox-deployer/sources"
 )
 
+// DefaultDownloadsPerSource is how many ISOs are fetched from one source at
+// once; more streams mostly slow each other down on a small mirror
+const DefaultDownloadsPerSource = 2
+
 // Downloader handles ISO download, caching, and verification
 type Downloader struct {
 	sources  []sources.ImageSource
 	cacheDir string
+
+	// Per-source download slots, keyed by source name
+	slotsMu   sync.Mutex
+	slots     map[string]chan struct{}
+	perSource int
+
+	// Where missing checksums are taken from, most preferred first
+	checksumOrder []sources.ChecksumOrigin
 }
 
 // NewDownloader creates a new downloader
 func NewDownloader(srcs []sources.ImageSource) *Downloader {
 	return &Downloader{
-		sources:  srcs,
-		cacheDir: sources.CacheDir(),
+		sources:   srcs,
+		cacheDir:  sources.CacheDir(),
+		slots:     make(map[string]chan struct{}),
+		perSource: DefaultDownloadsPerSource,
+
+		checksumOrder: sources.DefaultChecksumOrder,
+	}
+}
+
+// SetChecksumOrder sets where missing ISO checksums are taken from, most
+// preferred first (nil = sources.DefaultChecksumOrder). Leaving out
+// sources.OriginComputed stops the downloader hashing ISOs it fetched
+// without a known checksum.
+func (d *Downloader) SetChecksumOrder(order []sources.ChecksumOrigin) {
+	if len(order) == 0 {
+		order = sources.DefaultChecksumOrder
 	}
+	d.checksumOrder = order
+}
+
+// computesChecksums reports whether freshly downloaded ISOs without a known
+// checksum get one computed
+func (d *Downloader) computesChecksums() bool {
+	for _, origin := range d.checksumOrder {
+		if origin == sources.OriginComputed {
+			return true
+		}
+	}
+	return false
+}
+
+// SetDownloadsPerSource caps concurrent downloads from any single source
+// (0 = DefaultDownloadsPerSource). Downloads from different sources still
+// run in parallel. Call it before downloading.
+func (d *Downloader) SetDownloadsPerSource(n int) {
+	if n <= 0 {
+		n = DefaultDownloadsPerSource
+	}
+	d.slotsMu.Lock()
+	d.perSource = n
+	d.slots = make(map[string]chan struct{})
+	d.slotsMu.Unlock()
+}
+
+// acquireSource blocks until a download slot for the source is free and
+// returns the function releasing it
+func (d *Downloader) acquireSource(name string) func() {
+	d.slotsMu.Lock()
+	slot, ok := d.slots[name]
+	if !ok {
+		slot = make(chan struct{}, d.perSource)
+		d.slots[name] = slot
+	}
+	d.slotsMu.Unlock()
+
+	slot <- struct{}{}
+	return func() { <-slot }
 }
 
 // DownloadResult holds the result of a download operation
 type DownloadResult struct {
-	LocalPath  string
-	WasCached  bool
-	MD5        string
-	MD5Verified bool
-	Size       int64
+	LocalPath      string
+	WasCached      bool
+	MD5            string
+	MD5Verified    bool
+	SHA256         string
+	SHA256Verified bool
+	Size           int64
+	Resumed        int64  // Bytes already on disk from an interrupted download
+	SourceName     string // Source the ISO was downloaded from (empty when cached)
 }
 
 // EnsureISO ensures an ISO is available locally (downloads if needed)
@@ -61,6 +135,10 @@ func (d *Downloader) EnsureISO(iso sources.ISOFile, progress func(downloaded, to
 						result.MD5 = iso.MD5
 						result.MD5Verified = true // Trust the MD5 from scan
 					}
+					if iso.SHA256 != "" {
+						result.SHA256 = iso.SHA256
+						result.SHA256Verified = true
+					}
 					return result, nil
 				}
 			}
@@ -77,6 +155,14 @@ func (d *Downloader) EnsureISO(iso sources.ISOFile, progress func(downloaded, to
 					result.MD5 = iso.MD5
 					result.MD5Verified = true // Trust the MD5 from scan
 				}
+				if iso.SHA256 != "" {
+					result.SHA256 = iso.SHA256
+					result.SHA256Verified = true
+				}
+				if result.MD5 == "" && result.SHA256 == "" {
+					// Recorded when the ISO was downloaded (see SetChecksumOrder)
+					result.MD5, _ = ReadMD5File(cachePath + ".md5")
+				}
 				return result, nil
 			}
 			// Tiny file, likely a failed partial download — re-download
@@ -84,44 +170,213 @@ func (d *Downloader) EnsureISO(iso sources.ISOFile, progress func(downloaded, to
 		}
 	}
 
-	// Find the source for this ISO
-	var source sources.ImageSource
-	for _, src := range d.sources {
-		if src.Name() == iso.SourceName {
-			source = src
-			break
+	// Try every source carrying this ISO in priority order, falling back to
+	// the next one when a download fails or doesn't verify
+	var failures []string
+	for _, ref := range iso.SourceRefs() {
+		var source sources.ImageSource
+		for _, src := range d.sources {
+			if src.Name() == ref.Name {
+				source = src
+				break
+			}
+		}
+		if source == nil {
+			failures = append(failures, fmt.Sprintf("%s: source not configured", ref.Name))
+			continue
 		}
-	}
 
-	if source == nil {
-		return nil, fmt.Errorf("source not found: %s", iso.SourceName)
+		refISO := iso.FromSource(ref)
+		d.fillChecksums(source, &refISO)
+		err := d.downloadFrom(source, refISO, cachePath, result, progress)
+		if err == nil {
+			result.SourceName = ref.Name
+			return result, nil
+		}
+		failures = append(failures, fmt.Sprintf("%s: %v", ref.Name, err))
 	}
 
-	// Download (or symlink for local sources)
-	if err := source.Download(iso, cachePath, progress); err != nil {
-		os.Remove(cachePath)
-		return nil, fmt.Errorf("downloading ISO: %w", err)
+	if len(failures) == 1 {
+		return nil, fmt.Errorf("downloading ISO from %s", failures[0])
 	}
+	return nil, fmt.Errorf("downloading ISO failed from all %d sources: %s", len(failures), strings.Join(failures, "; "))
+}
 
-	// Resolve symlinks for the local path
-	if resolved, err := filepath.EvalSymlinks(cachePath); err == nil {
-		result.LocalPath = resolved
+// downloadFrom fetches an ISO from one source into the cache, verifying its
+// MD5 before committing it under the final name
+func (d *Downloader) downloadFrom(source sources.ImageSource, iso sources.ISOFile, cachePath string, result *DownloadResult, progress func(downloaded, total int64)) error {
+	// Download (or symlink for local sources) to a temporary name first so the
+	// cache never holds a partial file under the final name. Resumable sources
+	// keep their own partial file next to it, which survives a failed attempt.
+	tmpPath := cachePath + ".tmp"
+	os.Remove(tmpPath)
+	release := d.acquireSource(source.Name())
+	var err error
+	if rs, ok := source.(sources.ResumableDownloader); ok {
+		result.Resumed, err = rs.DownloadResumable(iso, tmpPath, progress)
+	} else {
+		err = source.Download(iso, tmpPath, progress)
+	}
+	release()
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
 	}
 
 	// Get file info (follows symlinks)
-	info, err := os.Stat(cachePath)
+	info, err := os.Stat(tmpPath)
 	if err != nil {
-		return nil, fmt.Errorf("getting file info: %w", err)
+		os.Remove(tmpPath)
+		return fmt.Errorf("getting file info: %w", err)
 	}
 	result.Size = info.Size()
 
-	// Trust MD5 from source scan rather than re-computing
-	if iso.MD5 != "" {
+	// Verify freshly downloaded files against the scanned checksum before
+	// committing, preferring SHA256 over MD5. Symlinks point at the user's own
+	// files, so those are trusted as-is.
+	lInfo, err := os.Lstat(tmpPath)
+	verify := err == nil && lInfo.Mode()&os.ModeSymlink == 0
+	switch {
+	case iso.SHA256 != "":
+		result.SHA256 = iso.SHA256
+		if verify {
+			ok, actual, err := VerifySHA256(tmpPath, strings.ToLower(iso.SHA256))
+			if err != nil {
+				os.Remove(tmpPath)
+				return fmt.Errorf("verifying SHA256: %w", err)
+			}
+			if !ok {
+				os.Remove(tmpPath)
+				return fmt.Errorf("SHA256 mismatch for %s: expected %s, got %s", iso.Filename, iso.SHA256, actual)
+			}
+		}
+		result.SHA256Verified = true
+	case iso.MD5 != "":
 		result.MD5 = iso.MD5
+		if verify {
+			ok, actual, err := VerifyMD5(tmpPath, strings.ToLower(iso.MD5))
+			if err != nil {
+				os.Remove(tmpPath)
+				return fmt.Errorf("verifying MD5: %w", err)
+			}
+			if !ok {
+				os.Remove(tmpPath)
+				return fmt.Errorf("MD5 mismatch for %s: expected %s, got %s", iso.Filename, iso.MD5, actual)
+			}
+		}
 		result.MD5Verified = true
+	case verify && d.computesChecksums():
+		// Nothing to verify against: hash the download so later deploys can
+		// match it on Proxmox storage by MD5
+		sum, err := CalculateMD5(tmpPath)
+		if err != nil {
+			os.Remove(tmpPath)
+			return fmt.Errorf("calculating MD5: %w", err)
+		}
+		result.MD5 = sum
+	}
+
+	if err := os.Rename(tmpPath, cachePath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("finalizing download: %w", err)
+	}
+	if err := sources.SyncDir(filepath.Dir(cachePath)); err != nil {
+		return fmt.Errorf("finalizing download: %w", err)
+	}
+	// Keep the cached .md5 in step with the file, for later runs that have
+	// no other checksum. Best effort: without it the MD5 is computed again.
+	if result.MD5 != "" && verify {
+		writeMD5File(cachePath, result.MD5)
+	} else {
+		os.Remove(cachePath + ".md5")
+	}
+
+	// Resolve symlinks for the local path
+	result.LocalPath = cachePath
+	if resolved, err := filepath.EvalSymlinks(cachePath); err == nil {
+		result.LocalPath = resolved
+	}
+
+	return nil
+}
+
+// FillChecksums fills in the checksums an ISO's listing didn't carry, from
+// its primary source, following the downloader's checksum order
+func (d *Downloader) FillChecksums(iso *sources.ISOFile) {
+	for _, src := range d.sources {
+		if src.Name() == iso.SourceName {
+			d.fillChecksums(src, iso)
+			return
+		}
+	}
+}
+
+// fillChecksums tries each checksum origin in order. Sidecar files fill in
+// any missing value; header and computed MD5s are only a fallback for ISOs
+// with no checksum yet. An origin that can't provide one is skipped.
+func (d *Downloader) fillChecksums(source sources.ImageSource, iso *sources.ISOFile) {
+	for _, origin := range d.checksumOrder {
+		switch origin {
+		case sources.OriginSidecar:
+			fetcher, ok := source.(sources.ChecksumDownloader)
+			if !ok {
+				continue
+			}
+			if iso.SHA256 == "" && iso.HasSHA256File {
+				if sum, err := fetcher.DownloadChecksum(*iso, sources.ChecksumSHA256); err == nil {
+					iso.SHA256 = sum
+				}
+			}
+			if iso.MD5 == "" && iso.HasMD5File {
+				if sum, err := fetcher.DownloadChecksum(*iso, sources.ChecksumMD5); err == nil {
+					iso.MD5 = sum
+				}
+			}
+		case sources.OriginHeader:
+			if iso.SHA256 != "" || iso.MD5 != "" {
+				continue
+			}
+			if hc, ok := source.(sources.HeaderChecksummer); ok {
+				if sum, err := hc.HeaderMD5(*iso); err == nil {
+					iso.MD5 = sum
+				}
+			}
+		case sources.OriginComputed:
+			if iso.SHA256 != "" || iso.MD5 != "" {
+				continue
+			}
+			// Computed when an earlier run downloaded the ISO into the cache
+			if sum, err := ReadMD5File(d.GetCachedPath(iso.Filename) + ".md5"); err == nil {
+				iso.MD5 = sum
+			}
+		}
+	}
+}
+
+// CalculateSHA256 calculates the SHA256 checksum of a file
+func CalculateSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// VerifySHA256 verifies a file against its expected SHA256
+func VerifySHA256(path, expectedSHA256 string) (bool, string, error) {
+	actual, err := CalculateSHA256(path)
+	if err != nil {
+		return false, "", err
 	}
 
-	return result, nil
+	return actual == expectedSHA256, actual, nil
 }
 
 // CalculateMD5 calculates the MD5 checksum of a file
@@ -207,11 +462,8 @@ func GenerateMD5File(isoPath string) (string, error) {
 		return "", fmt.Errorf("calculating MD5: %w", err)
 	}
 
-	md5Path := isoPath + ".md5"
-	content := fmt.Sprintf("%s  %s\n", md5sum, filepath.Base(isoPath))
-
-	if err := os.WriteFile(md5Path, []byte(content), 0644); err != nil {
-		return "", fmt.Errorf("writing MD5 file: %w", err)
+	if err := writeMD5File(isoPath, md5sum); err != nil {
+		return "", err
 	}
 
 	return md5sum, nil
@@ -219,6 +471,52 @@ func GenerateMD5File(isoPath string) (string, error) {
 
 // GenerateAllMD5Files generates MD5 files for all ISOs in a directory
 func GenerateAllMD5Files(dir string) ([]string, error) {
+	result, err := GenerateMD5Files(dir, MD5BatchOptions{})
+	if result == nil {
+		return nil, err
+	}
+	return result.Generated, err
+}
+
+// MD5Progress reports batch hashing progress for one file and overall
+type MD5Progress struct {
+	File       string
+	FileDone   int64
+	FileTotal  int64
+	BytesDone  int64
+	BytesTotal int64
+	FilesDone  int
+	FilesTotal int
+}
+
+// MD5BatchOptions controls GenerateMD5Files
+type MD5BatchOptions struct {
+	Parallel int               // Files hashed concurrently (0 = 1, capped at CPU count)
+	Verify   bool              // Re-hash ISOs with an existing .md5 and regenerate mismatches
+	Progress func(MD5Progress) // Called serially as bytes are hashed
+}
+
+// MD5BatchResult summarizes a GenerateMD5Files run ("name: md5" entries)
+type MD5BatchResult struct {
+	Generated   []string // New .md5 files
+	Regenerated []string // Existing .md5 files that did not match and were rewritten
+	Verified    []string // Existing .md5 files that matched (Verify only)
+	Skipped     int      // Existing .md5 files left unchecked
+}
+
+// md5Job is one ISO queued for hashing
+type md5Job struct {
+	name     string
+	path     string
+	size     int64
+	existing bool   // A .md5 file is already present (Verify only)
+	expected string // Its checksum, "" if unreadable
+}
+
+// GenerateMD5Files hashes every ISO in a directory that lacks a .md5 file (or
+// every ISO when verifying), with optional parallelism and progress reporting.
+// On error the partial result is returned alongside the first failure.
+func GenerateMD5Files(dir string, opts MD5BatchOptions) (*MD5BatchResult, error) {
 	dir = config.ExpandPath(dir)
 
 	entries, err := os.ReadDir(dir)
@@ -226,7 +524,9 @@ func GenerateAllMD5Files(dir string) ([]string, error) {
 		return nil, fmt.Errorf("reading directory: %w", err)
 	}
 
-	var generated []string
+	result := &MD5BatchResult{}
+	var jobs []md5Job
+	var bytesTotal int64
 
 	for _, entry := range entries {
 		if entry.IsDir() {
@@ -239,22 +539,148 @@ func GenerateAllMD5Files(dir string) ([]string, error) {
 		}
 
 		isoPath := filepath.Join(dir, name)
-		md5Path := isoPath + ".md5"
+		job := md5Job{name: name, path: isoPath}
 
-		// Skip if MD5 already exists
-		if _, err := os.Stat(md5Path); err == nil {
-			continue
+		// Skip if MD5 already exists, unless asked to verify it
+		if _, err := os.Stat(isoPath + ".md5"); err == nil {
+			if !opts.Verify {
+				result.Skipped++
+				continue
+			}
+			job.existing = true
+			job.expected, _ = ReadMD5File(isoPath + ".md5") // Unreadable .md5 is regenerated
 		}
 
-		md5sum, err := GenerateMD5File(isoPath)
+		info, err := os.Stat(isoPath)
 		if err != nil {
-			return generated, fmt.Errorf("generating MD5 for %s: %w", name, err)
+			return result, fmt.Errorf("stat %s: %w", name, err)
 		}
+		job.size = info.Size()
+		bytesTotal += job.size
+		jobs = append(jobs, job)
+	}
 
-		generated = append(generated, fmt.Sprintf("%s: %s", name, md5sum))
+	parallel := opts.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+	if parallel > runtime.NumCPU() {
+		parallel = runtime.NumCPU()
+	}
+
+	var (
+		mu        sync.Mutex
+		wg        sync.WaitGroup
+		firstErr  error
+		bytesDone int64
+		filesDone int
+	)
+
+	// report must be called with mu held
+	report := func(job md5Job, fileDone int64) {
+		if opts.Progress == nil {
+			return
+		}
+		opts.Progress(MD5Progress{
+			File:       job.name,
+			FileDone:   fileDone,
+			FileTotal:  job.size,
+			BytesDone:  bytesDone,
+			BytesTotal: bytesTotal,
+			FilesDone:  filesDone,
+			FilesTotal: len(jobs),
+		})
+	}
+
+	queue := make(chan md5Job)
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range queue {
+				var fileDone int64
+				sum, err := calculateMD5WithProgress(job.path, func(n int64) {
+					mu.Lock()
+					fileDone += n
+					bytesDone += n
+					report(job, fileDone)
+					mu.Unlock()
+				})
+				matched := job.existing && strings.EqualFold(job.expected, sum)
+				if err == nil && !matched {
+					err = writeMD5File(job.path, sum)
+				}
+
+				mu.Lock()
+				filesDone++
+				entry := fmt.Sprintf("%s: %s", job.name, sum)
+				switch {
+				case err != nil:
+					if firstErr == nil {
+						firstErr = fmt.Errorf("generating MD5 for %s: %w", job.name, err)
+					}
+				case !job.existing:
+					result.Generated = append(result.Generated, entry)
+				case matched:
+					result.Verified = append(result.Verified, entry)
+				default:
+					result.Regenerated = append(result.Regenerated, entry)
+				}
+				report(job, job.size)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, job := range jobs {
+		mu.Lock()
+		failed := firstErr != nil
+		mu.Unlock()
+		if failed {
+			break
+		}
+		queue <- job
+	}
+	close(queue)
+	wg.Wait()
+
+	return result, firstErr
+}
+
+// calculateMD5WithProgress hashes a file, reporting bytes read as they are consumed
+func calculateMD5WithProgress(path string, progress func(n int64)) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := md5.New()
+	buf := make([]byte, 4*1024*1024)
+	for {
+		n, err := f.Read(buf)
+		if n > 0 {
+			h.Write(buf[:n])
+			progress(int64(n))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
 	}
 
-	return generated, nil
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeMD5File writes a "checksum  filename" .md5 companion file
+func writeMD5File(isoPath, md5sum string) error {
+	content := fmt.Sprintf("%s  %s\n", md5sum, filepath.Base(isoPath))
+	if err := os.WriteFile(isoPath+".md5", []byte(content), 0644); err != nil {
+		return fmt.Errorf("writing MD5 file: %w", err)
+	}
+	return nil
 }
 
 // VerifyMD5 verifies a file against its expected MD5
@@ -1,9 +1,20 @@
 package main
 
 import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -11,6 +22,7 @@ import (
 	"github.com/mihailvovk/versa-proxmox-deployer/deployer"
 	"github.com/mihailvovk/versa-proxmox-deployer/director"
 	"github.com/mihailvovk/versa-proxmox-deployer/downloader"
+	"github.com/mihailvovk/versa-proxmox-deployer/proxmox"
 	"github.com/mihailvovk/versa-proxmox-deployer/sources"
 	"github.com/mihailvovk/versa-proxmox-deployer/ssh"
 	"github.com/mihailvovk/versa-proxmox-deployer/web"
@@ -22,21 +34,61 @@ var (
 	BuildTime = "unknown"
 )
 
+// hostKeyPolicy is the --host-key-policy flag shared by every command that connects over SSH
+var hostKeyPolicy string
+
+// sshCipher and compressUploads are the --ssh-cipher and --compress-uploads
+// flags, tuning throughput of ISO uploads over SSH
+var (
+	sshCipher       string
+	compressUploads bool
+)
+
+// caBundle is the --ca-bundle flag, or config.json's ca_bundle when unset
+var caBundle string
+
+// sshConfigHost is the --ssh-config-host flag: a ~/.ssh/config alias to take
+// connection settings from
+var sshConfigHost string
+
 func main() {
-	var httpPort int
-	var httpsPort int
+	config.ToolVersion = Version
+
+	var opts webUIOptions
 
 	rootCmd := &cobra.Command{
 		Use:   "versa-deployer",
 		Short: "Versa HeadEnd Proxmox Deployer",
 		Long:  `A tool to automate Versa HeadEnd deployment on Proxmox VE via a local web UI.`,
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			if err := applyCABundle(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: --ca-bundle: %v\n", err)
+				os.Exit(1)
+			}
+		},
 		Run: func(cmd *cobra.Command, args []string) {
-			runWebUI(httpPort, httpsPort)
+			runWebUI(opts)
 		},
 	}
 
-	rootCmd.Flags().IntVar(&httpPort, "http-port", 1050, "HTTP port for web UI")
-	rootCmd.Flags().IntVar(&httpsPort, "https-port", 1051, "HTTPS port for web UI")
+	rootCmd.PersistentFlags().StringVar(&sshConfigHost, "ssh-config-host", "", "Take host, user, port, identity file and ProxyJump from this ~/.ssh/config alias (explicit flags win)")
+	rootCmd.PersistentFlags().StringVar(&caBundle, "ca-bundle", "", "PEM CA certificates to trust for image downloads, e.g. a TLS inspection proxy's CA; downloads on Proxmox then verify TLS too (default: config ca_bundle)")
+	rootCmd.PersistentFlags().StringVar(&hostKeyPolicy, "host-key-policy", "tofu", "SSH host key verification: strict (known_hosts only), tofu (trust on first use) or ignore")
+	rootCmd.PersistentFlags().StringVar(&sshCipher, "ssh-cipher", "", "Preferred SSH cipher, e.g. chacha20-poly1305@openssh.com on hosts without AES instructions (default: aes128-gcm@openssh.com first)")
+	rootCmd.PersistentFlags().BoolVar(&compressUploads, "compress-uploads", false, "Gzip ISO uploads in transit; helps only sparse or compressible images on slow links")
+	rootCmd.Flags().IntVar(&opts.httpPort, "http-port", 1050, "HTTP port for web UI")
+	rootCmd.Flags().IntVar(&opts.httpsPort, "https-port", 1051, "HTTPS port for web UI")
+	rootCmd.Flags().StringVar(&opts.tlsCert, "tls-cert", "", "TLS certificate file (PEM) for the HTTPS server")
+	rootCmd.Flags().StringVar(&opts.tlsKey, "tls-key", "", "TLS private key file (PEM) for the HTTPS server")
+	rootCmd.Flags().StringVar(&opts.tlsMinVersion, "tls-min-version", "1.2", "Minimum TLS version for the HTTPS server (1.2 or 1.3)")
+	rootCmd.Flags().StringSliceVar(&opts.tlsCiphers, "tls-ciphers", nil, "Allowed TLS 1.2 cipher suites by IANA name (default: Go's secure defaults)")
+	rootCmd.Flags().BoolVar(&opts.hsts, "hsts", false, "Send Strict-Transport-Security on HTTPS responses")
+	rootCmd.Flags().DurationVar(&opts.readTimeout, "read-timeout", 0, "Maximum time to read a web request (default 1m; deploy progress and console streams are exempt)")
+	rootCmd.Flags().DurationVar(&opts.writeTimeout, "write-timeout", 0, "Maximum time to write a web response (default 5m; deploy progress and console streams are exempt)")
+	rootCmd.Flags().DurationVar(&opts.idleTimeout, "idle-timeout", 0, "Close idle keep-alive web connections after this long (default 2m)")
+	rootCmd.Flags().IntVar(&opts.maxConnections, "max-connections", 0, "Maximum concurrent connections per web listener; more wait their turn (default: unlimited)")
+	rootCmd.Flags().BoolVar(&opts.noStartupScan, "no-startup-scan", false, "Don't scan image sources at startup; scan on the first connect or rescan instead")
+	rootCmd.Flags().DurationVar(&opts.rescanInterval, "rescan-interval", 0, "Rescan image sources in the background this often, e.g. 30m (default: off)")
 
 	// Version command
 	rootCmd.AddCommand(&cobra.Command{
@@ -53,7 +105,16 @@ func main() {
 	deployCmd := &cobra.Command{
 		Use:   "deploy",
 		Short: "Deploy HeadEnd components (non-interactive)",
-		Run:   runDeploy,
+		Long: `Deploy HeadEnd components (non-interactive).
+
+Exit codes:
+  0  success
+  1  usage error
+  2  connection or discovery failed
+  3  validation failed (nothing created)
+  4  deployment failed, created VMs rolled back
+  5  deployment failed or partially succeeded, VMs left in place`,
+		Run: runDeploy,
 	}
 	deployCmd.Flags().String("host", "", "Proxmox host IP/hostname")
 	deployCmd.Flags().String("user", "root", "SSH username")
@@ -61,10 +122,42 @@ func main() {
 	deployCmd.Flags().String("password", "", "SSH password (if not using key)")
 	deployCmd.Flags().String("prefix", "versa", "Deployment prefix for VM names")
 	deployCmd.Flags().StringSlice("components", []string{"director", "analytics", "controller", "router"}, "Components to deploy")
-	deployCmd.Flags().String("node", "", "Target Proxmox node")
+	deployCmd.Flags().String("specs-file", "", "JSON file overriding component specs, e.g. {\"director\": {\"cpu\": 16, \"ram_gb\": 32}}")
+	deployCmd.Flags().String("node", "", "Target Proxmox node for all components")
+	deployCmd.Flags().StringSlice("nodes", nil, "Proxmox nodes to spread components across (default: all online nodes)")
+	deployCmd.Flags().String("strategy", "", "Node distribution strategy: auto_balance, all_on_one, ha_separate (default: recommended for the cluster)")
 	deployCmd.Flags().String("storage", "", "Storage pool for VM disks")
+	deployCmd.Flags().StringToString("balance-weights", nil, "auto_balance node scoring, e.g. cpu=0.5,ram=0.5,vm-penalty=5 (default cpu=0.4,ram=0.6,vm-penalty=5; cpu and ram are scaled to sum to 1)")
 	deployCmd.Flags().String("mgmt-bridge", "vmbr0", "Management network bridge")
+	deployCmd.Flags().String("mgmt-subnet", "", "Assign each VM the next free management IP from this subnet, e.g. 10.0.0.0/24 (skips addresses the Proxmox host sees in use)")
+	deployCmd.Flags().String("mgmt-gateway", "", "Management gateway, never assigned to a VM (default: .1 of --mgmt-subnet)")
+	deployCmd.Flags().StringToInt("mtu", nil, "Interface MTU by network purpose, e.g. northbound=9000,router-ha=9000 (1 = inherit bridge MTU)")
 	deployCmd.Flags().Bool("ha", false, "Enable HA mode")
+	deployCmd.Flags().StringArray("component", nil, "Per-component override: storage, iso, template (VMID to full-clone instead of installing from ISO), hugepages, affinity, disk-bus, bios (seabios, ovmf), machine (i440fx, q35), data-disk (GB, analytics only), and a cloud-init drive with ci-user, ci-password, ci-sshkeys (public key file), ci-ip (CIDR), ci-gw; e.g. router:hugepages=2,affinity=0-3,disk-bus=virtio (repeatable)")
+	deployCmd.Flags().Int("start-retries", 2, "Extra start attempts for VMs that fail to come up")
+	deployCmd.Flags().Duration("start-delay", 0, "Pause between VM starts, tripled after the Director (e.g. 30s)")
+	deployCmd.Flags().Bool("no-start", false, "Create VMs but leave them stopped")
+	deployCmd.Flags().Bool("snapshot", false, "Take a clean-install snapshot of each VM before first boot")
+	deployCmd.Flags().Bool("set-tag-colors", false, "Give versa-* tags distinct colors in the Proxmox UI (PVE 7.3+, cluster-wide; existing colors are kept)")
+	deployCmd.Flags().Bool("guest-agent", false, "Enable the QEMU guest agent on each VM (needed by the trim command)")
+	deployCmd.Flags().Int("downloads-per-source", 0, "Concurrent ISO downloads from any one image source (default 2, or downloads_per_source in config)")
+	deployCmd.Flags().Bool("management-only", false, "Create VMs with only the management interface; add the rest later with add-networks")
+	deployCmd.Flags().String("iso-policy", "latest", "How components without --component iso= get an image: latest, latest-stable (no beta/rc builds) or latest-in-major:<N>")
+	deployCmd.Flags().String("on-failure", "full", "Cleanup after a failure: full (destroy all created VMs), failed-only (destroy only VMs that failed) or none")
+	deployCmd.Flags().Bool("keep-on-failure", false, "Keep every created VM after a failure for debugging (same as --on-failure none)")
+	deployCmd.Flags().String("cloud-init-dir", "", "Directory of cloud-init user-data templates named <component>.yaml, rendered per VM with {{.IP}}, {{.Prefix}}, {{.Index}}, {{.DirectorIP}}")
+	deployCmd.Flags().String("snippets-storage", "", "Storage for rendered cloud-init user-data (default: first storage with snippets content)")
+	deployCmd.Flags().StringArray("qm-arg", nil, "Extra argument appended to every qm create, e.g. --qm-arg=--hookscript --qm-arg=local:snippets/hook.sh (repeatable, use with care)")
+	deployCmd.Flags().String("operator", "", "Operator name recorded in VM notes (default: current user)")
+	deployCmd.Flags().String("ticket", "", "Change/ticket reference recorded in VM notes")
+	deployCmd.Flags().String("director", "", "Director IP for the post-deploy registration check (default: saved director_ip)")
+	deployCmd.Flags().String("director-username", "Administrator", "Director username for the registration check")
+	deployCmd.Flags().String("director-password", "", "Director password; enables the registration check for new Analytics/Controller VMs")
+	deployCmd.Flags().Duration("registration-timeout", config.DefaultRegistrationTimeout, "How long to wait for new components to register with the Director")
+	deployCmd.Flags().String("env", "", "Environment name (e.g. lab, staging, prod), tagged as versa-env-<name>")
+	deployCmd.Flags().String("description-template", "", "File with a Go text/template for VM notes")
+	deployCmd.Flags().Bool("json", false, "Print the deployment result as JSON to stdout")
+	deployCmd.Flags().Bool("dry-run", false, "Show the ISO transfers and qm create commands a deployment would run, without changing anything")
 	rootCmd.AddCommand(deployCmd)
 
 	// Status command
@@ -76,6 +169,9 @@ func main() {
 	statusCmd.Flags().String("director", "", "Director IP address")
 	statusCmd.Flags().String("username", "Administrator", "Director username")
 	statusCmd.Flags().String("password", "", "Director password")
+	statusCmd.Flags().Bool("branches", false, "List individual branch devices")
+	statusCmd.Flags().String("org", "", "Only show branch devices in this organization")
+	statusCmd.Flags().String("branch-status", "", "Only show branch devices with this status (healthy, offline, degraded)")
 	rootCmd.AddCommand(statusCmd)
 
 	// Releases command
@@ -84,6 +180,9 @@ func main() {
 		Short: "List available ISO releases from configured sources",
 		Run:   runReleases,
 	}
+	releasesCmd.Flags().Bool("parallel", false, fmt.Sprintf("Scan all sources at once (default: %d at a time)", sources.DefaultScanConcurrency))
+	releasesCmd.Flags().Duration("timeout", sources.DefaultScanOptions.Timeout, "Per-source scan timeout for each attempt (0 = no limit)")
+	releasesCmd.Flags().Int("retries", sources.DefaultScanOptions.Retries, "Retries for a source whose scan fails transiently (timeouts, 5xx)")
 	rootCmd.AddCommand(releasesCmd)
 
 	// Generate MD5 command
@@ -93,6 +192,8 @@ func main() {
 		Run:   runGenerateMD5,
 	}
 	md5Cmd.Flags().String("path", ".", "Path to directory containing ISOs")
+	md5Cmd.Flags().Int("parallel", 1, "Number of ISOs to hash concurrently (capped at CPU count)")
+	md5Cmd.Flags().Bool("verify", false, "Re-hash ISOs that already have .md5 files and regenerate mismatches")
 	rootCmd.AddCommand(md5Cmd)
 
 	// Add source command
@@ -102,134 +203,1222 @@ func main() {
 		Args:  cobra.ExactArgs(1),
 		Run:   runAddSource,
 	}
+	addSourceCmd.Flags().String("username", "", "HTTP Basic Auth user for an http(s) source")
+	addSourceCmd.Flags().String("password", "", "HTTP Basic Auth password, or a bearer token without --username (also the SFTP password)")
+	addSourceCmd.Flags().StringToString("header", nil, "Extra request header for an http(s) source, e.g. X-JFrog-Art-Api=<key> (repeatable)")
 	rootCmd.AddCommand(addSourceCmd)
 
+	// Reclaim command
+	reclaimCmd := &cobra.Command{
+		Use:   "reclaim",
+		Short: "Re-apply deployer tags to a VM that lost its versa-deployer tag",
+		Long: `Re-apply the versa-deployer, component and prefix tags to an existing VM,
+bringing it back under management after its tags were cleared.
+
+The tags are derived from the VM name, which must match <prefix>-<component>[-N].
+This bypasses the tag safety check on stop/delete, so --confirm must repeat
+the VM's exact name.`,
+		Run: runReclaim,
+	}
+	reclaimCmd.Flags().String("host", "", "Proxmox host IP/hostname")
+	reclaimCmd.Flags().String("user", "root", "SSH username")
+	reclaimCmd.Flags().String("ssh-key", "", "Path to SSH private key")
+	reclaimCmd.Flags().String("password", "", "SSH password (if not using key)")
+	reclaimCmd.Flags().Int("vmid", 0, "VMID to reclaim")
+	reclaimCmd.Flags().String("confirm", "", "The VM's exact name, confirming the reclaim")
+	rootCmd.AddCommand(reclaimCmd)
+
+	// Add networks command
+	addNetworksCmd := &cobra.Command{
+		Use:   "add-networks",
+		Short: "Add the interfaces held back by deploy --management-only",
+		Long: `Attach the remaining interfaces to a VM deployed with --management-only,
+once its management connectivity has been verified. Without --vmid, lists
+the VMs still waiting for interfaces.`,
+		Run: runAddNetworks,
+	}
+	addNetworksCmd.Flags().String("host", "", "Proxmox host IP/hostname")
+	addNetworksCmd.Flags().String("user", "root", "SSH username")
+	addNetworksCmd.Flags().String("ssh-key", "", "Path to SSH private key")
+	addNetworksCmd.Flags().String("password", "", "SSH password (if not using key)")
+	addNetworksCmd.Flags().Int("vmid", 0, "VMID to add the remaining interfaces to")
+	rootCmd.AddCommand(addNetworksCmd)
+
+	// Attach/detach ISO commands
+	attachISOCmd := &cobra.Command{
+		Use:   "attach-iso",
+		Short: "Attach an ISO to a deployer-managed VM",
+		Long: `Insert an ISO into a VM's CD-ROM drive, e.g. to reinstall or repair it
+without recreating the VM, or to mount a tools ISO. With --installer the VM
+boots from the ISO on its next start; otherwise the disk stays first.`,
+		Run: runAttachISO,
+	}
+	attachISOCmd.Flags().String("host", "", "Proxmox host IP/hostname")
+	attachISOCmd.Flags().String("user", "root", "SSH username")
+	attachISOCmd.Flags().String("ssh-key", "", "Path to SSH private key")
+	attachISOCmd.Flags().String("password", "", "SSH password (if not using key)")
+	attachISOCmd.Flags().Int("vmid", 0, "VMID to attach the ISO to")
+	attachISOCmd.Flags().String("iso", "", "ISO filename")
+	attachISOCmd.Flags().String("storage", "", "Storage holding the ISO (default: search all ISO storage)")
+	attachISOCmd.Flags().Bool("installer", false, "Boot from the ISO first")
+	rootCmd.AddCommand(attachISOCmd)
+
+	detachISOCmd := &cobra.Command{
+		Use:   "detach-iso",
+		Short: "Eject the ISO from a deployer-managed VM",
+		Run:   runDetachISO,
+	}
+	detachISOCmd.Flags().String("host", "", "Proxmox host IP/hostname")
+	detachISOCmd.Flags().String("user", "root", "SSH username")
+	detachISOCmd.Flags().String("ssh-key", "", "Path to SSH private key")
+	detachISOCmd.Flags().String("password", "", "SSH password (if not using key)")
+	detachISOCmd.Flags().Int("vmid", 0, "VMID to eject the ISO from")
+	rootCmd.AddCommand(detachISOCmd)
+
+	// Verify images command
+	verifyImagesCmd := &cobra.Command{
+		Use:   "verify-images",
+		Short: "Re-verify the checksums of the components' ISOs on Proxmox",
+		Long: `Find each component's ISO on Proxmox storage, the same way deploy does,
+and re-check its checksum against the image source's SHA256 or MD5, e.g. when
+an uploaded ISO may have been corrupted on storage. Nothing is deployed.
+Exits non-zero if any ISO is missing or fails verification.`,
+		Run: runVerifyImages,
+	}
+	verifyImagesCmd.Flags().String("host", "", "Proxmox host IP/hostname")
+	verifyImagesCmd.Flags().String("user", "root", "SSH username")
+	verifyImagesCmd.Flags().String("ssh-key", "", "Path to SSH private key")
+	verifyImagesCmd.Flags().String("password", "", "SSH password (if not using key)")
+	verifyImagesCmd.Flags().StringSlice("components", []string{"director", "analytics", "controller", "router"}, "Components whose ISOs to verify")
+	verifyImagesCmd.Flags().StringArray("component", nil, "Per-component ISO, e.g. director:iso=versa-director.iso (repeatable)")
+	verifyImagesCmd.Flags().String("iso-policy", "latest", "How components without --component iso= get an image: latest, latest-stable or latest-in-major:<N>")
+	rootCmd.AddCommand(verifyImagesCmd)
+
+	// Trim command
+	trimCmd := &cobra.Command{
+		Use:   "trim",
+		Short: "Reclaim thin-provisioned space from a deployer-managed VM",
+		Long: `Run fstrim inside a running VM through the QEMU guest agent, so blocks freed
+after installation are returned to thin-provisioned storage. The VM needs the
+guest agent enabled (deploy --guest-agent) and running; without it, run
+'fstrim -av' from the VM console instead.`,
+		Run: runTrim,
+	}
+	trimCmd.Flags().String("host", "", "Proxmox host IP/hostname")
+	trimCmd.Flags().String("user", "root", "SSH username")
+	trimCmd.Flags().String("ssh-key", "", "Path to SSH private key")
+	trimCmd.Flags().String("password", "", "SSH password (if not using key)")
+	trimCmd.Flags().Int("vmid", 0, "VMID to trim")
+	rootCmd.AddCommand(trimCmd)
+
+	// Destroy command
+	destroyCmd := &cobra.Command{
+		Use:   "destroy",
+		Short: "Stop and delete deployer-managed VMs",
+		Long: `Stop and purge deployer-managed VMs, selected by --vmid or by deployment
+--prefix. VMs without the versa-deployer tag are refused.
+
+The VMs are listed and must be confirmed interactively; pass --yes to skip
+the prompt in scripts. Without a terminal, --yes is required.`,
+		Run: runDestroy,
+	}
+	destroyCmd.Flags().String("host", "", "Proxmox host IP/hostname")
+	destroyCmd.Flags().String("user", "root", "SSH username")
+	destroyCmd.Flags().String("ssh-key", "", "Path to SSH private key")
+	destroyCmd.Flags().String("password", "", "SSH password (if not using key)")
+	destroyCmd.Flags().IntSlice("vmid", nil, "VMID to destroy (repeatable or comma-separated)")
+	destroyCmd.Flags().String("prefix", "", "Destroy every VM in this deployment")
+	addYesFlag(destroyCmd)
+	rootCmd.AddCommand(destroyCmd)
+
+	// List command
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List deployer-managed VMs on a Proxmox host",
+		Run:   runList,
+	}
+	listCmd.Flags().String("host", "", "Proxmox host IP/hostname")
+	listCmd.Flags().String("user", "root", "SSH username")
+	listCmd.Flags().String("ssh-key", "", "Path to SSH private key")
+	listCmd.Flags().String("password", "", "SSH password (if not using key)")
+	listCmd.Flags().String("env", "", "Only list VMs tagged with this environment")
+	rootCmd.AddCommand(listCmd)
+
+	// Export deployment command
+	exportCmd := &cobra.Command{
+		Use:   "export-deployment",
+		Short: "Export deployed VMs' console URLs, logins and next steps as a hand-off bundle",
+		Run:   runExportDeployment,
+	}
+	exportCmd.Flags().String("host", "", "Proxmox host IP/hostname")
+	exportCmd.Flags().String("user", "root", "SSH username")
+	exportCmd.Flags().String("ssh-key", "", "Path to SSH private key")
+	exportCmd.Flags().String("password", "", "SSH password (if not using key)")
+	exportCmd.Flags().String("prefix", "", "Only export this deployment (default: all deployer-managed VMs)")
+	exportCmd.Flags().String("format", "markdown", "Output format: markdown or json")
+	exportCmd.Flags().StringP("output", "o", "", "Write to this file instead of stdout")
+	rootCmd.AddCommand(exportCmd)
+
+	// Config commands
+	configCmd := &cobra.Command{
+		Use:   "config",
+		Short: "Show or edit the deployer configuration (config.json)",
+	}
+	configShowCmd := &cobra.Command{
+		Use:   "show",
+		Short: "Print the effective configuration with resolved paths, secrets redacted",
+		Run:   runConfigShow,
+	}
+	configCmd.AddCommand(configShowCmd)
+	configGetCmd := &cobra.Command{
+		Use:   "get <key>",
+		Short: "Print one config.json setting (secrets need --show-secret)",
+		Args:  cobra.ExactArgs(1),
+		Run:   runConfigGet,
+	}
+	configGetCmd.Flags().Bool("show-secret", false, "Print the setting even if it is a password or token")
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(&cobra.Command{
+		Use:   "set <key>=<value>",
+		Short: "Change one config.json setting (an empty value clears it)",
+		Long: "Change one config.json setting. Keys:\n  " + strings.Join(config.Keys(), "\n  ") +
+			"\n\nImage sources are managed with add-source and the web UI.",
+		Args: cobra.ExactArgs(1),
+		Run:  runConfigSet,
+	})
+	rootCmd.AddCommand(configCmd)
+
+	// Regenerate self-signed certificate command
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "regen-cert",
+		Short: "Regenerate the self-signed web UI TLS certificate",
+		Run:   runRegenCert,
+	})
+
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
 
-func runWebUI(httpPort, httpsPort int) {
+// webUIOptions holds the root command's web server flags
+type webUIOptions struct {
+	httpPort      int
+	httpsPort     int
+	tlsCert       string
+	tlsKey        string
+	tlsMinVersion string
+	tlsCiphers    []string
+	hsts          bool
+
+	rescanInterval time.Duration
+	noStartupScan  bool
+
+	readTimeout    time.Duration
+	writeTimeout   time.Duration
+	idleTimeout    time.Duration
+	maxConnections int
+}
+
+func runWebUI(opts webUIOptions) {
+	if (opts.tlsCert == "") != (opts.tlsKey == "") {
+		fmt.Fprintln(os.Stderr, "Error: --tls-cert and --tls-key must be used together")
+		os.Exit(1)
+	}
+
+	minVersion, err := web.ParseTLSVersion(opts.tlsMinVersion)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --tls-min-version: %v\n", err)
+		os.Exit(1)
+	}
+	ciphers, err := web.ParseCipherSuites(opts.tlsCiphers)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --tls-ciphers: %v\n", err)
+		os.Exit(1)
+	}
+	if minVersion == tls.VersionTLS13 && len(ciphers) > 0 {
+		fmt.Fprintln(os.Stderr, "Error: --tls-ciphers only applies to TLS 1.2 and has no effect with --tls-min-version 1.3")
+		os.Exit(1)
+	}
+
 	cfg, err := config.Load()
 	if err != nil {
 		slog.Warn("could not load config", "error", err)
 		cfg = &config.Config{}
 	}
 
-	srv := web.NewServer(cfg, httpsPort)
-	if err := srv.Start(httpPort); err != nil {
+	policy, err := ssh.ParseHostKeyPolicy(hostKeyPolicy)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: --host-key-policy: %v\n", err)
+		os.Exit(1)
+	}
+
+	srv := web.NewServer(cfg, opts.httpsPort)
+	srv.SetHostKeyPolicy(policy)
+	srv.SetUploadTuning(sshCipher, compressUploads)
+	srv.SetCABundle(caBundle)
+	srv.SetRescanInterval(opts.rescanInterval)
+	if opts.noStartupScan {
+		srv.DisableStartupScan()
+	}
+	srv.SetConnLimits(web.ConnLimits{
+		ReadTimeout:  opts.readTimeout,
+		WriteTimeout: opts.writeTimeout,
+		IdleTimeout:  opts.idleTimeout,
+		MaxConns:     opts.maxConnections,
+	})
+	if opts.tlsCert != "" {
+		srv.SetTLSFiles(config.ExpandPath(opts.tlsCert), config.ExpandPath(opts.tlsKey))
+	}
+	srv.SetTLSPolicy(web.TLSPolicy{
+		MinVersion:   minVersion,
+		CipherSuites: ciphers,
+		HSTS:         opts.hsts,
+	})
+
+	// Ctrl+C closes console sessions and SSE streams before exiting, so no
+	// PTY processes are left running on the Proxmox host
+	stopped := make(chan struct{})
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		signal.Stop(sig) // A second Ctrl+C exits immediately
+		fmt.Println("\nShutting down...")
+
+		ctx, cancel := context.WithTimeout(context.Background(), webShutdownTimeout)
+		defer cancel()
+		if err := srv.Shutdown(ctx); err != nil {
+			slog.Warn("web UI did not shut down cleanly", "error", err)
+		}
+		close(stopped)
+	}()
+
+	if err := srv.Start(opts.httpPort); err != nil {
 		fmt.Fprintf(os.Stderr, "Server error: %v\n", err)
 		os.Exit(1)
 	}
+	<-stopped
 }
 
+// webShutdownTimeout bounds how long Ctrl+C waits for in-flight web requests
+const webShutdownTimeout = 10 * time.Second
 
-func runDeploy(cmd *cobra.Command, args []string) {
+func runReclaim(cmd *cobra.Command, args []string) {
 	host, _ := cmd.Flags().GetString("host")
-	if host == "" {
-		fmt.Fprintln(os.Stderr, "Error: --host is required")
+	vmid, _ := cmd.Flags().GetInt("vmid")
+	confirm, _ := cmd.Flags().GetString("confirm")
+
+	if (host == "" && sshConfigHost == "") || vmid <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --host (or --ssh-config-host) and --vmid are required")
+		os.Exit(1)
+	}
+	if confirm == "" {
+		fmt.Fprintln(os.Stderr, "Error: --confirm <vm-name> is required")
+		os.Exit(1)
+	}
+	client, err := connectFromFlags(cmd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	defer client.Close()
+
+	result, err := deployer.ReclaimVM(client, vmid, confirm)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Reclaimed %s (VMID %d) into deployment %s as %s\n", result.Name, result.VMID, result.Prefix, result.Component)
+	fmt.Printf("Tags: %s\n", strings.Join(result.Tags, ";"))
+}
 
+// effectiveConfig is the config show output: config.json plus the
+// resolved paths and defaults the deployer runs with
+type effectiveConfig struct {
+	Version       string         `json:"version"`
+	ConfigPath    string         `json:"config_path"`
+	ConfigFound   bool           `json:"config_found"`
+	CacheDir      string         `json:"cache_dir"`
+	ConsoleLogDir string         `json:"console_log_dir"`
+	HostKeyPolicy string         `json:"host_key_policy"`
+	DefaultSSHKey string         `json:"default_ssh_key,omitempty"` // Used when no key or password is given
+	Config        *config.Config `json:"config"`
+}
+
+// runConfigShow prints the effective configuration as JSON
+func runConfigShow(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	_, statErr := os.Stat(config.ConfigPath())
+
+	out := effectiveConfig{
+		Version:       Version,
+		ConfigPath:    config.ConfigPath(),
+		ConfigFound:   statErr == nil,
+		CacheDir:      config.CacheDir(),
+		ConsoleLogDir: config.ConsoleLogDir(),
+		HostKeyPolicy: hostKeyPolicy,
+		DefaultSSHKey: ssh.FindDefaultKey(),
+		Config:        cfg.Redacted(),
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(data))
+}
+
+// runConfigGet prints one config.json setting
+func runConfigGet(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	showSecret, _ := cmd.Flags().GetBool("show-secret")
+	value, err := cfg.Get(args[0], showSecret)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(value)
+}
+
+// runConfigSet changes one config.json setting
+func runConfigSet(cmd *cobra.Command, args []string) {
+	key, value, ok := strings.Cut(args[0], "=")
+	if !ok {
+		fmt.Fprintln(os.Stderr, "Error: expected <key>=<value>")
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := cfg.Set(strings.TrimSpace(key), value); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := cfg.Save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s updated in %s\n", strings.TrimSpace(key), config.ConfigPath())
+}
+
+// connectFromFlags connects with the --host/--user/--ssh-key/--password flags
+func connectFromFlags(cmd *cobra.Command) (*ssh.Client, error) {
+	opts, err := sshOptionsFromFlags(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := ssh.NewClient(opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("connection failed: %w", err)
+	}
+	return client, nil
+}
+
+// sshOptionsFromFlags builds client options from --host, --user, --ssh-key
+// and --password. With --ssh-config-host, settings those flags leave unset
+// come from the alias's ~/.ssh/config entry, including ProxyJump.
+func sshOptionsFromFlags(cmd *cobra.Command) (ssh.ClientOptions, error) {
+	host, _ := cmd.Flags().GetString("host")
 	user, _ := cmd.Flags().GetString("user")
 	keyPath, _ := cmd.Flags().GetString("ssh-key")
 	password, _ := cmd.Flags().GetString("password")
 
-	if keyPath == "" && password == "" {
-		// Try default key
-		keyPath = ssh.FindDefaultKey()
-		if keyPath == "" {
-			fmt.Fprintln(os.Stderr, "Error: --ssh-key or --password required")
+	opts := ssh.ClientOptions{
+		Host:          host,
+		KeyPath:       keyPath,
+		Password:      password,
+		HostKeyPolicy: ssh.HostKeyPolicy(hostKeyPolicy),
+		Cipher:        sshCipher,
+		Compression:   compressUploads,
+	}
+	if cmd.Flags().Changed("user") {
+		opts.User = user
+	}
+
+	if sshConfigHost != "" {
+		if opts.Host == "" {
+			opts.Host = sshConfigHost
+		}
+		found, err := ssh.ApplyConfigHost(&opts, sshConfigHost)
+		if err != nil {
+			return opts, fmt.Errorf("reading SSH config: %w", err)
+		}
+		if !found {
+			fmt.Fprintf(os.Stderr, "Warning: no entry for %s in %s, using flags and defaults\n", sshConfigHost, ssh.UserConfigPath())
+		}
+	}
+
+	if opts.Host == "" {
+		return opts, fmt.Errorf("--host or --ssh-config-host is required")
+	}
+	if opts.User == "" {
+		opts.User = user
+	}
+	if opts.KeyPath == "" && opts.Password == "" {
+		opts.KeyPath = ssh.FindDefaultKey()
+	}
+	return opts, nil
+}
+
+// runAttachISO inserts an ISO into a deployer-managed VM
+func runAttachISO(cmd *cobra.Command, args []string) {
+	vmid, _ := cmd.Flags().GetInt("vmid")
+	iso, _ := cmd.Flags().GetString("iso")
+	storage, _ := cmd.Flags().GetString("storage")
+	installer, _ := cmd.Flags().GetBool("installer")
+
+	if vmid <= 0 || iso == "" {
+		fmt.Fprintln(os.Stderr, "Error: --vmid and --iso are required")
+		os.Exit(1)
+	}
+
+	client, err := connectFromFlags(cmd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer client.Close()
+
+	storage, err = deployer.AttachISO(client, vmid, storage, iso, installer)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Attached %s:iso/%s to VMID %d\n", storage, iso, vmid)
+	if installer {
+		fmt.Println("The VM boots from the ISO on its next start")
+	}
+}
+
+// runDetachISO ejects the ISO from a deployer-managed VM
+func runDetachISO(cmd *cobra.Command, args []string) {
+	vmid, _ := cmd.Flags().GetInt("vmid")
+	if vmid <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --vmid is required")
+		os.Exit(1)
+	}
+
+	client, err := connectFromFlags(cmd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer client.Close()
+
+	if err := deployer.DetachISO(client, vmid); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Detached the ISO from VMID %d\n", vmid)
+}
+
+// runVerifyImages re-checks the checksums of the components' ISOs on Proxmox
+func runVerifyImages(cmd *cobra.Command, args []string) {
+	deployCfg := config.NewDeploymentConfig()
+	componentStrs, _ := cmd.Flags().GetStringSlice("components")
+	for _, cs := range componentStrs {
+		deployCfg.Components = append(deployCfg.Components, config.ComponentConfig{Type: config.ComponentType(cs), Count: 1})
+	}
+	overrides, _ := cmd.Flags().GetStringArray("component")
+	if err := applyComponentOverrides(deployCfg.Components, overrides); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	isoPolicy, _ := cmd.Flags().GetString("iso-policy")
+	var err error
+	if deployCfg.ISOPolicy, err = config.ParseISOPolicy(isoPolicy); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	deployCfg.CABundle = caBundle
+
+	client, err := connectFromFlags(cmd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer client.Close()
+
+	cfg, _ := config.Load()
+	imageSources, _ := sources.CreateSourcesFromConfig(cfg)
+
+	checks, err := deployer.VerifyImages(context.Background(), deployer.DeployRequest{
+		Client:  client,
+		Config:  deployCfg,
+		Sources: imageSources,
+		OnLog:   func(msg string) { fmt.Println(msg) },
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	failed := 0
+	for _, c := range checks {
+		where := c.Storage
+		if c.Filename != "" {
+			where += " as " + c.Filename
+		}
+		switch c.Status {
+		case deployer.ImageOK:
+			fmt.Printf("  PASS  %s (%s on %s)\n", c.ISO, c.Algorithm, where)
+		case deployer.ImageUnverified:
+			fmt.Printf("  SKIP  %s (on %s, no checksum known)\n", c.ISO, where)
+		case deployer.ImageMissing:
+			failed++
+			fmt.Printf("  FAIL  %s (not found on any ISO storage)\n", c.ISO)
+		case deployer.ImageMismatch:
+			failed++
+			fmt.Printf("  FAIL  %s (%s mismatch on %s)\n", c.ISO, c.Algorithm, where)
+		default:
+			failed++
+			fmt.Printf("  FAIL  %s (%s)\n", c.ISO, c.Error)
+		}
+	}
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d ISO(s) failed verification; delete and re-upload them before deploying\n", failed)
+		os.Exit(1)
+	}
+	fmt.Printf("Verified %d ISO(s)\n", len(checks))
+}
+
+// runTrim reclaims freed disk blocks inside a deployer-managed VM
+func runTrim(cmd *cobra.Command, args []string) {
+	vmid, _ := cmd.Flags().GetInt("vmid")
+	if vmid <= 0 {
+		fmt.Fprintln(os.Stderr, "Error: --vmid is required")
+		os.Exit(1)
+	}
+
+	client, err := connectFromFlags(cmd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer client.Close()
+
+	results, err := deployer.TrimVM(client, vmid)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var total uint64
+	for _, r := range results {
+		if r.Error != "" {
+			fmt.Printf("  %-20s %s\n", r.Path, r.Error)
+			continue
+		}
+		fmt.Printf("  %-20s %.2f GB trimmed\n", r.Path, float64(r.Trimmed)/(1<<30))
+		total += r.Trimmed
+	}
+	fmt.Printf("Reclaimed %.2f GB on VMID %d\n", float64(total)/(1<<30), vmid)
+}
+
+func runDestroy(cmd *cobra.Command, args []string) {
+	host, _ := cmd.Flags().GetString("host")
+	vmids, _ := cmd.Flags().GetIntSlice("vmid")
+	prefix, _ := cmd.Flags().GetString("prefix")
+
+	if host == "" && sshConfigHost == "" {
+		fmt.Fprintln(os.Stderr, "Error: --host or --ssh-config-host is required")
+		os.Exit(1)
+	}
+	if prefix == "" && len(vmids) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: --vmid or --prefix is required")
+		os.Exit(1)
+	}
+	client, err := connectFromFlags(cmd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer client.Close()
+
+	vms, err := deployer.FindDestroyTargets(client, prefix, vmids)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	items := make([]string, len(vms))
+	for i, vm := range vms {
+		items[i] = fmt.Sprintf("%d  %s (%s, %s)", vm.VMID, vm.Name, vm.Node, vm.Status)
+	}
+	if err := confirmDestructive(cmd, fmt.Sprintf("Destroy %d VMs on %s, including their disks?", len(vms), client.Host()), items); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	failed := 0
+	for _, r := range deployer.DestroyVMs(client, vms) {
+		if r.Error != nil {
+			failed++
+			fmt.Printf("  %d  %s: %v\n", r.VMID, r.Name, r.Error)
+			continue
+		}
+		fmt.Printf("  %d  %s destroyed\n", r.VMID, r.Name)
+	}
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "Error: %d of %d VMs could not be destroyed\n", failed, len(vms))
+		os.Exit(1)
+	}
+}
+
+func runAddNetworks(cmd *cobra.Command, args []string) {
+	vmid, _ := cmd.Flags().GetInt("vmid")
+
+	// Pending interfaces are recorded per resolved host
+	opts, err := sshOptionsFromFlags(cmd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if vmid <= 0 {
+		pending, err := deployer.ListPendingNetworks(opts.Host)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
+		if len(pending) == 0 {
+			fmt.Println("No VMs are waiting for interfaces")
+			return
+		}
+		for _, p := range pending {
+			names := make([]string, len(p.Networks))
+			for i, n := range p.Networks {
+				names[i] = fmt.Sprintf("net%d %s (%s)", p.First+i, n.Name, n.Bridge)
+			}
+			fmt.Printf("%d  %s: %s\n", p.VMID, p.Name, strings.Join(names, ", "))
+		}
+		return
 	}
 
-	// Connect
-	sshOpts := ssh.ClientOptions{
-		Host:         host,
-		User:         user,
-		KeyPath:      keyPath,
-		Password:     password,
-		HostKeyCheck: true,
+	client, err := connectFromFlags(cmd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
 	}
+	defer client.Close()
 
-	client, err := ssh.NewClient(sshOpts)
+	added, err := deployer.AddRemainingNetworks(client, vmid)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	fmt.Printf("Added %d interface(s) to %s (VMID %d)\n", len(added.Networks), added.Name, added.VMID)
+}
 
-	if err := client.Connect(); err != nil {
-		fmt.Fprintf(os.Stderr, "Connection failed: %v\n", err)
+func runList(cmd *cobra.Command, args []string) {
+	host, _ := cmd.Flags().GetString("host")
+	env, _ := cmd.Flags().GetString("env")
+
+	if host == "" && sshConfigHost == "" {
+		fmt.Fprintln(os.Stderr, "Error: --host or --ssh-config-host is required")
+		os.Exit(1)
+	}
+	client, err := connectFromFlags(cmd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer client.Close()
+
+	vms, unverified, err := proxmox.NewDiscoverer(client).FindVersaDeploymentsAndUnknown(env)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, vm := range unverified {
+		fmt.Fprintf(os.Stderr, "Warning: could not read tags of VM %d (%s); it may be deployer-managed\n", vm.VMID, vm.Name)
+	}
+	if len(vms) == 0 {
+		fmt.Println("No deployer-managed VMs found")
+		return
+	}
+
+	fmt.Printf("%-6s  %-30s  %-10s  %-12s  %-12s  %s\n", "VMID", "Name", "Status", "Environment", "Version", "Deployer")
+	for _, vm := range vms {
+		vmEnv := vm.Environment
+		if vmEnv == "" {
+			vmEnv = "-"
+		}
+		tool := vm.ToolVersion
+		if tool == "" {
+			tool = "-"
+		}
+		fmt.Printf("%-6d  %-30s  %-10s  %-12s  %-12s  %s\n", vm.VMID, vm.Name, vm.Status, vmEnv, vm.Version, tool)
+	}
+}
+
+func runExportDeployment(cmd *cobra.Command, args []string) {
+	host, _ := cmd.Flags().GetString("host")
+	prefix, _ := cmd.Flags().GetString("prefix")
+	format, _ := cmd.Flags().GetString("format")
+	output, _ := cmd.Flags().GetString("output")
+
+	if host == "" && sshConfigHost == "" {
+		fmt.Fprintln(os.Stderr, "Error: --host or --ssh-config-host is required")
+		os.Exit(1)
+	}
+	if format != "markdown" && format != "json" {
+		fmt.Fprintf(os.Stderr, "Error: unknown --format %q (expected markdown or json)\n", format)
+		os.Exit(1)
+	}
+	client, err := connectFromFlags(cmd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 	defer client.Close()
 
-	fmt.Println("Connected to Proxmox")
+	var directorIP string
+	if cfg, err := config.Load(); err == nil {
+		directorIP = cfg.DirectorIP
+	}
+	export, err := deployer.ExportDeployment(client, prefix, directorIP)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var data []byte
+	if format == "json" {
+		data, err = json.MarshalIndent(export, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		data = append(data, '\n')
+	} else {
+		data = []byte(export.Markdown())
+	}
+
+	if output == "" {
+		os.Stdout.Write(data)
+		return
+	}
+	if err := os.WriteFile(output, data, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: writing %s: %v\n", output, err)
+		os.Exit(1)
+	}
+	fmt.Printf("Exported %d VMs to %s\n", len(export.VMs), output)
+}
+
+func runRegenCert(cmd *cobra.Command, args []string) {
+	cert, err := web.RegenerateCert(config.ConfigDir())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Regenerated self-signed certificate in %s\n", config.ConfigDir())
+	fmt.Printf("  Valid until: %s\n", leaf.NotAfter.Format("2006-01-02"))
+	fmt.Printf("  DNS names:   %s\n", strings.Join(leaf.DNSNames, ", "))
+	var ips []string
+	for _, ip := range leaf.IPAddresses {
+		ips = append(ips, ip.String())
+	}
+	fmt.Printf("  IPs:         %s\n", strings.Join(ips, ", "))
+	fmt.Println("Restart the web UI to pick up the new certificate.")
+}
+
+// Exit codes for the deploy command
+const (
+	exitUsage      = 1 // bad flags or other generic failure
+	exitConnection = 2 // SSH connection or discovery failed
+	exitValidation = 3 // pre-flight validation failed, nothing created
+	exitRolledBack = 4 // deployment failed, created VMs were rolled back
+	exitVMsLeft    = 5 // deployment failed or partially succeeded, VMs left in place
+)
+
+// deployOutput is the machine-readable result printed by deploy --json
+type deployOutput struct {
+	Success  bool                       `json:"success"`
+	ExitCode int                        `json:"exitCode"`
+	Error    string                     `json:"error,omitempty"`
+	Result   *deployer.DeploymentResult `json:"result,omitempty"`
+}
+
+func runDeploy(cmd *cobra.Command, args []string) {
+	jsonOut, _ := cmd.Flags().GetBool("json")
+
+	// With --json, stdout carries only the result object; progress goes to stderr
+	out := os.Stdout
+	if jsonOut {
+		out = os.Stderr
+	}
+
+	finish := func(code int, err error, result *deployer.DeploymentResult) {
+		if jsonOut {
+			o := deployOutput{Success: code == 0, ExitCode: code, Result: result}
+			if err != nil {
+				o.Error = err.Error()
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			enc.Encode(o)
+		} else if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		if code != 0 {
+			os.Exit(code)
+		}
+	}
+
+	sshOpts, err := sshOptionsFromFlags(cmd)
+	if err != nil {
+		finish(exitUsage, err, nil)
+	}
+	if sshOpts.KeyPath == "" && sshOpts.Password == "" {
+		finish(exitUsage, fmt.Errorf("--ssh-key or --password required"), nil)
+	}
 
 	// Build deployment config from flags
 	deployCfg := config.NewDeploymentConfig()
-	deployCfg.ProxmoxHost = host
-	deployCfg.SSHUser = user
-	deployCfg.SSHKeyPath = keyPath
-	deployCfg.SSHPassword = password
+	deployCfg.ProxmoxHost = sshOpts.Host
+	deployCfg.SSHUser = sshOpts.User
+	deployCfg.SSHKeyPath = sshOpts.KeyPath
+	deployCfg.SSHPassword = sshOpts.Password
+	deployCfg.CABundle = caBundle
 
 	deployCfg.Prefix, _ = cmd.Flags().GetString("prefix")
 	deployCfg.HAMode, _ = cmd.Flags().GetBool("ha")
 	deployCfg.StoragePool, _ = cmd.Flags().GetString("storage")
+	deployCfg.StartRetries, _ = cmd.Flags().GetInt("start-retries")
+	deployCfg.StartDelay, _ = cmd.Flags().GetDuration("start-delay")
+	noStart, _ := cmd.Flags().GetBool("no-start")
+	deployCfg.ExtraVMArgs, _ = cmd.Flags().GetStringArray("qm-arg")
+	deployCfg.CloudInitDir, _ = cmd.Flags().GetString("cloud-init-dir")
+	deployCfg.SnippetsStorage, _ = cmd.Flags().GetString("snippets-storage")
+	deployCfg.IPConfig.ManagementSubnet, _ = cmd.Flags().GetString("mgmt-subnet")
+	deployCfg.IPConfig.ManagementGateway, _ = cmd.Flags().GetString("mgmt-gateway")
+	deployCfg.StartAfterCreate = !noStart
+	deployCfg.SnapshotBeforeBoot, _ = cmd.Flags().GetBool("snapshot")
+	deployCfg.GuestAgent, _ = cmd.Flags().GetBool("guest-agent")
+	deployCfg.SetTagColors, _ = cmd.Flags().GetBool("set-tag-colors")
+	deployCfg.ManagementOnlyFirst, _ = cmd.Flags().GetBool("management-only")
+	onFailure, _ := cmd.Flags().GetString("on-failure")
+	if keep, _ := cmd.Flags().GetBool("keep-on-failure"); keep {
+		if cmd.Flags().Changed("on-failure") {
+			finish(exitUsage, fmt.Errorf("--keep-on-failure and --on-failure cannot be used together"), nil)
+		}
+		onFailure = string(config.RollbackNone)
+	}
+	rollbackPolicy, err := config.ParseRollbackPolicy(onFailure)
+	if err != nil {
+		finish(exitUsage, err, nil)
+	}
+	deployCfg.RollbackPolicy = rollbackPolicy
+	isoPolicy, _ := cmd.Flags().GetString("iso-policy")
+	if deployCfg.ISOPolicy, err = config.ParseISOPolicy(isoPolicy); err != nil {
+		finish(exitUsage, err, nil)
+	}
+	deployCfg.Operator, _ = cmd.Flags().GetString("operator")
+	deployCfg.Ticket, _ = cmd.Flags().GetString("ticket")
+	deployCfg.Environment, _ = cmd.Flags().GetString("env")
 
 	mgmtBridge, _ := cmd.Flags().GetString("mgmt-bridge")
 	deployCfg.Networks.NorthboundBridge = mgmtBridge
+	deployCfg.Networks.MTU, _ = cmd.Flags().GetStringToInt("mtu")
+
+	specs := config.DefaultVMSpecs
+	if specsFile, _ := cmd.Flags().GetString("specs-file"); specsFile != "" {
+		var err error
+		if specs, err = config.LoadVMSpecs(specsFile); err != nil {
+			finish(exitUsage, err, nil)
+		}
+	}
 
 	componentStrs, _ := cmd.Flags().GetStringSlice("components")
 	for _, cs := range componentStrs {
 		compType := config.ComponentType(cs)
-		spec := config.DefaultVMSpecs[compType]
+		spec := specs[compType]
 		deployCfg.Components = append(deployCfg.Components, config.ComponentConfig{
-			Type:   compType,
-			Count:  1,
-			CPU:    spec.DefaultCPU,
-			RAMGB:  spec.DefaultRAMGB,
-			DiskGB: spec.DefaultDiskGB,
+			Type:    compType,
+			Count:   1,
+			CPU:     spec.DefaultCPU,
+			RAMGB:   spec.DefaultRAMGB,
+			DiskGB:  spec.DefaultDiskGB,
+			DiskBus: spec.DiskBus,
+			BIOS:    spec.BIOS,
+			Machine: spec.Machine,
 		})
 	}
 
+	// A single --node pins every component; otherwise nodes are assigned
+	// after discovery, across --nodes or all online nodes
 	targetNode, _ := cmd.Flags().GetString("node")
+	nodeNames, _ := cmd.Flags().GetStringSlice("nodes")
+	strategyName, _ := cmd.Flags().GetString("strategy")
+	if targetNode != "" && len(nodeNames) > 0 {
+		finish(exitUsage, fmt.Errorf("--node and --nodes cannot be used together"), nil)
+	}
+	strategy, err := deployer.ParseStrategy(strategyName)
+	if err != nil {
+		finish(exitUsage, err, nil)
+	}
+	if weights, _ := cmd.Flags().GetStringToString("balance-weights"); len(weights) > 0 {
+		if deployCfg.BalanceWeights, err = config.ParseBalanceWeights(weights); err != nil {
+			finish(exitUsage, err, nil)
+		}
+	}
 	for i := range deployCfg.Components {
 		deployCfg.Components[i].Node = targetNode
 	}
 
+	overrides, _ := cmd.Flags().GetStringArray("component")
+	if err := applyComponentOverrides(deployCfg.Components, overrides); err != nil {
+		finish(exitUsage, err, nil)
+	}
+
 	// Create sources and deployer
 	cfg, _ := config.Load()
 	imageSources, _ := sources.CreateSourcesFromConfig(cfg)
 
-	d := deployer.NewDeployer(client, imageSources)
-	d.SetConfig(deployCfg)
-
-	d.OnLog = func(msg string) {
-		fmt.Println(msg)
+	if cfg != nil {
+		deployCfg.DescriptionTemplate = cfg.DescriptionTemplate
+		deployCfg.DownloadsPerSource = cfg.DownloadsPerSource
+		deployCfg.ChecksumSources = cfg.ChecksumSources
+		deployCfg.FeatureMinVersions = cfg.FeatureMinVersions
+	}
+	if cmd.Flags().Changed("downloads-per-source") {
+		deployCfg.DownloadsPerSource, _ = cmd.Flags().GetInt("downloads-per-source")
 	}
 
-	// Discover first
-	if _, err := d.Discover(); err != nil {
-		fmt.Fprintf(os.Stderr, "Discovery failed: %v\n", err)
-		os.Exit(1)
+	deployCfg.Registration.DirectorIP, _ = cmd.Flags().GetString("director")
+	deployCfg.Registration.Username, _ = cmd.Flags().GetString("director-username")
+	deployCfg.Registration.Password, _ = cmd.Flags().GetString("director-password")
+	deployCfg.Registration.Timeout, _ = cmd.Flags().GetDuration("registration-timeout")
+	if deployCfg.Registration.DirectorIP == "" && cfg != nil {
+		deployCfg.Registration.DirectorIP = cfg.DirectorIP
+	}
+	if tmplPath, _ := cmd.Flags().GetString("description-template"); tmplPath != "" {
+		data, err := os.ReadFile(config.ExpandPath(tmplPath))
+		if err != nil {
+			finish(exitUsage, fmt.Errorf("reading description template: %w", err), nil)
+		}
+		deployCfg.DescriptionTemplate = string(data)
 	}
 
-	// Deploy
-	result, err := d.Deploy()
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	// Connect, pick the newest ISO for each component, discover and deploy
+	result, err := deployer.Run(context.Background(), deployer.DeployRequest{
+		SSH:         sshOpts,
+		Config:      deployCfg,
+		Sources:     imageSources,
+		AssignNodes: targetNode == "",
+		Nodes:       nodeNames,
+		Strategy:    strategy,
+		DryRun:      dryRun,
+		OnLog: func(msg string) {
+			fmt.Fprintln(out, msg)
+		},
+	})
+	if result != nil && len(result.RemovedVMIDs) > 0 {
+		fmt.Fprintf(out, "Removed failed VMs (--on-failure failed-only): %v\n", result.RemovedVMIDs)
+	}
+	if result != nil && result.Preserved {
+		fmt.Fprintln(out, "Created VMs were preserved for inspection; delete them once done:")
+		for _, vm := range result.VMs {
+			if vm.ConsoleURL != "" {
+				fmt.Fprintf(out, "  %s (VMID %d, %s): %s\n", vm.Name, vm.VMID, vm.Status, vm.ConsoleURL)
+			}
+		}
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Deployment failed: %v\n", err)
-		os.Exit(1)
+		switch {
+		case errors.Is(err, deployer.ErrConnection):
+			finish(exitConnection, err, nil)
+		case errors.Is(err, deployer.ErrNoISO):
+			finish(exitValidation, fmt.Errorf("%w (add an image source or use --component <type>:iso=<file>)", err), nil)
+		case errors.Is(err, deployer.ErrValidation):
+			if result != nil && len(result.Errors) > 1 {
+				fmt.Fprintf(out, "Validation failed with %d problems:\n", len(result.Errors))
+				for _, e := range result.Errors {
+					fmt.Fprintf(out, "  • %s\n", e)
+				}
+			}
+			finish(exitValidation, err, result)
+		case result != nil && result.RolledBack:
+			finish(exitRolledBack, fmt.Errorf("deployment failed: %w", err), result)
+		default:
+			finish(exitVMsLeft, fmt.Errorf("deployment failed: %w", err), result)
+		}
 	}
 
-	if result.Success {
-		fmt.Println("\nDeployment successful!")
+	if !result.Success {
+		finish(exitVMsLeft, fmt.Errorf("deployment completed with errors: %s", strings.Join(result.Errors, "; ")), result)
+	}
+
+	if result.Status == deployer.StatusPlanned {
+		fmt.Fprintln(out, "\nDry run: nothing was changed. Planned VMs:")
 		for _, vm := range result.VMs {
-			fmt.Printf("  %s (VMID %d): %s\n", vm.Name, vm.VMID, vm.ConsoleURL)
+			fmt.Fprintf(out, "  %s (VMID %d) on %s\n", vm.Name, vm.VMID, vm.Node)
+			for _, iface := range vm.Interfaces {
+				fmt.Fprintf(out, "      %s\n", iface)
+			}
 		}
+		finish(0, nil, result)
+		return
 	}
+
+	fmt.Fprintln(out, "\nDeployment successful!")
+	if result.NotStarted {
+		fmt.Fprintln(out, "VMs were created but not started (--no-start). Start them with 'qm start <vmid>' once reviewed.")
+	}
+	for _, vm := range result.VMs {
+		fmt.Fprintf(out, "  %s (VMID %d, %s): %s\n", vm.Name, vm.VMID, vm.Status, vm.ConsoleURL)
+		for _, iface := range vm.Interfaces {
+			fmt.Fprintf(out, "      %s\n", iface)
+		}
+	}
+	if len(result.Registrations) > 0 {
+		fmt.Fprintln(out, "Director registration:")
+		for _, r := range result.Registrations {
+			fmt.Fprintf(out, "  %s (%s): %s\n", r.Name, r.Type, r.Status)
+		}
+	}
+	finish(0, nil, result)
+}
+
+// applyComponentOverrides applies --component type:key=value[,key=value] flags
+func applyComponentOverrides(components []config.ComponentConfig, overrides []string) error {
+	for _, o := range overrides {
+		compName, settings, ok := strings.Cut(o, ":")
+		if !ok || settings == "" {
+			return fmt.Errorf("invalid --component %q (expected type:key=value)", o)
+		}
+
+		var target *config.ComponentConfig
+		for i := range components {
+			if string(components[i].Type) == compName {
+				target = &components[i]
+				break
+			}
+		}
+		if target == nil {
+			return fmt.Errorf("--component %q: %s is not in --components", o, compName)
+		}
+
+		// A segment without '=' continues the previous value, so CPU lists
+		// like affinity=0-3,8-11 survive the comma split
+		var kvs []string
+		for _, part := range strings.Split(settings, ",") {
+			if !strings.Contains(part, "=") && len(kvs) > 0 {
+				kvs[len(kvs)-1] += "," + part
+				continue
+			}
+			kvs = append(kvs, part)
+		}
+
+		for _, kv := range kvs {
+			key, value, ok := strings.Cut(kv, "=")
+			if !ok || value == "" {
+				return fmt.Errorf("invalid --component setting %q (expected key=value)", kv)
+			}
+			switch key {
+			case "storage":
+				target.StoragePool = value
+			case "iso":
+				target.ISOPath = value
+			case "template":
+				vmid, err := strconv.Atoi(value)
+				if err != nil || vmid < 100 {
+					return fmt.Errorf("--component %q: invalid template VMID %q", o, value)
+				}
+				target.TemplateVMID = vmid
+			case "hugepages":
+				target.Hugepages = value
+			case "affinity":
+				target.Affinity = value
+			case "disk-bus":
+				bus, err := config.ParseDiskBus(value)
+				if err != nil {
+					return fmt.Errorf("--component %q: %w", o, err)
+				}
+				target.DiskBus = bus
+			case "data-disk":
+				size, err := strconv.Atoi(value)
+				if err != nil || size <= 0 {
+					return fmt.Errorf("--component %q: invalid data disk size %q (GB)", o, value)
+				}
+				target.DataDiskGB = size
+			case "bios":
+				bios, err := config.ParseBIOS(value)
+				if err != nil {
+					return fmt.Errorf("--component %q: %w", o, err)
+				}
+				target.BIOS = bios
+			case "machine":
+				machine, err := config.ParseMachine(value)
+				if err != nil {
+					return fmt.Errorf("--component %q: %w", o, err)
+				}
+				target.Machine = machine
+			case "ci-user", "ci-password", "ci-sshkeys", "ci-ip", "ci-gw":
+				if target.CloudInit == nil {
+					target.CloudInit = &config.CloudInitConfig{}
+				}
+				if err := setCloudInitOverride(target.CloudInit, key, value); err != nil {
+					return fmt.Errorf("--component %q: %w", o, err)
+				}
+			default:
+				return fmt.Errorf("unknown --component setting %q", key)
+			}
+		}
+	}
+	return nil
+}
+
+// setCloudInitOverride applies one ci-* --component setting
+func setCloudInitOverride(ci *config.CloudInitConfig, key, value string) error {
+	switch key {
+	case "ci-user":
+		ci.User = value
+	case "ci-password":
+		ci.Password = value
+	case "ci-ip":
+		ci.IP = value
+	case "ci-gw":
+		ci.Gateway = value
+	case "ci-sshkeys":
+		data, err := os.ReadFile(value)
+		if err != nil {
+			return fmt.Errorf("reading SSH keys: %w", err)
+		}
+		for _, line := range strings.Split(string(data), "\n") {
+			if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+				ci.SSHKeys = append(ci.SSHKeys, line)
+			}
+		}
+	}
+	return nil
 }
 
 func runStatus(cmd *cobra.Command, args []string) {
@@ -292,10 +1481,51 @@ func runStatus(cmd *cobra.Command, args []string) {
 	}
 
 	// Also get branch status
-	branchStatus, err := client.GetBranchStatus()
-	if err == nil && branchStatus != nil {
-		fmt.Printf("\nBranch Devices: %d online, %d offline\n",
-			branchStatus.OnlineCount, branchStatus.OfflineCount)
+	showBranches, _ := cmd.Flags().GetBool("branches")
+	org, _ := cmd.Flags().GetString("org")
+	branchFilter, _ := cmd.Flags().GetString("branch-status")
+
+	branchStatus, err := client.QueryBranchStatus(director.BranchQuery{
+		Organization: org,
+		Status:       branchFilter,
+	})
+	if err != nil {
+		if showBranches {
+			fmt.Fprintf(os.Stderr, "Failed to get branch status: %v\n", err)
+		}
+		return
+	}
+
+	label := "Branch Devices"
+	if org != "" {
+		label += fmt.Sprintf(" (org %s)", org)
+	}
+	fmt.Printf("\n%s: %d total, %d online, %d offline\n",
+		label, branchStatus.TotalDevices, branchStatus.OnlineCount, branchStatus.OfflineCount)
+	if branchStatus.Truncated {
+		fmt.Fprintf(os.Stderr, "WARNING: branch list is incomplete (%d page(s) failed): %s\n",
+			len(branchStatus.PageErrors), strings.Join(branchStatus.PageErrors, "; "))
+	}
+
+	if showBranches && len(branchStatus.Devices) > 0 {
+		fmt.Printf("\n  %-25s  %-15s  %-10s  %-12s  %-20s  %s\n", "Device", "IP", "Status", "Version", "Organization", "Last Seen")
+		for _, dev := range branchStatus.Devices {
+			fmt.Printf("  %-25s  %-15s  %-10s  %-12s  %-20s  %s\n",
+				dev.Name, dev.IP, dev.Status, dev.Version, dev.Organization, dev.LastSeen)
+		}
+	}
+}
+
+// printSourcesTable prints one line per scanned source, with its error if
+// the scan failed or timed out
+func printSourcesTable(summaries []sources.SourceSummary) {
+	fmt.Printf("\n%-30s  %-10s  %-6s  %-6s  %s\n", "Source", "Type", "ISOs", "MD5s", "Error")
+	for _, s := range summaries {
+		name := s.Name
+		if len(name) > 30 {
+			name = name[:27] + "..."
+		}
+		fmt.Printf("%-30s  %-10s  %-6d  %-6d  %s\n", name, s.Type, s.ISOCount, s.MD5Count, s.Error)
 	}
 }
 
@@ -307,22 +1537,24 @@ func runReleases(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	opts := sources.DefaultScanOptions
+	opts.Timeout, _ = cmd.Flags().GetDuration("timeout")
+	opts.Retries, _ = cmd.Flags().GetInt("retries")
+	if parallel, _ := cmd.Flags().GetBool("parallel"); parallel {
+		opts.Concurrency = len(imageSources)
+	}
+
 	fmt.Println("Scanning image sources...")
 
-	collection, err := sources.ScanAllSources(imageSources)
+	collection, err := sources.ScanAllSources(imageSources, opts)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Scan failed: %v\n", err)
 		os.Exit(1)
 	}
+	sources.RecordScanResults(cfg, collection)
+	cfg.Save()
 
-	fmt.Printf("\n%-30s  %-10s  %-6s  %-6s\n", "Source", "Type", "ISOs", "MD5s")
-	for _, s := range collection.Sources {
-		name := s.Name
-		if len(name) > 30 {
-			name = name[:27] + "..."
-		}
-		fmt.Printf("%-30s  %-10s  %-6d  %-6d\n", name, s.Type, s.ISOCount, s.MD5Count)
-	}
+	printSourcesTable(collection.Sources)
 
 	printISOs := func(isos []sources.ISOFile, label string) {
 		if len(isos) == 0 {
@@ -346,23 +1578,65 @@ func runReleases(cmd *cobra.Command, args []string) {
 
 func runGenerateMD5(cmd *cobra.Command, args []string) {
 	path, _ := cmd.Flags().GetString("path")
+	parallel, _ := cmd.Flags().GetInt("parallel")
+	verify, _ := cmd.Flags().GetBool("verify")
 
 	fmt.Printf("Generating MD5 files in %s...\n", path)
 
-	generated, err := downloader.GenerateAllMD5Files(path)
+	var lastDraw time.Time
+	result, err := downloader.GenerateMD5Files(path, downloader.MD5BatchOptions{
+		Parallel: parallel,
+		Verify:   verify,
+		Progress: func(p downloader.MD5Progress) {
+			// Redraw at most every 200ms, plus once per finished file
+			if time.Since(lastDraw) < 200*time.Millisecond && p.FileDone < p.FileTotal {
+				return
+			}
+			lastDraw = time.Now()
+			printMD5Progress(p)
+		},
+	})
+	if !lastDraw.IsZero() {
+		fmt.Println()
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	if len(generated) == 0 {
+	if len(result.Generated) == 0 && len(result.Regenerated) == 0 {
 		fmt.Println("No new MD5 files generated (all ISOs already have MD5 files)")
-	} else {
-		fmt.Printf("Generated %d MD5 files:\n", len(generated))
-		for _, g := range generated {
+	}
+	if len(result.Generated) > 0 {
+		fmt.Printf("Generated %d MD5 files:\n", len(result.Generated))
+		for _, g := range result.Generated {
+			fmt.Printf("  • %s\n", g)
+		}
+	}
+	if len(result.Regenerated) > 0 {
+		fmt.Printf("Regenerated %d mismatched MD5 files:\n", len(result.Regenerated))
+		for _, g := range result.Regenerated {
 			fmt.Printf("  • %s\n", g)
 		}
 	}
+	if verify {
+		fmt.Printf("Verified %d existing MD5 files\n", len(result.Verified))
+	}
+}
+
+// printMD5Progress redraws a single-line progress bar for batch hashing
+func printMD5Progress(p downloader.MD5Progress) {
+	const width = 30
+	pct := 0.0
+	if p.BytesTotal > 0 {
+		pct = float64(p.BytesDone) / float64(p.BytesTotal)
+	}
+	filled := int(pct * width)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", width-filled)
+	fmt.Printf("\r\033[K[%s] %3.0f%% %s/%s (%d/%d files) %s",
+		bar, pct*100,
+		sources.FormatFileSize(p.BytesDone), sources.FormatFileSize(p.BytesTotal),
+		p.FilesDone, p.FilesTotal, p.File)
 }
 
 func runAddSource(cmd *cobra.Command, args []string) {
@@ -378,6 +1652,18 @@ func runAddSource(cmd *cobra.Command, args []string) {
 		URL:  args[0],
 		Type: string(sourceType),
 	}
+	source.Username, _ = cmd.Flags().GetString("username")
+	source.Password, _ = cmd.Flags().GetString("password")
+	source.Headers, _ = cmd.Flags().GetStringToString("header")
+	if source.Username != "" && source.Password == "" {
+		fmt.Fprintln(os.Stderr, "Error: --username requires --password")
+		os.Exit(1)
+	}
+
+	if err := cfg.CanAddImageSource(source.URL); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Validate source
 	fmt.Printf("Testing connection to %s...\n", source.URL)
@@ -406,3 +1692,61 @@ func runAddSource(cmd *cobra.Command, args []string) {
 
 	fmt.Printf("Source added successfully: %s (%s)\n", source.Name, source.Type)
 }
+
+// addYesFlag registers the --yes/-y flag shared by destructive commands
+func addYesFlag(cmd *cobra.Command) {
+	cmd.Flags().BoolP("yes", "y", false, "Skip the confirmation prompt")
+}
+
+// errNotConfirmed is returned when the user declines a destructive action
+var errNotConfirmed = errors.New("aborted")
+
+// confirmDestructive lists what a destructive command is about to do and asks
+// for confirmation, unless --yes was given. Without a terminal on stdin it
+// refuses rather than proceeding unattended.
+func confirmDestructive(cmd *cobra.Command, question string, items []string) error {
+	if yes, _ := cmd.Flags().GetBool("yes"); yes {
+		return nil
+	}
+	if !stdinIsTerminal() {
+		return fmt.Errorf("refusing to proceed without confirmation; pass --yes to run non-interactively")
+	}
+
+	for _, item := range items {
+		fmt.Fprintf(os.Stderr, "  %s\n", item)
+	}
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		return errNotConfirmed
+	}
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return nil
+	}
+	return errNotConfirmed
+}
+
+// stdinIsTerminal reports whether stdin is an interactive terminal
+func stdinIsTerminal() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// applyCABundle makes image sources trust --ca-bundle, or else config.json's
+// ca_bundle. A broken config value only warns, so config set can still fix it.
+func applyCABundle() error {
+	if caBundle != "" {
+		return sources.SetCABundle(caBundle)
+	}
+	cfg, err := config.Load()
+	if err != nil || cfg.CABundle == "" {
+		return nil
+	}
+	if err := sources.SetCABundle(cfg.CABundle); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring config ca_bundle: %v\n", err)
+		return nil
+	}
+	caBundle = cfg.CABundle
+	return nil
+}
@@ -0,0 +1,143 @@
+package proxmox
+
+import (
+	"crypto/rand"
+	"crypto/sha512"
+	"fmt"
+	"strings"
+)
+
+// cryptAlphabet is the base64 alphabet used by crypt(3) hashes
+const cryptAlphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// sha512CryptRounds is crypt(3)'s default round count, used when the hash
+// carries no rounds= parameter
+const sha512CryptRounds = 5000
+
+// isCryptHash reports whether a cloud-init password is already a crypt(3)
+// hash, which Proxmox stores as is
+func isCryptHash(password string) bool {
+	for _, prefix := range []string{"$1$", "$5$", "$6$", "$2a$", "$2y$"} {
+		if strings.HasPrefix(password, prefix) && strings.Count(password, "$") >= 3 {
+			return true
+		}
+	}
+	return false
+}
+
+// hashCloudInitPassword returns a SHA-512 crypt hash of a cloud-init
+// password with a random salt, so the plain password never appears on a qm
+// command line. Proxmox keeps a hashed --cipassword unchanged.
+func hashCloudInitPassword(password string) (string, error) {
+	if isCryptHash(password) {
+		return password, nil
+	}
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("generating password salt: %w", err)
+	}
+	salt := make([]byte, len(raw))
+	for i, b := range raw {
+		salt[i] = cryptAlphabet[int(b)%len(cryptAlphabet)]
+	}
+	return sha512Crypt(password, string(salt)), nil
+}
+
+// sha512Crypt implements the $6$ scheme of crypt(3) with the default 5000
+// rounds, following Ulrich Drepper's SHA-crypt specification
+func sha512Crypt(password, salt string) string {
+	if len(salt) > 16 {
+		salt = salt[:16]
+	}
+	p, s := []byte(password), []byte(salt)
+
+	alt := sha512.New()
+	alt.Write(p)
+	alt.Write(s)
+	alt.Write(p)
+	altSum := alt.Sum(nil)
+
+	a := sha512.New()
+	a.Write(p)
+	a.Write(s)
+	i := len(p)
+	for ; i > sha512.Size; i -= sha512.Size {
+		a.Write(altSum)
+	}
+	a.Write(altSum[:i])
+	for i = len(p); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			a.Write(altSum)
+		} else {
+			a.Write(p)
+		}
+	}
+	sum := a.Sum(nil)
+
+	dp := sha512.New()
+	for range p {
+		dp.Write(p)
+	}
+	pSeq := repeatBytes(dp.Sum(nil), len(p))
+
+	ds := sha512.New()
+	for n := 0; n < 16+int(sum[0]); n++ {
+		ds.Write(s)
+	}
+	sSeq := repeatBytes(ds.Sum(nil), len(s))
+
+	for r := 0; r < sha512CryptRounds; r++ {
+		c := sha512.New()
+		if r&1 != 0 {
+			c.Write(pSeq)
+		} else {
+			c.Write(sum)
+		}
+		if r%3 != 0 {
+			c.Write(sSeq)
+		}
+		if r%7 != 0 {
+			c.Write(pSeq)
+		}
+		if r&1 != 0 {
+			c.Write(sum)
+		} else {
+			c.Write(pSeq)
+		}
+		sum = c.Sum(nil)
+	}
+
+	// The digest is encoded in a fixed byte order, three bytes at a time
+	order := [][3]int{
+		{0, 21, 42}, {22, 43, 1}, {44, 2, 23}, {3, 24, 45}, {25, 46, 4},
+		{47, 5, 26}, {6, 27, 48}, {28, 49, 7}, {50, 8, 29}, {9, 30, 51},
+		{31, 52, 10}, {53, 11, 32}, {12, 33, 54}, {34, 55, 13}, {56, 14, 35},
+		{15, 36, 57}, {37, 58, 16}, {59, 17, 38}, {18, 39, 60}, {40, 61, 19},
+		{62, 20, 41},
+	}
+	var out strings.Builder
+	out.WriteString("$6$" + salt + "$")
+	for _, o := range order {
+		writeCrypt64(&out, uint(sum[o[0]])<<16|uint(sum[o[1]])<<8|uint(sum[o[2]]), 4)
+	}
+	writeCrypt64(&out, uint(sum[63]), 2)
+	return out.String()
+}
+
+// repeatBytes returns n bytes of b repeated
+func repeatBytes(b []byte, n int) []byte {
+	out := make([]byte, 0, n)
+	for len(out) < n {
+		out = append(out, b[:min(len(b), n-len(out))]...)
+	}
+	return out
+}
+
+// writeCrypt64 writes the low 6*n bits of v in crypt(3) base64, least
+// significant first
+func writeCrypt64(out *strings.Builder, v uint, n int) {
+	for ; n > 0; n-- {
+		out.WriteByte(cryptAlphabet[v&0x3f])
+		v >>= 6
+	}
+}
@@ -0,0 +1,43 @@
+package proxmox
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSHA512Crypt(t *testing.T) {
+	// The first vector is from the SHA-crypt specification, the others
+	// from openssl passwd -6
+	tests := []struct {
+		password, salt, want string
+	}{
+		{"Hello world!", "saltstring", "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1"},
+		{"x", "short", "$6$short$QyVoKWqPNl6RNenmBA6a/vLfaqSQjTh9uln9bU2ulhek2oiVdUAoCfuszVp.1rkl8XCLSBKCMmlydCXQ0ITpm/"},
+		{"a much longer password that exceeds sixty-four bytes in total length, for the loop", "Rh3bD8kq0aZ1xYtP",
+			"$6$Rh3bD8kq0aZ1xYtP$11SZ17roTdvREFpIcapnAtJT72UQ7g6XFlsoYHl8gdufzytuH9VpyO9HmwWfo65Fug/b8Ffrua5kvMnI5RuNd."},
+	}
+	for _, tt := range tests {
+		if got := sha512Crypt(tt.password, tt.salt); got != tt.want {
+			t.Errorf("sha512Crypt(%q, %q) = %q, want %q", tt.password, tt.salt, got, tt.want)
+		}
+	}
+}
+
+func TestHashCloudInitPassword(t *testing.T) {
+	hash, err := hashCloudInitPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(hash, "$6$") || strings.Contains(hash, "secret") {
+		t.Errorf("hashCloudInitPassword = %q, want a $6$ hash", hash)
+	}
+	salt := strings.Split(hash, "$")[2]
+	if want := sha512Crypt("secret", salt); hash != want {
+		t.Errorf("hash %q doesn't verify, want %q", hash, want)
+	}
+
+	pre := "$6$abc$def"
+	if got, _ := hashCloudInitPassword(pre); got != pre {
+		t.Errorf("hashCloudInitPassword(%q) = %q, want it unchanged", pre, got)
+	}
+}
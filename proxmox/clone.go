@@ -0,0 +1,139 @@
+package proxmox
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/ssh"
+)
+
+// CloneVMCommand builds the qm clone command CloneVM runs: a full clone of
+// a template into storage, moved to node when it is set
+func CloneVMCommand(templateVMID, newVMID int, name, storage, node string) (string, error) {
+	if err := ValidateStorageName(storage); err != nil {
+		return "", err
+	}
+	args := []string{
+		fmt.Sprintf("%d %d", templateVMID, newVMID),
+		"--name " + ssh.ShellEscape(name),
+		"--full 1",
+		"--storage " + ssh.ShellEscape(storage),
+	}
+	if node != "" {
+		if err := ValidateNodeName(node); err != nil {
+			return "", err
+		}
+		args = append(args, "--target "+ssh.ShellEscape(node))
+	}
+	return "qm clone " + strings.Join(args, " "), nil
+}
+
+// CloneVM makes a full clone of a pre-installed template. node moves the
+// clone to another cluster node ("" = the template's node), which needs the
+// template on shared storage; later calls for the clone run there.
+func (c *VMCreator) CloneVM(templateVMID, newVMID int, name, storage, node string) error {
+	cmd, err := CloneVMCommand(templateVMID, newVMID, name, storage, node)
+	if err != nil {
+		return fmt.Errorf("cloning VM: %w", err)
+	}
+	if err := c.runQuiet(cmd); err != nil {
+		if isVMIDExistsError(err, newVMID) {
+			return fmt.Errorf("cloning VM: %w: %w", ErrVMIDExists, err)
+		}
+		return fmt.Errorf("cloning template %d: %w", templateVMID, err)
+	}
+	c.setVMNode(newVMID, node)
+	return nil
+}
+
+// ConfigureCloneCommand builds the qm set command ConfigureClone runs.
+// templateNets are the netN slots the clone inherited from its template;
+// any beyond cfg.Networks are deleted so they don't stay attached.
+func ConfigureCloneCommand(cfg VMConfig, templateNets []int) (string, error) {
+	if err := validateNetworks(cfg.Networks); err != nil {
+		return "", err
+	}
+
+	settings, err := settingsArgs(cfg)
+	if err != nil {
+		return "", err
+	}
+	args := append([]string{fmt.Sprintf("%d", cfg.VMID)}, settings...)
+
+	var extra []string
+	for _, slot := range templateNets {
+		if slot >= len(cfg.Networks) {
+			extra = append(extra, fmt.Sprintf("net%d", slot))
+		}
+	}
+	if len(extra) > 0 {
+		args = append(args, "--delete "+strings.Join(extra, ","))
+	}
+	return withSSHKeys(cfg, "qm set "+strings.Join(args, " ")), nil
+}
+
+// ConfigureClone applies a component's CPU, RAM, networks, tags and
+// cloud-init to a cloned VM. The template's disk and install are kept, but
+// network interfaces the component doesn't use are removed.
+func (c *VMCreator) ConfigureClone(cfg VMConfig) error {
+	nets, err := c.NetSlots(cfg.VMID)
+	if err != nil {
+		return fmt.Errorf("configuring clone: %w", err)
+	}
+	cmd, err := ConfigureCloneCommand(cfg, nets)
+	if err != nil {
+		return fmt.Errorf("configuring clone: %w", err)
+	}
+	if err := c.runQuiet(c.onNode(cfg.VMID, cmd)); err != nil {
+		return fmt.Errorf("configuring clone: %w", err)
+	}
+	return nil
+}
+
+// NetSlots returns the N of every netN interface in a VM's config
+func (c *VMCreator) NetSlots(vmid int) ([]int, error) {
+	result, err := c.run(c.onNode(vmid, fmt.Sprintf("qm config %d", vmid)))
+	if err != nil {
+		return nil, fmt.Errorf("reading VM %d config: %w", vmid, err)
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("reading VM %d config: %s", vmid, strings.TrimSpace(result.Stderr))
+	}
+	return parseNetSlots(result.Stdout), nil
+}
+
+// parseNetSlots returns the netN slots in qm config output
+func parseNetSlots(config string) []int {
+	var slots []int
+	for _, line := range strings.Split(config, "\n") {
+		key, _, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		if n, ok := strings.CutPrefix(key, "net"); ok {
+			if slot, err := strconv.Atoi(n); err == nil {
+				slots = append(slots, slot)
+			}
+		}
+	}
+	sort.Ints(slots)
+	return slots
+}
+
+// ErrNotTemplate is returned by CheckTemplate for a VMID that isn't a template
+var ErrNotTemplate = errors.New("not a VM template")
+
+// CheckTemplate reports whether a VMID is a template that can be cloned
+func (c *VMCreator) CheckTemplate(vmid int) error {
+	value, err := c.configValue(vmid, "template")
+	if err != nil {
+		return err
+	}
+	if value != "1" {
+		return fmt.Errorf("VM %d: %w", vmid, ErrNotTemplate)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package proxmox
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseNetSlots(t *testing.T) {
+	config := `boot: order=scsi0;ide2
+cores: 4
+net0: virtio=BC:24:11:00:00:01,bridge=vmbr0
+net10: virtio=BC:24:11:00:00:0A,bridge=vmbr1
+net2: e1000=BC:24:11:00:00:02,bridge=vmbr2
+netx: not an interface
+description: net5: mentioned only in text
+`
+	if got, want := parseNetSlots(config), []int{0, 2, 10}; !reflect.DeepEqual(got, want) {
+		t.Errorf("parseNetSlots() = %v, want %v", got, want)
+	}
+}
+
+func TestConfigureCloneCommand(t *testing.T) {
+	cfg := VMConfig{
+		VMID:     120,
+		Name:     "lab-controller-1",
+		CPUCores: 4,
+		RAMGB:    8,
+		Networks: []VMNetwork{{Bridge: "vmbr0"}, {Bridge: "vmbr1"}},
+		Tags:     []string{"versa-deployer"},
+	}
+
+	tests := []struct {
+		name         string
+		templateNets []int
+		wantDelete   string
+	}{
+		{"template has fewer interfaces", []int{0}, ""},
+		{"template has the same interfaces", []int{0, 1}, ""},
+		{"template has extra interfaces", []int{0, 1, 2, 10}, "--delete net2,net10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, err := ConfigureCloneCommand(cfg, tt.templateNets)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(cmd, "qm set 120 ") {
+				t.Errorf("command is not a qm set for the clone: %s", cmd)
+			}
+			if !strings.Contains(cmd, "--net1 'virtio,bridge=vmbr1'") {
+				t.Errorf("command doesn't set the component's networks: %s", cmd)
+			}
+			hasDelete := strings.Contains(cmd, "--delete")
+			if tt.wantDelete == "" && hasDelete {
+				t.Errorf("command deletes interfaces the component uses: %s", cmd)
+			}
+			if tt.wantDelete != "" && !strings.Contains(cmd, tt.wantDelete) {
+				t.Errorf("command lacks %q: %s", tt.wantDelete, cmd)
+			}
+		})
+	}
+}
+
+func TestCloneAndCreateShareSettings(t *testing.T) {
+	cfg := VMConfig{
+		VMID:       121,
+		Name:       "lab-router-1",
+		CPUCores:   2,
+		RAMGB:      4,
+		DiskGB:     20,
+		Storage:    "local-lvm",
+		Networks:   []VMNetwork{{Bridge: "vmbr0"}},
+		Hugepages:  "1024",
+		NUMA:       true,
+		GuestAgent: true,
+		OnBoot:     true,
+	}
+	createCmd, err := CreateVMCommand(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setCmd, err := ConfigureCloneCommand(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings, err := settingsArgs(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, arg := range settings {
+		if !strings.Contains(createCmd, arg) || !strings.Contains(setCmd, arg) {
+			t.Errorf("%q missing from qm create or qm set:\n%s\n%s", arg, createCmd, setCmd)
+		}
+	}
+}
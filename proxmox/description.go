@@ -0,0 +1,80 @@
+package proxmox
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// DescriptionInfo holds the deployment metadata written into a VM's notes
+type DescriptionInfo struct {
+	Summary     string // Component description from the VM spec
+	Name        string
+	Component   string
+	Version     string
+	Prefix      string
+	DeployedAt  string
+	ToolVersion string
+	Operator    string
+	Ticket      string
+
+	// Provenance of the install image
+	ISOFile      string
+	ISOSource    string
+	ISOSourceURL string
+	ISOChecksum  string
+}
+
+// DefaultDescriptionTemplate renders the VM notes as markdown, which the
+// Proxmox UI displays in the Notes panel
+const DefaultDescriptionTemplate = `## {{.Summary}}
+
+| | |
+|---|---|
+| **VM** | {{.Name}} |
+| **Component** | {{.Component}}{{if .Version}} {{.Version}}{{end}} |
+| **Deployment** | {{.Prefix}} |
+| **Deployed** | {{.DeployedAt}} |
+| **Tool version** | {{.ToolVersion}} |
+{{- if .Operator}}
+| **Operator** | {{.Operator}} |
+{{- end}}
+{{- if .Ticket}}
+| **Ticket** | {{.Ticket}} |
+{{- end}}
+
+### Install image
+
+| | |
+|---|---|
+| **ISO** | {{or .ISOFile "-"}} |
+{{- if .ISOSource}}
+| **Source** | {{.ISOSource}} |
+{{- end}}
+{{- if .ISOSourceURL}}
+| **Source URL** | {{.ISOSourceURL}} |
+{{- end}}
+{{- if .ISOChecksum}}
+| **Checksum** | ` + "`{{.ISOChecksum}}`" + ` |
+{{- end}}
+`
+
+// RenderDescription renders VM notes from a text/template. An empty template
+// uses DefaultDescriptionTemplate.
+func RenderDescription(tmpl string, info DescriptionInfo) (string, error) {
+	if tmpl == "" {
+		tmpl = DefaultDescriptionTemplate
+	}
+
+	t, err := template.New("description").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("parsing description template: %w", err)
+	}
+
+	var sb strings.Builder
+	if err := t.Execute(&sb, info); err != nil {
+		return "", fmt.Errorf("rendering description template: %w", err)
+	}
+
+	return strings.TrimSpace(sb.String()), nil
+}
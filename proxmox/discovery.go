@@ -1,7 +1,10 @@
 package proxmox
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"path"
 	"regexp"
 	"sort"
 	"strconv"
@@ -45,8 +48,37 @@ type StorageInfo struct {
 	Content      []string // images, iso, backup, etc.
 	Shared       bool     // Available across cluster
 	Active       bool
+	Nodes        []string // Nodes the storage is restricted to, empty for all
 }
 
+// Storage content types as listed in /etc/pve/storage.cfg
+const (
+	ContentImages   = "images"   // VM disks
+	ContentRootDir  = "rootdir"  // Container volumes
+	ContentISO      = "iso"      // ISO images
+	ContentSnippets = "snippets" // Hook scripts and cloud-init custom configs
+	ContentImport   = "import"   // Disk images for import (PVE 8.2+)
+)
+
+// HasContent reports whether the storage accepts the given content type
+func (s StorageInfo) HasContent(content string) bool {
+	for _, c := range s.Content {
+		if strings.TrimSpace(c) == content {
+			return true
+		}
+	}
+	return false
+}
+
+// AvailableOn reports whether the storage may be used on node
+func (s StorageInfo) AvailableOn(node string) bool {
+	return nodeListed(s.Nodes, node)
+}
+
+// ErrNoSnippetsStorage is returned when cloud-init custom config needs a
+// storage with the snippets content type and none has it enabled
+var ErrNoSnippetsStorage = errors.New("no storage has the snippets content type enabled")
+
 // NetworkInfo holds information about a network bridge
 type NetworkInfo struct {
 	Name       string   // Bridge name (vmbr0, vmbr1, etc.)
@@ -65,11 +97,23 @@ type VMInfo struct {
 	Status string // running, stopped
 	Node   string
 	Tags   []string
+
+	Version     string // Deployed version from the versa-version tag, if any
+	Environment string // Environment from the versa-env tag, if any
+	ToolVersion string // Deployer version from the versa-deployer-v tag, if any
+
+	// TagsUnknown is set when the VM's tags could not be read, so it may be
+	// deployer-managed even though Tags is empty
+	TagsUnknown bool
 }
 
 // Discoverer handles Proxmox environment discovery
 type Discoverer struct {
 	client *ssh.Client
+
+	// VMIDs handed out by GetNextVMID but possibly not yet committed by qm create
+	reservedMu sync.Mutex
+	reserved   map[int]bool
 }
 
 // NewDiscoverer creates a new Proxmox discoverer
@@ -81,6 +125,10 @@ func NewDiscoverer(client *ssh.Client) *Discoverer {
 func (d *Discoverer) Discover() (*ProxmoxInfo, error) {
 	info := &ProxmoxInfo{}
 
+	if err := CheckProxmoxHost(d.client); err != nil {
+		return nil, err
+	}
+
 	// Get version
 	version, err := d.GetVersion()
 	if err != nil {
@@ -130,11 +178,15 @@ func (d *Discoverer) Discover() (*ProxmoxInfo, error) {
 
 // DiscoverParallel performs environment discovery with concurrent operations.
 // Phase 1 runs version check (fast, must succeed). Phase 2 runs nodes, storage,
-// networks, and VMs in parallel. Partial results are returned even if some
-// sub-discoveries fail; per-section errors are embedded in the result.
+// networks, and VMs in parallel, capped at discoveryTimeout. Partial results are
+// returned even if some sub-discoveries fail or don't finish in time.
 func (d *Discoverer) DiscoverParallel() (*ProxmoxInfo, error) {
 	info := &ProxmoxInfo{}
 
+	if err := CheckProxmoxHost(d.client); err != nil {
+		return nil, err
+	}
+
 	// Phase 1: Version (fast, required)
 	version, err := d.GetVersion()
 	if err != nil {
@@ -148,28 +200,37 @@ func (d *Discoverer) DiscoverParallel() (*ProxmoxInfo, error) {
 	info.ClusterName = clusterName
 
 	// Phase 2: Everything else in parallel
+	ctx, cancel := context.WithTimeout(context.Background(), discoveryTimeout)
+	defer cancel()
+
 	var wg sync.WaitGroup
 	var mu sync.Mutex
+	expired := false // Set once the deadline passes; late results are dropped
+
+	// set stores a section's result unless discovery already timed out
+	set := func(apply func()) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !expired {
+			apply()
+		}
+	}
 
 	wg.Add(4)
 
 	go func() {
 		defer wg.Done()
-		nodes, err := d.GetNodes()
+		nodes, err := d.GetNodesContext(ctx)
 		if err == nil {
-			mu.Lock()
-			info.Nodes = nodes
-			mu.Unlock()
+			set(func() { info.Nodes = nodes })
 		}
 	}()
 
 	go func() {
 		defer wg.Done()
-		storage, err := d.GetStorage()
+		storage, err := d.GetStorageContext(ctx)
 		if err == nil {
-			mu.Lock()
-			info.Storage = storage
-			mu.Unlock()
+			set(func() { info.Storage = storage })
 		}
 	}()
 
@@ -177,9 +238,7 @@ func (d *Discoverer) DiscoverParallel() (*ProxmoxInfo, error) {
 		defer wg.Done()
 		networks, err := d.GetNetworks()
 		if err == nil {
-			mu.Lock()
-			info.Networks = networks
-			mu.Unlock()
+			set(func() { info.Networks = networks })
 		}
 	}()
 
@@ -187,13 +246,23 @@ func (d *Discoverer) DiscoverParallel() (*ProxmoxInfo, error) {
 		defer wg.Done()
 		vms, err := d.GetVMs()
 		if err == nil {
-			mu.Lock()
-			info.ExistingVMs = vms
-			mu.Unlock()
+			set(func() { info.ExistingVMs = vms })
 		}
 	}()
 
-	wg.Wait()
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		mu.Lock()
+		expired = true
+		mu.Unlock()
+	}
 
 	return info, nil
 }
@@ -254,6 +323,11 @@ func (d *Discoverer) GetClusterInfo() (bool, string, error) {
 
 // GetNodes returns information about all nodes
 func (d *Discoverer) GetNodes() ([]NodeInfo, error) {
+	return d.GetNodesContext(context.Background())
+}
+
+// GetNodesContext is GetNodes with transient pvesh failures retried until ctx is done
+func (d *Discoverer) GetNodesContext(ctx context.Context) ([]NodeInfo, error) {
 	// Try to get nodes via pvesh API (works on all versions)
 	var nodeList []struct {
 		Node   string  `json:"node"`
@@ -265,7 +339,7 @@ func (d *Discoverer) GetNodes() ([]NodeInfo, error) {
 		Uptime int64   `json:"uptime"`
 	}
 
-	err := d.client.RunJSON("pvesh get /nodes --output-format json", &nodeList)
+	err := d.runJSONRetry(ctx, "pvesh get /nodes --output-format json", &nodeList)
 	if err == nil && len(nodeList) > 0 {
 		var nodes []NodeInfo
 		for _, n := range nodeList {
@@ -336,7 +410,7 @@ func (d *Discoverer) getNodeInfo(nodeName string) (*NodeInfo, error) {
 		} `json:"memory"`
 	}
 
-	cmd := fmt.Sprintf("pvesh get /nodes/%s/status --output-format json", nodeName)
+	cmd := fmt.Sprintf("pvesh get /nodes/%s/status --output-format json", ssh.ShellEscape(nodeName))
 	err := d.client.RunJSON(cmd, &status)
 
 	if err == nil && status.CPUInfo.CPUs > 0 {
@@ -412,6 +486,11 @@ func (d *Discoverer) getNodeInfoFromSystem() (cpuCores, cpuUsed, ramGB, ramUsedG
 
 // GetStorage returns information about all storage pools
 func (d *Discoverer) GetStorage() ([]StorageInfo, error) {
+	return d.GetStorageContext(context.Background())
+}
+
+// GetStorageContext is GetStorage with transient pvesh failures retried until ctx is done
+func (d *Discoverer) GetStorageContext(ctx context.Context) ([]StorageInfo, error) {
 	// Get storage config from pvesh (works on all versions)
 	var storageConfig []struct {
 		Storage  string `json:"storage"`
@@ -420,17 +499,19 @@ func (d *Discoverer) GetStorage() ([]StorageInfo, error) {
 		Path     string `json:"path"`
 		Shared   int    `json:"shared"`
 		Disabled int    `json:"disable"`
+		Nodes    string `json:"nodes"`
 	}
 
 	// Get storage definitions
-	err := d.client.RunJSON("pvesh get /storage --output-format json", &storageConfig)
+	err := d.runJSONRetry(ctx, "pvesh get /storage --output-format json", &storageConfig)
 	if err != nil {
 		// Fallback to text parsing
 		result, err := d.client.Run("pvesm status")
 		if err != nil {
 			return nil, err
 		}
-		return d.parseStorageText(result.Stdout)
+		cfg, _ := readStorageConfig(d.client) // nil keeps the default content types
+		return d.parseStorageText(result.Stdout, cfg)
 	}
 
 	// Get storage status (usage info) via text output
@@ -449,6 +530,7 @@ func (d *Discoverer) GetStorage() ([]StorageInfo, error) {
 			Content: strings.Split(s.Content, ","),
 			Shared:  s.Shared == 1,
 			Active:  true,
+			Nodes:   splitList(s.Nodes),
 		}
 
 		// Merge status info if available
@@ -501,8 +583,9 @@ func (d *Discoverer) parseStorageStatusToMap(output string) map[string]StorageIn
 	return result
 }
 
-// parseStorageText parses text output from pvesm status
-func (d *Discoverer) parseStorageText(output string) ([]StorageInfo, error) {
+// parseStorageText parses text output from pvesm status, taking content
+// types, sharing and node restrictions from the parsed storage.cfg
+func (d *Discoverer) parseStorageText(output string, cfg map[string]StorageConfig) ([]StorageInfo, error) {
 	var storage []StorageInfo
 	lines := strings.Split(output, "\n")
 
@@ -527,49 +610,22 @@ func (d *Discoverer) parseStorageText(output string) ([]StorageInfo, error) {
 		used, _ := strconv.ParseInt(fields[4], 10, 64)
 		avail, _ := strconv.ParseInt(fields[5], 10, 64)
 
-		// Get content types from storage config
-		content := d.getStorageContent(name)
-
 		storage = append(storage, StorageInfo{
 			Name:        name,
 			Type:        storageType,
 			TotalGB:     int(total / (1024 * 1024)), // KB to GB
 			UsedGB:      int(used / (1024 * 1024)),
 			AvailableGB: int(avail / (1024 * 1024)),
-			Content:     content,
+			Content:     contentOrDefault(cfg, name),
 			Active:      status == "active",
-			Shared:      false, // Will be updated from config
+			Shared:      cfg[name].Shared,
+			Nodes:       cfg[name].Nodes,
 		})
 	}
 
 	return storage, nil
 }
 
-// validStorageName checks if a storage name contains only safe characters.
-var validStorageName = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
-
-// getStorageContent gets content types for a storage from config
-func (d *Discoverer) getStorageContent(storageName string) []string {
-	if !validStorageName.MatchString(storageName) {
-		return []string{"images"}
-	}
-	result, err := d.client.Run(fmt.Sprintf("grep -A 10 '^%s:' /etc/pve/storage.cfg 2>/dev/null | grep 'content' | head -1", storageName))
-	if err != nil || result.ExitCode != 0 {
-		return []string{"images", "rootdir"} // Default assumption
-	}
-
-	// Parse "content images,rootdir,iso"
-	line := strings.TrimSpace(result.Stdout)
-	if strings.HasPrefix(line, "content") {
-		parts := strings.Fields(line)
-		if len(parts) >= 2 {
-			return strings.Split(parts[1], ",")
-		}
-	}
-
-	return []string{"images"}
-}
-
 // GetNetworks returns information about network bridges
 func (d *Discoverer) GetNetworks() ([]NetworkInfo, error) {
 	// Read /etc/network/interfaces
@@ -664,7 +720,7 @@ func parseNetworkInterfaces(content string) []NetworkInfo {
 				current.Interface = strings.TrimPrefix(line, "bridge-ports ")
 			} else if strings.HasPrefix(line, "bridge_ports ") {
 				current.Interface = strings.TrimPrefix(line, "bridge_ports ")
-			} else if strings.Contains(line, "bridge-vlan-aware") {
+			} else if strings.Contains(line, "bridge-vlan-aware") || strings.Contains(line, "bridge_vlan_aware") {
 				if strings.Contains(line, "yes") {
 					current.VLANAware = true
 				}
@@ -731,46 +787,129 @@ func (d *Discoverer) GetVMs() ([]VMInfo, error) {
 		fields := strings.Fields(line)
 		if len(fields) >= 3 {
 			vmid, _ := strconv.Atoi(fields[0])
-			vm := VMInfo{
+			vms = append(vms, VMInfo{
 				VMID:   vmid,
 				Name:   fields[1],
 				Status: fields[2],
+			})
+		}
+	}
+
+	// Read every VM's tags in one call, falling back to per-VM lookups
+	// (retried once) for VMs the batch missed
+	allTags, batchErr := d.getAllVMTags()
+	for i := range vms {
+		tags, ok := allTags[vms[i].VMID]
+		if batchErr != nil || !ok {
+			var err error
+			if tags, err = d.getVMTags(vms[i].VMID); err != nil {
+				if tags, err = d.getVMTags(vms[i].VMID); err != nil {
+					vms[i].TagsUnknown = true
+					continue
+				}
 			}
+		}
+		vms[i].Tags = tags
+		vms[i].Version = config.VersionFromTags(tags)
+		vms[i].Environment = config.EnvironmentFromTags(tags)
+		vms[i].ToolVersion = config.ToolVersionFromTags(tags)
+	}
+
+	return vms, nil
+}
 
-			// Get tags for this VM
-			tags, _ := d.getVMTags(vmid)
-			vm.Tags = tags
+// getAllVMTags reads the tags of every VM on the node from its config file,
+// skipping snapshot sections. VMs without tags map to nil.
+func (d *Discoverer) getAllVMTags() (map[int][]string, error) {
+	// Prints "<file>\t<tags line>" for every config file
+	cmd := `awk 'FNR==1{if(f)print f"\t"t; f=FILENAME; t=""; s=0} /^\[/{s=1} !s && /^tags:/{t=$0} END{if(f)print f"\t"t}' /etc/pve/qemu-server/*.conf`
+	result, err := d.client.Run(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("reading VM configs: %s", strings.TrimSpace(result.Stderr))
+	}
 
-			vms = append(vms, vm)
+	all := make(map[int][]string)
+	for _, line := range strings.Split(result.Stdout, "\n") {
+		file, tagLine, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		vmid, err := strconv.Atoi(strings.TrimSuffix(path.Base(file), ".conf"))
+		if err != nil {
+			continue
 		}
+		all[vmid] = parseTagsLine(tagLine)
 	}
+	return all, nil
+}
 
-	return vms, nil
+// GetAttachedISOs returns the ISOs in the ide2 CD-ROM drives of the node's
+// VMs, mapping each filename to its storage. An ISO a VM boots from is known
+// to be present, so it can be reused without downloading it again.
+func (d *Discoverer) GetAttachedISOs() (map[string]string, error) {
+	// Prints "<file>\t<ide2 line>" for every VM with a CD-ROM on ide2,
+	// skipping snapshot sections
+	cmd := `awk 'FNR==1{s=0} /^\[/{s=1} !s && /^ide2:/{print FILENAME"\t"$0}' /etc/pve/qemu-server/*.conf`
+	result, err := d.client.Run(cmd)
+	if err != nil {
+		return nil, err
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("reading VM configs: %s", strings.TrimSpace(result.Stderr))
+	}
+
+	attached := make(map[string]string)
+	for _, line := range strings.Split(result.Stdout, "\n") {
+		_, drive, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		storage, filename, err := parseCDROMVolume(strings.TrimPrefix(drive, "ide2:"))
+		if err != nil || filename == "" || !strings.HasSuffix(strings.ToLower(filename), ".iso") {
+			continue
+		}
+		attached[filename] = storage
+	}
+	return attached, nil
 }
 
 // getVMTags gets tags for a specific VM
 func (d *Discoverer) getVMTags(vmid int) ([]string, error) {
-	result, err := d.client.Run(fmt.Sprintf("qm config %d 2>/dev/null | grep '^tags:' || true", vmid))
-	if err != nil || result.ExitCode != 0 {
-		return nil, nil
+	result, err := d.client.Run(fmt.Sprintf("qm config %d", vmid))
+	if err != nil {
+		return nil, fmt.Errorf("reading VM %d tags: %w", vmid, err)
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("reading VM %d tags: %s", vmid, strings.TrimSpace(result.Stderr))
 	}
 
-	line := strings.TrimSpace(result.Stdout)
-	if line == "" {
-		return nil, nil
+	for _, line := range strings.Split(result.Stdout, "\n") {
+		if strings.HasPrefix(line, "tags:") {
+			return parseTagsLine(line), nil
+		}
 	}
+	return nil, nil
+}
 
-	// Parse "tags: tag1;tag2;tag3"
-	tagStr := strings.TrimPrefix(line, "tags:")
-	tagStr = strings.TrimSpace(tagStr)
+// parseTagsLine parses "tags: tag1;tag2;tag3"
+func parseTagsLine(line string) []string {
+	tagStr := strings.TrimSpace(strings.TrimPrefix(line, "tags:"))
 	if tagStr == "" {
-		return nil, nil
+		return nil
 	}
-
-	return strings.Split(tagStr, ";"), nil
+	return strings.Split(tagStr, ";")
 }
 
-// GetNextVMID returns the next available VMID
+// maxVMIDProbes bounds how far GetNextVMID walks past /cluster/nextid
+const maxVMIDProbes = 100
+
+// GetNextVMID returns the next available VMID and reserves it locally.
+// /cluster/nextid keeps returning the same ID until a VM occupies it, so IDs
+// already handed out by this Discoverer are skipped and each candidate is
+// re-checked on the cluster before being returned.
 func (d *Discoverer) GetNextVMID() (int, error) {
 	result, err := d.client.Run("pvesh get /cluster/nextid")
 	if err != nil {
@@ -782,27 +921,163 @@ func (d *Discoverer) GetNextVMID() (int, error) {
 		return 0, fmt.Errorf("parsing VMID: %w", err)
 	}
 
-	return vmid, nil
+	d.reservedMu.Lock()
+	defer d.reservedMu.Unlock()
+
+	if d.reserved == nil {
+		d.reserved = make(map[int]bool)
+	}
+
+	for probes := 0; probes < maxVMIDProbes; probes++ {
+		candidate := vmid + probes
+		if d.reserved[candidate] {
+			continue
+		}
+		if probes > 0 && !d.isVMIDFree(candidate) {
+			continue
+		}
+		d.reserved[candidate] = true
+		return candidate, nil
+	}
+
+	return 0, fmt.Errorf("no free VMID found in %d-%d", vmid, vmid+maxVMIDProbes-1)
+}
+
+// isVMIDFree asks the cluster whether a specific VMID is unused
+func (d *Discoverer) isVMIDFree(vmid int) bool {
+	result, err := d.client.Run(fmt.Sprintf("pvesh get /cluster/nextid --vmid %d", vmid))
+	return err == nil && result.ExitCode == 0
+}
+
+// ReleaseVMID drops a local reservation, e.g. after qm create failed
+func (d *Discoverer) ReleaseVMID(vmid int) {
+	d.reservedMu.Lock()
+	defer d.reservedMu.Unlock()
+	delete(d.reserved, vmid)
+}
+
+// HugepageStatus reports hugepages reserved on the host for one page size
+type HugepageStatus struct {
+	PageSizeKB int
+	Total      int
+	Free       int
+}
+
+// FreeMB returns the memory available in free hugepages
+func (h HugepageStatus) FreeMB() int {
+	return h.Free * h.PageSizeKB / 1024
+}
+
+// GetHugepages reads hugepage reservations on the host for a Proxmox
+// --hugepages size ("2" or "1024" MB; "any" uses the default page size)
+func (d *Discoverer) GetHugepages(size string) (*HugepageStatus, error) {
+	result, err := d.client.Run("grep -E '^(HugePages_Total|HugePages_Free|Hugepagesize):' /proc/meminfo")
+	if err != nil {
+		return nil, fmt.Errorf("reading /proc/meminfo: %w", err)
+	}
+
+	status := &HugepageStatus{}
+	for _, line := range strings.Split(result.Stdout, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		n, _ := strconv.Atoi(fields[1])
+		switch fields[0] {
+		case "HugePages_Total:":
+			status.Total = n
+		case "HugePages_Free:":
+			status.Free = n
+		case "Hugepagesize:":
+			status.PageSizeKB = n
+		}
+	}
+
+	if size == "any" {
+		return status, nil
+	}
+	mb, err := strconv.Atoi(size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid hugepage size %q", size)
+	}
+	if mb*1024 == status.PageSizeKB {
+		return status, nil
+	}
+
+	// Not the default size: read that size's pool from sysfs
+	dir := fmt.Sprintf("/sys/kernel/mm/hugepages/hugepages-%dkB", mb*1024)
+	result, err = d.client.Run(fmt.Sprintf("cat %s/nr_hugepages %s/free_hugepages 2>/dev/null", dir, dir))
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", dir, err)
+	}
+	sized := &HugepageStatus{PageSizeKB: mb * 1024}
+	if lines := strings.Fields(result.Stdout); len(lines) == 2 {
+		sized.Total, _ = strconv.Atoi(lines[0])
+		sized.Free, _ = strconv.Atoi(lines[1])
+	}
+	return sized, nil
 }
 
 // FindVersaDeployments finds existing Versa VMs by the versa-deployer tag
 func (d *Discoverer) FindVersaDeployments() ([]VMInfo, error) {
+	return d.FindVersaDeploymentsInEnv("")
+}
+
+// FindManagedVM returns a VM carrying the versa-deployer tag, refusing any other
+func (d *Discoverer) FindManagedVM(vmid int) (VMInfo, error) {
+	vms, err := d.FindVersaDeployments()
+	if err != nil {
+		return VMInfo{}, fmt.Errorf("finding deployments: %w", err)
+	}
+	for _, vm := range vms {
+		if vm.VMID == vmid {
+			return vm, nil
+		}
+	}
+	return VMInfo{}, fmt.Errorf("VM %d not found or does not have versa-deployer tag", vmid)
+}
+
+// FindVersaDeploymentsInEnv finds deployer-managed VMs tagged with the given
+// environment. An empty env matches every environment.
+func (d *Discoverer) FindVersaDeploymentsInEnv(env string) ([]VMInfo, error) {
+	managed, _, err := d.FindVersaDeploymentsAndUnknown(env)
+	return managed, err
+}
+
+// FindVersaDeploymentsAndUnknown is FindVersaDeploymentsInEnv that also
+// returns the VMs whose tags could not be read. Those may be deployer-managed
+// but must not be treated as such until their tags are confirmed.
+func (d *Discoverer) FindVersaDeploymentsAndUnknown(env string) (managed, unknown []VMInfo, err error) {
 	vms, err := d.GetVMs()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+
+	envTag := ""
+	if env != "" {
+		envTag = config.EnvironmentTag(env)
 	}
 
-	var versaVMs []VMInfo
 	for _, vm := range vms {
+		if vm.TagsUnknown {
+			unknown = append(unknown, vm)
+			continue
+		}
+		isManaged, inEnv := false, envTag == ""
 		for _, tag := range vm.Tags {
-			if tag == config.TagVersaDeployer {
-				versaVMs = append(versaVMs, vm)
-				break
+			switch tag {
+			case config.TagVersaDeployer:
+				isManaged = true
+			case envTag:
+				inEnv = true
 			}
 		}
+		if isManaged && inEnv {
+			managed = append(managed, vm)
+		}
 	}
 
-	return versaVMs, nil
+	return managed, unknown, nil
 }
 
 // GetImageCapableStorage returns storage that can hold VM images
@@ -814,14 +1089,8 @@ func (d *Discoverer) GetImageCapableStorage() ([]StorageInfo, error) {
 
 	var imageStorage []StorageInfo
 	for _, s := range storage {
-		if !s.Active {
-			continue
-		}
-		for _, content := range s.Content {
-			if content == "images" || content == "rootdir" {
-				imageStorage = append(imageStorage, s)
-				break
-			}
+		if s.Active && (s.HasContent(ContentImages) || s.HasContent(ContentRootDir)) {
+			imageStorage = append(imageStorage, s)
 		}
 	}
 
@@ -838,14 +1107,8 @@ func (d *Discoverer) GetISOStorage() ([]StorageInfo, error) {
 
 	var isoStorage []StorageInfo
 	for _, s := range storage {
-		if !s.Active {
-			continue
-		}
-		for _, content := range s.Content {
-			if content == "iso" {
-				isoStorage = append(isoStorage, s)
-				break
-			}
+		if s.Active && s.HasContent(ContentISO) {
+			isoStorage = append(isoStorage, s)
 		}
 	}
 
@@ -857,6 +1120,83 @@ func (d *Discoverer) GetISOStorage() ([]StorageInfo, error) {
 	return isoStorage, nil
 }
 
+// GetSnippetsStorage returns active storage that can hold snippets (cloud-init
+// custom configs), sorted by most available space first
+func (d *Discoverer) GetSnippetsStorage() ([]StorageInfo, error) {
+	storage, err := d.GetStorage()
+	if err != nil {
+		return nil, err
+	}
+
+	var snippetsStorage []StorageInfo
+	for _, s := range storage {
+		if s.Active && s.HasContent(ContentSnippets) {
+			snippetsStorage = append(snippetsStorage, s)
+		}
+	}
+
+	sort.Slice(snippetsStorage, func(i, j int) bool {
+		return snippetsStorage[i].AvailableGB > snippetsStorage[j].AvailableGB
+	})
+
+	return snippetsStorage, nil
+}
+
+// SelectSnippetsStorage returns the named snippets storage, or the one with
+// most free space when name is empty. The error explains how to enable
+// snippets when no storage qualifies.
+func (d *Discoverer) SelectSnippetsStorage(name string) (StorageInfo, error) {
+	storage, err := d.GetStorage()
+	if err != nil {
+		return StorageInfo{}, fmt.Errorf("listing storage: %w", err)
+	}
+
+	if name != "" {
+		for _, s := range storage {
+			if s.Name != name {
+				continue
+			}
+			if !s.HasContent(ContentSnippets) {
+				return StorageInfo{}, fmt.Errorf("storage %s does not allow snippets; enable it with: %s", name, enableSnippetsCommand(s))
+			}
+			if !s.Active {
+				return StorageInfo{}, fmt.Errorf("snippets storage %s is not active", name)
+			}
+			return s, nil
+		}
+		return StorageInfo{}, fmt.Errorf("snippets storage %s not found", name)
+	}
+
+	snippets, err := d.GetSnippetsStorage()
+	if err != nil {
+		return StorageInfo{}, fmt.Errorf("listing storage: %w", err)
+	}
+	if len(snippets) > 0 {
+		return snippets[0], nil
+	}
+
+	// Suggest a file-based storage, which is the only kind that can hold snippets
+	for _, s := range storage {
+		if s.Active && (s.Type == "dir" || s.Type == "nfs" || s.Type == "cifs" || s.Type == "cephfs") {
+			return StorageInfo{}, fmt.Errorf("%w; enable it on a file-based storage, e.g.: %s", ErrNoSnippetsStorage, enableSnippetsCommand(s))
+		}
+	}
+	return StorageInfo{}, fmt.Errorf("%w; add a directory storage with snippets content (Datacenter > Storage)", ErrNoSnippetsStorage)
+}
+
+// enableSnippetsCommand returns the pvesm command adding snippets to a
+// storage's existing content types
+func enableSnippetsCommand(s StorageInfo) string {
+	content := make([]string, 0, len(s.Content)+1)
+	for _, c := range s.Content {
+		if c = strings.TrimSpace(c); c != "" {
+			content = append(content, c)
+		}
+	}
+	content = append(content, ContentSnippets)
+	return fmt.Sprintf("pvesm set %s --content %s", ssh.ShellEscape(s.Name), strings.Join(content, ","))
+}
+
 // parseJSON is a simple helper for JSON parsing
 func parseJSON(data string, v interface{}) error {
 	// Simple regex-based parsing for basic structures
@@ -0,0 +1,86 @@
+package proxmox
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/ssh"
+)
+
+// ErrNotProxmox is returned when the connected host lacks the Proxmox VE
+// tools, e.g. when pointed at a plain Linux box
+var ErrNotProxmox = errors.New("this host does not appear to be a Proxmox VE node")
+
+// CheckProxmoxHost verifies qm and pvesh exist on the client's host
+func CheckProxmoxHost(client *ssh.Client) error {
+	result, err := client.Run("command -v qm >/dev/null && command -v pvesh >/dev/null")
+	if err != nil {
+		return fmt.Errorf("checking for Proxmox tools: %w", err)
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("%w: qm or pvesh not found on %s", ErrNotProxmox, client.Host())
+	}
+	return nil
+}
+
+// hostCheck caches CheckProxmoxHost for one client, so only the first call
+// costs a round trip. SSH failures are not cached.
+type hostCheck struct {
+	mu   sync.Mutex
+	done bool
+	err  error // nil when the host passed
+}
+
+// check returns the cached answer, running CheckProxmoxHost the first time
+func (h *hostCheck) check(client *ssh.Client) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.done {
+		return h.err
+	}
+	err := CheckProxmoxHost(client)
+	if err == nil || errors.Is(err, ErrNotProxmox) {
+		h.done, h.err = true, err
+	}
+	return err
+}
+
+// notProxmoxError turns a shell "command not found" failure into
+// ErrNotProxmox, leaving other errors alone
+func notProxmoxError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if msg := err.Error(); strings.Contains(msg, "command not found") || strings.Contains(msg, "(exit 127)") {
+		return fmt.Errorf("%w (%s)", ErrNotProxmox, msg)
+	}
+	return err
+}
+
+// run executes a qm/pvesh command once the host is known to be Proxmox
+func (c *VMCreator) run(cmd string) (*ssh.ExecResult, error) {
+	if err := c.hostCheck.check(c.client); err != nil {
+		return nil, err
+	}
+	return c.client.Run(cmd)
+}
+
+// runQuiet is ssh.Client.RunQuiet for qm/pvesh commands, reporting a
+// non-Proxmox host clearly
+func (c *VMCreator) runQuiet(cmd string) error {
+	if err := c.hostCheck.check(c.client); err != nil {
+		return err
+	}
+	return notProxmoxError(c.client.RunQuiet(cmd))
+}
+
+// runJSON is ssh.Client.RunJSON for qm/pvesh commands, reporting a
+// non-Proxmox host clearly
+func (c *VMCreator) runJSON(cmd string, v interface{}) error {
+	if err := c.hostCheck.check(c.client); err != nil {
+		return err
+	}
+	return notProxmoxError(c.client.RunJSON(cmd, v))
+}
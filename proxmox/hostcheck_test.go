@@ -0,0 +1,49 @@
+package proxmox
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/ssh/sshtest"
+)
+
+func TestVMCreatorCachesHostCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		toolsExit int
+		wantErr   error
+	}{
+		{"proxmox host", 0, nil},
+		{"plain linux host", 1, ErrNotProxmox},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var checks atomic.Int32
+			client := sshtest.NewClient(t, func(cmd string) (string, int) {
+				if strings.HasPrefix(cmd, "command -v qm") {
+					checks.Add(1)
+					return "", tt.toolsExit
+				}
+				return "status: running\n", 0
+			})
+
+			c := NewVMCreator(client)
+			for i := 0; i < 3; i++ {
+				if _, err := c.GetVMStatus(101); !errors.Is(err, tt.wantErr) {
+					t.Fatalf("GetVMStatus() error = %v, want %v", err, tt.wantErr)
+				}
+			}
+			if n := checks.Load(); n != 1 {
+				t.Errorf("host checked %d times, want once", n)
+			}
+
+			// A new creator, e.g. after reconnecting, checks again
+			NewVMCreator(client).GetVMStatus(101)
+			if n := checks.Load(); n != 2 {
+				t.Errorf("host checked %d times after a new creator, want 2", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package proxmox
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/ssh"
+)
+
+// isoCopyTimeout bounds a single ISO copy between storages or nodes
+const isoCopyTimeout = 2 * time.Hour
+
+// nodeSSHOptions are the ssh/scp options the connected host uses to reach
+// other cluster nodes: PVE sets up root key trust between members and keeps
+// their host keys in the cluster-wide known_hosts, so this needs no
+// credentials of our own and still verifies each node
+const nodeSSHOptions = "-o BatchMode=yes -o StrictHostKeyChecking=yes -o UserKnownHostsFile=/etc/pve/priv/known_hosts"
+
+// nodeSSH is the command prefix for running a command on another node
+const nodeSSH = "ssh " + nodeSSHOptions
+
+// CopyISO copies an ISO between two storages on the connected host and
+// verifies the copy's MD5 against the original
+func (s *StorageManager) CopyISO(srcStorage, dstStorage, filename string) error {
+	if err := ValidateStorageName(dstStorage); err != nil {
+		return err
+	}
+	srcPath, err := s.GetISOPath(srcStorage, filename)
+	if err != nil {
+		return fmt.Errorf("resolving source ISO: %w", err)
+	}
+	dstDir, err := s.GetISOStoragePath(dstStorage)
+	if err != nil {
+		return fmt.Errorf("resolving storage path: %w", err)
+	}
+	dstPath := dstDir + "/" + filename
+	if dstPath == srcPath {
+		return nil
+	}
+
+	// Copy under a temporary name so a partial file is never picked up as the ISO
+	tmpPath := dstPath + ".partial"
+	cmd := fmt.Sprintf("mkdir -p %s && cp %s %s && mv %s %s",
+		ssh.ShellEscape(dstDir), ssh.ShellEscape(srcPath), ssh.ShellEscape(tmpPath),
+		ssh.ShellEscape(tmpPath), ssh.ShellEscape(dstPath))
+	result, err := s.client.RunWithTimeout(cmd, isoCopyTimeout)
+	if err != nil {
+		return fmt.Errorf("copying %s to %s: %w", filename, dstStorage, err)
+	}
+	if result.ExitCode != 0 {
+		s.client.Run("rm -f " + ssh.ShellEscape(tmpPath))
+		return fmt.Errorf("copying %s to %s: %s", filename, dstStorage, strings.TrimSpace(result.Stderr))
+	}
+
+	return s.verifyCopy(srcPath, dstPath, "", func() {
+		s.client.Run("rm -f " + ssh.ShellEscape(dstPath))
+	})
+}
+
+// CopyISOToNode copies an ISO from a storage on the connected host to the
+// same storage on another cluster node, for storages that aren't shared
+func (s *StorageManager) CopyISOToNode(storage, filename, node string) error {
+	if err := ValidateNodeName(node); err != nil {
+		return err
+	}
+	srcPath, err := s.GetISOPath(storage, filename)
+	if err != nil {
+		return fmt.Errorf("resolving source ISO: %w", err)
+	}
+
+	// The storage definition is cluster-wide, so the path is the same on the node
+	remote := nodeSSH + " root@" + ssh.ShellEscape(node) + " "
+	tmpPath := srcPath + ".partial"
+	cmd := fmt.Sprintf("%s%s && scp -q %s %s %s && %s%s",
+		remote, ssh.ShellEscape("mkdir -p "+ssh.ShellEscape(path.Dir(srcPath))), nodeSSHOptions,
+		ssh.ShellEscape(srcPath), ssh.ShellEscape("root@"+node+":"+tmpPath),
+		remote, ssh.ShellEscape("mv "+ssh.ShellEscape(tmpPath)+" "+ssh.ShellEscape(srcPath)))
+	result, err := s.client.RunWithTimeout(cmd, isoCopyTimeout)
+	if err != nil {
+		return fmt.Errorf("copying %s to node %s: %w", filename, node, err)
+	}
+	if result.ExitCode != 0 {
+		s.client.Run(remote + ssh.ShellEscape("rm -f "+ssh.ShellEscape(tmpPath)))
+		return fmt.Errorf("copying %s to node %s: %s", filename, node, strings.TrimSpace(result.Stderr))
+	}
+
+	return s.verifyCopy(srcPath, srcPath, remote, func() {
+		s.client.Run(remote + ssh.ShellEscape("rm -f "+ssh.ShellEscape(srcPath)))
+	})
+}
+
+// verifyCopy compares the MD5 of srcPath on the connected host with dstPath,
+// read through the remote command prefix when set, calling cleanup on mismatch
+func (s *StorageManager) verifyCopy(srcPath, dstPath, remote string, cleanup func()) error {
+	srcMD5, err := s.GetRemoteMD5(srcPath)
+	if err != nil {
+		return fmt.Errorf("checksumming %s: %w", srcPath, err)
+	}
+
+	cmd := "md5sum " + ssh.ShellEscape(dstPath)
+	if remote != "" {
+		cmd = remote + ssh.ShellEscape(cmd)
+	}
+	result, err := s.client.RunWithTimeout(cmd, isoCopyTimeout)
+	if err != nil {
+		return fmt.Errorf("checksumming copy: %w", err)
+	}
+	fields := strings.Fields(result.Stdout)
+	if result.ExitCode != 0 || len(fields) == 0 {
+		return fmt.Errorf("checksumming copy: %s", strings.TrimSpace(result.Stderr))
+	}
+	if dstMD5 := strings.ToLower(fields[0]); dstMD5 != srcMD5 {
+		cleanup()
+		return fmt.Errorf("copy of %s is corrupt: MD5 %s, expected %s", path.Base(srcPath), dstMD5, srcMD5)
+	}
+	return nil
+}
+
+// ISOExistsOnNode checks a storage's ISO list as seen by node, which for
+// node-local storages can differ from the connected host's
+func (s *StorageManager) ISOExistsOnNode(node, storage, filename string) (bool, error) {
+	if err := ValidateNodeName(node); err != nil {
+		return false, err
+	}
+	if err := validateVolume(storage, filename); err != nil {
+		return false, err
+	}
+
+	var content []struct {
+		VolID string `json:"volid"`
+	}
+	cmd := fmt.Sprintf("pvesh get /nodes/%s/storage/%s/content --content iso --output-format json",
+		ssh.ShellEscape(node), ssh.ShellEscape(storage))
+	if err := s.client.RunJSON(cmd, &content); err != nil {
+		return false, fmt.Errorf("listing ISOs on %s/%s: %w", node, storage, err)
+	}
+	want := storage + ":iso/" + filename
+	for _, c := range content {
+		if c.VolID == want {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,61 @@
+package proxmox
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/ssh"
+)
+
+// nicModels are the qm netN keys that name the NIC model (and carry its MAC)
+var nicModels = map[string]bool{
+	"virtio": true, "e1000": true, "e1000e": true, "vmxnet3": true, "rtl8139": true,
+}
+
+// GetNetworks reads a VM's netN interfaces as configured, keyed by N. It
+// goes through the cluster API so VMs on other nodes can be read.
+func (c *VMCreator) GetNetworks(node string, vmid int) (map[int]VMNetwork, error) {
+	var cfg map[string]interface{}
+	cmd := fmt.Sprintf("pvesh get /nodes/%s/qemu/%d/config --output-format json", ssh.ShellEscape(node), vmid)
+	if err := c.runJSON(cmd, &cfg); err != nil {
+		return nil, fmt.Errorf("reading VM %d config: %w", vmid, err)
+	}
+
+	networks := make(map[int]VMNetwork)
+	for key, v := range cfg {
+		idx, ok := strings.CutPrefix(key, "net")
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(idx)
+		value, isString := v.(string)
+		if err != nil || !isString {
+			continue
+		}
+		networks[n] = parseNetValue(value)
+	}
+	return networks, nil
+}
+
+// parseNetValue parses a qm netN value such as
+// "virtio=BC:24:11:00:00:01,bridge=vmbr0,tag=100,firewall=1"
+func parseNetValue(value string) VMNetwork {
+	var net VMNetwork
+	for _, part := range strings.Split(value, ",") {
+		key, val, _ := strings.Cut(part, "=")
+		switch {
+		case nicModels[key]:
+			net.Model = key
+		case key == "bridge":
+			net.Bridge = val
+		case key == "tag":
+			net.VLAN, _ = strconv.Atoi(val)
+		case key == "firewall":
+			net.Firewall = val == "1"
+		case key == "mtu":
+			net.MTU, _ = strconv.Atoi(val)
+		}
+	}
+	return net
+}
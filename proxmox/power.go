@@ -0,0 +1,77 @@
+package proxmox
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/ssh"
+)
+
+// VMPowerState is a VM's live run state
+type VMPowerState struct {
+	VMID   int
+	Node   string
+	Status string // running, stopped, paused, etc.
+	Uptime int64  // Seconds; 0 when stopped
+}
+
+// GetPowerStates returns the live state of every VM in the cluster, across
+// all nodes, in one call
+func (c *VMCreator) GetPowerStates() (map[int]VMPowerState, error) {
+	var resources []struct {
+		VMID   int    `json:"vmid"`
+		Type   string `json:"type"`
+		Node   string `json:"node"`
+		Status string `json:"status"`
+		Uptime int64  `json:"uptime"`
+	}
+	if err := c.runJSON("pvesh get /cluster/resources --type vm --output-format json", &resources); err != nil {
+		return nil, fmt.Errorf("reading VM states: %w", err)
+	}
+
+	states := make(map[int]VMPowerState, len(resources))
+	for _, r := range resources {
+		if r.Type != "qemu" {
+			continue
+		}
+		states[r.VMID] = VMPowerState{VMID: r.VMID, Node: r.Node, Status: r.Status, Uptime: r.Uptime}
+	}
+	return states, nil
+}
+
+// GetGuestIP returns the first non-loopback IPv4 address the guest agent
+// reports for a running VM, or ErrGuestAgentUnavailable
+func (c *VMCreator) GetGuestIP(node string, vmid int) (string, error) {
+	result, err := c.run(fmt.Sprintf("pvesh get /nodes/%s/qemu/%d/agent/network-get-interfaces --output-format json",
+		ssh.ShellEscape(node), vmid))
+	if err != nil {
+		return "", err
+	}
+	if result.ExitCode != 0 {
+		return "", fmt.Errorf("%w: %s", ErrGuestAgentUnavailable, strings.TrimSpace(result.Stderr))
+	}
+
+	var out struct {
+		Result []struct {
+			Name      string `json:"name"`
+			Addresses []struct {
+				Type    string `json:"ip-address-type"`
+				Address string `json:"ip-address"`
+			} `json:"ip-addresses"`
+		} `json:"result"`
+	}
+	if err := json.Unmarshal([]byte(result.Stdout), &out); err != nil {
+		return "", fmt.Errorf("parsing guest interfaces: %w", err)
+	}
+	for _, iface := range out.Result {
+		for _, addr := range iface.Addresses {
+			ip := net.ParseIP(addr.Address)
+			if addr.Type == "ipv4" && ip != nil && !ip.IsLoopback() && !ip.IsLinkLocalUnicast() {
+				return addr.Address, nil
+			}
+		}
+	}
+	return "", nil
+}
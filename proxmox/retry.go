@@ -0,0 +1,89 @@
+package proxmox
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Retry policy for pvesh calls during discovery
+const (
+	pveshMaxAttempts = 3
+	pveshBaseBackoff = 500 * time.Millisecond
+
+	// discoveryTimeout caps the parallel phase of DiscoverParallel, including retries
+	discoveryTimeout = 60 * time.Second
+)
+
+// transientPveshErrors are error fragments from a momentarily busy host
+// (cluster filesystem locks, pveproxy/pvedaemon timeouts) that are worth retrying
+var transientPveshErrors = []string{
+	"got timeout",
+	"timed out",
+	"unable to get lock",
+	"can't lock file",
+	"cfs-lock",
+	"trying to acquire lock",
+	"ipcc_send_rec",
+	"connection refused",
+	"temporarily unavailable",
+}
+
+// permanentPveshErrors never succeed on retry, even if they also match a
+// transient fragment
+var permanentPveshErrors = []string{
+	"permission check failed",
+	"permission denied",
+	"not a cluster",
+	"no such cluster",
+	"no such file",
+	"parsing json output",
+}
+
+// isTransientPveshError reports whether a pvesh failure looks like load or
+// lock contention rather than a real error
+func isTransientPveshError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range permanentPveshErrors {
+		if strings.Contains(msg, s) {
+			return false
+		}
+	}
+	for _, s := range transientPveshErrors {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// runJSONRetry runs a pvesh JSON command, retrying transient failures with
+// exponential backoff until the attempts run out or ctx is done
+func (d *Discoverer) runJSONRetry(ctx context.Context, cmd string, v interface{}) error {
+	backoff := pveshBaseBackoff
+	var err error
+	for attempt := 1; attempt <= pveshMaxAttempts; attempt++ {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			if err != nil {
+				return err
+			}
+			return ctxErr
+		}
+
+		err = d.client.RunJSON(cmd, v)
+		if err == nil || !isTransientPveshError(err) || attempt == pveshMaxAttempts {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+	return err
+}
@@ -1,16 +1,19 @@
 package proxmox
 
 import (
+	"context"
 	"crypto/md5"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/mihailvovk/versa-proxmox-deployer/ssh"
@@ -19,6 +22,14 @@ import (
 // StorageManager handles storage operations on Proxmox
 type StorageManager struct {
 	client *ssh.Client
+
+	// Parsed /etc/pve/storage.cfg, read on first use
+	storageCfgMu sync.Mutex
+	storageCfg   map[string]StorageConfig
+
+	// CA bundle on the Proxmox host that direct downloads verify TLS
+	// against; empty skips verification (set by SetCABundle)
+	caBundle string
 }
 
 // NewStorageManager creates a new storage manager
@@ -31,11 +42,59 @@ type ISOInfo struct {
 	Storage  string
 	Filename string
 	Size     int64
-	Path     string
+	Path     string // Path on the connected node, for hashing; empty if unknown
+	VolID    string // Proxmox volume ID, e.g. "cephfs:iso/versa-director.iso"
 }
 
-// ListISOs lists ISO files in a storage
+// pvesmVolume is one entry of pvesm list --output-format json
+type pvesmVolume struct {
+	VolID string `json:"volid"`
+	Size  int64  `json:"size"`
+}
+
+// ListISOs lists ISO files in a storage through pvesm, which works for
+// shared storages (NFS, CephFS) and filenames with spaces. It falls back
+// to listing the storage directory when pvesm can't report JSON.
 func (s *StorageManager) ListISOs(storage string) ([]ISOInfo, error) {
+	var volumes []pvesmVolume
+	err := s.client.RunJSON("pvesm list "+ssh.ShellEscape(storage)+" --content iso --output-format json", &volumes)
+	if err != nil {
+		return s.listISOFiles(storage)
+	}
+
+	// Storages without a local directory (or an unresolvable one) get no
+	// Path, so they're never hashed at a made-up location
+	basePath, err := s.GetISOStoragePath(storage)
+	if err != nil || !path.IsAbs(basePath) {
+		basePath = ""
+	}
+	var isos []ISOInfo
+	for _, v := range volumes {
+		_, volPath, ok := strings.Cut(v.VolID, ":")
+		if !ok {
+			continue
+		}
+		filename := strings.TrimPrefix(volPath, "iso/")
+		if !strings.HasSuffix(strings.ToLower(filename), ".iso") {
+			continue
+		}
+		iso := ISOInfo{
+			Storage:  storage,
+			Filename: filename,
+			Size:     v.Size,
+			VolID:    v.VolID,
+		}
+		if basePath != "" {
+			iso.Path = basePath + "/" + filename
+		}
+		isos = append(isos, iso)
+	}
+	return isos, nil
+}
+
+// listISOFiles lists the ISO files in a storage's directory on the
+// connected node, for hosts where pvesm list has no JSON output
+func (s *StorageManager) listISOFiles(storage string) ([]ISOInfo, error) {
 	// Get storage path
 	result, err := s.client.Run("pvesm path " + ssh.ShellEscape(storage+":iso/dummy.iso") + " 2>/dev/null | sed 's|/dummy.iso||'")
 	if err != nil {
@@ -70,6 +129,7 @@ func (s *StorageManager) ListISOs(storage string) ([]ISOInfo, error) {
 					Storage:  storage,
 					Filename: filepath.Base(filename),
 					Path:     filename,
+					VolID:    storage + ":iso/" + filepath.Base(filename),
 				})
 			}
 		}
@@ -79,7 +139,7 @@ func (s *StorageManager) ListISOs(storage string) ([]ISOInfo, error) {
 }
 
 // ISOExists checks if an ISO exists in storage by looking for the file on disk.
-// Falls back to pvesm list if the storage path can't be resolved.
+// Falls back to ListISOs when the file isn't at the storage's local path.
 func (s *StorageManager) ISOExists(storage, filename string) (bool, error) {
 	// First try: check file directly on the filesystem (most reliable)
 	storagePath, err := s.GetISOStoragePath(storage)
@@ -94,12 +154,18 @@ func (s *StorageManager) ISOExists(storage, filename string) (bool, error) {
 		}
 	}
 
-	// Fallback: pvesm list (may not work on all storage types)
-	result, err := s.client.Run("pvesm list " + ssh.ShellEscape(storage) + " --content iso 2>/dev/null | grep -qF " + ssh.ShellEscape(filename))
+	// Fallback: the storage's volume list, for storages whose ISOs aren't
+	// at a path on this node
+	isos, err := s.ListISOs(storage)
 	if err != nil {
 		return false, fmt.Errorf("checking ISO existence: %w", err)
 	}
-	return result.ExitCode == 0, nil
+	for _, iso := range isos {
+		if iso.Filename == filename {
+			return true, nil
+		}
+	}
+	return false, nil
 }
 
 // ISOExistsOnAny checks if an ISO exists on any ISO-capable storage.
@@ -125,30 +191,76 @@ func (s *StorageManager) ISOExistsOnAny(storages []StorageInfo, filename string)
 	return "", nil
 }
 
+// NormalizeISOName reduces an ISO filename to a comparable form: URL-decoded,
+// trimmed and lower-cased, so cosmetic differences between a source's name
+// and the file on storage still match
+func NormalizeISOName(name string) string {
+	if decoded, err := url.PathUnescape(name); err == nil {
+		name = decoded
+	}
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// FindISOTolerant looks for an ISO by exact filename first, then by
+// NormalizeISOName across the ISO files on each storage. It returns the
+// storage and the filename as stored, both empty when nothing matches.
+func (s *StorageManager) FindISOTolerant(storages []StorageInfo, filename string) (storage, found string, err error) {
+	if stor, err := s.ISOExistsOnAny(storages, filename); err == nil && stor != "" {
+		return stor, filename, nil
+	}
+
+	want := NormalizeISOName(filename)
+	for _, stor := range storages {
+		isos, err := s.ListISOs(stor.Name)
+		if err != nil {
+			continue
+		}
+		for _, iso := range isos {
+			if NormalizeISOName(iso.Filename) == want {
+				return stor.Name, iso.Filename, nil
+			}
+		}
+	}
+	return "", "", nil
+}
+
 // FindISOByMD5 searches all ISO-capable storages for an ISO matching the given
 // MD5 checksum. Returns the storage name and filename if found.
 // This is used to detect when the same image exists under a different filename.
 func (s *StorageManager) FindISOByMD5(storages []StorageInfo, expectedMD5 string) (storage, filename string, err error) {
-	if expectedMD5 == "" {
-		return "", "", fmt.Errorf("no MD5 provided")
+	return s.findISOByChecksum(storages, expectedMD5, "md5sum", "MD5")
+}
+
+// FindISOBySHA256 is FindISOByMD5 for a SHA256 checksum
+func (s *StorageManager) FindISOBySHA256(storages []StorageInfo, expectedSHA256 string) (storage, filename string, err error) {
+	return s.findISOByChecksum(storages, expectedSHA256, "sha256sum", "SHA256")
+}
+
+// findISOByChecksum hashes every ISO on the storages with tool (md5sum,
+// sha256sum) until one matches expected
+func (s *StorageManager) findISOByChecksum(storages []StorageInfo, expected, tool, label string) (storage, filename string, err error) {
+	if expected == "" {
+		return "", "", fmt.Errorf("no %s provided", label)
 	}
-	expectedMD5 = strings.ToLower(strings.TrimSpace(expectedMD5))
+	expected = strings.ToLower(strings.TrimSpace(expected))
 
 	for _, stor := range storages {
 		isos, err := s.ListISOs(stor.Name)
 		if err != nil {
 			continue
 		}
-		if len(isos) == 0 {
-			continue
-		}
 
-		// Build a single md5sum command for all ISOs on this storage to avoid N round-trips
+		// Build a single command for all ISOs on this storage to avoid N round-trips
 		var paths []string
 		for _, iso := range isos {
-			paths = append(paths, ssh.ShellEscape(iso.Path))
+			if iso.Path != "" {
+				paths = append(paths, ssh.ShellEscape(iso.Path))
+			}
+		}
+		if len(paths) == 0 {
+			continue
 		}
-		cmd := "md5sum " + strings.Join(paths, " ") + " 2>/dev/null"
+		cmd := tool + " " + strings.Join(paths, " ") + " 2>/dev/null"
 		result, err := s.client.RunWithTimeout(cmd, 10*time.Minute)
 		if err != nil || result.ExitCode != 0 {
 			continue
@@ -156,20 +268,20 @@ func (s *StorageManager) FindISOByMD5(storages []StorageInfo, expectedMD5 string
 
 		for _, line := range strings.Split(result.Stdout, "\n") {
 			parts := strings.Fields(line)
-			if len(parts) >= 2 {
-				md5 := strings.ToLower(parts[0])
-				if md5 == expectedMD5 {
-					return stor.Name, filepath.Base(parts[1]), nil
-				}
+			if len(parts) >= 2 && strings.ToLower(parts[0]) == expected {
+				return stor.Name, filepath.Base(parts[1]), nil
 			}
 		}
 	}
 
-	return "", "", fmt.Errorf("no ISO with MD5 %s found", expectedMD5)
+	return "", "", fmt.Errorf("no ISO with %s %s found", label, expected)
 }
 
 // GetISOPath returns the full path to an ISO on Proxmox
 func (s *StorageManager) GetISOPath(storage, filename string) (string, error) {
+	if err := validateVolume(storage, filename); err != nil {
+		return "", err
+	}
 	result, err := s.client.Run("pvesm path " + ssh.ShellEscape(storage+":iso/"+filename))
 	if err != nil {
 		return "", err
@@ -198,6 +310,9 @@ func (s *StorageManager) GetISOStoragePath(storage string) (string, error) {
 // UploadISO uploads an ISO file to Proxmox storage
 func (s *StorageManager) UploadISO(localPath, storage string, progress func(written, total int64)) error {
 	filename := filepath.Base(localPath)
+	if err := validateVolume(storage, filename); err != nil {
+		return err
+	}
 
 	// Get storage path
 	storagePath, err := s.GetISOStoragePath(storage)
@@ -215,28 +330,77 @@ func (s *StorageManager) UploadISO(localPath, storage string, progress func(writ
 	return nil
 }
 
+// UploadSnippet writes a file to a storage's snippets directory and returns
+// its volume ID, e.g. local:snippets/vm-101-user.yaml
+func (s *StorageManager) UploadSnippet(storage, filename string, data []byte) (string, error) {
+	if err := validateVolume(storage, filename); err != nil {
+		return "", err
+	}
+	volume := storage + ":snippets/" + filename
+	result, err := s.client.Run("pvesm path " + ssh.ShellEscape(volume))
+	if err != nil {
+		return "", err
+	}
+	remotePath := strings.TrimSpace(result.Stdout)
+	if result.ExitCode != 0 || remotePath == "" {
+		return "", fmt.Errorf("could not determine path for %s: %s", volume, strings.TrimSpace(result.Stderr))
+	}
+
+	if err := s.client.RunQuiet("mkdir -p " + ssh.ShellEscape(path.Dir(remotePath))); err != nil {
+		return "", fmt.Errorf("creating snippets directory: %w", err)
+	}
+	if err := s.client.UploadBytes(data, remotePath); err != nil {
+		return "", fmt.Errorf("uploading %s: %w", volume, err)
+	}
+	return volume, nil
+}
+
+// remoteCABundlePath is where SetCABundle stores the CA bundle on Proxmox
+const remoteCABundlePath = "/root/.versa-deployer/ca-bundle.pem"
+
+// SetCABundle uploads PEM CA certificates to the Proxmox host, so direct
+// downloads verify TLS against them instead of skipping verification
+func (s *StorageManager) SetCABundle(pem []byte) error {
+	if err := s.client.RunQuiet("mkdir -p " + ssh.ShellEscape(path.Dir(remoteCABundlePath))); err != nil {
+		return fmt.Errorf("creating CA bundle directory: %w", err)
+	}
+	if err := s.client.UploadBytes(pem, remoteCABundlePath); err != nil {
+		return fmt.Errorf("uploading CA bundle: %w", err)
+	}
+	s.caBundle = remoteCABundlePath
+	return nil
+}
+
 // VerifyISOMD5 verifies the MD5 checksum of an ISO on Proxmox
 func (s *StorageManager) VerifyISOMD5(storage, filename, expectedMD5 string) (bool, error) {
+	return s.verifyISOChecksum(storage, filename, expectedMD5, "md5sum")
+}
+
+// VerifyISOSHA256 verifies the SHA256 checksum of an ISO on Proxmox
+func (s *StorageManager) VerifyISOSHA256(storage, filename, expectedSHA256 string) (bool, error) {
+	return s.verifyISOChecksum(storage, filename, expectedSHA256, "sha256sum")
+}
+
+// verifyISOChecksum hashes an ISO on Proxmox with tool (md5sum, sha256sum)
+// and compares the digest with expected
+func (s *StorageManager) verifyISOChecksum(storage, filename, expected, tool string) (bool, error) {
 	path, err := s.GetISOPath(storage, filename)
 	if err != nil {
 		return false, err
 	}
 
-	result, err := s.client.Run("md5sum " + ssh.ShellEscape(path))
+	result, err := s.client.RunWithTimeout(tool+" "+ssh.ShellEscape(path), 30*time.Minute)
 	if err != nil {
 		return false, err
 	}
 
-	// Parse MD5 from output "checksum  filename"
+	// Parse the digest from output "checksum  filename"
 	parts := strings.Fields(result.Stdout)
-	if len(parts) < 1 {
-		return false, fmt.Errorf("could not parse MD5 output")
+	if result.ExitCode != 0 || len(parts) < 1 {
+		return false, fmt.Errorf("could not parse %s output: %s", tool, strings.TrimSpace(result.Stderr))
 	}
 
-	actualMD5 := strings.ToLower(parts[0])
-	expectedMD5 = strings.ToLower(strings.TrimSpace(expectedMD5))
-
-	return actualMD5 == expectedMD5, nil
+	return strings.ToLower(parts[0]) == strings.ToLower(strings.TrimSpace(expected)), nil
 }
 
 // GetRemoteMD5 calculates MD5 of a file on Proxmox
@@ -307,21 +471,36 @@ func CreateMD5File(isoPath string) (string, error) {
 // DownloadISOFromURL downloads an ISO directly on Proxmox using the native
 // pvesh download-url API (PVE 7.0+). pvesh blocks until the download finishes,
 // so we run it in the background via nohup and poll the Proxmox task list.
-// The optional log callback receives progress messages.
-func (s *StorageManager) DownloadISOFromURL(node, storage, filename, downloadURL string, log func(string)) error {
+// The optional log callback receives progress messages and onTask the task's
+// UPID once it is known. Cancelling ctx stops the task on Proxmox.
+func (s *StorageManager) DownloadISOFromURL(ctx context.Context, node, storage, filename, downloadURL string, log func(string), onTask func(upid string)) error {
 	if log == nil {
 		log = func(string) {}
 	}
+	if err := ValidateNodeName(node); err != nil {
+		return err
+	}
+	if err := validateVolume(storage, filename); err != nil {
+		return err
+	}
 
 	// Start pvesh in the background (it blocks until download completes,
 	// and we don't want our SSH timeout to kill it via broken pipe).
-	// --verify-certificates 0 skips SSL verification for enterprise SSL decryption.
+	// --verify-certificates 0 skips SSL verification for enterprise SSL
+	// decryption. pvesh can't take a CA file, so with a CA bundle it verifies
+	// against the host's trust store; if that lacks the inspection CA the
+	// download fails and the wget/curl fallback verifies with the bundle.
+	verify := 0
+	if s.caBundle != "" {
+		verify = 1
+	}
 	cmd := fmt.Sprintf(
-		"nohup pvesh create /nodes/%s/storage/%s/download-url --content iso --filename %s --url %s --verify-certificates 0 >/dev/null 2>&1 & echo started",
+		"nohup pvesh create /nodes/%s/storage/%s/download-url --content iso --filename %s --url %s --verify-certificates %d >/dev/null 2>&1 & echo started",
 		ssh.ShellEscape(node),
 		ssh.ShellEscape(storage),
 		ssh.ShellEscape(filename),
 		ssh.ShellEscape(downloadURL),
+		verify,
 	)
 	result, err := s.client.RunWithTimeout(cmd, 30*time.Second)
 	if err != nil {
@@ -342,77 +521,39 @@ func (s *StorageManager) DownloadISOFromURL(node, storage, filename, downloadURL
 		return fmt.Errorf("download task did not start: %w", err)
 	}
 	log(fmt.Sprintf("Download task started (UPID: %s)", upid))
+	if onTask != nil {
+		onTask(upid)
+	}
 
 	// Poll task status until completion, reading task log for progress
-	deadline := time.Now().Add(2 * time.Hour)
-	lastLogLine := 0
-	for {
-		if time.Now().After(deadline) {
-			return fmt.Errorf("download timed out after 2 hours (UPID: %s)", upid)
-		}
-
-		time.Sleep(10 * time.Second)
-
-		// Check task status
-		statusCmd := fmt.Sprintf("pvesh get /nodes/%s/tasks/%s/status --output-format json",
-			ssh.ShellEscape(node),
-			ssh.ShellEscape(upid),
-		)
-		statusResult, err := s.client.Run(statusCmd)
-		if err != nil {
-			continue
-		}
-		if statusResult.ExitCode != 0 {
-			continue
-		}
+	wait := taskWait{timeout: 2 * time.Hour, pollInterval: 10 * time.Second, filter: isDownloadLogLine, ctx: ctx}
+	if err := waitForTask(s.client, node, upid, wait, log); err != nil {
+		return fmt.Errorf("download %w", err)
+	}
+	return nil
+}
 
-		var status struct {
-			Status     string `json:"status"`
-			ExitStatus string `json:"exitstatus"`
-		}
-		if err := json.Unmarshal([]byte(statusResult.Stdout), &status); err != nil {
-			continue
-		}
+// isDownloadLogLine keeps wget progress lines (they contain %) and key status
+// lines from a download task's log
+func isDownloadLogLine(line string) bool {
+	return strings.Contains(line, "%") || strings.Contains(line, "downloading") ||
+		strings.Contains(line, "Saving to") || strings.Contains(line, "Length:") ||
+		strings.Contains(line, "ERROR") || strings.Contains(line, "error")
+}
 
-		// Read task log for progress info (wget output shows % and speed)
-		logCmd := fmt.Sprintf("pvesh get /nodes/%s/tasks/%s/log --output-format json --start %d --limit 50 2>/dev/null",
-			ssh.ShellEscape(node),
-			ssh.ShellEscape(upid),
-			lastLogLine,
-		)
-		logResult, err := s.client.Run(logCmd)
-		if err == nil && logResult.ExitCode == 0 {
-			var logEntries []struct {
-				N int    `json:"n"`
-				T string `json:"t"`
-			}
-			if json.Unmarshal([]byte(logResult.Stdout), &logEntries) == nil {
-				for _, entry := range logEntries {
-					if entry.N > lastLogLine {
-						lastLogLine = entry.N
-					}
-					line := strings.TrimSpace(entry.T)
-					if line == "" {
-						continue
-					}
-					// Log wget progress lines (contain %) and key status lines
-					if strings.Contains(line, "%") || strings.Contains(line, "downloading") ||
-						strings.Contains(line, "Saving to") || strings.Contains(line, "Length:") ||
-						strings.Contains(line, "ERROR") || strings.Contains(line, "error") {
-						log(fmt.Sprintf("Proxmox: %s", line))
-					}
-				}
-			}
-		}
+// WaitForTask polls a Proxmox task until it stops, streaming its log to the
+// optional log callback. It returns an error if the task fails or runs past
+// the default task timeout.
+func (s *StorageManager) WaitForTask(node, upid string, log func(string)) error {
+	return waitForTask(s.client, node, upid, taskWait{}, log)
+}
 
-		if status.Status == "stopped" {
-			if status.ExitStatus == "OK" {
-				return nil
-			}
-			return fmt.Errorf("download task failed: %s", status.ExitStatus)
-		}
-		// status is "running" — keep polling
+// StopTask stops a running Proxmox task, e.g. a stalled download
+func (s *StorageManager) StopTask(node, upid string) error {
+	if err := ValidateNodeName(node); err != nil {
+		return err
 	}
+	return stopTask(s.client, node, upid)
 }
 
 // findDownloadTask searches active and recent Proxmox tasks for a download
@@ -465,6 +606,10 @@ func (s *StorageManager) findDownloadTask(node, filename string) (string, error)
 // DownloadISODirect downloads an ISO directly on Proxmox using wget or curl
 // as a fallback when the pvesh download-url API is unavailable or fails.
 func (s *StorageManager) DownloadISODirect(storage, filename, downloadURL string, expectedSize int64) error {
+	if err := validateVolume(storage, filename); err != nil {
+		return err
+	}
+
 	// Resolve the storage path
 	storagePath, err := s.GetISOStoragePath(storage)
 	if err != nil {
@@ -481,13 +626,22 @@ func (s *StorageManager) DownloadISODirect(storage, filename, downloadURL string
 		return err
 	}
 
-	// Build download command (skip SSL verification for enterprise SSL decryption)
+	// Build download command. Without a CA bundle, skip SSL verification for
+	// enterprise SSL decryption; with one, verify against it.
 	var cmd string
 	switch tool {
 	case "wget":
-		cmd = fmt.Sprintf("wget -q --no-check-certificate -O %s %s", ssh.ShellEscape(destPath), ssh.ShellEscape(downloadURL))
+		tlsOpt := "--no-check-certificate"
+		if s.caBundle != "" {
+			tlsOpt = "--ca-certificate=" + ssh.ShellEscape(s.caBundle)
+		}
+		cmd = fmt.Sprintf("wget -q %s -O %s %s", tlsOpt, ssh.ShellEscape(destPath), ssh.ShellEscape(downloadURL))
 	case "curl":
-		cmd = fmt.Sprintf("curl -ksfL -o %s %s", ssh.ShellEscape(destPath), ssh.ShellEscape(downloadURL))
+		tlsOpt := "-k"
+		if s.caBundle != "" {
+			tlsOpt = "--cacert " + ssh.ShellEscape(s.caBundle)
+		}
+		cmd = fmt.Sprintf("curl -sfL %s -o %s %s", tlsOpt, ssh.ShellEscape(destPath), ssh.ShellEscape(downloadURL))
 	}
 
 	// Run with a generous timeout (2 hours for large ISOs)
@@ -519,6 +673,48 @@ func (s *StorageManager) DownloadISODirect(storage, filename, downloadURL string
 		return fmt.Errorf("downloaded file too small (%d bytes), likely failed", fileSize)
 	}
 
+	// An error page saved under the ISO's name passes the size floor, so
+	// compare against the size the source advertised
+	if expectedSize > 0 && !sizeWithinTolerance(fileSize, expectedSize) {
+		s.client.Run("rm -f " + ssh.ShellEscape(destPath))
+		return fmt.Errorf("downloaded file is %d bytes, expected %d", fileSize, expectedSize)
+	}
+
+	if err := s.checkISOSignature(destPath); err != nil {
+		s.client.Run("rm -f " + ssh.ShellEscape(destPath))
+		return err
+	}
+
+	return nil
+}
+
+// isoSizeTolerance is how far, as a fraction of the expected size, a direct
+// download may differ before it is rejected. Sources often round sizes.
+const isoSizeTolerance = 0.02
+
+// sizeWithinTolerance reports whether actual is within isoSizeTolerance of expected
+func sizeWithinTolerance(actual, expected int64) bool {
+	diff := actual - expected
+	if diff < 0 {
+		diff = -diff
+	}
+	return float64(diff) <= float64(expected)*isoSizeTolerance
+}
+
+// iso9660MagicOffset is where the primary volume descriptor's "CD001"
+// identifier sits: sector 16 (2048-byte sectors), after the type byte
+const iso9660MagicOffset = 16*2048 + 1
+
+// checkISOSignature verifies a remote file carries the ISO9660 identifier
+func (s *StorageManager) checkISOSignature(path string) error {
+	cmd := fmt.Sprintf("dd if=%s bs=1 skip=%d count=5 2>/dev/null", ssh.ShellEscape(path), iso9660MagicOffset)
+	result, err := s.client.Run(cmd)
+	if err != nil {
+		return fmt.Errorf("reading ISO signature: %w", err)
+	}
+	if result.Stdout != "CD001" {
+		return fmt.Errorf("downloaded file is not an ISO9660 image (no CD001 signature at offset %d)", iso9660MagicOffset)
+	}
 	return nil
 }
 
@@ -577,43 +773,36 @@ func (s *StorageManager) GetStorageInfo(storage string) (*StorageInfo, error) {
 		used, _ := strconv.ParseInt(fields[4], 10, 64)
 		avail, _ := strconv.ParseInt(fields[5], 10, 64)
 
-		// Get content from storage.cfg
-		content := s.getStorageContent(name)
-
+		cfg := s.storageConfig()
 		return &StorageInfo{
 			Name:        name,
 			Type:        storageType,
 			TotalGB:     int(total / (1024 * 1024)), // KB to GB
 			UsedGB:      int(used / (1024 * 1024)),
 			AvailableGB: int(avail / (1024 * 1024)),
-			Content:     content,
+			Content:     contentOrDefault(cfg, name),
 			Active:      status == "active",
-			Shared:      false,
+			Shared:      cfg[name].Shared,
+			Nodes:       cfg[name].Nodes,
 		}, nil
 	}
 
 	return nil, fmt.Errorf("storage %s not found", storage)
 }
 
-// getStorageContent gets content types for a storage from config
-func (s *StorageManager) getStorageContent(storageName string) []string {
-	if !validStorageName.MatchString(storageName) {
-		return []string{"images"}
-	}
-	result, err := s.client.Run(fmt.Sprintf("grep -A 10 '^%s:' /etc/pve/storage.cfg 2>/dev/null | grep 'content' | head -1", storageName))
-	if err != nil || result.ExitCode != 0 {
-		return []string{"images", "rootdir"}
-	}
-
-	line := strings.TrimSpace(result.Stdout)
-	if strings.HasPrefix(line, "content") {
-		parts := strings.Fields(line)
-		if len(parts) >= 2 {
-			return strings.Split(parts[1], ",")
+// storageConfig returns the parsed storage.cfg, reading it once per manager.
+// A failed read is retried on the next call; until then it returns nil.
+func (s *StorageManager) storageConfig() map[string]StorageConfig {
+	s.storageCfgMu.Lock()
+	defer s.storageCfgMu.Unlock()
+	if s.storageCfg == nil {
+		cfg, err := readStorageConfig(s.client)
+		if err != nil {
+			return nil
 		}
+		s.storageCfg = cfg
 	}
-
-	return []string{"images"}
+	return s.storageCfg
 }
 
 // EnsureStorageHasSpace checks if storage has enough space
@@ -0,0 +1,141 @@
+package proxmox
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/ssh"
+)
+
+// storageConfigPath is the cluster-wide storage definition file
+const storageConfigPath = "/etc/pve/storage.cfg"
+
+// StorageConfig is one storage definition from /etc/pve/storage.cfg
+type StorageConfig struct {
+	Name     string
+	Type     string   // dir, lvmthin, rbd, nfs, etc.
+	Content  []string // Empty when the entry has no content line
+	Path     string   // Mount point or directory, empty for block storages
+	Shared   bool
+	Disabled bool
+	Nodes    []string // Nodes the storage is restricted to, empty for all
+}
+
+// sharedStorageTypes are network storages that every node sees without a
+// "shared 1" line
+var sharedStorageTypes = map[string]bool{
+	"nfs": true, "cifs": true, "glusterfs": true, "cephfs": true,
+	"rbd": true, "iscsi": true, "iscsidirect": true, "pbs": true,
+}
+
+// ParseStorageConfig parses the contents of /etc/pve/storage.cfg into
+// definitions keyed by storage name. Each entry starts with a
+// "<type>: <name>" line followed by indented "<key> [value]" properties.
+func ParseStorageConfig(data string) map[string]StorageConfig {
+	storages := make(map[string]StorageConfig)
+	var cur *StorageConfig
+	flush := func() {
+		if cur != nil {
+			storages[cur.Name] = *cur
+			cur = nil
+		}
+	}
+
+	for _, line := range strings.Split(data, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		// Definitions start at column 0; properties are indented
+		if line[0] != ' ' && line[0] != '\t' {
+			flush()
+			typ, name, ok := strings.Cut(trimmed, ":")
+			if !ok {
+				continue
+			}
+			typ, name = strings.TrimSpace(typ), strings.TrimSpace(name)
+			if typ == "" || name == "" {
+				continue
+			}
+			cur = &StorageConfig{Name: name, Type: typ, Shared: sharedStorageTypes[typ]}
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+
+		key, value, _ := strings.Cut(trimmed, " ")
+		value = strings.TrimSpace(value)
+		switch key {
+		case "content":
+			cur.Content = splitList(value)
+		case "path":
+			cur.Path = value
+		case "shared":
+			cur.Shared = value != "0"
+		case "disable":
+			cur.Disabled = value != "0"
+		case "nodes":
+			cur.Nodes = splitList(value)
+		}
+	}
+	flush()
+	return storages
+}
+
+// splitList splits a comma-separated storage.cfg value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// AvailableOn reports whether the storage may be used on node
+func (s StorageConfig) AvailableOn(node string) bool {
+	return nodeListed(s.Nodes, node)
+}
+
+// nodeListed reports whether node is in a storage's node restriction list;
+// an empty list means every node
+func nodeListed(nodes []string, node string) bool {
+	if len(nodes) == 0 {
+		return true
+	}
+	for _, n := range nodes {
+		if n == node {
+			return true
+		}
+	}
+	return false
+}
+
+// readStorageConfig reads and parses /etc/pve/storage.cfg in one SSH call
+func readStorageConfig(client *ssh.Client) (map[string]StorageConfig, error) {
+	result, err := client.Run("cat " + storageConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", storageConfigPath, err)
+	}
+	if result.ExitCode != 0 {
+		return nil, fmt.Errorf("reading %s: %s", storageConfigPath, strings.TrimSpace(result.Stderr))
+	}
+	return ParseStorageConfig(result.Stdout), nil
+}
+
+// contentOrDefault returns a storage's content types as the per-storage grep
+// used to: images and rootdir when the storage isn't defined, images alone
+// when it has no content line
+func contentOrDefault(cfg map[string]StorageConfig, name string) []string {
+	sc, ok := cfg[name]
+	if !ok {
+		return []string{ContentImages, ContentRootDir}
+	}
+	if len(sc.Content) == 0 {
+		return []string{ContentImages}
+	}
+	return sc.Content
+}
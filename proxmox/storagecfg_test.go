@@ -0,0 +1,85 @@
+package proxmox
+
+import (
+	"reflect"
+	"testing"
+)
+
+const sampleStorageConfig = `# storage.cfg of a three-node cluster
+dir: local
+	path /var/lib/vz
+	content iso,vztmpl,backup
+	shared 0
+
+lvmthin: local-lvm
+	thinpool data
+	vgname pve
+	content rootdir,images
+
+nfs: isos
+	export /export/isos
+	path /mnt/pve/isos
+	server 10.0.0.5
+	content iso
+	options vers=4.2
+
+rbd: ceph-vm
+	content images
+	krbd 0
+	pool vm
+	nodes pve1, pve2,
+
+dir: old-backups
+	path /srv/backups
+	content backup
+	disable
+
+dir: scratch
+	path /srv/scratch
+	shared 1
+	nodes pve3
+`
+
+func TestParseStorageConfig(t *testing.T) {
+	got := ParseStorageConfig(sampleStorageConfig)
+	want := map[string]StorageConfig{
+		"local":       {Name: "local", Type: "dir", Content: []string{"iso", "vztmpl", "backup"}, Path: "/var/lib/vz"},
+		"local-lvm":   {Name: "local-lvm", Type: "lvmthin", Content: []string{"rootdir", "images"}},
+		"isos":        {Name: "isos", Type: "nfs", Content: []string{"iso"}, Path: "/mnt/pve/isos", Shared: true},
+		"ceph-vm":     {Name: "ceph-vm", Type: "rbd", Content: []string{"images"}, Shared: true, Nodes: []string{"pve1", "pve2"}},
+		"old-backups": {Name: "old-backups", Type: "dir", Content: []string{"backup"}, Path: "/srv/backups", Disabled: true},
+		"scratch":     {Name: "scratch", Type: "dir", Path: "/srv/scratch", Shared: true, Nodes: []string{"pve3"}},
+	}
+	if len(got) != len(want) {
+		t.Errorf("parsed %d storages, want %d: %v", len(got), len(want), got)
+	}
+	for name, w := range want {
+		if g, ok := got[name]; !ok || !reflect.DeepEqual(g, w) {
+			t.Errorf("storage %s = %+v, want %+v", name, g, w)
+		}
+	}
+
+	if !got["ceph-vm"].AvailableOn("pve2") || got["ceph-vm"].AvailableOn("pve3") {
+		t.Error("ceph-vm node restriction not applied")
+	}
+	if !got["local"].AvailableOn("pve3") {
+		t.Error("unrestricted storage not available on every node")
+	}
+}
+
+func TestContentOrDefault(t *testing.T) {
+	cfg := ParseStorageConfig(sampleStorageConfig)
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"local", []string{"iso", "vztmpl", "backup"}},
+		{"scratch", []string{ContentImages}},
+		{"undefined", []string{ContentImages, ContentRootDir}},
+	}
+	for _, tt := range tests {
+		if got := contentOrDefault(cfg, tt.name); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("contentOrDefault(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
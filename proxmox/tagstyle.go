@@ -0,0 +1,84 @@
+package proxmox
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/ssh"
+)
+
+// datacenterConfigPath holds cluster-wide options, including tag-style
+const datacenterConfigPath = "/etc/pve/datacenter.cfg"
+
+// EnsureTagColors adds the given tag colors (hex RGB backgrounds) to the
+// datacenter tag-style color map. Tags that already have a color and the
+// other tag-style settings are left alone. It returns the tags it colored.
+func (d *Discoverer) EnsureTagColors(colors map[string]string) ([]string, error) {
+	result, err := d.client.Run("cat " + datacenterConfigPath + " 2>/dev/null")
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", datacenterConfigPath, err)
+	}
+	current := ""
+	for _, line := range strings.Split(result.Stdout, "\n") {
+		if v, ok := strings.CutPrefix(strings.TrimSpace(line), "tag-style:"); ok {
+			current = strings.TrimSpace(v)
+		}
+	}
+
+	style, added := mergeTagColors(current, colors)
+	if len(added) == 0 {
+		return nil, nil
+	}
+	if err := d.client.RunQuiet("pvesh set /cluster/options --tag-style " + ssh.ShellEscape(style)); err != nil {
+		return nil, fmt.Errorf("setting tag colors: %w", err)
+	}
+	return added, nil
+}
+
+// mergeTagColors adds the missing tags to a tag-style property string such
+// as "color-map=a:ff0000;b:00ff00:ffffff,shape=circle", in sorted tag order
+func mergeTagColors(style string, colors map[string]string) (string, []string) {
+	var options []string
+	colorMap := ""
+	for _, opt := range strings.Split(style, ",") {
+		opt = strings.TrimSpace(opt)
+		if opt == "" {
+			continue
+		}
+		if v, ok := strings.CutPrefix(opt, "color-map="); ok {
+			colorMap = v
+			continue
+		}
+		options = append(options, opt)
+	}
+
+	var entries []string
+	have := make(map[string]bool)
+	for _, entry := range strings.Split(colorMap, ";") {
+		if entry == "" {
+			continue
+		}
+		entries = append(entries, entry)
+		tag, _, _ := strings.Cut(entry, ":")
+		have[tag] = true
+	}
+
+	tags := make([]string, 0, len(colors))
+	for tag := range colors {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	var added []string
+	for _, tag := range tags {
+		if !have[tag] {
+			entries = append(entries, tag+":"+colors[tag])
+			added = append(added, tag)
+		}
+	}
+	if len(added) == 0 {
+		return style, nil
+	}
+	return strings.Join(append([]string{"color-map=" + strings.Join(entries, ";")}, options...), ","), added
+}
@@ -0,0 +1,186 @@
+package proxmox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/ssh"
+)
+
+// Task polling defaults, used when a taskWait field is zero
+const (
+	defaultTaskTimeout      = 2 * time.Hour
+	defaultTaskPollInterval = 5 * time.Second
+)
+
+// taskLogPageSize is how many task log lines are fetched per poll
+const taskLogPageSize = 50
+
+// taskWait tunes waitForTask
+type taskWait struct {
+	timeout      time.Duration
+	pollInterval time.Duration
+	filter       func(line string) bool // Lines to pass to log; nil passes all
+	ctx          context.Context        // Stops the task when cancelled; nil waits until it ends
+}
+
+// waitForTask polls a task's status until it stops, sending new task log
+// lines to log as they appear. Reads survive SSH reconnects: the recent log
+// window is re-read and lines already seen are dropped.
+func waitForTask(client *ssh.Client, node, upid string, wait taskWait, log func(string)) error {
+	if log == nil {
+		log = func(string) {}
+	}
+	if wait.timeout == 0 {
+		wait.timeout = defaultTaskTimeout
+	}
+	if wait.pollInterval == 0 {
+		wait.pollInterval = defaultTaskPollInterval
+	}
+
+	deadline := time.Now().Add(wait.timeout)
+	cursor := &taskLogCursor{}
+	connGen := client.Generation()
+	for {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %s (UPID: %s)", wait.timeout, upid)
+		}
+
+		if wait.ctx != nil {
+			select {
+			case <-wait.ctx.Done():
+				if err := stopTask(client, node, upid); err != nil {
+					return fmt.Errorf("cancelled, but stopping the task failed (UPID: %s): %w", upid, err)
+				}
+				return fmt.Errorf("cancelled (UPID: %s): %w", upid, wait.ctx.Err())
+			case <-time.After(wait.pollInterval):
+			}
+		} else {
+			time.Sleep(wait.pollInterval)
+		}
+
+		// The SSH control connection may have been re-established since the last
+		// poll; re-read the recent log window and let the cursor drop duplicates
+		if gen := client.Generation(); gen != connGen {
+			connGen = gen
+			cursor.rewind(taskLogPageSize)
+			log(fmt.Sprintf("SSH connection re-established, resuming task log from line %d", cursor.start()))
+		}
+
+		// Check task status
+		statusCmd := fmt.Sprintf("pvesh get /nodes/%s/tasks/%s/status --output-format json",
+			ssh.ShellEscape(node),
+			ssh.ShellEscape(upid),
+		)
+		statusResult, err := client.Run(statusCmd)
+		if err != nil {
+			continue
+		}
+		if statusResult.ExitCode != 0 {
+			continue
+		}
+
+		var status struct {
+			Status     string `json:"status"`
+			ExitStatus string `json:"exitstatus"`
+		}
+		if err := json.Unmarshal([]byte(statusResult.Stdout), &status); err != nil {
+			continue
+		}
+
+		// Read the log after the status so a stopped task's last lines are included
+		logCmd := fmt.Sprintf("pvesh get /nodes/%s/tasks/%s/log --output-format json --start %d --limit %d 2>/dev/null",
+			ssh.ShellEscape(node),
+			ssh.ShellEscape(upid),
+			cursor.start(),
+			taskLogPageSize,
+		)
+		logResult, err := client.Run(logCmd)
+		if err == nil && logResult.ExitCode == 0 {
+			var logEntries []taskLogEntry
+			if json.Unmarshal([]byte(logResult.Stdout), &logEntries) == nil {
+				for _, line := range cursor.consume(logEntries) {
+					if wait.filter == nil || wait.filter(line) {
+						log(fmt.Sprintf("Proxmox: %s", line))
+					}
+				}
+			}
+		}
+
+		if status.Status == "stopped" {
+			if status.ExitStatus == "OK" {
+				return nil
+			}
+			return fmt.Errorf("task failed: %s", status.ExitStatus)
+		}
+		// status is "running" — keep polling
+	}
+}
+
+// stopTask stops a running Proxmox task. Stopping a task that already ended
+// is harmless.
+func stopTask(client *ssh.Client, node, upid string) error {
+	cmd := fmt.Sprintf("pvesh delete /nodes/%s/tasks/%s",
+		ssh.ShellEscape(node),
+		ssh.ShellEscape(upid),
+	)
+	result, err := client.Run(cmd)
+	if err != nil {
+		return err
+	}
+	if result.ExitCode != 0 {
+		return fmt.Errorf("pvesh delete failed: %s", strings.TrimSpace(result.Stderr))
+	}
+	return nil
+}
+
+// taskLogEntry is a line from /nodes/{node}/tasks/{upid}/log
+type taskLogEntry struct {
+	N int    `json:"n"`
+	T string `json:"t"`
+}
+
+// taskLogCursor tracks the highest task log line seen so re-reads after a
+// reconnect never emit the same line twice
+type taskLogCursor struct {
+	lastN   int
+	from    int  // start offset after a rewind
+	rewound bool // next query starts at from instead of lastN
+}
+
+// start returns the --start offset for the next log query (0-based, while
+// entry numbers are 1-based)
+func (c *taskLogCursor) start() int {
+	if c.rewound {
+		return c.from
+	}
+	return c.lastN
+}
+
+// rewind re-reads up to window lines before the cursor on the next query
+func (c *taskLogCursor) rewind(window int) {
+	c.from = c.lastN - window
+	if c.from < 0 {
+		c.from = 0
+	}
+	c.rewound = true
+}
+
+// consume returns the non-empty text of entries not seen before
+func (c *taskLogCursor) consume(entries []taskLogEntry) []string {
+	var lines []string
+	for _, entry := range entries {
+		if entry.N <= c.lastN {
+			continue
+		}
+		c.lastN = entry.N
+		if line := strings.TrimSpace(entry.T); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	c.rewound = false
+	return lines
+}
@@ -0,0 +1,51 @@
+package proxmox
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// taskLog returns entries n..m with text "line <n>"
+func taskLog(n, m int) []taskLogEntry {
+	var entries []taskLogEntry
+	for i := n; i <= m; i++ {
+		entries = append(entries, taskLogEntry{N: i, T: fmt.Sprintf("line %d", i)})
+	}
+	return entries
+}
+
+func TestTaskLogCursor(t *testing.T) {
+	c := &taskLogCursor{}
+	if got := c.start(); got != 0 {
+		t.Fatalf("fresh cursor starts at %d, want 0", got)
+	}
+
+	steps := []struct {
+		name      string
+		rewind    int // window to rewind by before the query, 0 = none
+		wantStart int
+		entries   []taskLogEntry
+		want      []string
+	}{
+		{"first page", 0, 0, taskLog(1, 3), []string{"line 1", "line 2", "line 3"}},
+		{"next page", 0, 3, taskLog(4, 5), []string{"line 4", "line 5"}},
+		{"no new lines", 0, 5, nil, nil},
+		{"reconnect rereads the window", 2, 3, taskLog(4, 7), []string{"line 6", "line 7"}},
+		{"cursor resumes after the rewind", 0, 7, taskLog(8, 8), []string{"line 8"}},
+		{"rewind past the start", 50, 0, taskLog(1, 9), []string{"line 9"}},
+		{"blank lines are skipped but counted", 0, 9, []taskLogEntry{{N: 10, T: "  "}, {N: 11, T: "done\n"}}, []string{"done"}},
+		{"out-of-order duplicates are dropped", 0, 11, []taskLogEntry{{N: 11, T: "done"}, {N: 12, T: "TASK OK"}}, []string{"TASK OK"}},
+	}
+	for _, step := range steps {
+		if step.rewind > 0 {
+			c.rewind(step.rewind)
+		}
+		if got := c.start(); got != step.wantStart {
+			t.Errorf("%s: start = %d, want %d", step.name, got, step.wantStart)
+		}
+		if got := c.consume(step.entries); !reflect.DeepEqual(got, step.want) {
+			t.Errorf("%s: consume = %q, want %q", step.name, got, step.want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package proxmox
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Allowlists for names interpolated into shell commands, pvesh API paths and
+// qm property strings. Escaping keeps the shell safe; these also keep a value
+// from adding properties (",media=disk") or walking out of a path ("../").
+var (
+	validNodeName      = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?$`)
+	validStorageName   = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9._-]*$`)
+	validInterfaceName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,14}$`)
+)
+
+// ValidateNodeName rejects node names that aren't plain hostnames
+func ValidateNodeName(name string) error {
+	if !validNodeName.MatchString(name) {
+		return fmt.Errorf("invalid node name %q", name)
+	}
+	return nil
+}
+
+// ValidateStorageName rejects storage IDs Proxmox wouldn't accept
+func ValidateStorageName(name string) error {
+	if !validStorageName.MatchString(name) {
+		return fmt.Errorf("invalid storage name %q", name)
+	}
+	return nil
+}
+
+// ValidateInterfaceName rejects bridge and port names that aren't valid
+// Linux interface names (at most 15 characters)
+func ValidateInterfaceName(name string) error {
+	if !validInterfaceName.MatchString(name) {
+		return fmt.Errorf("invalid interface name %q", name)
+	}
+	return nil
+}
+
+// ValidateVolumeFilename rejects ISO and snippet filenames that could escape
+// the storage directory or inject qm volume properties
+func ValidateVolumeFilename(name string) error {
+	if name == "" || name == "." || name == ".." {
+		return fmt.Errorf("invalid filename %q", name)
+	}
+	if strings.HasPrefix(name, "-") {
+		return fmt.Errorf("invalid filename %q: must not start with -", name)
+	}
+	for _, r := range name {
+		if r < 0x20 || r == 0x7f || strings.ContainsRune(`/\,=:;'"`, r) {
+			return fmt.Errorf("invalid filename %q: contains %q", name, r)
+		}
+	}
+	return nil
+}
+
+// validateVolume checks both halves of a storage:content/filename volume
+func validateVolume(storage, filename string) error {
+	if err := ValidateStorageName(storage); err != nil {
+		return err
+	}
+	return ValidateVolumeFilename(filename)
+}
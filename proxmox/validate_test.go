@@ -0,0 +1,84 @@
+package proxmox
+
+import "testing"
+
+func TestValidateNames(t *testing.T) {
+	tests := []struct {
+		name     string
+		validate func(string) error
+		value    string
+		valid    bool
+	}{
+		{"node", ValidateNodeName, "pve1", true},
+		{"node", ValidateNodeName, "pve-node-02", true},
+		{"node", ValidateNodeName, "a", true},
+		{"node", ValidateNodeName, "", false},
+		{"node", ValidateNodeName, "-pve", false},
+		{"node", ValidateNodeName, "pve-", false},
+		{"node", ValidateNodeName, "pve.example.com", false},
+		{"node", ValidateNodeName, "../pve1", false},
+		{"node", ValidateNodeName, "pve1; reboot", false},
+		{"node", ValidateNodeName, "pve1$(id)", false},
+		{"node", ValidateNodeName, "pve1\n", false},
+		{"node", ValidateNodeName, "pve1 ", false},
+
+		{"storage", ValidateStorageName, "local-lvm", true},
+		{"storage", ValidateStorageName, "ceph_pool.1", true},
+		{"storage", ValidateStorageName, "", false},
+		{"storage", ValidateStorageName, "1local", false},
+		{"storage", ValidateStorageName, "local:iso", false},
+		{"storage", ValidateStorageName, "local,media=disk", false},
+		{"storage", ValidateStorageName, "local/../etc", false},
+		{"storage", ValidateStorageName, "local`id`", false},
+		{"storage", ValidateStorageName, "local\x00", false},
+
+		{"interface", ValidateInterfaceName, "vmbr0", true},
+		{"interface", ValidateInterfaceName, "eno1.100", true},
+		{"interface", ValidateInterfaceName, "abcdefghijklmno", true},
+		{"interface", ValidateInterfaceName, "abcdefghijklmnop", false},
+		{"interface", ValidateInterfaceName, "", false},
+		{"interface", ValidateInterfaceName, "-vmbr0", false},
+		{"interface", ValidateInterfaceName, "vmbr0 vmbr1", false},
+		{"interface", ValidateInterfaceName, "vmbr0/..", false},
+		{"interface", ValidateInterfaceName, "vmbr0'", false},
+
+		{"filename", ValidateVolumeFilename, "versa-director-22.1.4-B.iso", true},
+		{"filename", ValidateVolumeFilename, "user data (lab).yaml", true},
+		{"filename", ValidateVolumeFilename, "", false},
+		{"filename", ValidateVolumeFilename, ".", false},
+		{"filename", ValidateVolumeFilename, "..", false},
+		{"filename", ValidateVolumeFilename, "../../etc/shadow", false},
+		{"filename", ValidateVolumeFilename, `..\director.iso`, false},
+		{"filename", ValidateVolumeFilename, "-rf.iso", false},
+		{"filename", ValidateVolumeFilename, "director.iso,media=disk", false},
+		{"filename", ValidateVolumeFilename, "local:iso/director.iso", false},
+		{"filename", ValidateVolumeFilename, "size=1G.iso", false},
+		{"filename", ValidateVolumeFilename, "a;b.iso", false},
+		{"filename", ValidateVolumeFilename, "it's.iso", false},
+		{"filename", ValidateVolumeFilename, `"quoted".iso`, false},
+		{"filename", ValidateVolumeFilename, "line\nbreak.iso", false},
+		{"filename", ValidateVolumeFilename, "nul\x00.iso", false},
+		{"filename", ValidateVolumeFilename, "del\x7f.iso", false},
+	}
+	for _, tt := range tests {
+		err := tt.validate(tt.value)
+		if tt.valid && err != nil {
+			t.Errorf("%s %q rejected: %v", tt.name, tt.value, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("%s %q accepted, want an error", tt.name, tt.value)
+		}
+	}
+}
+
+func TestValidateVolume(t *testing.T) {
+	if err := validateVolume("local", "director.iso"); err != nil {
+		t.Errorf("validateVolume(local, director.iso): %v", err)
+	}
+	if err := validateVolume("local,x", "director.iso"); err == nil {
+		t.Error("validateVolume accepted a bad storage name")
+	}
+	if err := validateVolume("local", "../director.iso"); err == nil {
+		t.Error("validateVolume accepted a bad filename")
+	}
+}
@@ -0,0 +1,135 @@
+package proxmox
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed Proxmox VE version such as 8.1.3
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+	Raw   string // Original string as reported by pveversion
+}
+
+// Version thresholds for feature gating
+var (
+	// MinSupportedVersion is the oldest PVE release the deployer is tested against
+	MinSupportedVersion = Version{Major: 7, Minor: 0}
+
+	// downloadURLVersion introduced the storage download-url API (pvesh)
+	downloadURLVersion = Version{Major: 7, Minor: 0}
+
+	// tagStyleVersion added the datacenter tag-style option (tag colors)
+	tagStyleVersion = Version{Major: 7, Minor: 3}
+
+	// ifupdown2Version made ifupdown2, with hyphenated bridge options, the
+	// default on new installs
+	ifupdown2Version = Version{Major: 7, Minor: 0}
+)
+
+// ParseVersion parses a PVE version string like "8.1.3", "7.4-17" or the full
+// pveversion line ("pve-manager/8.1.3/ec5affc9..."). Missing minor/patch
+// components are treated as 0.
+func ParseVersion(s string) (Version, error) {
+	raw := strings.TrimSpace(s)
+	v := raw
+	if rest, ok := strings.CutPrefix(v, "pve-manager/"); ok {
+		v = rest
+	}
+	if i := strings.IndexAny(v, "/ "); i >= 0 {
+		v = v[:i]
+	}
+	// Drop Debian-style package revisions ("7.4-17") and pre-release suffixes
+	if i := strings.IndexAny(v, "-~+"); i >= 0 {
+		v = v[:i]
+	}
+
+	parts := strings.Split(v, ".")
+	if v == "" || len(parts) > 3 {
+		return Version{}, fmt.Errorf("invalid Proxmox version %q", s)
+	}
+
+	var nums [3]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return Version{}, fmt.Errorf("invalid Proxmox version %q", s)
+		}
+		nums[i] = n
+	}
+
+	return Version{Major: nums[0], Minor: nums[1], Patch: nums[2], Raw: raw}, nil
+}
+
+// Known reports whether the version was parsed (the zero value is unknown)
+func (v Version) Known() bool {
+	return v.Major > 0
+}
+
+// Compare returns -1, 0 or 1 as v is older than, equal to or newer than other
+func (v Version) Compare(other Version) int {
+	for _, pair := range [][2]int{{v.Major, other.Major}, {v.Minor, other.Minor}, {v.Patch, other.Patch}} {
+		if pair[0] < pair[1] {
+			return -1
+		}
+		if pair[0] > pair[1] {
+			return 1
+		}
+	}
+	return 0
+}
+
+// AtLeast reports whether v is major.minor or newer
+func (v Version) AtLeast(major, minor int) bool {
+	return v.Compare(Version{Major: major, Minor: minor}) >= 0
+}
+
+// String returns the version as major.minor.patch
+func (v Version) String() string {
+	if !v.Known() {
+		return "unknown"
+	}
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Supported reports whether v meets MinSupportedVersion. An unknown version
+// is assumed to be supported.
+func (v Version) Supported() bool {
+	return !v.Known() || v.Compare(MinSupportedVersion) >= 0
+}
+
+// SupportsDownloadURL reports whether the storage download-url API is
+// available. An unknown version is assumed to be recent.
+func (v Version) SupportsDownloadURL() bool {
+	return !v.Known() || v.Compare(downloadURLVersion) >= 0
+}
+
+// SupportsTagStyle reports whether tag colors can be set. Unlike other
+// features an unknown version is assumed not to, since the setting is
+// cluster-wide.
+func (v Version) SupportsTagStyle() bool {
+	return v.Known() && v.Compare(tagStyleVersion) >= 0
+}
+
+// BridgeOption returns a bridge option name in the syntax the host's
+// /etc/network/interfaces expects: hyphenated for ifupdown2 (PVE 7+),
+// underscored for classic ifupdown. name is given hyphenated, e.g. "bridge-ports".
+func (v Version) BridgeOption(name string) string {
+	if v.Known() && v.Compare(ifupdown2Version) < 0 {
+		return strings.ReplaceAll(name, "-", "_")
+	}
+	return name
+}
+
+// PVEVersion parses the discovered version string. An unparseable version
+// yields the zero (unknown) Version.
+func (info *ProxmoxInfo) PVEVersion() Version {
+	v, err := ParseVersion(info.Version)
+	if err != nil {
+		return Version{Raw: info.Version}
+	}
+	return v
+}
@@ -0,0 +1,57 @@
+package proxmox
+
+import "testing"
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Version
+		wantErr bool
+	}{
+		{in: "8.1.3", want: Version{Major: 8, Minor: 1, Patch: 3}},
+		{in: "7.4-17", want: Version{Major: 7, Minor: 4}},
+		{in: "8", want: Version{Major: 8}},
+		{in: "pve-manager/8.1.3/ec5affc9e41f1d79 (running kernel: 6.5.11-7-pve)", want: Version{Major: 8, Minor: 1, Patch: 3}},
+		{in: "pve-manager/7.0-11/63d82f4e", want: Version{Major: 7}},
+		{in: "  8.2.2\n", want: Version{Major: 8, Minor: 2, Patch: 2}},
+		{in: "9.0~beta1", want: Version{Major: 9}},
+		{in: "", wantErr: true},
+		{in: "pve-manager/", wantErr: true},
+		{in: "8.1.3.4", wantErr: true},
+		{in: "eight", wantErr: true},
+		{in: "8.x", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseVersion(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseVersion(%q) = %v, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseVersion(%q): %v", tt.in, err)
+			continue
+		}
+		if got.Compare(tt.want) != 0 || got.Raw == "" {
+			t.Errorf("ParseVersion(%q) = %v (raw %q), want %v", tt.in, got, got.Raw, tt.want)
+		}
+	}
+}
+
+func TestBridgeOption(t *testing.T) {
+	tests := []struct {
+		version Version
+		want    string
+	}{
+		{Version{}, "bridge-ports"},
+		{Version{Major: 6, Minor: 4}, "bridge_ports"},
+		{Version{Major: 7}, "bridge-ports"},
+		{Version{Major: 8, Minor: 1}, "bridge-ports"},
+	}
+	for _, tt := range tests {
+		if got := tt.version.BridgeOption("bridge-ports"); got != tt.want {
+			t.Errorf("%v.BridgeOption(bridge-ports) = %q, want %q", tt.version, got, tt.want)
+		}
+	}
+}
@@ -1,8 +1,14 @@
 package proxmox
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/mihailvovk/versa-proxmox-deployer/config"
 	"github.com/mihailvovk/versa-proxmox-deployer/ssh"
@@ -10,7 +16,12 @@ import (
 
 // VMCreator handles VM creation on Proxmox
 type VMCreator struct {
-	client *ssh.Client
+	client    *ssh.Client
+	hostCheck hostCheck
+
+	nodesMu   sync.Mutex
+	localNode string         // Node the client is connected to, if known
+	vmNodes   map[int]string // Node each VM was created on, for follow-up commands
 }
 
 // NewVMCreator creates a new VM creator
@@ -27,22 +38,92 @@ type VMConfig struct {
 	CPUCores    int
 	RAMGB       int
 	DiskGB      int
-	Storage     string // Storage pool for disk
-	ISOStorage  string // Storage pool for ISO
-	ISOFile     string // ISO filename
+	Storage     string         // Storage pool for disk
+	DiskBus     config.DiskBus // Boot disk controller (empty = scsi)
+	BIOS        config.BIOS    // Firmware (empty = seabios); ovmf adds an EFI disk on Storage
+	Machine     config.Machine // Machine type (empty = i440fx)
+	ExtraDisks  []ExtraDisk    // Data disks after the boot disk, on the same bus
+	ISOStorage  string         // Storage pool for ISO
+	ISOFile     string         // ISO filename
 	Networks    []VMNetwork
 	Tags        []string
 	StartOnBoot bool
 	OnBoot      bool
+	GuestAgent  bool // Enable the QEMU guest agent (needed by TrimDisks)
+
+	// Host boot sequencing (0 = Proxmox default order, no delay)
+	StartupOrder int
+	StartupDelay int // Seconds to wait after start before the next VM
+
+	// Data-plane tuning
+	Hugepages string // "2", "1024" or "any" (empty = off)
+	NUMA      bool
+	Affinity  string // Host CPU list for vCPU pinning
+
+	// Proxmox cloud-init drive on Storage (nil = none); IP is this VM's own
+	CloudInit *config.CloudInitConfig
+
+	// Extra qm create arguments appended after the generated ones, e.g.
+	// {"--hookscript", "local:snippets/hook.sh"}. Each is shell-escaped, but
+	// they bypass the tool's own modelling, so conflicting flags make qm fail.
+	ExtraArgs []string
+}
+
+// ExtraDisk is an additional disk attached at Index on the boot disk's bus,
+// e.g. scsi1
+type ExtraDisk struct {
+	Storage string
+	SizeGB  int
+	Index   int
+}
+
+// maxDiskIndex is the highest disk slot Proxmox allows per bus
+var maxDiskIndex = map[config.DiskBus]int{
+	config.DiskBusSCSI:   30,
+	config.DiskBusVirtio: 15,
+	config.DiskBusSATA:   5,
+	config.DiskBusIDE:    3,
+}
+
+// extraDiskSlot validates an extra disk and returns its slot name on bus
+func extraDiskSlot(bus config.DiskBus, disk ExtraDisk) (string, error) {
+	if err := ValidateStorageName(disk.Storage); err != nil {
+		return "", err
+	}
+	if disk.SizeGB <= 0 {
+		return "", fmt.Errorf("invalid extra disk size %dGB", disk.SizeGB)
+	}
+	// Index 0 is the boot disk, and ide2 holds the install CD-ROM
+	if disk.Index < 1 || disk.Index > maxDiskIndex[bus] || (bus == config.DiskBusIDE && disk.Index == 2) {
+		return "", fmt.Errorf("invalid extra disk slot %s%d", bus, disk.Index)
+	}
+	return fmt.Sprintf("%s%d", bus, disk.Index), nil
 }
 
 // VMNetwork holds network interface configuration
 type VMNetwork struct {
-	Bridge   string
-	VLAN     int    // 0 for native/untagged
-	Model    string // virtio, e1000, etc.
-	Firewall bool
-	Name     string // Descriptive name for the network purpose
+	Bridge   string `json:"bridge"`
+	VLAN     int    `json:"vlan,omitempty"`  // 0 for native/untagged
+	Model    string `json:"model,omitempty"` // virtio, e1000, etc.
+	Firewall bool   `json:"firewall,omitempty"`
+	Name     string `json:"name"`          // Descriptive name for the network purpose
+	MTU      int    `json:"mtu,omitempty"` // 0 = Proxmox default, 1 = inherit the bridge MTU
+}
+
+// MTU limits accepted by Proxmox for virtio NICs
+const (
+	MTUInheritBridge = 1
+	MinMTU           = 576
+	MaxMTU           = 65520
+)
+
+// ValidateMTU checks an interface MTU is 0 (unset), 1 (inherit bridge) or
+// within MinMTU..MaxMTU
+func ValidateMTU(mtu int) error {
+	if mtu == 0 || mtu == MTUInheritBridge || (mtu >= MinMTU && mtu <= MaxMTU) {
+		return nil
+	}
+	return fmt.Errorf("invalid MTU %d: must be %d (inherit bridge) or %d-%d", mtu, MTUInheritBridge, MinMTU, MaxMTU)
 }
 
 // NetworkPurpose defines the purpose of a network interface
@@ -83,22 +164,72 @@ func GetNetworkDescription(purpose NetworkPurpose) string {
 	return string(purpose)
 }
 
-// CreateVM creates a new VM on Proxmox
+// ErrVMIDExists is returned by CreateVM when the VMID was taken between
+// allocation and qm create, e.g. by another process on the cluster
+var ErrVMIDExists = errors.New("VMID already in use")
+
+// CreateVM creates a new VM on Proxmox, on cfg.Node when that is another
+// cluster node. Later calls for the VM run on the same node.
 func (c *VMCreator) CreateVM(cfg VMConfig) error {
+	cmd, err := CreateVMCommand(cfg)
+	if err != nil {
+		return fmt.Errorf("creating VM: %w", err)
+	}
+	cmd, err = c.nodeCommand(cfg.Node, cmd)
+	if err != nil {
+		return fmt.Errorf("creating VM: %w", err)
+	}
+	if err := c.runQuiet(cmd); err != nil {
+		if isVMIDExistsError(err, cfg.VMID) {
+			return fmt.Errorf("creating VM: %w: %w", ErrVMIDExists, err)
+		}
+		return fmt.Errorf("creating VM: %w", err)
+	}
+
+	c.setVMNode(cfg.VMID, cfg.Node)
+	return nil
+}
+
+// CreateVMCommand validates a VM config and builds the qm create command
+// CreateVM runs, e.g. to show it in a dry run
+func CreateVMCommand(cfg VMConfig) (string, error) {
+	if err := ValidateStorageName(cfg.Storage); err != nil {
+		return "", err
+	}
+	if cfg.ISOFile != "" {
+		if err := validateVolume(cfg.ISOStorage, cfg.ISOFile); err != nil {
+			return "", err
+		}
+	}
+	if err := validateNetworks(cfg.Networks); err != nil {
+		return "", err
+	}
+
 	// Build qm create command
 	args := []string{
 		fmt.Sprintf("%d", cfg.VMID),
 		"--name " + ssh.ShellEscape(cfg.Name),
-		fmt.Sprintf("--memory %d", cfg.RAMGB*1024),
-		fmt.Sprintf("--cores %d", cfg.CPUCores),
 		"--cpu cputype=host",
 		"--ostype l26",
-		"--scsihw virtio-scsi-pci",
 	}
 
-	// Add description if provided
-	if cfg.Description != "" {
-		args = append(args, "--description "+ssh.ShellEscape(cfg.Description))
+	if cfg.Machine == config.MachineQ35 {
+		args = append(args, "--machine q35")
+	}
+	// UEFI keeps its variables on a small disk next to the boot disk.
+	// Without pre-enrolled keys Secure Boot is off, so the ISO boots.
+	if cfg.BIOS == config.BIOSOVMF {
+		args = append(args, "--bios ovmf",
+			"--efidisk0 "+ssh.ShellEscape(fmt.Sprintf("%s:1,efitype=4m,pre-enrolled-keys=0", cfg.Storage)))
+	}
+
+	bus := cfg.DiskBus
+	if bus == "" {
+		bus = config.DiskBusSCSI
+	}
+	diskSlot := string(bus) + "0"
+	if bus == config.DiskBusSCSI {
+		args = append(args, "--scsihw virtio-scsi-pci")
 	}
 
 	// Add IDE for CD-ROM with ISO
@@ -108,78 +239,271 @@ func (c *VMCreator) CreateVM(cfg VMConfig) error {
 	}
 
 	// Boot order: disk first so after OS install the VM boots from disk, not ISO again
-	args = append(args, "--boot "+ssh.ShellEscape("order=scsi0;ide2"))
-
-	// Add network interfaces
-	for i, net := range cfg.Networks {
-		model := net.Model
-		if model == "" {
-			model = "virtio"
-		}
+	args = append(args, "--boot "+ssh.ShellEscape("order="+diskSlot+";ide2"))
 
-		netValue := fmt.Sprintf("%s,bridge=%s", model, net.Bridge)
-		if net.VLAN > 0 {
-			netValue += fmt.Sprintf(",tag=%d", net.VLAN)
+	// Create disk; discard passes guest TRIMs through so thin storage
+	// reclaims deleted blocks
+	diskValue := fmt.Sprintf("%s:%d,discard=on", cfg.Storage, cfg.DiskGB)
+	args = append(args, "--"+diskSlot+" "+ssh.ShellEscape(diskValue))
+	for _, disk := range cfg.ExtraDisks {
+		slot, err := extraDiskSlot(bus, disk)
+		if err != nil {
+			return "", err
 		}
-		if net.Firewall {
-			netValue += ",firewall=1"
-		}
-		args = append(args, fmt.Sprintf("--net%d ", i)+ssh.ShellEscape(netValue))
+		diskValue := fmt.Sprintf("%s:%d,discard=on", disk.Storage, disk.SizeGB)
+		args = append(args, "--"+slot+" "+ssh.ShellEscape(diskValue))
 	}
 
-	// Create disk
-	diskValue := fmt.Sprintf("%s:%d", cfg.Storage, cfg.DiskGB)
-	args = append(args, "--scsi0 "+ssh.ShellEscape(diskValue))
-
 	// Add serial console device for terminal access
 	args = append(args, "--serial0 socket")
 
-	// Add tags
+	settings, err := settingsArgs(cfg)
+	if err != nil {
+		return "", err
+	}
+	args = append(args, settings...)
+
+	// Operator-supplied escape hatch, escaped one argument at a time
+	for _, a := range cfg.ExtraArgs {
+		args = append(args, ssh.ShellEscape(a))
+	}
+
+	return withSSHKeys(cfg, fmt.Sprintf("qm create %s", strings.Join(args, " "))), nil
+}
+
+// settingsArgs builds the qm arguments a created VM and a configured clone
+// share: CPU, RAM, networks, tuning, tags, startup and cloud-init
+func settingsArgs(cfg VMConfig) ([]string, error) {
+	args := []string{
+		fmt.Sprintf("--memory %d", cfg.RAMGB*1024),
+		fmt.Sprintf("--cores %d", cfg.CPUCores),
+	}
+	if cfg.Description != "" {
+		args = append(args, "--description "+ssh.ShellEscape(cfg.Description))
+	}
+	for i, net := range cfg.Networks {
+		args = append(args, fmt.Sprintf("--net%d ", i)+ssh.ShellEscape(net.qmValue()))
+	}
+
+	// Hugepages and NUMA for data-plane performance
+	if cfg.Hugepages != "" {
+		args = append(args, "--hugepages "+ssh.ShellEscape(cfg.Hugepages))
+	}
+	if cfg.NUMA {
+		args = append(args, "--numa 1")
+	}
+	if cfg.Affinity != "" {
+		args = append(args, "--affinity "+ssh.ShellEscape(cfg.Affinity))
+	}
+	if cfg.GuestAgent {
+		args = append(args, "--agent enabled=1")
+	}
 	if len(cfg.Tags) > 0 {
 		args = append(args, "--tags "+ssh.ShellEscape(strings.Join(cfg.Tags, ";")))
 	}
-
-	// Add start on boot
-	if cfg.StartOnBoot {
+	if cfg.StartOnBoot || cfg.OnBoot {
 		args = append(args, "--onboot 1")
 	}
+	if startup := cfg.startupValue(); startup != "" {
+		args = append(args, "--startup "+ssh.ShellEscape(startup))
+	}
 
-	// Execute command
-	cmd := fmt.Sprintf("qm create %s", strings.Join(args, " "))
-	if err := c.client.RunQuiet(cmd); err != nil {
-		return fmt.Errorf("creating VM: %w", err)
+	if cfg.CloudInit != nil {
+		ciArgs, err := cloudInitArgs(cfg)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, ciArgs...)
+	}
+	return args, nil
+}
+
+// sshKeysVar is the shell variable holding the staged keys file that
+// cloudInitArgs passes to --sshkeys
+const sshKeysVar = "sshkeys"
+
+// withSSHKeys wraps a qm command so it can read the VM's cloud-init SSH keys
+// from a file on the host, as --sshkeys requires. The file comes from mktemp,
+// so it is private and not at a predictable path, and is removed whatever
+// qm's result.
+func withSSHKeys(cfg VMConfig, cmd string) string {
+	if cfg.CloudInit == nil || len(cfg.CloudInit.SSHKeys) == 0 {
+		return cmd
+	}
+	keys := strings.Join(cfg.CloudInit.SSHKeys, "\n") + "\n"
+	return fmt.Sprintf(`%[1]s=$(mktemp) && printf '%%s' %[2]s > "$%[1]s" && { %[3]s; rc=$?; rm -f "$%[1]s"; exit $rc; }`,
+		sshKeysVar, ssh.ShellEscape(keys), cmd)
+}
+
+// cloudInitArgs builds the qm create arguments for a cloud-init drive. It
+// goes on ide3, since ide2 holds the ISO and ide0 may be the boot disk.
+func cloudInitArgs(cfg VMConfig) ([]string, error) {
+	ci := cfg.CloudInit
+	ipConfig := "ip=dhcp"
+	if ci.IP != "" {
+		ip, _, err := net.ParseCIDR(ci.IP)
+		if err != nil || ip.To4() == nil {
+			return nil, fmt.Errorf("cloud-init IP %q must be an IPv4 address in CIDR form, e.g. 10.0.0.10/24", ci.IP)
+		}
+		ipConfig = "ip=" + ci.IP
+		if ci.Gateway != "" {
+			if net.ParseIP(ci.Gateway) == nil {
+				return nil, fmt.Errorf("invalid cloud-init gateway %q", ci.Gateway)
+			}
+			ipConfig += ",gw=" + ci.Gateway
+		}
+	}
+
+	args := []string{
+		"--ide3 " + ssh.ShellEscape(cfg.Storage+":cloudinit"),
+		"--ipconfig0 " + ssh.ShellEscape(ipConfig),
+	}
+	if ci.User != "" {
+		args = append(args, "--ciuser "+ssh.ShellEscape(ci.User))
+	}
+	if ci.Password != "" {
+		hash, err := hashCloudInitPassword(ci.Password)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "--cipassword "+ssh.ShellEscape(hash))
+	}
+	if len(ci.SSHKeys) > 0 {
+		args = append(args, `--sshkeys "$`+sshKeysVar+`"`)
+	}
+	return args, nil
+}
+
+// startupValue formats the qm --startup value, empty when no order is set
+func (cfg VMConfig) startupValue() string {
+	if cfg.StartupOrder <= 0 {
+		return ""
 	}
+	value := fmt.Sprintf("order=%d", cfg.StartupOrder)
+	if cfg.StartupDelay > 0 {
+		value += fmt.Sprintf(",up=%d", cfg.StartupDelay)
+	}
+	return value
+}
+
+// VMIDExists reports whether a VMID is taken anywhere in the cluster, by a
+// VM or a container on any node
+func (c *VMCreator) VMIDExists(vmid int) (bool, error) {
+	var resources []struct {
+		VMID int `json:"vmid"`
+	}
+	if err := c.runJSON("pvesh get /cluster/resources --type vm --output-format json", &resources); err != nil {
+		return false, fmt.Errorf("checking VMID %d: %w", vmid, err)
+	}
+	for _, r := range resources {
+		if r.VMID == vmid {
+			return true, nil
+		}
+	}
+	return false, nil
+}
 
+// isVMIDExistsError matches qm's "VM N already exists" failure (also
+// reported as "unable to create VM N - VM N already exists on node 'x'")
+func isVMIDExistsError(err error, vmid int) bool {
+	return strings.Contains(err.Error(), fmt.Sprintf("VM %d already exists", vmid))
+}
+
+// ValidateExtraArgs rejects extra qm arguments that don't start with a flag or
+// carry control characters (newlines, NUL) that could smuggle extra commands
+// past logging even though each argument is quoted
+func ValidateExtraArgs(args []string) error {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "--") {
+		return fmt.Errorf("extra qm arguments must start with a --flag, got %q", args[0])
+	}
+	for _, a := range args {
+		if a == "" {
+			return fmt.Errorf("extra qm arguments must not be empty")
+		}
+		for _, r := range a {
+			if r < 0x20 || r == 0x7f {
+				return fmt.Errorf("extra qm argument %q contains a control character", a)
+			}
+		}
+	}
 	return nil
 }
 
 // StartVM starts a VM
 func (c *VMCreator) StartVM(vmid int) error {
-	return c.client.RunQuiet(fmt.Sprintf("qm start %d", vmid))
+	return c.runQuiet(c.onNode(vmid, fmt.Sprintf("qm start %d", vmid)))
 }
 
 // StopVM stops a VM (force after 10s timeout)
 func (c *VMCreator) StopVM(vmid int) error {
-	return c.client.RunQuiet(fmt.Sprintf("qm stop %d --timeout 10", vmid))
+	return c.runQuiet(c.onNode(vmid, fmt.Sprintf("qm stop %d --timeout 10", vmid)))
 }
 
 // DestroyVM destroys a VM and purges its disks
 func (c *VMCreator) DestroyVM(vmid int) error {
 	// First try to stop if running
-	c.client.Run(fmt.Sprintf("qm stop %d 2>/dev/null || true", vmid))
+	c.run(c.onNode(vmid, fmt.Sprintf("qm stop %d 2>/dev/null || true", vmid)))
 
 	// Then destroy with purge
-	return c.client.RunQuiet(fmt.Sprintf("qm destroy %d --purge", vmid))
+	return c.runQuiet(c.onNode(vmid, fmt.Sprintf("qm destroy %d --purge", vmid)))
+}
+
+// qmValue formats the network as a qm --netN value
+func (n VMNetwork) qmValue() string {
+	model := n.Model
+	if model == "" {
+		model = "virtio"
+	}
+
+	value := fmt.Sprintf("%s,bridge=%s", model, n.Bridge)
+	if n.VLAN > 0 {
+		value += fmt.Sprintf(",tag=%d", n.VLAN)
+	}
+	if n.Firewall {
+		value += ",firewall=1"
+	}
+	if n.MTU > 0 {
+		value += fmt.Sprintf(",mtu=%d", n.MTU)
+	}
+	return value
+}
+
+// validateNetworks checks each network's bridge, which qmValue puts in a
+// property string unescaped
+func validateNetworks(networks []VMNetwork) error {
+	for _, n := range networks {
+		if err := ValidateInterfaceName(n.Bridge); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetNetworks sets the VM's interfaces net<first>, net<first+1>, ... to
+// networks. Interfaces below first are left alone, so existing NICs keep
+// their MAC addresses.
+func (c *VMCreator) SetNetworks(vmid int, networks []VMNetwork, first int) error {
+	if len(networks) == 0 {
+		return nil
+	}
+	if err := validateNetworks(networks); err != nil {
+		return err
+	}
+	args := []string{fmt.Sprintf("qm set %d", vmid)}
+	for i, net := range networks {
+		args = append(args, fmt.Sprintf("--net%d ", first+i)+ssh.ShellEscape(net.qmValue()))
+	}
+	return c.runQuiet(c.onNode(vmid, strings.Join(args, " ")))
 }
 
 // SetVMTags sets tags on a VM
 func (c *VMCreator) SetVMTags(vmid int, tags []string) error {
-	return c.client.RunQuiet(fmt.Sprintf("qm set %d --tags ", vmid) + ssh.ShellEscape(strings.Join(tags, ";")))
+	return c.runQuiet(c.onNode(vmid, fmt.Sprintf("qm set %d --tags ", vmid) + ssh.ShellEscape(strings.Join(tags, ";"))))
 }
 
 // GetVMStatus gets the status of a VM
 func (c *VMCreator) GetVMStatus(vmid int) (string, error) {
-	result, err := c.client.Run(fmt.Sprintf("qm status %d", vmid))
+	result, err := c.run(c.onNode(vmid, fmt.Sprintf("qm status %d", vmid)))
 	if err != nil {
 		return "", err
 	}
@@ -194,11 +518,276 @@ func (c *VMCreator) GetVMStatus(vmid int) (string, error) {
 	return output, nil
 }
 
+// ErrGuestAgentUnavailable is returned by TrimDisks when the VM has no guest
+// agent configured or the agent isn't running inside it
+var ErrGuestAgentUnavailable = errors.New("QEMU guest agent not available")
+
+// FSTrimResult is the guest agent's report for one trimmed filesystem
+type FSTrimResult struct {
+	Path    string `json:"path"`
+	Trimmed uint64 `json:"trimmed"`
+	Error   string `json:"error,omitempty"`
+}
+
+// TrimDisks runs fstrim on every mounted filesystem through the guest agent
+// and returns what each one released
+func (c *VMCreator) TrimDisks(vmid int) ([]FSTrimResult, error) {
+	result, err := c.run(c.onNode(vmid, fmt.Sprintf("qm guest cmd %d fstrim", vmid)))
+	if err != nil {
+		return nil, err
+	}
+	if result.ExitCode != 0 {
+		stderr := strings.TrimSpace(result.Stderr)
+		if strings.Contains(stderr, "guest agent") {
+			return nil, fmt.Errorf("%w: %s", ErrGuestAgentUnavailable, stderr)
+		}
+		return nil, fmt.Errorf("fstrim on VM %d: %s", vmid, stderr)
+	}
+
+	var out struct {
+		Paths []FSTrimResult `json:"paths"`
+	}
+	if err := json.Unmarshal([]byte(result.Stdout), &out); err != nil {
+		return nil, fmt.Errorf("parsing fstrim result: %w", err)
+	}
+	return out.Paths, nil
+}
+
+// GetAttachedISO returns the storage and filename of the ISO in a VM's ide2
+// CD-ROM drive. Both are empty when the drive is missing or has no media.
+func (c *VMCreator) GetAttachedISO(vmid int) (storage, filename string, err error) {
+	result, err := c.run(c.onNode(vmid, fmt.Sprintf("qm config %d", vmid)))
+	if err != nil {
+		return "", "", fmt.Errorf("reading VM %d config: %w", vmid, err)
+	}
+	if result.ExitCode != 0 {
+		return "", "", fmt.Errorf("reading VM %d config: %s", vmid, strings.TrimSpace(result.Stderr))
+	}
+
+	for _, line := range strings.Split(result.Stdout, "\n") {
+		value, ok := strings.CutPrefix(strings.TrimSpace(line), "ide2:")
+		if !ok {
+			continue
+		}
+		storage, filename, err = parseCDROMVolume(value)
+		if err != nil {
+			return "", "", fmt.Errorf("VM %d: %w", vmid, err)
+		}
+		return storage, filename, nil
+	}
+	return "", "", nil
+}
+
+// parseCDROMVolume parses a CD-ROM drive value such as
+// "local:iso/versa-director.iso,media=cdrom,size=3G". An empty drive
+// returns empty strings.
+func parseCDROMVolume(value string) (storage, filename string, err error) {
+	volume, _, _ := strings.Cut(strings.TrimSpace(value), ",")
+	if volume == "none" || volume == "cdrom" {
+		return "", "", nil
+	}
+	stor, path, ok := strings.Cut(volume, ":")
+	if !ok {
+		return "", "", fmt.Errorf("unexpected ide2 volume %q", volume)
+	}
+	return stor, strings.TrimPrefix(path, "iso/"), nil
+}
+
+// AttachISO inserts an ISO into a VM's ide2 CD-ROM drive, creating the
+// drive if needed
+func (c *VMCreator) AttachISO(vmid int, storage, filename string) error {
+	if err := validateVolume(storage, filename); err != nil {
+		return err
+	}
+	volume := fmt.Sprintf("%s:iso/%s", storage, filename)
+	return c.runQuiet(c.onNode(vmid, fmt.Sprintf("qm set %d --ide2 ", vmid) + ssh.ShellEscape(volume+",media=cdrom")))
+}
+
+// DetachISO ejects the media from a VM's ide2 CD-ROM drive, keeping the drive
+func (c *VMCreator) DetachISO(vmid int) error {
+	return c.runQuiet(c.onNode(vmid, fmt.Sprintf("qm set %d --ide2 none,media=cdrom", vmid)))
+}
+
+// SetCICustom takes the user-data of a VM that already has a cloud-init
+// drive from a snippet volume instead of the generated default
+func (c *VMCreator) SetCICustom(vmid int, userVolume string) error {
+	return c.runQuiet(c.onNode(vmid, fmt.Sprintf("qm set %d --cicustom %s", vmid, ssh.ShellEscape("user="+userVolume))))
+}
+
+// SetCloudInit adds a cloud-init drive on storage and takes the VM's
+// user-data from a snippet volume instead of the generated default
+func (c *VMCreator) SetCloudInit(vmid int, storage, userVolume string) error {
+	if err := ValidateStorageName(storage); err != nil {
+		return err
+	}
+	return c.runQuiet(c.onNode(vmid, fmt.Sprintf("qm set %d --ide3 %s --cicustom %s", vmid,
+		ssh.ShellEscape(storage+":cloudinit"), ssh.ShellEscape("user="+userVolume))))
+}
+
+// SetCDROMBoot moves the ide2 CD-ROM to the front of the VM's boot order
+// (first) or behind the other boot devices, keeping their relative order
+func (c *VMCreator) SetCDROMBoot(vmid int, first bool) error {
+	boot, err := c.configValue(vmid, "boot")
+	if err != nil {
+		return err
+	}
+
+	var devices []string
+	if order, ok := strings.CutPrefix(boot, "order="); ok {
+		for _, dev := range strings.Split(order, ";") {
+			if dev != "" && dev != "ide2" {
+				devices = append(devices, dev)
+			}
+		}
+	}
+	if first {
+		devices = append([]string{"ide2"}, devices...)
+	} else {
+		devices = append(devices, "ide2")
+	}
+
+	return c.runQuiet(c.onNode(vmid, fmt.Sprintf("qm set %d --boot ", vmid) + ssh.ShellEscape("order="+strings.Join(devices, ";"))))
+}
+
+// configValue returns one key from a VM's qm config, empty if unset
+func (c *VMCreator) configValue(vmid int, key string) (string, error) {
+	result, err := c.run(c.onNode(vmid, fmt.Sprintf("qm config %d", vmid)))
+	if err != nil {
+		return "", fmt.Errorf("reading VM %d config: %w", vmid, err)
+	}
+	if result.ExitCode != 0 {
+		return "", fmt.Errorf("reading VM %d config: %s", vmid, strings.TrimSpace(result.Stderr))
+	}
+
+	for _, line := range strings.Split(result.Stdout, "\n") {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(line), key+":"); ok {
+			return strings.TrimSpace(value), nil
+		}
+	}
+	return "", nil
+}
+
+// CleanInstallSnapshot is the snapshot taken before a VM's first boot, so a
+// botched install can be retried from a blank disk
+const CleanInstallSnapshot = "clean-install"
+
+// SnapshotInfo describes one VM snapshot
+type SnapshotInfo struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parent      string `json:"parent,omitempty"`
+	SnapTime    int64  `json:"snaptime,omitempty"` // Unix time, 0 if unknown
+}
+
+// ValidateSnapshotName checks a name against Proxmox's snapshot naming rules
+func ValidateSnapshotName(name string) error {
+	if len(name) < 2 || len(name) > 40 {
+		return fmt.Errorf("snapshot name %q must be 2-40 characters", name)
+	}
+	for i, r := range name {
+		letter := r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+		if i == 0 && !letter {
+			return fmt.Errorf("snapshot name %q must start with a letter", name)
+		}
+		if !letter && !(r >= '0' && r <= '9') && r != '-' && r != '_' {
+			return fmt.Errorf("snapshot name %q may only contain letters, digits, '-' and '_'", name)
+		}
+	}
+	return nil
+}
+
+// Snapshot creates a disk-only snapshot of a VM. The VM's storage must
+// support snapshots (qcow2, LVM-thin, ZFS, Ceph).
+func (c *VMCreator) Snapshot(vmid int, name string) error {
+	if err := ValidateSnapshotName(name); err != nil {
+		return err
+	}
+	return c.runQuiet(c.onNode(vmid, fmt.Sprintf("qm snapshot %d %s", vmid, ssh.ShellEscape(name))))
+}
+
+// ListSnapshots returns a VM's snapshots, oldest first
+func (c *VMCreator) ListSnapshots(vmid int) ([]SnapshotInfo, error) {
+	var all []SnapshotInfo
+	if err := c.runJSON(c.onNode(vmid, fmt.Sprintf("pvesh get /nodes/localhost/qemu/%d/snapshot --output-format json", vmid)), &all); err != nil {
+		return nil, fmt.Errorf("listing snapshots of VM %d: %w", vmid, err)
+	}
+
+	// "current" is the live state, not a snapshot
+	snapshots := make([]SnapshotInfo, 0, len(all))
+	for _, s := range all {
+		if s.Name != "current" {
+			snapshots = append(snapshots, s)
+		}
+	}
+	sort.Slice(snapshots, func(i, j int) bool {
+		return snapshots[i].SnapTime < snapshots[j].SnapTime
+	})
+	return snapshots, nil
+}
+
+// RollbackSnapshot reverts a VM to a snapshot, discarding changes made since
+func (c *VMCreator) RollbackSnapshot(vmid int, name string) error {
+	if err := ValidateSnapshotName(name); err != nil {
+		return err
+	}
+	return c.runQuiet(c.onNode(vmid, fmt.Sprintf("qm rollback %d %s", vmid, ssh.ShellEscape(name))))
+}
+
+// WaitForTask polls a Proxmox task (clone, disk import, backup) until it
+// stops, streaming its log to the optional log callback
+func (c *VMCreator) WaitForTask(node, upid string, log func(string)) error {
+	return waitForTask(c.client, node, upid, taskWait{}, log)
+}
+
+// clusterTask is an entry from /cluster/tasks
+type clusterTask struct {
+	UPID      string `json:"upid"`
+	Type      string `json:"type"`
+	ID        string `json:"id"`
+	Status    string `json:"status"`
+	StartTime int64  `json:"starttime"`
+}
+
+// GetStartFailureReason returns the error Proxmox recorded for the most recent
+// failed qmstart task of a VM, or "" if none is found
+func (c *VMCreator) GetStartFailureReason(vmid int) string {
+	var tasks []clusterTask
+	if err := c.runJSON("pvesh get /cluster/tasks --output-format json", &tasks); err != nil {
+		return ""
+	}
+
+	var latest *clusterTask
+	for i := range tasks {
+		t := &tasks[i]
+		if t.Type != "qmstart" || t.ID != strconv.Itoa(vmid) {
+			continue
+		}
+		if latest == nil || t.StartTime > latest.StartTime {
+			latest = t
+		}
+	}
+
+	if latest == nil || latest.Status == "" || latest.Status == "OK" {
+		return ""
+	}
+	return latest.Status
+}
+
 // GetConsoleURL returns the URL for VM console access
 func (c *VMCreator) GetConsoleURL(vmid int, host string) string {
 	return fmt.Sprintf("https://%s:8006/#v1:0:qemu/%d", host, vmid)
 }
 
+// VMName returns the name of a component's VM: prefix-component, with a
+// 1-based instance suffix when the component has more than one
+func VMName(prefix string, comp config.ComponentConfig, index int) string {
+	name := fmt.Sprintf("%s-%s", prefix, comp.Type)
+	if index > 0 || comp.Count > 1 {
+		name = fmt.Sprintf("%s-%d", name, index+1)
+	}
+	return name
+}
+
 // BuildVMConfigForComponent creates a VMConfig for a Versa component
 func BuildVMConfigForComponent(
 	comp config.ComponentConfig,
@@ -209,11 +798,7 @@ func BuildVMConfigForComponent(
 	networks []VMNetwork,
 	vmid int,
 ) VMConfig {
-	// Build name
-	name := fmt.Sprintf("%s-%s", prefix, comp.Type)
-	if index > 0 || comp.Count > 1 {
-		name = fmt.Sprintf("%s-%d", name, index+1)
-	}
+	name := VMName(prefix, comp, index)
 
 	// Build tags
 	tags := []string{
@@ -224,31 +809,86 @@ func BuildVMConfigForComponent(
 	if comp.Count > 1 {
 		tags = append(tags, fmt.Sprintf("versa-ha-%d", index+1))
 	}
+	if comp.Version != "" {
+		tags = append(tags, config.VersionTag(comp.Version))
+	}
+	tags = append(tags, config.ToolVersionTag(config.ToolVersion))
+
+	// HA instances get consecutive static addresses
+	var cloudInit *config.CloudInitConfig
+	if comp.CloudInit != nil {
+		ci := *comp.CloudInit
+		ci.IP = offsetCIDR(ci.IP, index)
+		cloudInit = &ci
+	}
 
 	// Build description
 	spec := config.DefaultVMSpecs[comp.Type]
+	diskBus := comp.DiskBus
+	if diskBus == "" {
+		diskBus = spec.DiskBus
+	}
+	bios := comp.BIOS
+	if bios == "" {
+		bios = spec.BIOS
+	}
+	machine := comp.Machine
+	if machine == "" {
+		machine = spec.Machine
+	}
+	var extraDisks []ExtraDisk
+	if size := comp.DataDisk(); size > 0 {
+		extraDisks = append(extraDisks, ExtraDisk{Storage: storage, SizeGB: size, Index: 1})
+	}
 	description := spec.Description
 	if comp.Version != "" {
 		description += fmt.Sprintf(" (v%s)", comp.Version)
 	}
 
 	return VMConfig{
-		VMID:        vmid,
-		Name:        name,
-		Description: description,
-		Node:        comp.Node,
-		CPUCores:    comp.CPU,
-		RAMGB:       comp.RAMGB,
-		DiskGB:      comp.DiskGB,
-		Storage:     storage,
-		ISOStorage:  isoStorage,
-		ISOFile:     comp.ISOPath,
-		Networks:    networks,
-		Tags:        tags,
-		OnBoot:      true,
+		VMID:         vmid,
+		Name:         name,
+		Description:  description,
+		Node:         comp.Node,
+		CPUCores:     comp.CPU,
+		RAMGB:        comp.RAMGB,
+		DiskGB:       comp.DiskGB,
+		Storage:      storage,
+		DiskBus:      diskBus,
+		ExtraDisks:   extraDisks,
+		BIOS:         bios,
+		Machine:      machine,
+		ISOStorage:   isoStorage,
+		ISOFile:      comp.ISOPath,
+		Networks:     networks,
+		Tags:         tags,
+		OnBoot:       true,
+		Hugepages:    comp.Hugepages,
+		NUMA:         comp.Hugepages != "",
+		Affinity:     comp.Affinity,
+		StartupOrder: spec.StartupOrder,
+		StartupDelay: spec.StartupDelay,
+		CloudInit:    cloudInit,
 	}
 }
 
+// offsetCIDR adds n to the address of an IPv4 CIDR, keeping the prefix.
+// Anything unparseable is returned unchanged for CreateVMCommand to reject.
+func offsetCIDR(cidr string, n int) string {
+	if cidr == "" || n == 0 {
+		return cidr
+	}
+	ip, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil || ip.To4() == nil {
+		return cidr
+	}
+	ones, _ := ipNet.Mask.Size()
+	v4 := ip.To4()
+	addr := uint32(v4[0])<<24 | uint32(v4[1])<<16 | uint32(v4[2])<<8 | uint32(v4[3])
+	addr += uint32(n)
+	return fmt.Sprintf("%d.%d.%d.%d/%d", byte(addr>>24), byte(addr>>16), byte(addr>>8), byte(addr), ones)
+}
+
 // taggedNetwork pairs a VMNetwork with a stable ID for reordering.
 type taggedNetwork struct {
 	id  string
@@ -300,7 +940,8 @@ func BuildNetworksForComponent(
 
 	case config.ComponentAnalytics:
 		addBase(netConfig.NorthboundBridge, netConfig.NorthboundVLAN, string(NetworkNorthbound))
-		addBase(netConfig.DirectorRouterBridge, netConfig.DirectorRouterVLAN, string(NetworkAnalyticsSouthbound))
+		southBridge, southVLAN := netConfig.AnalyticsSouthbound()
+		addBase(southBridge, southVLAN, string(NetworkAnalyticsSouthbound))
 		if netConfig.AnalyticsClusterBridge != "" {
 			addExtra(0, netConfig.AnalyticsClusterBridge, netConfig.AnalyticsClusterVLAN, string(NetworkAnalyticsCluster))
 		}
@@ -366,10 +1007,25 @@ func BuildNetworksForComponent(
 	networks := make([]VMNetwork, len(tagged))
 	for i, t := range tagged {
 		networks[i] = t.net
+		networks[i].MTU = mtuForNetwork(netConfig, t.net.Name)
 	}
 	return networks
 }
 
+// mtuForNetwork looks up an interface's MTU by its exact name (e.g.
+// "controller-wan-2"), then by its purpose with any index stripped
+func mtuForNetwork(netConfig config.NetworkConfig, name string) int {
+	if mtu, ok := netConfig.MTU[name]; ok {
+		return mtu
+	}
+	if i := strings.LastIndex(name, "-"); i > 0 {
+		if _, err := strconv.Atoi(name[i+1:]); err == nil {
+			return netConfig.MTU[name[:i]]
+		}
+	}
+	return 0
+}
+
 // AddRouterHANetwork adds the HA synchronization network to a Router's network config
 func AddRouterHANetwork(networks []VMNetwork, bridge string, vlan int) []VMNetwork {
 	return append(networks, VMNetwork{
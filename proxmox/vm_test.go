@@ -0,0 +1,61 @@
+package proxmox
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/config"
+)
+
+func TestCreateVMCommandCloudInitSecrets(t *testing.T) {
+	cfg := VMConfig{
+		VMID:     101,
+		Name:     "lab-director-1",
+		CPUCores: 4,
+		RAMGB:    8,
+		DiskGB:   80,
+		Storage:  "local-lvm",
+		CloudInit: &config.CloudInitConfig{
+			User:     "admin",
+			Password: "s3cret",
+			SSHKeys:  []string{"ssh-ed25519 AAAA admin@lab"},
+		},
+	}
+	cmd, err := CreateVMCommand(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(cmd, "s3cret") {
+		t.Errorf("command carries the plain cloud-init password: %s", cmd)
+	}
+	if !strings.Contains(cmd, "--cipassword '$6$") {
+		t.Errorf("command has no hashed --cipassword: %s", cmd)
+	}
+	if strings.Contains(cmd, "/tmp/") || !strings.Contains(cmd, "$(mktemp)") {
+		t.Errorf("SSH keys not staged in a mktemp file: %s", cmd)
+	}
+	if !strings.Contains(cmd, `--sshkeys "$sshkeys"`) || !strings.Contains(cmd, `rm -f "$sshkeys"`) {
+		t.Errorf("staged SSH keys not passed to qm and removed: %s", cmd)
+	}
+}
+
+func TestIsVMIDExistsError(t *testing.T) {
+	tests := []struct {
+		stderr string
+		vmid   int
+		want   bool
+	}{
+		{"VM 105 already exists", 105, true},
+		{"unable to create VM 105 - VM 105 already exists on node 'pve2'", 105, true},
+		{"command failed (exit 255): unable to create VM 105 - VM 105 already exists on node 'pve1'", 105, true},
+		{"VM 1050 already exists", 105, false},
+		{"VM 105 already exists", 106, false},
+		{"storage 'local-lvm' does not exist", 105, false},
+	}
+	for _, tt := range tests {
+		if got := isVMIDExistsError(errors.New(tt.stderr), tt.vmid); got != tt.want {
+			t.Errorf("isVMIDExistsError(%q, %d) = %v, want %v", tt.stderr, tt.vmid, got, tt.want)
+		}
+	}
+}
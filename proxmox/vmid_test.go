@@ -0,0 +1,60 @@
+package proxmox
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/ssh/sshtest"
+)
+
+func TestGetNextVMIDConcurrent(t *testing.T) {
+	const taken = 102
+	client := sshtest.NewClient(t, func(cmd string) (string, int) {
+		switch cmd {
+		case "pvesh get /cluster/nextid":
+			// Proxmox returns the same ID until a VM occupies it
+			return "100\n", 0
+		case "pvesh get /cluster/nextid --vmid 102":
+			return "", 2
+		}
+		return "", 0
+	})
+	d := NewDiscoverer(client)
+
+	const n = 20
+	ids := make(chan int, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			vmid, err := d.GetNextVMID()
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			ids <- vmid
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[int]bool)
+	for vmid := range ids {
+		if seen[vmid] {
+			t.Errorf("VMID %d handed out twice", vmid)
+		}
+		if vmid == taken {
+			t.Errorf("VMID %d is in use on the cluster", vmid)
+		}
+		seen[vmid] = true
+	}
+	if len(seen) != n {
+		t.Errorf("got %d distinct VMIDs, want %d", len(seen), n)
+	}
+
+	d.ReleaseVMID(105)
+	if vmid, err := d.GetNextVMID(); err != nil || vmid != 105 {
+		t.Errorf("GetNextVMID after releasing 105 = %d, %v; want 105", vmid, err)
+	}
+}
@@ -0,0 +1,54 @@
+package proxmox
+
+import "github.com/mihailvovk/versa-proxmox-deployer/ssh"
+
+// SetLocalNode names the node the client is connected to. qm only manages
+// VMs on its own node, so VMs created on any other node are created and
+// managed there over the cluster's SSH trust.
+func (c *VMCreator) SetLocalNode(node string) {
+	c.nodesMu.Lock()
+	defer c.nodesMu.Unlock()
+	c.localNode = node
+}
+
+// setVMNode records the node a VM was created on
+func (c *VMCreator) setVMNode(vmid int, node string) {
+	c.nodesMu.Lock()
+	defer c.nodesMu.Unlock()
+	if c.vmNodes == nil {
+		c.vmNodes = make(map[int]string)
+	}
+	c.vmNodes[vmid] = node
+}
+
+// nodeCommand returns cmd to run on node: over SSH from the connected host
+// when node is another cluster node, unchanged when it is the connected
+// node or either is unknown
+func (c *VMCreator) nodeCommand(node, cmd string) (string, error) {
+	c.nodesMu.Lock()
+	local := c.localNode
+	c.nodesMu.Unlock()
+
+	if node == "" || local == "" || node == local {
+		return cmd, nil
+	}
+	if err := ValidateNodeName(node); err != nil {
+		return "", err
+	}
+	return nodeSSH + " root@" + ssh.ShellEscape(node) + " " + ssh.ShellEscape(cmd), nil
+}
+
+// onNode returns a qm command for a VM to run on the node it was created
+// on. VMs this creator didn't create are managed on the connected node.
+func (c *VMCreator) onNode(vmid int, cmd string) string {
+	c.nodesMu.Lock()
+	node := c.vmNodes[vmid]
+	c.nodesMu.Unlock()
+
+	// Recorded nodes were validated when the VM was created
+	remote, err := c.nodeCommand(node, cmd)
+	if err != nil {
+		return cmd
+	}
+	return remote
+}
@@ -0,0 +1,105 @@
+package sources
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ChecksumOrigin is where an ISO's checksum may come from
+type ChecksumOrigin string
+
+const (
+	OriginSidecar  ChecksumOrigin = "sidecar"  // Companion .sha256/.md5 file next to the ISO
+	OriginHeader   ChecksumOrigin = "header"   // Server-provided Content-MD5, or an S3 ETag
+	OriginComputed ChecksumOrigin = "computed" // MD5 computed locally after download
+)
+
+// DefaultChecksumOrder tries the most trustworthy origin first
+var DefaultChecksumOrder = []ChecksumOrigin{OriginSidecar, OriginHeader, OriginComputed}
+
+// ParseChecksumOrder parses a configured checksum origin preference. An
+// empty list means DefaultChecksumOrder.
+func ParseChecksumOrder(names []string) ([]ChecksumOrigin, error) {
+	if len(names) == 0 {
+		return DefaultChecksumOrder, nil
+	}
+	order := make([]ChecksumOrigin, 0, len(names))
+	seen := make(map[ChecksumOrigin]bool)
+	for _, name := range names {
+		origin := ChecksumOrigin(strings.ToLower(strings.TrimSpace(name)))
+		switch origin {
+		case OriginSidecar, OriginHeader, OriginComputed:
+		default:
+			return nil, fmt.Errorf("unknown checksum source %q (expected sidecar, header or computed)", name)
+		}
+		if !seen[origin] {
+			seen[origin] = true
+			order = append(order, origin)
+		}
+	}
+	return order, nil
+}
+
+// HeaderChecksummer is implemented by sources whose servers can report an
+// ISO's MD5 in response headers, without downloading it
+type HeaderChecksummer interface {
+	HeaderMD5(iso ISOFile) (string, error)
+}
+
+var md5HexPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// headMD5 sends a HEAD request for url and returns the MD5 its headers report
+func headMD5(rt http.RoundTripper, url string, etagIsMD5 bool) (string, error) {
+	client := &http.Client{
+		Transport: rt,
+		Timeout:   30 * time.Second,
+	}
+
+	resp, err := client.Head(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HEAD request failed with status %d", resp.StatusCode)
+	}
+
+	if sum := md5FromHeaders(resp.Header, etagIsMD5); sum != "" {
+		return sum, nil
+	}
+	return "", fmt.Errorf("server reported no MD5")
+}
+
+// md5FromHeaders extracts a hex MD5 from a base64 Content-MD5 header or,
+// when etagIsMD5, from an S3 ETag. Multipart-upload ETags ("<hash>-<parts>")
+// and the ETags of SSE-KMS and SSE-C encrypted objects are not the object's
+// MD5 and are ignored.
+func md5FromHeaders(h http.Header, etagIsMD5 bool) string {
+	if v := h.Get("Content-MD5"); v != "" {
+		if raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(v)); err == nil && len(raw) == 16 {
+			return hex.EncodeToString(raw)
+		}
+	}
+	if etagIsMD5 && !encryptedETag(h) {
+		etag := strings.ToLower(strings.Trim(h.Get("ETag"), `"`))
+		if md5HexPattern.MatchString(etag) {
+			return etag
+		}
+	}
+	return ""
+}
+
+// encryptedETag reports whether S3 encrypted the object with a KMS or
+// customer-provided key, which gives it an ETag that isn't its MD5
+func encryptedETag(h http.Header) bool {
+	if strings.HasPrefix(strings.ToLower(h.Get("X-Amz-Server-Side-Encryption")), "aws:kms") {
+		return true
+	}
+	return h.Get("X-Amz-Server-Side-Encryption-Customer-Algorithm") != ""
+}
@@ -0,0 +1,34 @@
+package sources
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestMD5FromHeaders(t *testing.T) {
+	const sum = "9e107d9d372bb6826bd81d3542a419d6"
+	tests := []struct {
+		name      string
+		header    map[string]string
+		etagIsMD5 bool
+		want      string
+	}{
+		{"content-md5", map[string]string{"Content-MD5": "nhB9nTcrtoJr2B01QqQZ1g=="}, false, sum},
+		{"s3 etag", map[string]string{"ETag": `"` + sum + `"`}, true, sum},
+		{"etag from a plain http server", map[string]string{"ETag": `"` + sum + `"`}, false, ""},
+		{"multipart etag", map[string]string{"ETag": `"` + sum + `-12"`}, true, ""},
+		{"sse-s3 etag", map[string]string{"ETag": `"` + sum + `"`, "X-Amz-Server-Side-Encryption": "AES256"}, true, sum},
+		{"sse-kms etag", map[string]string{"ETag": `"` + sum + `"`, "X-Amz-Server-Side-Encryption": "aws:kms"}, true, ""},
+		{"dsse-kms etag", map[string]string{"ETag": `"` + sum + `"`, "X-Amz-Server-Side-Encryption": "aws:kms:dsse"}, true, ""},
+		{"sse-c etag", map[string]string{"ETag": `"` + sum + `"`, "X-Amz-Server-Side-Encryption-Customer-Algorithm": "AES256"}, true, ""},
+	}
+	for _, tt := range tests {
+		h := make(http.Header)
+		for k, v := range tt.header {
+			h.Set(k, v)
+		}
+		if got := md5FromHeaders(h, tt.etagIsMD5); got != tt.want {
+			t.Errorf("%s: md5FromHeaders = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
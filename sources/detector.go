@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/mihailvovk/versa-proxmox-deployer/config"
 	"github.com/mihailvovk/versa-proxmox-deployer/ssh"
@@ -73,7 +74,12 @@ func CreateSource(src config.ImageSource) (ImageSource, error) {
 		return NewDropboxSource(src.URL, name), nil
 
 	case SourceTypeHTTP:
-		return NewHTTPSource(src.URL, name), nil
+		httpSrc := NewHTTPSource(src.URL, name)
+		if src.Password != "" {
+			httpSrc.SetCredentials(src.Username, src.Password)
+		}
+		httpSrc.SetHeaders(src.Headers)
+		return httpSrc, nil
 
 	case SourceTypeS3:
 		return NewS3Source(src.URL, name)
@@ -120,7 +126,29 @@ func CreateSourcesFromConfig(cfg *config.Config) ([]ImageSource, error) {
 	return sources, nil
 }
 
-// TestSourceConnection tests if a source is accessible
+// RecordScanResults stores each configured source's outcome from collection
+// in cfg. Summaries are matched through the source built from each config
+// entry, since sources may normalize their URL; a config entry that can't be
+// built is recorded as failed.
+func RecordScanResults(cfg *config.Config, collection *ISOCollection) {
+	now := time.Now().UTC()
+	for _, src := range cfg.ImageSources {
+		source, err := CreateSource(src)
+		if err != nil {
+			cfg.RecordSourceScan(src.URL, 0, fmt.Sprintf("invalid source: %v", err), now)
+			continue
+		}
+		for _, summary := range collection.Sources {
+			if summary.URL == source.URL() {
+				cfg.RecordSourceScan(src.URL, summary.ISOCount, summary.Error, now)
+				break
+			}
+		}
+	}
+}
+
+// TestSourceConnection tests if a source is accessible by listing it, with
+// the same credentials and headers scans and downloads use
 func TestSourceConnection(source ImageSource) error {
 	_, err := source.List()
 	return err
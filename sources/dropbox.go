@@ -92,8 +92,9 @@ func (s *DropboxSource) List() ([]ISOFile, error) {
 	}
 
 	client := &http.Client{
-		Jar:     jar,
-		Timeout: 30 * time.Second,
+		Transport: transport(),
+		Jar:       jar,
+		Timeout:   30 * time.Second,
 	}
 
 	req, err := http.NewRequest("GET", s.url, nil)
@@ -168,16 +169,18 @@ func (s *DropboxSource) List() ([]ISOFile, error) {
 	}
 
 	// Step 3: Process entries into ISOFile list
-	md5Files := make(map[string]dropboxFolderEntry) // ISO filename -> MD5 entry
+	checksumFiles := make(map[string]map[ChecksumAlgo]dropboxFolderEntry) // ISO filename -> checksum entries
 	var isoEntries []dropboxFolderEntry
 
 	for _, entry := range folderResp.Entries {
 		if entry.IsDir {
 			continue
 		}
-		if IsMD5File(entry.Filename) {
-			isoName := GetISOForMD5(entry.Filename)
-			md5Files[isoName] = entry
+		if isoName, algo, ok := ParseChecksumFilename(entry.Filename); ok {
+			if checksumFiles[isoName] == nil {
+				checksumFiles[isoName] = make(map[ChecksumAlgo]dropboxFolderEntry)
+			}
+			checksumFiles[isoName][algo] = entry
 		} else if IsISOFile(entry.Filename) {
 			isoEntries = append(isoEntries, entry)
 		}
@@ -189,9 +192,8 @@ func (s *DropboxSource) List() ([]ISOFile, error) {
 		iso.Size = entry.Bytes
 		iso.SourceURL = buildFileDownloadURL(entry.Href)
 
-		if md5Entry, ok := md5Files[entry.Filename]; ok {
-			iso.HasMD5File = true
-			iso.MD5FileURL = buildFileDownloadURL(md5Entry.Href)
+		for algo, sumEntry := range checksumFiles[entry.Filename] {
+			iso.SetChecksumFile(algo, buildFileDownloadURL(sumEntry.Href))
 		}
 
 		isos = append(isos, iso)
@@ -223,7 +225,8 @@ func (s *DropboxSource) Download(iso ISOFile, destPath string, progress func(dow
 	}
 
 	client := &http.Client{
-		Timeout: 0, // No timeout for large downloads
+		Transport: transport(),
+		Timeout:   0, // No timeout for large downloads
 	}
 
 	resp, err := client.Get(downloadURL)
@@ -277,41 +280,22 @@ func (s *DropboxSource) Download(iso ISOFile, destPath string, progress func(dow
 		}
 	}
 
-	return nil
+	return syncAndClose(dst)
 }
 
 // DownloadMD5 downloads the MD5 file for an ISO
 func (s *DropboxSource) DownloadMD5(iso ISOFile) (string, error) {
-	if !iso.HasMD5File || iso.MD5FileURL == "" {
-		return "", fmt.Errorf("no MD5 file available")
-	}
-
-	client := &http.Client{
-		Timeout: 30 * time.Second,
-	}
-
-	resp, err := client.Get(iso.MD5FileURL)
-	if err != nil {
-		return "", fmt.Errorf("downloading MD5: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("MD5 download failed with status %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("reading MD5: %w", err)
-	}
+	return s.DownloadChecksum(iso, ChecksumMD5)
+}
 
-	content := strings.TrimSpace(string(body))
-	parts := strings.Fields(content)
-	if len(parts) < 1 {
-		return "", fmt.Errorf("invalid MD5 file format")
+// DownloadChecksum downloads an ISO's companion checksum file. Dropbox
+// links can't be derived from the ISO's, so only listed files are fetched.
+func (s *DropboxSource) DownloadChecksum(iso ISOFile, algo ChecksumAlgo) (string, error) {
+	sumURL := iso.ChecksumFileURL(algo)
+	if sumURL == "" {
+		return "", fmt.Errorf("no %s file available", strings.ToUpper(string(algo)))
 	}
-
-	return strings.ToLower(parts[0]), nil
+	return fetchChecksumFile(transport(), sumURL, algo)
 }
 
 func truncate(s string, maxLen int) string {
@@ -1,11 +1,11 @@
 package sources
 
 import (
+	"encoding/base64"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -14,8 +14,9 @@ import (
 
 // HTTPSource represents an HTTP/HTTPS directory source for ISOs
 type HTTPSource struct {
-	name string
-	url  string
+	name   string
+	url    string
+	header http.Header // Authorization and custom headers sent with every request
 }
 
 // NewHTTPSource creates a new HTTP source
@@ -31,6 +32,71 @@ func NewHTTPSource(urlStr, name string) *HTTPSource {
 	}
 }
 
+// SetCredentials authenticates requests with HTTP Basic Auth, or with a
+// bearer token when username is empty
+func (s *HTTPSource) SetCredentials(username, password string) {
+	if username == "" {
+		s.setHeader("Authorization", "Bearer "+password)
+		return
+	}
+	basic := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	s.setHeader("Authorization", "Basic "+basic)
+}
+
+// SetHeaders adds custom headers to every request, e.g. an API token
+func (s *HTTPSource) SetHeaders(headers map[string]string) {
+	for k, v := range headers {
+		s.setHeader(k, v)
+	}
+}
+
+func (s *HTTPSource) setHeader(key, value string) {
+	if s.header == nil {
+		s.header = make(http.Header)
+	}
+	s.header.Set(key, value)
+}
+
+// transport returns the shared transport, adding the source's headers
+func (s *HTTPSource) transport() http.RoundTripper {
+	if len(s.header) == 0 {
+		return transport()
+	}
+	host := ""
+	if u, err := url.Parse(s.url); err == nil {
+		host = u.Host
+	}
+	return &headerTransport{base: transport(), header: s.header, host: host}
+}
+
+// headerTransport sets fixed headers on requests to the source's own host.
+// It sits below http.Client's redirect handling, so it must not add them to
+// a redirect elsewhere, e.g. a presigned S3 or CDN URL: that would leak the
+// credentials and break the redirect's own authentication.
+type headerTransport struct {
+	base   http.RoundTripper
+	header http.Header
+	host   string
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if req.URL.Host != t.host {
+		return base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	for k, v := range t.header {
+		if req.Header.Get(k) == "" {
+			req.Header[k] = v
+		}
+	}
+	return base.RoundTrip(req)
+}
+
 // Name returns the source name
 func (s *HTTPSource) Name() string {
 	return s.name
@@ -63,7 +129,8 @@ func (s *HTTPSource) listRecursive(baseURL string, visited map[string]bool, maxD
 	visited[baseURL] = true
 
 	client := &http.Client{
-		Timeout: 30 * time.Second,
+		Transport: s.transport(),
+		Timeout:   30 * time.Second,
 	}
 
 	resp, err := client.Get(baseURL)
@@ -72,6 +139,9 @@ func (s *HTTPSource) listRecursive(baseURL string, visited map[string]bool, maxD
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return nil, fmt.Errorf("server returned status %d (check the source's username, password or headers)", resp.StatusCode)
+	}
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("server returned status %d", resp.StatusCode)
 	}
@@ -104,7 +174,7 @@ func (s *HTTPSource) parseDirectoryListing(html string) ([]ISOFile, error) {
 func (s *HTTPSource) parseDirectoryListingWithDirs(html string, baseURL string) ([]ISOFile, []string) {
 	var isos []ISOFile
 	var subdirs []string
-	md5Files := make(map[string]bool)
+	checksumFiles := make(map[string][]ChecksumAlgo) // ISO filename -> companion files
 
 	// Ensure baseURL ends with /
 	if !strings.HasSuffix(baseURL, "/") {
@@ -148,8 +218,8 @@ func (s *HTTPSource) parseDirectoryListingWithDirs(html string, baseURL string)
 		// Get just the filename
 		filename := filepath.Base(href)
 
-		if IsMD5File(filename) {
-			md5Files[GetISOForMD5(filename)] = true
+		if isoName, algo, ok := ParseChecksumFilename(filename); ok {
+			checksumFiles[isoName] = append(checksumFiles[isoName], algo)
 			continue
 		}
 
@@ -166,15 +236,15 @@ func (s *HTTPSource) parseDirectoryListingWithDirs(html string, baseURL string)
 		fileURL := baseURL + href
 
 		iso := ParseISOFilename(filename, s.name, s.Type(), fileURL)
+		iso.RequiresAuth = len(s.header) > 0
 
 		isos = append(isos, iso)
 	}
 
-	// Update MD5 status for found ISOs
+	// Update checksum file status for found ISOs
 	for i := range isos {
-		if md5Files[isos[i].Filename] {
-			isos[i].HasMD5File = true
-			isos[i].MD5FileURL = baseURL + isos[i].Filename + ".md5"
+		for _, algo := range checksumFiles[isos[i].Filename] {
+			isos[i].SetChecksumFile(algo, baseURL+isos[i].Filename+algo.Suffix())
 		}
 	}
 
@@ -183,67 +253,23 @@ func (s *HTTPSource) parseDirectoryListingWithDirs(html string, baseURL string)
 
 // Download downloads an ISO from HTTP
 func (s *HTTPSource) Download(iso ISOFile, destPath string, progress func(downloaded, total int64)) error {
+	_, err := s.DownloadResumable(iso, destPath, progress)
+	return err
+}
+
+// DownloadResumable downloads an ISO, resuming an earlier partial download
+// when the server supports byte ranges
+func (s *HTTPSource) DownloadResumable(iso ISOFile, destPath string, progress func(downloaded, total int64)) (int64, error) {
 	downloadURL := iso.SourceURL
 	if downloadURL == "" {
 		downloadURL = s.url + iso.Filename
 	}
 
 	client := &http.Client{
-		Timeout: 0, // No timeout for large downloads
+		Transport: s.transport(),
+		Timeout:   0, // No timeout for large downloads
 	}
-
-	resp, err := client.Get(downloadURL)
-	if err != nil {
-		return fmt.Errorf("starting download: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download failed with status %d", resp.StatusCode)
-	}
-
-	totalSize := resp.ContentLength
-
-	// Ensure destination directory exists
-	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
-		return fmt.Errorf("creating destination directory: %w", err)
-	}
-
-	// Create destination file
-	dst, err := os.Create(destPath)
-	if err != nil {
-		return fmt.Errorf("creating destination file: %w", err)
-	}
-	defer dst.Close()
-
-	// Copy with progress
-	buf := make([]byte, 32*1024)
-	var downloaded int64
-
-	for {
-		n, err := resp.Body.Read(buf)
-		if n > 0 {
-			nw, werr := dst.Write(buf[:n])
-			if werr != nil {
-				return fmt.Errorf("writing: %w", werr)
-			}
-			if nw != n {
-				return fmt.Errorf("short write")
-			}
-			downloaded += int64(nw)
-			if progress != nil {
-				progress(downloaded, totalSize)
-			}
-		}
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return fmt.Errorf("reading: %w", err)
-		}
-	}
-
-	return nil
+	return downloadHTTPResumable(client, downloadURL, destPath, iso.Size, progress)
 }
 
 // GetFileSize gets the size of a file via HEAD request
@@ -251,7 +277,8 @@ func (s *HTTPSource) GetFileSize(filename string) (int64, error) {
 	fileURL := s.url + filename
 
 	client := &http.Client{
-		Timeout: 30 * time.Second,
+		Transport: s.transport(),
+		Timeout:   30 * time.Second,
 	}
 
 	resp, err := client.Head(fileURL)
@@ -269,36 +296,49 @@ func (s *HTTPSource) GetFileSize(filename string) (int64, error) {
 
 // DownloadMD5 downloads the MD5 file for an ISO
 func (s *HTTPSource) DownloadMD5(iso ISOFile) (string, error) {
-	md5URL := iso.MD5FileURL
-	if md5URL == "" {
-		md5URL = s.url + iso.Filename + ".md5"
+	return s.DownloadChecksum(iso, ChecksumMD5)
+}
+
+// DownloadChecksum downloads an ISO's companion checksum file
+func (s *HTTPSource) DownloadChecksum(iso ISOFile, algo ChecksumAlgo) (string, error) {
+	sumURL := iso.ChecksumFileURL(algo)
+	if sumURL == "" {
+		sumURL = s.url + iso.Filename + algo.Suffix()
 	}
+	return fetchChecksumFile(s.transport(), sumURL, algo)
+}
+
+// HeaderMD5 returns the MD5 the server reports in a Content-MD5 header
+func (s *HTTPSource) HeaderMD5(iso ISOFile) (string, error) {
+	fileURL := iso.SourceURL
+	if fileURL == "" {
+		fileURL = s.url + iso.Filename
+	}
+	return headMD5(s.transport(), fileURL, false)
+}
 
+// fetchChecksumFile downloads and parses a companion checksum file over HTTP
+func fetchChecksumFile(rt http.RoundTripper, sumURL string, algo ChecksumAlgo) (string, error) {
+	name := strings.ToUpper(string(algo))
 	client := &http.Client{
-		Timeout: 30 * time.Second,
+		Transport: rt,
+		Timeout:   30 * time.Second,
 	}
 
-	resp, err := client.Get(md5URL)
+	resp, err := client.Get(sumURL)
 	if err != nil {
-		return "", fmt.Errorf("downloading MD5: %w", err)
+		return "", fmt.Errorf("downloading %s: %w", name, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("MD5 download failed with status %d", resp.StatusCode)
+		return "", fmt.Errorf("%s download failed with status %d", name, resp.StatusCode)
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("reading MD5: %w", err)
-	}
-
-	// Parse MD5 value
-	content := strings.TrimSpace(string(body))
-	parts := strings.Fields(content)
-	if len(parts) < 1 {
-		return "", fmt.Errorf("invalid MD5 file format")
+		return "", fmt.Errorf("reading %s: %w", name, err)
 	}
 
-	return strings.ToLower(parts[0]), nil
+	return ParseChecksumFile(body, algo)
 }
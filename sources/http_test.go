@@ -0,0 +1,37 @@
+package sources
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPSourceHeadersStayOnSourceHost(t *testing.T) {
+	var cdnAuth, cdnToken string
+	cdn := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cdnAuth, cdnToken = r.Header.Get("Authorization"), r.Header.Get("X-Api-Token")
+		w.Write([]byte("d41d8cd98f00b204e9800998ecf8427e  director.iso\n"))
+	}))
+	defer cdn.Close()
+
+	var srcAuth string
+	src := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		srcAuth = r.Header.Get("Authorization")
+		http.Redirect(w, r, cdn.URL+"/signed/director.iso.md5", http.StatusFound)
+	}))
+	defer src.Close()
+
+	s := NewHTTPSource(src.URL, "artifacts")
+	s.SetCredentials("deploy", "s3cret")
+	s.SetHeaders(map[string]string{"X-Api-Token": "abc"})
+
+	if _, err := s.DownloadMD5(ISOFile{Filename: "director.iso"}); err != nil {
+		t.Fatal(err)
+	}
+	if srcAuth == "" {
+		t.Error("source request carried no Authorization header")
+	}
+	if cdnAuth != "" || cdnToken != "" {
+		t.Errorf("redirect to another host carried Authorization %q, X-Api-Token %q", cdnAuth, cdnToken)
+	}
+}
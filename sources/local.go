@@ -2,9 +2,9 @@ package sources
 
 import (
 	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/mihailvovk/versa-proxmox-deployer/config"
 )
@@ -40,8 +40,8 @@ func (s *LocalSource) URL() string {
 
 // List returns all ISO files in the local directory (recursive)
 func (s *LocalSource) List() ([]ISOFile, error) {
-	// First pass: collect all MD5 files recursively
-	md5Files := make(map[string]string) // ISO name -> MD5 file path
+	// First pass: collect all checksum files recursively
+	checksumFiles := make(map[string]map[ChecksumAlgo]string) // ISO name -> checksum file paths
 
 	err := filepath.WalkDir(s.path, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
@@ -50,14 +50,16 @@ func (s *LocalSource) List() ([]ISOFile, error) {
 		if d.IsDir() {
 			return nil
 		}
-		if IsMD5File(d.Name()) {
-			isoName := GetISOForMD5(d.Name())
-			md5Files[isoName] = path
+		if isoName, algo, ok := ParseChecksumFilename(d.Name()); ok {
+			if checksumFiles[isoName] == nil {
+				checksumFiles[isoName] = make(map[ChecksumAlgo]string)
+			}
+			checksumFiles[isoName][algo] = path
 		}
 		return nil
 	})
 	if err != nil {
-		return nil, fmt.Errorf("walking directory for MD5 files: %w", err)
+		return nil, fmt.Errorf("walking directory for checksum files: %w", err)
 	}
 
 	// Second pass: collect ISOs recursively
@@ -84,14 +86,15 @@ func (s *LocalSource) List() ([]ISOFile, error) {
 		iso := ParseISOFilename(name, s.name, s.Type(), path)
 		iso.Size = info.Size()
 
-		// Check for MD5 file (can be in same directory or matched by full filename)
-		if md5Path, ok := md5Files[name]; ok {
-			iso.HasMD5File = true
-			iso.MD5FileURL = md5Path
+		// Check for checksum files (can be in same directory or matched by full filename)
+		for algo, sumPath := range checksumFiles[name] {
+			iso.SetChecksumFile(algo, sumPath)
 
-			// Read MD5 value
-			if md5, err := readMD5File(md5Path); err == nil {
-				iso.MD5 = md5
+			// Read the checksum value; a malformed file is ignored
+			if sum, err := readChecksumFile(sumPath, algo); err == nil {
+				iso.SetChecksum(algo, sum)
+			} else {
+				slog.Warn("Ignoring checksum file", "path", sumPath, "error", err)
 			}
 		}
 
@@ -137,19 +140,17 @@ func (s *LocalSource) Download(iso ISOFile, destPath string, progress func(downl
 
 // readMD5File reads an MD5 checksum from a .md5 file
 func readMD5File(path string) (string, error) {
+	return readChecksumFile(path, ChecksumMD5)
+}
+
+// readChecksumFile reads a checksum from a companion file
+func readChecksumFile(path string, algo ChecksumAlgo) (string, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return "", err
 	}
 
-	// Format: "checksum  filename" or just "checksum"
-	content := strings.TrimSpace(string(data))
-	parts := strings.Fields(content)
-	if len(parts) < 1 {
-		return "", fmt.Errorf("invalid MD5 file format")
-	}
-
-	return strings.ToLower(parts[0]), nil
+	return ParseChecksumFile(data, algo)
 }
 
 // GetISOPath returns the full path to an ISO
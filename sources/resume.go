@@ -0,0 +1,210 @@
+package sources
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PartialSuffix is appended to destPath while a resumable download is in
+// progress; a later download of the same destination resumes from it
+const PartialSuffix = ".part"
+
+// validatorSuffix is appended to the partial file's path for the ETag or
+// Last-Modified of the response it came from, sent as If-Range on resume so
+// a partial of an older object is never extended with a newer one
+const validatorSuffix = ".validator"
+
+// ResumableDownloader is implemented by sources that can continue an
+// interrupted download from its partial file
+type ResumableDownloader interface {
+	// DownloadResumable is Download that also returns how many bytes were
+	// already on disk from an earlier attempt
+	DownloadResumable(iso ISOFile, destPath string, progress func(downloaded, total int64)) (resumed int64, err error)
+}
+
+// downloadHTTPResumable downloads url into destPath+PartialSuffix, resuming
+// with a Range request when a partial file exists, and renames it to
+// destPath once complete. The resume is conditional on the object being
+// unchanged (If-Range), and a partial that doesn't line up with the server's
+// answer is discarded. Servers that ignore the Range header get a full
+// download; the partial file is only kept on failure when the server
+// advertises byte ranges and a validator. sizeHint is used when the response
+// has no length.
+func downloadHTTPResumable(client *http.Client, url, destPath string, sizeHint int64, progress func(downloaded, total int64)) (int64, error) {
+	partPath := destPath + PartialSuffix
+	validatorPath := partPath + validatorSuffix
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return 0, fmt.Errorf("creating destination directory: %w", err)
+	}
+	discard := func() {
+		os.Remove(partPath)
+		os.Remove(validatorPath)
+	}
+
+	var offset int64
+	var validator string
+	if info, err := os.Stat(partPath); err == nil && info.Mode().IsRegular() {
+		offset = info.Size()
+		data, _ := os.ReadFile(validatorPath)
+		validator = strings.TrimSpace(string(data))
+		if validator == "" {
+			// Nothing proves the partial belongs to the current object
+			discard()
+			offset = 0
+		}
+	}
+
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("starting download: %w", err)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", validator)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("starting download: %w", err)
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch {
+	case resp.StatusCode == http.StatusPartialContent:
+		if start := contentRangeStart(resp); offset == 0 || start != offset {
+			// Appending would corrupt the file; drop it so the next
+			// attempt starts clean
+			discard()
+			return 0, fmt.Errorf("server resumed at byte %d, expected %d (partial download discarded)", start, offset)
+		}
+		flags |= os.O_APPEND
+	case resp.StatusCode == http.StatusOK:
+		// Ranges not supported, the object changed, or no partial file:
+		// start over
+		offset = 0
+		flags |= os.O_TRUNC
+		validator = rangeValidator(resp)
+		if validator == "" {
+			os.Remove(validatorPath)
+		} else if err := os.WriteFile(validatorPath, []byte(validator+"\n"), 0644); err != nil {
+			return 0, fmt.Errorf("saving download validator: %w", err)
+		}
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// The partial file is no prefix of the current object; drop it so
+		// the next attempt starts clean
+		discard()
+		return 0, fmt.Errorf("download failed with status %d (partial download discarded)", resp.StatusCode)
+	default:
+		return 0, fmt.Errorf("download failed with status %d", resp.StatusCode)
+	}
+	resumable := validator != "" &&
+		(resp.StatusCode == http.StatusPartialContent || strings.EqualFold(resp.Header.Get("Accept-Ranges"), "bytes"))
+
+	totalSize := sizeHint
+	if resp.ContentLength > 0 {
+		totalSize = offset + resp.ContentLength
+	}
+
+	dst, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("creating destination file: %w", err)
+	}
+	defer dst.Close()
+
+	fail := func(err error) (int64, error) {
+		if !resumable {
+			dst.Close()
+			discard()
+		}
+		return offset, err
+	}
+
+	buf := make([]byte, 32*1024)
+	downloaded := offset
+	for {
+		n, err := resp.Body.Read(buf)
+		if n > 0 {
+			nw, werr := dst.Write(buf[:n])
+			if werr != nil {
+				return fail(fmt.Errorf("writing: %w", werr))
+			}
+			if nw != n {
+				return fail(fmt.Errorf("short write"))
+			}
+			downloaded += int64(nw)
+			if progress != nil {
+				progress(downloaded, totalSize)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fail(fmt.Errorf("reading: %w", err))
+		}
+	}
+
+	if err := syncAndClose(dst); err != nil {
+		return fail(err)
+	}
+	if err := os.Rename(partPath, destPath); err != nil {
+		return offset, fmt.Errorf("finishing download: %w", err)
+	}
+	if err := SyncDir(filepath.Dir(destPath)); err != nil {
+		return offset, fmt.Errorf("finishing download: %w", err)
+	}
+	os.Remove(validatorPath)
+	return offset, nil
+}
+
+// syncAndClose flushes a downloaded file to disk before closing it, so a
+// power loss can't leave a truncated image that looks complete
+func syncAndClose(f *os.File) error {
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("syncing: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("closing: %w", err)
+	}
+	return nil
+}
+
+// SyncDir flushes a directory's entries to disk, making a rename into it
+// durable
+func SyncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("syncing directory: %w", err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("syncing directory: %w", err)
+	}
+	return nil
+}
+
+// rangeValidator returns the value to send as If-Range when resuming from
+// resp: its strong ETag, else its Last-Modified date, else ""
+func rangeValidator(resp *http.Response) string {
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return resp.Header.Get("Last-Modified")
+}
+
+// contentRangeStart parses the first byte position of a 206 response's
+// "Content-Range: bytes start-end/total" header, or -1
+func contentRangeStart(resp *http.Response) int64 {
+	var start, end int64
+	var total string
+	if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"), "bytes %d-%d/%s", &start, &end, &total); err != nil {
+		return -1
+	}
+	return start
+}
@@ -0,0 +1,88 @@
+package sources
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// isoServer serves content with an ETag, honouring Range and If-Range
+func isoServer(content []byte, etag string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "director.iso", time.Time{}, bytes.NewReader(content))
+	}))
+}
+
+func TestDownloadHTTPResumable(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+
+	tests := []struct {
+		name        string
+		partial     []byte
+		validator   string // "" = no validator file
+		etag        string
+		wantResumed int64
+	}{
+		{"fresh", nil, "", `"v1"`, 0},
+		{"resume", content[:4000], `"v1"`, `"v1"`, 4000},
+		{"object changed", []byte("stale bytes of an older image"), `"v0"`, `"v1"`, 0},
+		{"partial without validator", content[:4000], "", `"v1"`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := isoServer(content, tt.etag)
+			defer srv.Close()
+
+			dest := filepath.Join(t.TempDir(), "director.iso")
+			if tt.partial != nil {
+				os.WriteFile(dest+PartialSuffix, tt.partial, 0644)
+			}
+			if tt.validator != "" {
+				os.WriteFile(dest+PartialSuffix+validatorSuffix, []byte(tt.validator), 0644)
+			}
+
+			resumed, err := downloadHTTPResumable(srv.Client(), srv.URL, dest, 0, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resumed != tt.wantResumed {
+				t.Errorf("resumed %d bytes, want %d", resumed, tt.wantResumed)
+			}
+			got, _ := os.ReadFile(dest)
+			if !bytes.Equal(got, content) {
+				t.Errorf("downloaded %d bytes that don't match the object", len(got))
+			}
+			for _, leftover := range []string{dest + PartialSuffix, dest + PartialSuffix + validatorSuffix} {
+				if _, err := os.Stat(leftover); err == nil {
+					t.Errorf("%s left behind", filepath.Base(leftover))
+				}
+			}
+		})
+	}
+}
+
+func TestDownloadHTTPResumableDiscardsMisalignedPartial(t *testing.T) {
+	// A server that answers every range from byte 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Range", "bytes 0-9/10")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte("0123456789"))
+	}))
+	defer srv.Close()
+
+	dest := filepath.Join(t.TempDir(), "director.iso")
+	os.WriteFile(dest+PartialSuffix, []byte("01234"), 0644)
+	os.WriteFile(dest+PartialSuffix+validatorSuffix, []byte(`"v1"`), 0644)
+
+	if _, err := downloadHTTPResumable(srv.Client(), srv.URL, dest, 0, nil); err == nil {
+		t.Fatal("expected an error for a misaligned 206")
+	}
+	if _, err := os.Stat(dest + PartialSuffix); err == nil {
+		t.Error("misaligned partial download was kept")
+	}
+}
@@ -6,12 +6,14 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"os"
 	"path/filepath"
 	"strings"
 	"time"
 )
 
+// defaultS3Region is used when the endpoint does not encode a region
+const defaultS3Region = "us-east-1"
+
 // S3Source represents an S3 bucket source for ISOs.
 // Works with public buckets — no credentials needed.
 type S3Source struct {
@@ -52,11 +54,7 @@ func NewS3Source(rawURL, name string) (*S3Source, error) {
 		if len(parts) > 1 {
 			s.prefix = strings.TrimSuffix(parts[1], "/")
 		}
-		s.region = "us-east-1" // default, will be resolved
-		s.baseURL = fmt.Sprintf("https://%s.s3.amazonaws.com/", s.bucket)
-		if s.prefix != "" {
-			s.baseURL += s.prefix + "/"
-		}
+		s.setRegion(defaultS3Region) // default, resolved on first list if the bucket lives elsewhere
 		s.bucketURL = s.baseURL
 		return s, nil
 	}
@@ -95,32 +93,60 @@ func NewS3Source(rawURL, name string) (*S3Source, error) {
 	}
 
 	// Normalize base URL for downloads (virtual-hosted style)
-	if s.region != "" {
-		s.baseURL = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/", s.bucket, s.region)
-	} else {
-		s.baseURL = fmt.Sprintf("https://%s.s3.amazonaws.com/", s.bucket)
-	}
-	if s.prefix != "" {
-		s.baseURL += s.prefix + "/"
-	}
+	s.setRegion(s.region)
 	s.bucketURL = rawURL
 
 	return s, nil
 }
 
+// extractS3Region returns the region encoded in an S3 hostname.
+// Handles regional (s3.us-west-2), dash-style (s3-us-west-2), dualstack
+// (s3.dualstack.us-west-2), website and legacy global (s3, s3-external-1)
+// endpoints. Labels are read from the right, so a bucket named like an
+// endpoint (s3-backups.s3.eu-west-1) isn't mistaken for one.
+// Hostnames without an explicit region resolve to us-east-1.
 func extractS3Region(host string) string {
-	// s3.us-west-2.amazonaws.com → us-west-2
-	// bucket.s3.us-west-2.amazonaws.com → us-west-2
-	parts := strings.Split(host, ".")
-	for i, p := range parts {
-		if (p == "s3" || strings.HasPrefix(p, "s3-")) && i+1 < len(parts) {
-			next := parts[i+1]
-			if next != "amazonaws" {
+	parts := strings.Split(strings.ToLower(host), ".")
+	for i := len(parts) - 1; i >= 0; i-- {
+		p := parts[i]
+		switch {
+		case p == "s3" || p == "s3-fips" || p == "s3-website":
+			// s3.us-west-2.amazonaws.com, s3.dualstack.us-west-2.amazonaws.com
+			for _, next := range parts[i+1:] {
+				if next == "dualstack" {
+					continue
+				}
+				if next == "amazonaws" {
+					return defaultS3Region
+				}
 				return next
 			}
+			return defaultS3Region
+		case p == "s3-external-1":
+			return defaultS3Region
+		case strings.HasPrefix(p, "s3-"):
+			// s3-us-west-2.amazonaws.com, bucket.s3-website-eu-west-1.amazonaws.com
+			region := strings.TrimPrefix(p, "s3-")
+			region = strings.TrimPrefix(region, "website-")
+			region = strings.TrimPrefix(region, "fips-")
+			if region != "" {
+				return region
+			}
 		}
 	}
-	return ""
+	return defaultS3Region
+}
+
+// setRegion updates the region and rebuilds the download base URL
+func (s *S3Source) setRegion(region string) {
+	if region == "" {
+		region = defaultS3Region
+	}
+	s.region = region
+	s.baseURL = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/", s.bucket, s.region)
+	if s.prefix != "" {
+		s.baseURL += s.prefix + "/"
+	}
 }
 
 func (s *S3Source) Name() string { return s.name }
@@ -130,18 +156,18 @@ func (s *S3Source) URL() string  { return s.bucketURL }
 // List lists all ISO files in the S3 bucket/prefix
 func (s *S3Source) List() ([]ISOFile, error) {
 	var isos []ISOFile
-	md5Keys := make(map[string]bool)
+	checksumKeys := make(map[string][]ChecksumAlgo) // ISO filename -> companion files
 
 	objects, err := s.listObjects()
 	if err != nil {
 		return nil, err
 	}
 
-	// First pass: find MD5 files
+	// First pass: find checksum files
 	for _, obj := range objects {
 		filename := filepath.Base(obj.Key)
-		if IsMD5File(filename) {
-			md5Keys[GetISOForMD5(filename)] = true
+		if isoName, algo, ok := ParseChecksumFilename(filename); ok {
+			checksumKeys[isoName] = append(checksumKeys[isoName], algo)
 		}
 	}
 
@@ -156,9 +182,8 @@ func (s *S3Source) List() ([]ISOFile, error) {
 		iso := ParseISOFilename(filename, s.name, s.Type(), fileURL)
 		iso.Size = obj.Size
 
-		if md5Keys[filename] {
-			iso.HasMD5File = true
-			iso.MD5FileURL = s.baseURL + filename + ".md5"
+		for _, algo := range checksumKeys[filename] {
+			iso.SetChecksumFile(algo, s.baseURL+filename+algo.Suffix())
 		}
 
 		isos = append(isos, iso)
@@ -172,29 +197,20 @@ func (s *S3Source) listObjects() ([]s3Object, error) {
 	var all []s3Object
 	continuationToken := ""
 
-	client := &http.Client{Timeout: 30 * time.Second}
+	client := &http.Client{Timeout: 30 * time.Second, Transport: transport()}
+	redirected := false
 
 	for {
-		listURL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/?list-type=2&prefix=%s",
-			s.bucket, s.region, url.QueryEscape(s.prefix+"/"))
-		if continuationToken != "" {
-			listURL += "&continuation-token=" + url.QueryEscape(continuationToken)
-		}
-
-		resp, err := client.Get(listURL)
+		result, bucketRegion, err := s.listPage(client, continuationToken)
 		if err != nil {
-			return nil, fmt.Errorf("listing S3 objects: %w", err)
-		}
-		defer resp.Body.Close()
-
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			return nil, fmt.Errorf("S3 list failed (status %d): %s", resp.StatusCode, string(body))
-		}
-
-		var result s3ListResult
-		if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
-			return nil, fmt.Errorf("parsing S3 response: %w", err)
+			// Bucket lives in another region: S3 answers with its real
+			// region in x-amz-bucket-region, so switch and retry once.
+			if !redirected && bucketRegion != "" && bucketRegion != s.region {
+				s.setRegion(bucketRegion)
+				redirected = true
+				continue
+			}
+			return nil, err
 		}
 
 		all = append(all, result.Contents...)
@@ -208,96 +224,77 @@ func (s *S3Source) listObjects() ([]s3Object, error) {
 	return all, nil
 }
 
-// Download downloads an ISO from S3
-func (s *S3Source) Download(iso ISOFile, destPath string, progress func(downloaded, total int64)) error {
-	downloadURL := iso.SourceURL
-	if downloadURL == "" {
-		downloadURL = s.baseURL + iso.Filename
+// listPage fetches a single ListObjectsV2 page. On failure it also returns the
+// bucket region reported by S3, if any.
+func (s *S3Source) listPage(client *http.Client, continuationToken string) (*s3ListResult, string, error) {
+	region := s.region
+	if region == "" {
+		region = defaultS3Region
+	}
+	listURL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/?list-type=2&prefix=%s",
+		s.bucket, region, url.QueryEscape(s.prefix+"/"))
+	if continuationToken != "" {
+		listURL += "&continuation-token=" + url.QueryEscape(continuationToken)
 	}
 
-	client := &http.Client{Timeout: 0}
-	resp, err := client.Get(downloadURL)
+	resp, err := client.Get(listURL)
 	if err != nil {
-		return fmt.Errorf("starting download: %w", err)
+		return nil, "", fmt.Errorf("listing S3 objects: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("download failed with status %d", resp.StatusCode)
+		body, _ := io.ReadAll(resp.Body)
+		bucketRegion := resp.Header.Get("x-amz-bucket-region")
+		return nil, bucketRegion, fmt.Errorf("S3 list failed (status %d): %s", resp.StatusCode, string(body))
 	}
 
-	totalSize := resp.ContentLength
-	if totalSize <= 0 && iso.Size > 0 {
-		totalSize = iso.Size
+	var result s3ListResult
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, "", fmt.Errorf("parsing S3 response: %w", err)
 	}
 
-	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
-		return fmt.Errorf("creating destination directory: %w", err)
-	}
-
-	dst, err := os.Create(destPath)
-	if err != nil {
-		return fmt.Errorf("creating destination file: %w", err)
-	}
-	defer dst.Close()
+	return &result, "", nil
+}
 
-	buf := make([]byte, 32*1024)
-	var downloaded int64
+// Download downloads an ISO from S3
+func (s *S3Source) Download(iso ISOFile, destPath string, progress func(downloaded, total int64)) error {
+	_, err := s.DownloadResumable(iso, destPath, progress)
+	return err
+}
 
-	for {
-		n, err := resp.Body.Read(buf)
-		if n > 0 {
-			nw, werr := dst.Write(buf[:n])
-			if werr != nil {
-				return fmt.Errorf("writing: %w", werr)
-			}
-			if nw != n {
-				return fmt.Errorf("short write")
-			}
-			downloaded += int64(nw)
-			if progress != nil {
-				progress(downloaded, totalSize)
-			}
-		}
-		if err == io.EOF {
-			break
-		}
-		if err != nil {
-			return fmt.Errorf("reading: %w", err)
-		}
+// DownloadResumable downloads an ISO from S3, resuming an earlier partial
+// download with a Range request
+func (s *S3Source) DownloadResumable(iso ISOFile, destPath string, progress func(downloaded, total int64)) (int64, error) {
+	downloadURL := iso.SourceURL
+	if downloadURL == "" {
+		downloadURL = s.baseURL + iso.Filename
 	}
 
-	return nil
+	client := &http.Client{Timeout: 0, Transport: transport()}
+	return downloadHTTPResumable(client, downloadURL, destPath, iso.Size, progress)
 }
 
 // DownloadMD5 downloads the MD5 file for an ISO from S3
 func (s *S3Source) DownloadMD5(iso ISOFile) (string, error) {
-	md5URL := iso.MD5FileURL
-	if md5URL == "" {
-		md5URL = s.baseURL + iso.Filename + ".md5"
-	}
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Get(md5URL)
-	if err != nil {
-		return "", fmt.Errorf("downloading MD5: %w", err)
-	}
-	defer resp.Body.Close()
+	return s.DownloadChecksum(iso, ChecksumMD5)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("MD5 download failed with status %d", resp.StatusCode)
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("reading MD5: %w", err)
+// DownloadChecksum downloads an ISO's companion checksum file from S3
+func (s *S3Source) DownloadChecksum(iso ISOFile, algo ChecksumAlgo) (string, error) {
+	sumURL := iso.ChecksumFileURL(algo)
+	if sumURL == "" {
+		sumURL = s.baseURL + iso.Filename + algo.Suffix()
 	}
+	return fetchChecksumFile(transport(), sumURL, algo)
+}
 
-	content := strings.TrimSpace(string(body))
-	parts := strings.Fields(content)
-	if len(parts) < 1 {
-		return "", fmt.Errorf("invalid MD5 file format")
+// HeaderMD5 returns the object's MD5 from its ETag, which S3 sets to the MD5
+// for objects that weren't uploaded in parts or encrypted with KMS or SSE-C
+func (s *S3Source) HeaderMD5(iso ISOFile) (string, error) {
+	fileURL := iso.SourceURL
+	if fileURL == "" {
+		fileURL = s.baseURL + iso.Filename
 	}
-
-	return strings.ToLower(parts[0]), nil
+	return headMD5(transport(), fileURL, true)
 }
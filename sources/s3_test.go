@@ -0,0 +1,42 @@
+package sources
+
+import "testing"
+
+func TestExtractS3Region(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		// Virtual-hosted style
+		{"isos.s3.eu-west-1.amazonaws.com", "eu-west-1"},
+		{"isos.s3.amazonaws.com", "us-east-1"},
+		{"my.dotted.bucket.s3.ap-south-1.amazonaws.com", "ap-south-1"},
+		{"s3-backups.s3.us-west-2.amazonaws.com", "us-west-2"},
+		{"s3.isos.s3.eu-central-1.amazonaws.com", "eu-central-1"},
+		// Path style
+		{"s3.us-west-2.amazonaws.com", "us-west-2"},
+		{"s3.amazonaws.com", "us-east-1"},
+		{"S3.EU-WEST-3.AMAZONAWS.COM", "eu-west-3"},
+		{"s3.cn-north-1.amazonaws.com.cn", "cn-north-1"},
+		// Dualstack and FIPS
+		{"isos.s3.dualstack.eu-west-1.amazonaws.com", "eu-west-1"},
+		{"s3.dualstack.us-east-2.amazonaws.com", "us-east-2"},
+		{"s3-fips.us-gov-west-1.amazonaws.com", "us-gov-west-1"},
+		{"s3-fips.dualstack.us-east-1.amazonaws.com", "us-east-1"},
+		// Legacy dash-style, website and global endpoints
+		{"s3-us-west-2.amazonaws.com", "us-west-2"},
+		{"isos.s3-eu-west-1.amazonaws.com", "eu-west-1"},
+		{"s3-backups.s3-eu-west-1.amazonaws.com", "eu-west-1"},
+		{"isos.s3-website-eu-west-1.amazonaws.com", "eu-west-1"},
+		{"isos.s3-website.eu-central-1.amazonaws.com", "eu-central-1"},
+		{"isos.s3-external-1.amazonaws.com", "us-east-1"},
+		// No region at all
+		{"", "us-east-1"},
+		{"isos.example.com", "us-east-1"},
+	}
+	for _, tt := range tests {
+		if got := extractS3Region(tt.host); got != tt.want {
+			t.Errorf("extractS3Region(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
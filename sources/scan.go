@@ -0,0 +1,113 @@
+package sources
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// ScanOptions controls how ScanAllSources lists sources
+type ScanOptions struct {
+	Timeout     time.Duration // Per listing attempt (0 = no limit)
+	Retries     int           // Further attempts after a transient failure
+	Concurrency int           // Sources listed at once (0 = DefaultScanConcurrency)
+}
+
+// DefaultScanConcurrency is how many sources are listed at once by default
+const DefaultScanConcurrency = 4
+
+// DefaultScanOptions keeps one slow or flaky source from stalling a scan
+var DefaultScanOptions = ScanOptions{
+	Timeout:     2 * time.Minute,
+	Retries:     2,
+	Concurrency: DefaultScanConcurrency,
+}
+
+// scanBaseBackoff is the wait before the first retry; it doubles after each
+const scanBaseBackoff = time.Second
+
+// errScanTimeout is returned for a listing still running after the timeout
+var errScanTimeout = errors.New("scan timed out")
+
+// transientScanErrors are error fragments from an overloaded or briefly
+// unreachable server that are worth retrying
+var transientScanErrors = []string{
+	"status 429",
+	"status 500",
+	"status 502",
+	"status 503",
+	"status 504",
+	"timeout",
+	"timed out",
+	"connection reset",
+	"connection refused",
+	"temporarily unavailable",
+	"unexpected eof",
+}
+
+// isTransientScanError reports whether a failed listing may succeed if retried
+func isTransientScanError(err error) bool {
+	if errors.Is(err, errScanTimeout) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range transientScanErrors {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// listWithRetry lists a source, retrying transient failures with
+// exponential backoff up to opts.Retries times
+func listWithRetry(source ImageSource, opts ScanOptions) ([]ISOFile, error) {
+	backoff := scanBaseBackoff
+	var err error
+	for attempt := 0; attempt <= opts.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		var isos []ISOFile
+		isos, err = listWithTimeout(source, opts.Timeout)
+		if err == nil || !isTransientScanError(err) {
+			return isos, err
+		}
+	}
+	if opts.Retries > 0 {
+		return nil, fmt.Errorf("%w (gave up after %d attempts)", err, opts.Retries+1)
+	}
+	return nil, err
+}
+
+// listWithTimeout stops waiting for a source after timeout. List can't be
+// cancelled, so a timed-out listing finishes in the background and is dropped.
+func listWithTimeout(source ImageSource, timeout time.Duration) ([]ISOFile, error) {
+	if timeout <= 0 {
+		return source.List()
+	}
+
+	type listing struct {
+		isos []ISOFile
+		err  error
+	}
+	done := make(chan listing, 1)
+	go func() {
+		isos, err := source.List()
+		done <- listing{isos, err}
+	}()
+
+	select {
+	case l := <-done:
+		return l.isos, l.err
+	case <-time.After(timeout):
+		return nil, fmt.Errorf("%w after %s", errScanTimeout, timeout)
+	}
+}
@@ -3,9 +3,9 @@ package sources
 import (
 	"fmt"
 	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/mihailvovk/versa-proxmox-deployer/config"
 	"github.com/mihailvovk/versa-proxmox-deployer/ssh"
@@ -139,16 +139,18 @@ func (s *SFTPSource) List() ([]ISOFile, error) {
 	}
 	defer cleanup()
 
-	// Collect MD5 files and ISOs recursively
-	md5Files := make(map[string]string) // ISO filename -> MD5 file full path
+	// Collect checksum files and ISOs recursively
+	checksumFiles := make(map[string]map[ChecksumAlgo]string) // ISO filename -> checksum file full paths
 	var isos []ISOFile
 
 	// Walk the directory tree
 	err = s.walkDir(client, s.path, func(path string, info os.FileInfo) {
 		name := info.Name()
-		if IsMD5File(name) {
-			isoName := GetISOForMD5(name)
-			md5Files[isoName] = path
+		if isoName, algo, ok := ParseChecksumFilename(name); ok {
+			if checksumFiles[isoName] == nil {
+				checksumFiles[isoName] = make(map[ChecksumAlgo]string)
+			}
+			checksumFiles[isoName][algo] = path
 		} else if IsISOFile(name) {
 			iso := ParseISOFilename(name, s.name, s.Type(), path)
 			iso.Size = info.Size()
@@ -159,16 +161,18 @@ func (s *SFTPSource) List() ([]ISOFile, error) {
 		return nil, fmt.Errorf("walking directory: %w", err)
 	}
 
-	// Match MD5 files with ISOs
+	// Match checksum files with ISOs
 	for i := range isos {
-		if md5Path, ok := md5Files[isos[i].Filename]; ok {
-			isos[i].HasMD5File = true
-			isos[i].MD5FileURL = md5Path
-
-			// Try to read MD5 value
-			if md5, err := s.readRemoteMD5(client, md5Path); err == nil {
-				isos[i].MD5 = md5
+		for algo, sumPath := range checksumFiles[isos[i].Filename] {
+			isos[i].SetChecksumFile(algo, sumPath)
+
+			// Try to read the checksum value; a malformed file is ignored
+			sum, err := s.readRemoteChecksum(client, sumPath, algo)
+			if err != nil {
+				slog.Warn("Ignoring checksum file", "path", sumPath, "error", err)
+				continue
 			}
+			isos[i].SetChecksum(algo, sum)
 		}
 	}
 
@@ -197,6 +201,11 @@ func (s *SFTPSource) walkDir(client *sftp.Client, path string, fn func(path stri
 
 // readRemoteMD5 reads an MD5 file from the SFTP server
 func (s *SFTPSource) readRemoteMD5(client *sftp.Client, path string) (string, error) {
+	return s.readRemoteChecksum(client, path, ChecksumMD5)
+}
+
+// readRemoteChecksum reads a companion checksum file from the SFTP server
+func (s *SFTPSource) readRemoteChecksum(client *sftp.Client, path string, algo ChecksumAlgo) (string, error) {
 	f, err := client.Open(path)
 	if err != nil {
 		return "", err
@@ -208,13 +217,7 @@ func (s *SFTPSource) readRemoteMD5(client *sftp.Client, path string) (string, er
 		return "", err
 	}
 
-	content := strings.TrimSpace(string(data))
-	parts := strings.Fields(content)
-	if len(parts) < 1 {
-		return "", fmt.Errorf("invalid MD5 file format")
-	}
-
-	return strings.ToLower(parts[0]), nil
+	return ParseChecksumFile(data, algo)
 }
 
 // Download downloads an ISO from SFTP
@@ -283,7 +286,7 @@ func (s *SFTPSource) Download(iso ISOFile, destPath string, progress func(downlo
 		}
 	}
 
-	return nil
+	return syncAndClose(dstFile)
 }
 
 // GetMD5 reads the MD5 for an ISO from the SFTP server
@@ -5,7 +5,9 @@ import (
 	"path/filepath"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/mihailvovk/versa-proxmox-deployer/config"
 )
@@ -30,16 +32,120 @@ type ImageSource interface {
 
 // ISOFile represents an ISO file found in a source
 type ISOFile struct {
-	Filename    string            // e.g., "versa-director-d58d641-22.1.4-B.iso"
-	Component   config.ComponentType // Detected component type
-	Version     string            // Extracted version (e.g., "22.1.4-B")
-	Size        int64             // File size in bytes
-	MD5         string            // MD5 checksum if available
-	HasMD5File  bool              // Whether .md5 companion file exists
-	SourceName  string            // Name of the source
-	SourceType  string            // Type of source (dropbox, http, sftp, local)
-	SourceURL   string            // Full URL or path to file
-	MD5FileURL  string            // URL or path to .md5 file
+	Filename      string               // e.g., "versa-director-d58d641-22.1.4-B.iso"
+	Component     config.ComponentType // Detected component type
+	Version       string               // Extracted version (e.g., "22.1.4-B")
+	Size          int64                // File size in bytes
+	MD5           string               // MD5 checksum if available
+	HasMD5File    bool                 // Whether .md5 companion file exists
+	SourceName    string               // Name of the source
+	SourceType    string               // Type of source (dropbox, http, sftp, local)
+	SourceURL     string               // Full URL or path to file
+	MD5FileURL    string               // URL or path to .md5 file
+	SHA256        string               // SHA256 checksum if available
+	HasSHA256File bool                 // Whether .sha256 companion file exists
+	SHA256FileURL string               // URL or path to .sha256 file
+	RequiresAuth  bool                 // Source needs credentials, so Proxmox can't fetch it directly
+
+	// Every source providing this same file, preferred first. The fields
+	// above always describe Sources[0].
+	Sources []SourceRef
+}
+
+// SourceRef locates one copy of an ISO in a particular source
+type SourceRef struct {
+	Name          string
+	Type          string
+	URL           string
+	MD5FileURL    string
+	SHA256FileURL string
+	Priority      int  // Lower is preferred (source scan order)
+	RequiresAuth  bool // Source needs credentials
+}
+
+// SourceRefs returns every source for the ISO, preferred first
+func (iso ISOFile) SourceRefs() []SourceRef {
+	if len(iso.Sources) > 0 {
+		return iso.Sources
+	}
+	return []SourceRef{iso.primaryRef(0)}
+}
+
+// FromSource returns a copy of the ISO whose primary fields point at ref
+func (iso ISOFile) FromSource(ref SourceRef) ISOFile {
+	iso.SourceName = ref.Name
+	iso.SourceType = ref.Type
+	iso.SourceURL = ref.URL
+	if ref.MD5FileURL != "" {
+		iso.MD5FileURL = ref.MD5FileURL
+	}
+	if ref.SHA256FileURL != "" {
+		iso.SHA256FileURL = ref.SHA256FileURL
+	}
+	iso.RequiresAuth = ref.RequiresAuth
+	return iso
+}
+
+func (iso ISOFile) primaryRef(priority int) SourceRef {
+	return SourceRef{
+		Name:          iso.SourceName,
+		Type:          iso.SourceType,
+		URL:           iso.SourceURL,
+		MD5FileURL:    iso.MD5FileURL,
+		SHA256FileURL: iso.SHA256FileURL,
+		Priority:      priority,
+		RequiresAuth:  iso.RequiresAuth,
+	}
+}
+
+// sameImage reports whether two listings are the same file. Checksums decide
+// when both sides have one; otherwise filename and size must match.
+func sameImage(a, b ISOFile) bool {
+	if a.SHA256 != "" && b.SHA256 != "" {
+		return strings.EqualFold(a.SHA256, b.SHA256)
+	}
+	if a.MD5 != "" && b.MD5 != "" {
+		return strings.EqualFold(a.MD5, b.MD5)
+	}
+	return a.Filename == b.Filename && a.Size == b.Size
+}
+
+// dedupISOs merges identical ISOs listed by several sources into one entry
+// carrying every source reference, ordered by priority
+func dedupISOs(isos []ISOFile) []ISOFile {
+	var result []ISOFile
+	for _, iso := range isos {
+		merged := false
+		for i := range result {
+			if !sameImage(result[i], iso) {
+				continue
+			}
+			existing := &result[i]
+			existing.Sources = append(existing.Sources, iso.Sources...)
+			if existing.MD5 == "" && iso.MD5 != "" {
+				existing.MD5 = iso.MD5
+			}
+			existing.HasMD5File = existing.HasMD5File || iso.HasMD5File
+			if existing.SHA256 == "" && iso.SHA256 != "" {
+				existing.SHA256 = iso.SHA256
+			}
+			existing.HasSHA256File = existing.HasSHA256File || iso.HasSHA256File
+			merged = true
+			break
+		}
+		if !merged {
+			result = append(result, iso)
+		}
+	}
+
+	for i := range result {
+		refs := result[i].Sources
+		sort.SliceStable(refs, func(a, b int) bool {
+			return refs[a].Priority < refs[b].Priority
+		})
+		result[i] = result[i].FromSource(refs[0])
+	}
+	return result
 }
 
 // ISOCollection holds categorized ISOs from all sources
@@ -57,12 +163,14 @@ type ISOCollection struct {
 
 // SourceSummary holds summary info about a scanned source
 type SourceSummary struct {
-	Name      string
-	Type      string
-	URL       string
-	ISOCount  int
-	MD5Count  int
-	Error     string
+	Name        string
+	Type        string
+	URL         string
+	Priority    int // Position in scan order, lower is preferred for downloads
+	ISOCount    int
+	MD5Count    int
+	SHA256Count int
+	Error       string
 }
 
 // DetectComponent detects the component type from an ISO filename
@@ -122,67 +230,134 @@ func ParseISOFilename(filename, sourceName, sourceType, sourceURL string) ISOFil
 	return iso
 }
 
-// ScanAllSources scans all configured sources and returns categorized ISOs
-func ScanAllSources(sources []ImageSource) (*ISOCollection, error) {
-	collection := &ISOCollection{}
+// ScanAllSources scans all configured sources and returns categorized ISOs.
+// sources must be in preference order; an ISO found in several sources is
+// downloaded from the earliest one first. Sources are listed concurrently
+// per opts, and results keep source order whichever finishes first.
+func ScanAllSources(sources []ImageSource, opts ScanOptions) (*ISOCollection, error) {
+	workers := opts.Concurrency
+	if workers <= 0 {
+		workers = DefaultScanConcurrency
+	}
+	if workers > len(sources) {
+		workers = len(sources)
+	}
 
-	for _, source := range sources {
-		summary := SourceSummary{
-			Name: source.Name(),
-			Type: source.Type(),
-			URL:  source.URL(),
-		}
+	scans := make([]sourceScan, len(sources))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for priority := range jobs {
+				scans[priority] = scanSource(priority, sources[priority], opts)
+			}
+		}()
+	}
+	for priority := range sources {
+		jobs <- priority
+	}
+	close(jobs)
+	wg.Wait()
+	return collectScans(scans), nil
+}
 
-		isos, err := source.List()
-		if err != nil {
-			summary.Error = err.Error()
-			collection.Sources = append(collection.Sources, summary)
-			continue
-		}
+// sourceScan is one source's listing and summary
+type sourceScan struct {
+	summary SourceSummary
+	isos    []ISOFile
+}
 
-		// Count ISOs and MD5s
-		for _, iso := range isos {
-			summary.ISOCount++
-			if iso.HasMD5File || iso.MD5 != "" {
-				summary.MD5Count++
-			}
+// scanSource lists one source, retrying transient failures per opts. The
+// summary carries the final error when every attempt failed.
+func scanSource(priority int, source ImageSource, opts ScanOptions) sourceScan {
+	scan := sourceScan{summary: SourceSummary{
+		Name:     source.Name(),
+		Type:     source.Type(),
+		URL:      source.URL(),
+		Priority: priority,
+	}}
+
+	isos, err := listWithRetry(source, opts)
+	if err != nil {
+		scan.summary.Error = err.Error()
+		return scan
+	}
 
-			// Categorize by component
-			switch iso.Component {
-			case config.ComponentDirector:
-				collection.Director = append(collection.Director, iso)
-			case config.ComponentAnalytics:
-				collection.Analytics = append(collection.Analytics, iso)
-			case config.ComponentConcerto:
-				collection.Concerto = append(collection.Concerto, iso)
-			case config.ComponentFlexVNF:
-				// FlexVNF is used for Controller, Router, and FlexVNF
-				collection.FlexVNF = append(collection.FlexVNF, iso)
-			}
+	for _, iso := range isos {
+		iso.Sources = []SourceRef{iso.primaryRef(priority)}
+		scan.summary.ISOCount++
+		if iso.HasMD5File || iso.MD5 != "" {
+			scan.summary.MD5Count++
 		}
-
-		collection.Sources = append(collection.Sources, summary)
+		if iso.HasSHA256File || iso.SHA256 != "" {
+			scan.summary.SHA256Count++
+		}
+		scan.isos = append(scan.isos, iso)
 	}
+	return scan
+}
 
-	// Sort each category by version (newest first)
-	sortByVersion := func(isos []ISOFile) {
-		sort.Slice(isos, func(i, j int) bool {
-			return compareVersions(isos[i].Version, isos[j].Version) > 0
-		})
+// collectScans categorizes the scanned ISOs in source order
+func collectScans(scans []sourceScan) *ISOCollection {
+	collection := &ISOCollection{}
+
+	for _, scan := range scans {
+		collection.add(scan.isos)
+		collection.Sources = append(collection.Sources, scan.summary)
 	}
 
-	sortByVersion(collection.Director)
-	sortByVersion(collection.Analytics)
-	sortByVersion(collection.Controller)
-	sortByVersion(collection.Concerto)
-	sortByVersion(collection.FlexVNF)
+	// Merge copies of the same image found in several sources
+	collection.Director = dedupISOs(collection.Director)
+	collection.Analytics = dedupISOs(collection.Analytics)
+	collection.Controller = dedupISOs(collection.Controller)
+	collection.Concerto = dedupISOs(collection.Concerto)
+	collection.FlexVNF = dedupISOs(collection.FlexVNF)
+
+	collection.sortByVersion()
+	return collection
+}
+
+// NewISOCollection categorizes already scanned ISOs, such as the All() of an
+// earlier scan, newest first
+func NewISOCollection(isos []ISOFile) *ISOCollection {
+	collection := &ISOCollection{}
+	collection.add(isos)
+	collection.sortByVersion()
+	return collection
+}
 
-	return collection, nil
+// add files ISOs under their component, keeping their order
+func (c *ISOCollection) add(isos []ISOFile) {
+	for _, iso := range isos {
+		switch iso.Component {
+		case config.ComponentDirector:
+			c.Director = append(c.Director, iso)
+		case config.ComponentAnalytics:
+			c.Analytics = append(c.Analytics, iso)
+		case config.ComponentConcerto:
+			c.Concerto = append(c.Concerto, iso)
+		case config.ComponentFlexVNF:
+			// FlexVNF is used for Controller, Router, and FlexVNF
+			c.FlexVNF = append(c.FlexVNF, iso)
+		}
+	}
+}
+
+// sortByVersion sorts each category newest first, keeping the given order
+// among equal versions
+func (c *ISOCollection) sortByVersion() {
+	for _, isos := range [][]ISOFile{c.Director, c.Analytics, c.Controller, c.Concerto, c.FlexVNF} {
+		sort.SliceStable(isos, func(i, j int) bool {
+			return CompareVersions(isos[i].Version, isos[j].Version) > 0
+		})
+	}
 }
 
-// compareVersions compares two version strings
+// CompareVersions compares two version strings
 // Returns: -1 if a < b, 0 if a == b, 1 if a > b
-func compareVersions(a, b string) int {
+func CompareVersions(a, b string) int {
 	// Parse versions like "22.1.4" or "22.1.4-B"
 	parseVersion := func(v string) (major, minor, patch int, suffix string) {
 		// Remove leading 'v' if present
@@ -231,8 +406,36 @@ func compareVersions(a, b string) int {
 		return -1
 	}
 
-	// Compare suffixes (B > A > empty)
-	return strings.Compare(aSuffix, bSuffix)
+	// Compare suffixes (B > A > empty). Case-insensitive, since versions
+	// read back from VM tags are lowercased.
+	return strings.Compare(strings.ToLower(aSuffix), strings.ToLower(bSuffix))
+}
+
+// prereleaseSuffixes mark builds that aren't general releases. Versa's own
+// build letters (22.1.4-B) are releases.
+var prereleaseSuffixes = []string{"alpha", "beta", "rc", "dev", "eng", "snapshot", "test"}
+
+// IsPrerelease reports whether a version's suffix marks a beta, release
+// candidate or other pre-release build, e.g. 22.1.4-beta2 or 22.1.4-rc1
+func IsPrerelease(version string) bool {
+	_, suffix, _ := strings.Cut(strings.TrimPrefix(version, "v"), "-")
+	suffix = strings.ToLower(suffix)
+	for _, p := range prereleaseSuffixes {
+		if strings.HasPrefix(suffix, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// VersionMajor returns the major number of a version like 22.1.4, or -1
+func VersionMajor(version string) int {
+	major, _, _ := strings.Cut(strings.TrimPrefix(version, "v"), ".")
+	n, err := strconv.Atoi(major)
+	if err != nil {
+		return -1
+	}
+	return n
 }
 
 // GetLatestISO returns the latest version ISO for a component
@@ -274,6 +477,43 @@ func (c *ISOCollection) GetISOsForComponent(component config.ComponentType) []IS
 	}
 }
 
+// All returns every ISO in the collection
+func (c *ISOCollection) All() []ISOFile {
+	var all []ISOFile
+	all = append(all, c.Director...)
+	all = append(all, c.Analytics...)
+	all = append(all, c.FlexVNF...)
+	all = append(all, c.Concerto...)
+	return all
+}
+
+// ComponentAvailability reports whether any source provides an ISO for a component
+type ComponentAvailability struct {
+	Component     config.ComponentType `json:"component"`
+	Available     bool                 `json:"available"`
+	ISOCount      int                  `json:"isoCount"`
+	LatestVersion string               `json:"latestVersion,omitempty"`
+	LatestISO     string               `json:"latestIso,omitempty"`
+}
+
+// Availability returns ISO availability for each of the given components
+func (c *ISOCollection) Availability(components []config.ComponentType) []ComponentAvailability {
+	result := make([]ComponentAvailability, 0, len(components))
+	for _, comp := range components {
+		a := ComponentAvailability{
+			Component: comp,
+			ISOCount:  len(c.GetISOsForComponent(comp)),
+		}
+		if latest := c.GetLatestISO(comp); latest != nil {
+			a.Available = true
+			a.LatestVersion = latest.Version
+			a.LatestISO = latest.Filename
+		}
+		result = append(result, a)
+	}
+	return result
+}
+
 // FindISOByVersion finds an ISO with a specific version
 func (c *ISOCollection) FindISOByVersion(component config.ComponentType, version string) *ISOFile {
 	isos := c.GetISOsForComponent(component)
@@ -286,8 +526,12 @@ func (c *ISOCollection) FindISOByVersion(component config.ComponentType, version
 }
 
 // SupportsDirectDownload returns true if the ISO can be downloaded directly
-// by Proxmox (i.e. it has an HTTP/HTTPS source URL from an http or dropbox source).
+// by Proxmox (i.e. it has an HTTP/HTTPS source URL from an http or dropbox
+// source). Proxmox can't send a source's credentials, so those are excluded.
 func SupportsDirectDownload(iso ISOFile) bool {
+	if iso.RequiresAuth {
+		return false
+	}
 	switch iso.SourceType {
 	case "http", "dropbox", "s3":
 		return strings.HasPrefix(iso.SourceURL, "http://") || strings.HasPrefix(iso.SourceURL, "https://")
@@ -296,6 +540,121 @@ func SupportsDirectDownload(iso ISOFile) bool {
 	}
 }
 
+// Hex digest lengths of the checksum formats found in companion files
+const (
+	MD5HexLen    = 32
+	SHA256HexLen = 64
+)
+
+// ValidChecksum reports whether sum is a lowercase or uppercase hex digest of
+// exactly hexLen characters
+func ValidChecksum(sum string, hexLen int) bool {
+	if len(sum) != hexLen {
+		return false
+	}
+	for _, r := range sum {
+		if !('0' <= r && r <= '9' || 'a' <= r && r <= 'f' || 'A' <= r && r <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// ChecksumAlgo is a checksum format published in companion files next to ISOs
+type ChecksumAlgo string
+
+const (
+	ChecksumMD5    ChecksumAlgo = "md5"
+	ChecksumSHA256 ChecksumAlgo = "sha256"
+)
+
+// checksumHexLen is the hex digest length of each algorithm; its keys are
+// also the companion file suffixes (".md5", ".sha256")
+var checksumHexLen = map[ChecksumAlgo]int{
+	ChecksumMD5:    MD5HexLen,
+	ChecksumSHA256: SHA256HexLen,
+}
+
+// Suffix returns the algorithm's companion file suffix, e.g. ".sha256"
+func (a ChecksumAlgo) Suffix() string {
+	return "." + string(a)
+}
+
+// ParseChecksumFilename splits a companion checksum filename into the ISO
+// it describes and its algorithm; ok is false for other files
+func ParseChecksumFilename(filename string) (isoName string, algo ChecksumAlgo, ok bool) {
+	lower := strings.ToLower(filename)
+	for a := range checksumHexLen {
+		if strings.HasSuffix(lower, a.Suffix()) {
+			return filename[:len(filename)-len(a.Suffix())], a, true
+		}
+	}
+	return "", "", false
+}
+
+// ParseChecksumFile extracts the checksum from the contents of a companion
+// file ("checksum  filename" or just "checksum"). Truncated files and HTML
+// error pages served with a 200 status are rejected.
+func ParseChecksumFile(data []byte, algo ChecksumAlgo) (string, error) {
+	name := strings.ToUpper(string(algo))
+	hexLen := checksumHexLen[algo]
+	parts := strings.Fields(string(data))
+	if len(parts) < 1 {
+		return "", fmt.Errorf("invalid %s file format: empty file", name)
+	}
+	sum := parts[0]
+	if !ValidChecksum(sum, hexLen) {
+		if len(sum) > 40 {
+			sum = sum[:40] + "..."
+		}
+		return "", fmt.Errorf("invalid %s file format: %q is not a %d-character hex digest", name, sum, hexLen)
+	}
+	return strings.ToLower(sum), nil
+}
+
+// ParseMD5File extracts the checksum from the contents of a .md5 companion file
+func ParseMD5File(data []byte) (string, error) {
+	return ParseChecksumFile(data, ChecksumMD5)
+}
+
+// SetChecksumFile records that a companion checksum file exists at url
+func (iso *ISOFile) SetChecksumFile(algo ChecksumAlgo, url string) {
+	switch algo {
+	case ChecksumMD5:
+		iso.HasMD5File, iso.MD5FileURL = true, url
+	case ChecksumSHA256:
+		iso.HasSHA256File, iso.SHA256FileURL = true, url
+	}
+}
+
+// SetChecksum records a checksum value read from a companion file
+func (iso *ISOFile) SetChecksum(algo ChecksumAlgo, sum string) {
+	switch algo {
+	case ChecksumMD5:
+		iso.MD5 = sum
+	case ChecksumSHA256:
+		iso.SHA256 = sum
+	}
+}
+
+// ChecksumFileURL returns where the companion file for algo lives, empty if
+// the listing found none
+func (iso ISOFile) ChecksumFileURL(algo ChecksumAlgo) string {
+	switch algo {
+	case ChecksumMD5:
+		return iso.MD5FileURL
+	case ChecksumSHA256:
+		return iso.SHA256FileURL
+	}
+	return ""
+}
+
+// ChecksumDownloader is implemented by sources whose listings only note that
+// companion checksum files exist; the values are fetched on demand
+type ChecksumDownloader interface {
+	DownloadChecksum(iso ISOFile, algo ChecksumAlgo) (string, error)
+}
+
 // GetMD5FilePath returns the expected .md5 file path for an ISO
 func GetMD5FilePath(isoPath string) string {
 	return isoPath + ".md5"
@@ -308,12 +667,23 @@ func IsISOFile(filename string) bool {
 
 // IsMD5File checks if a filename is an MD5 file
 func IsMD5File(filename string) bool {
-	return strings.HasSuffix(strings.ToLower(filename), ".md5")
+	_, algo, ok := ParseChecksumFilename(filename)
+	return ok && algo == ChecksumMD5
 }
 
-// GetISOForMD5 returns the ISO filename for an MD5 file
+// IsChecksumFile checks if a filename is a companion checksum file of any
+// supported algorithm
+func IsChecksumFile(filename string) bool {
+	_, _, ok := ParseChecksumFilename(filename)
+	return ok
+}
+
+// GetISOForMD5 returns the ISO filename for an MD5 (or other checksum) file
 func GetISOForMD5(md5Filename string) string {
-	return strings.TrimSuffix(md5Filename, ".md5")
+	if isoName, _, ok := ParseChecksumFilename(md5Filename); ok {
+		return isoName
+	}
+	return md5Filename
 }
 
 // FormatFileSize formats a file size in human-readable form
@@ -0,0 +1,72 @@
+package sources
+
+import "testing"
+
+// listing returns an ISO as one source lists it before deduplication
+func listing(filename string, size int64, source string, priority int) ISOFile {
+	iso := ISOFile{
+		Filename:   filename,
+		Size:       size,
+		SourceName: source,
+		SourceType: "http",
+		SourceURL:  "https://" + source + "/" + filename,
+	}
+	iso.Sources = []SourceRef{iso.primaryRef(priority)}
+	return iso
+}
+
+func TestDedupISOs(t *testing.T) {
+	mirror := listing("versa-director-22.1.4-B.iso", 1000, "mirror", 2)
+	primary := listing("versa-director-22.1.4-B.iso", 1000, "primary", 1)
+	primary.MD5 = "0123456789abcdef0123456789abcdef"
+	primary.HasMD5File = true
+
+	renamed := listing("director-22.1.4.iso", 1000, "nas", 3)
+	renamed.SHA256 = "AAAA"
+	cached := listing("versa-director-22.1.4-B (1).iso", 1000, "cache", 4)
+	cached.SHA256 = "aaaa"
+
+	truncated := listing("versa-director-22.1.4-B.iso", 999, "broken", 0)
+
+	sameNameNewBuild := listing("versa-director-22.1.4-B.iso", 1000, "builds", 5)
+	sameNameNewBuild.MD5 = "ffffffffffffffffffffffffffffffff"
+
+	got := dedupISOs([]ISOFile{mirror, primary, renamed, cached, truncated, sameNameNewBuild})
+
+	want := []struct {
+		filename string
+		sources  []string
+		md5      string
+	}{
+		{"versa-director-22.1.4-B.iso", []string{"primary", "mirror"}, primary.MD5},
+		{"director-22.1.4.iso", []string{"nas", "cache"}, ""},
+		{"versa-director-22.1.4-B.iso", []string{"broken"}, ""},
+		{"versa-director-22.1.4-B.iso", []string{"builds"}, sameNameNewBuild.MD5},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("dedupISOs returned %d ISOs, want %d: %+v", len(got), len(want), got)
+	}
+	for i, w := range want {
+		iso := got[i]
+		var names []string
+		for _, ref := range iso.Sources {
+			names = append(names, ref.Name)
+		}
+		if iso.Filename != w.filename || iso.MD5 != w.md5 || len(names) != len(w.sources) {
+			t.Errorf("ISO %d = %s from %v (MD5 %q), want %s from %v (MD5 %q)", i, iso.Filename, names, iso.MD5, w.filename, w.sources, w.md5)
+			continue
+		}
+		for j := range names {
+			if names[j] != w.sources[j] {
+				t.Errorf("ISO %d sources = %v, want %v", i, names, w.sources)
+				break
+			}
+		}
+		if iso.SourceName != w.sources[0] || iso.SourceURL != iso.Sources[0].URL {
+			t.Errorf("ISO %d primary is %s (%s), want the preferred source %s", i, iso.SourceName, iso.SourceURL, w.sources[0])
+		}
+	}
+	if !got[0].HasMD5File {
+		t.Error("merged ISO lost the .md5 companion of one of its sources")
+	}
+}
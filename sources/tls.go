@@ -0,0 +1,64 @@
+package sources
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// caTransport is the transport every HTTP-based source uses, nil until
+// SetCABundle installs one (nil means http.DefaultTransport)
+var (
+	caTransportMu sync.RWMutex
+	caTransport   http.RoundTripper
+)
+
+// SetCABundle makes HTTP, S3 and Dropbox sources trust the PEM CA
+// certificates in path on top of the system roots, e.g. the CA of a TLS
+// inspection proxy. An empty path restores the system roots alone.
+func SetCABundle(path string) error {
+	if path == "" {
+		caTransportMu.Lock()
+		caTransport = nil
+		caTransportMu.Unlock()
+		return nil
+	}
+
+	pool, err := LoadCABundle(path)
+	if err != nil {
+		return err
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = &tls.Config{RootCAs: pool}
+
+	caTransportMu.Lock()
+	caTransport = t
+	caTransportMu.Unlock()
+	return nil
+}
+
+// LoadCABundle returns the system roots plus the PEM certificates in path
+func LoadCABundle(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading CA bundle: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("CA bundle %s contains no PEM certificates", path)
+	}
+	return pool, nil
+}
+
+// transport returns the RoundTripper for source HTTP clients
+func transport() http.RoundTripper {
+	caTransportMu.RLock()
+	defer caTransportMu.RUnlock()
+	return caTransport
+}
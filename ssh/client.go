@@ -21,7 +21,31 @@ type Client struct {
 	config    *ssh.ClientConfig
 	mu        sync.Mutex
 	timeout   time.Duration
+	jump      *Client       // bastion the connection is tunnelled through, if any
 	stopKeep  chan struct{} // signal to stop keepalive goroutine
+	gen       uint64        // incremented on every (re)connect
+	compress  bool          // gzip uploads in transit
+}
+
+// HostKeyPolicy controls how unknown and changed host keys are handled
+type HostKeyPolicy string
+
+const (
+	HostKeyStrict HostKeyPolicy = "strict" // Only hosts already in known_hosts are accepted
+	HostKeyTOFU   HostKeyPolicy = "tofu"   // Unknown hosts are trusted and recorded on first use
+	HostKeyIgnore HostKeyPolicy = "ignore" // No host key verification (insecure)
+)
+
+// ParseHostKeyPolicy validates a policy name; empty means TOFU
+func ParseHostKeyPolicy(s string) (HostKeyPolicy, error) {
+	switch p := HostKeyPolicy(s); p {
+	case "":
+		return HostKeyTOFU, nil
+	case HostKeyStrict, HostKeyTOFU, HostKeyIgnore:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown host key policy %q (expected strict, tofu or ignore)", s)
+	}
 }
 
 // ClientOptions configures the SSH client
@@ -32,7 +56,10 @@ type ClientOptions struct {
 	KeyPath        string
 	KeyPassphrase  string
 	Timeout        time.Duration
-	HostKeyCheck   bool
+	HostKeyPolicy  HostKeyPolicy // Defaults to TOFU
+	Jump           *ClientOptions // Bastion to connect through (ProxyJump), nil for direct
+	Cipher         string        // Preferred cipher (see Ciphers); empty keeps the defaults
+	Compression    bool          // Gzip file uploads in transit, for compressible images on slow links
 }
 
 // NewClient creates a new SSH client with the given options
@@ -68,16 +95,27 @@ func NewClient(opts ClientOptions) (*Client, error) {
 		return nil, fmt.Errorf("no authentication method provided (need password or SSH key)")
 	}
 
-	// Host key callback — TOFU (Trust On First Use)
+	// Host key callback — TOFU (Trust On First Use) unless strict or ignore is requested
+	policy, err := ParseHostKeyPolicy(string(opts.HostKeyPolicy))
+	if err != nil {
+		return nil, err
+	}
 	var hostKeyCallback ssh.HostKeyCallback
-	if opts.HostKeyCheck {
+	switch policy {
+	case HostKeyIgnore:
+		hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	case HostKeyStrict:
+		cb, err := strictHostKeyCallback()
+		if err != nil {
+			return nil, fmt.Errorf("setting up host key verification: %w", err)
+		}
+		hostKeyCallback = cb
+	default:
 		cb, err := tofuHostKeyCallback()
 		if err != nil {
 			return nil, fmt.Errorf("setting up host key verification: %w", err)
 		}
 		hostKeyCallback = cb
-	} else {
-		hostKeyCallback = ssh.InsecureIgnoreHostKey()
 	}
 
 	config := &ssh.ClientConfig{
@@ -86,12 +124,28 @@ func NewClient(opts ClientOptions) (*Client, error) {
 		HostKeyCallback: hostKeyCallback,
 		Timeout:         opts.Timeout,
 	}
+	if opts.Cipher != "" {
+		ciphers, err := cipherPreference(opts.Cipher)
+		if err != nil {
+			return nil, err
+		}
+		config.Ciphers = ciphers
+	}
+
+	var jump *Client
+	if opts.Jump != nil {
+		if jump, err = NewClient(*opts.Jump); err != nil {
+			return nil, fmt.Errorf("jump host %s: %w", opts.Jump.Host, err)
+		}
+	}
 
 	return &Client{
-		host:    opts.Host,
-		user:    opts.User,
-		config:  config,
-		timeout: opts.Timeout,
+		host:     opts.Host,
+		user:     opts.User,
+		config:   config,
+		timeout:  opts.Timeout,
+		jump:     jump,
+		compress: opts.Compression,
 	}, nil
 }
 
@@ -110,6 +164,7 @@ func (c *Client) Connect() error {
 	}
 
 	c.client = client
+	c.gen++
 	c.startKeepalive()
 	return nil
 }
@@ -121,6 +176,10 @@ func (c *Client) dialSSH() (*ssh.Client, error) {
 		host = net.JoinHostPort(host, "22")
 	}
 
+	if c.jump != nil {
+		return c.dialViaJump(host)
+	}
+
 	client, err := ssh.Dial("tcp", host, c.config)
 	if err != nil {
 		return nil, fmt.Errorf("connecting to %s: %w", c.host, err)
@@ -128,6 +187,46 @@ func (c *Client) dialSSH() (*ssh.Client, error) {
 	return client, nil
 }
 
+// dialViaJump opens the SSH connection through a tunnel on the jump host
+func (c *Client) dialViaJump(hostport string) (*ssh.Client, error) {
+	jc, err := c.jump.getClient()
+	if err != nil {
+		return nil, fmt.Errorf("connecting to jump host %s: %w", c.jump.host, err)
+	}
+	conn, err := jc.Dial("tcp", hostport)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %s via %s: %w", c.host, c.jump.host, err)
+	}
+
+	// Tunnelled connections don't support deadlines, so the handshake is
+	// timed out by closing the connection under it
+	type handshake struct {
+		conn  ssh.Conn
+		chans <-chan ssh.NewChannel
+		reqs  <-chan *ssh.Request
+		err   error
+	}
+	done := make(chan handshake, 1)
+	go func() {
+		sc, chans, reqs, err := ssh.NewClientConn(conn, hostport, c.config)
+		done <- handshake{sc, chans, reqs, err}
+	}()
+
+	timer := time.NewTimer(c.timeout)
+	defer timer.Stop()
+	select {
+	case h := <-done:
+		if h.err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("connecting to %s via %s: %w", c.host, c.jump.host, h.err)
+		}
+		return ssh.NewClient(h.conn, h.chans, h.reqs), nil
+	case <-timer.C:
+		conn.Close()
+		return nil, fmt.Errorf("connecting to %s via %s: SSH handshake timed out after %s", c.host, c.jump.host, c.timeout)
+	}
+}
+
 // startKeepalive sends periodic keepalive requests to prevent SSH timeout.
 // Must be called with c.client already set. Stops when c.stopKeep is closed
 // or when a keepalive fails (which triggers c.client = nil for auto-reconnect).
@@ -180,12 +279,15 @@ func (c *Client) Close() error {
 		c.stopKeep = nil
 	}
 
+	var err error
 	if c.client != nil {
-		err := c.client.Close()
+		err = c.client.Close()
 		c.client = nil
-		return err
 	}
-	return nil
+	if c.jump != nil {
+		c.jump.Close()
+	}
+	return err
 }
 
 // IsConnected returns true if the client is connected
@@ -195,6 +297,14 @@ func (c *Client) IsConnected() bool {
 	return c.client != nil
 }
 
+// Generation returns a counter that changes whenever the underlying SSH
+// connection is re-established, so long-running pollers can detect reconnects
+func (c *Client) Generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
 // Reconnect closes and reopens the connection
 func (c *Client) Reconnect() error {
 	c.Close()
@@ -230,6 +340,7 @@ func (c *Client) getClient() (*ssh.Client, error) {
 
 	c.mu.Lock()
 	c.client = client
+	c.gen++
 	c.startKeepalive()
 	c.mu.Unlock()
 
@@ -278,6 +389,39 @@ func knownHostsPath() string {
 	return filepath.Join(knownHostsDir(), "known_hosts")
 }
 
+// strictHostKeyCallback accepts only hosts whose key is already in known_hosts
+func strictHostKeyCallback() (ssh.HostKeyCallback, error) {
+	khPath := knownHostsPath()
+	existingCb, err := knownhosts.New(khPath)
+	if err != nil {
+		return nil, fmt.Errorf("loading known_hosts (strict host key policy requires a pre-seeded %s): %w", khPath, err)
+	}
+
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := existingCb(hostname, remote, key)
+		if err == nil {
+			return nil
+		}
+
+		var keyErr *knownhosts.KeyError
+		if errors.As(err, &keyErr) && len(keyErr.Want) == 0 {
+			return fmt.Errorf("host %s is not in %s and the host key policy is strict; add its key first (e.g. ssh-keyscan %s >> %s)",
+				hostname, khPath, hostnameOnly(hostname), khPath)
+		}
+		return fmt.Errorf("WARNING: host key for %s has changed! This could indicate a MITM attack. "+
+			"If you trust this host, remove the old entry from %s and reconnect. Original error: %w",
+			hostname, khPath, err)
+	}, nil
+}
+
+// hostnameOnly strips the port from a host:port string
+func hostnameOnly(hostport string) string {
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		return h
+	}
+	return hostport
+}
+
 // tofuHostKeyCallback returns a TOFU (Trust On First Use) host key callback.
 // On first connection to a host, the key is accepted and written to the known_hosts file.
 // On subsequent connections, the key is verified against the stored key.
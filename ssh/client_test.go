@@ -0,0 +1,85 @@
+package ssh_test
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/ssh"
+	"github.com/mihailvovk/versa-proxmox-deployer/ssh/sshtest"
+)
+
+func TestConnectViaJumpTimesOut(t *testing.T) {
+	jump := sshtest.NewServer(t, func(string) (string, int) { return "", 0 })
+
+	// A target that accepts TCP but never speaks SSH
+	target, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer target.Close()
+	go func() {
+		for {
+			conn, err := target.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	client, err := ssh.NewClient(ssh.ClientOptions{
+		Host:          target.Addr().String(),
+		Password:      "test",
+		Timeout:       200 * time.Millisecond,
+		HostKeyPolicy: ssh.HostKeyIgnore,
+		Jump: &ssh.ClientOptions{
+			Host:          jump,
+			Password:      "test",
+			HostKeyPolicy: ssh.HostKeyIgnore,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- client.Connect() }()
+	select {
+	case err := <-done:
+		if err == nil || !strings.Contains(err.Error(), "timed out") {
+			t.Errorf("Connect() = %v, want a handshake timeout", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Connect() through the jump host hung")
+	}
+}
+
+func TestConnectViaJump(t *testing.T) {
+	jump := sshtest.NewServer(t, func(string) (string, int) { return "", 0 })
+	target := sshtest.NewServer(t, func(cmd string) (string, int) { return "pve1\n", 0 })
+
+	client, err := ssh.NewClient(ssh.ClientOptions{
+		Host:          target,
+		Password:      "test",
+		HostKeyPolicy: ssh.HostKeyIgnore,
+		Jump: &ssh.ClientOptions{
+			Host:          jump,
+			Password:      "test",
+			HostKeyPolicy: ssh.HostKeyIgnore,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	result, err := client.Run("hostname")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Stdout != "pve1\n" {
+		t.Errorf("Run() stdout = %q, want %q", result.Stdout, "pve1\n")
+	}
+}
@@ -0,0 +1,281 @@
+package ssh
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// HostConfig is the part of an OpenSSH client config entry the deployer uses
+type HostConfig struct {
+	HostName     string
+	User         string
+	Port         int
+	IdentityFile string
+	ProxyJump    string // Comma-separated [user@]host[:port] hops, empty for none
+}
+
+// maxIncludeDepth bounds nested Include directives, as OpenSSH does
+const maxIncludeDepth = 16
+
+// UserConfigPath returns ~/.ssh/config
+func UserConfigPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".ssh", "config")
+}
+
+// LookupConfigHost resolves an alias against ~/.ssh/config. Like OpenSSH, the
+// first value found for each keyword wins. ok is false when no Host block
+// other than "Host *" names the alias. Match blocks are not evaluated.
+func LookupConfigHost(alias string) (cfg HostConfig, ok bool, err error) {
+	p := UserConfigPath()
+	if p == "" {
+		return HostConfig{}, false, nil
+	}
+	if err := parseConfigFile(p, alias, &cfg, &ok, 0); err != nil {
+		if os.IsNotExist(err) {
+			return HostConfig{}, false, nil
+		}
+		return HostConfig{}, false, err
+	}
+	cfg.HostName = strings.ReplaceAll(cfg.HostName, "%h", alias)
+	if strings.EqualFold(cfg.ProxyJump, "none") {
+		cfg.ProxyJump = ""
+	}
+	return cfg, ok, nil
+}
+
+// parseConfigFile applies the entries of one config file that match alias
+func parseConfigFile(file, alias string, cfg *HostConfig, ok *bool, depth int) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	active := true // Lines before the first Host apply to every host
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		key, value := splitConfigLine(scanner.Text())
+		if key == "" {
+			continue
+		}
+
+		switch key {
+		case "host":
+			active = false
+			matched, named := matchHostPatterns(strings.Fields(value), alias)
+			if matched {
+				active = true
+				*ok = *ok || named
+			}
+			continue
+		case "match":
+			active = false
+			continue
+		}
+		if !active {
+			continue
+		}
+
+		switch key {
+		case "include":
+			if depth >= maxIncludeDepth {
+				return fmt.Errorf("%s:%d: too many nested Include directives", file, lineNo)
+			}
+			for _, pattern := range strings.Fields(value) {
+				pattern = expandHome(pattern)
+				if !filepath.IsAbs(pattern) {
+					pattern = filepath.Join(filepath.Dir(UserConfigPath()), pattern)
+				}
+				matches, err := filepath.Glob(pattern)
+				if err != nil {
+					return fmt.Errorf("%s:%d: %w", file, lineNo, err)
+				}
+				for _, m := range matches {
+					if err := parseConfigFile(m, alias, cfg, ok, depth+1); err != nil {
+						return err
+					}
+				}
+			}
+		case "hostname":
+			if cfg.HostName == "" {
+				cfg.HostName = value
+			}
+		case "user":
+			if cfg.User == "" {
+				cfg.User = value
+			}
+		case "port":
+			if cfg.Port == 0 {
+				port, err := strconv.Atoi(value)
+				if err != nil || port <= 0 || port > 65535 {
+					return fmt.Errorf("%s:%d: invalid port %q", file, lineNo, value)
+				}
+				cfg.Port = port
+			}
+		case "identityfile":
+			if cfg.IdentityFile == "" {
+				cfg.IdentityFile = expandHome(strings.Trim(value, `"`))
+			}
+		case "proxyjump":
+			if cfg.ProxyJump == "" {
+				cfg.ProxyJump = value
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading %s: %w", file, err)
+	}
+	return nil
+}
+
+// splitConfigLine returns a lowercased keyword and its value, accepting both
+// "Key value" and "Key=value"; comments and blank lines give an empty key
+func splitConfigLine(line string) (string, string) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return "", ""
+	}
+	i := strings.IndexAny(line, " \t=")
+	if i < 0 {
+		return strings.ToLower(line), ""
+	}
+	key := strings.ToLower(line[:i])
+	value := strings.TrimSpace(line[i:])
+	value = strings.TrimSpace(strings.TrimPrefix(value, "="))
+	return key, value
+}
+
+// matchHostPatterns reports whether alias matches a Host line, and whether
+// it matched a pattern other than "*". A matching !pattern rejects the line.
+func matchHostPatterns(patterns []string, alias string) (matched, named bool) {
+	for _, p := range patterns {
+		if neg, ok := strings.CutPrefix(p, "!"); ok {
+			if m, _ := path.Match(neg, alias); m {
+				return false, false
+			}
+			continue
+		}
+		if m, _ := path.Match(p, alias); m {
+			matched = true
+			named = named || p != "*"
+		}
+	}
+	return matched, named
+}
+
+// expandHome expands a leading ~/ or %d/ to the home directory
+func expandHome(p string) string {
+	rest, ok := strings.CutPrefix(p, "~/")
+	if !ok {
+		rest, ok = strings.CutPrefix(p, "%d/")
+	}
+	if !ok {
+		return p
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return p
+	}
+	return filepath.Join(home, rest)
+}
+
+// ApplyConfigHost fills unset fields of opts from the ~/.ssh/config entry for
+// alias: Host (HostName and Port), User, KeyPath (IdentityFile) and Jump
+// (ProxyJump, each hop also resolved through the config). Jump hosts reuse
+// opts' credentials unless their own entry names an IdentityFile. ok is false,
+// and opts unchanged, when the config has no entry for alias.
+func ApplyConfigHost(opts *ClientOptions, alias string) (ok bool, err error) {
+	cfg, ok, err := LookupConfigHost(alias)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	applyHostConfig(opts, alias, cfg)
+	if cfg.ProxyJump == "" {
+		return true, nil
+	}
+
+	// Hops are listed first to last; the last one dials the target
+	var jump *ClientOptions
+	for _, hop := range strings.Split(cfg.ProxyJump, ",") {
+		hopOpts, err := jumpOptions(strings.TrimSpace(hop), *opts)
+		if err != nil {
+			return false, err
+		}
+		hopOpts.Jump = jump
+		jump = &hopOpts
+	}
+	opts.Jump = jump
+	return true, nil
+}
+
+// applyHostConfig copies config values into opts where opts has none
+func applyHostConfig(opts *ClientOptions, alias string, cfg HostConfig) {
+	host := alias
+	if cfg.HostName != "" {
+		host = cfg.HostName
+	}
+	if cfg.Port != 0 {
+		host = net.JoinHostPort(host, strconv.Itoa(cfg.Port))
+	}
+	if opts.Host == "" || opts.Host == alias {
+		opts.Host = host
+	}
+	if opts.User == "" {
+		opts.User = cfg.User
+	}
+	if opts.KeyPath == "" && opts.Password == "" {
+		opts.KeyPath = cfg.IdentityFile
+	}
+}
+
+// jumpOptions builds the options for one ProxyJump hop, [user@]host[:port]
+func jumpOptions(hop string, target ClientOptions) (ClientOptions, error) {
+	if hop == "" {
+		return ClientOptions{}, fmt.Errorf("empty ProxyJump hop")
+	}
+	opts := ClientOptions{
+		Password:      target.Password,
+		KeyPassphrase: target.KeyPassphrase,
+		Timeout:       target.Timeout,
+		HostKeyPolicy: target.HostKeyPolicy,
+	}
+	if user, host, found := strings.Cut(hop, "@"); found {
+		opts.User = user
+		hop = host
+	}
+	alias := hop
+	port := ""
+	if h, p, err := net.SplitHostPort(hop); err == nil {
+		alias, port = h, p
+	}
+
+	cfg, _, err := LookupConfigHost(alias)
+	if err != nil {
+		return ClientOptions{}, fmt.Errorf("resolving jump host %s: %w", alias, err)
+	}
+	opts.KeyPath = cfg.IdentityFile
+	if opts.KeyPath == "" {
+		opts.KeyPath = target.KeyPath
+	}
+	applyHostConfig(&opts, alias, cfg)
+	if opts.User == "" {
+		opts.User = target.User
+	}
+	if port != "" {
+		opts.Host = net.JoinHostPort(hostnameOnly(opts.Host), port)
+	}
+	return opts, nil
+}
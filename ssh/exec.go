@@ -126,8 +126,13 @@ func (c *Client) RunQuiet(cmd string) error {
 	return nil
 }
 
-// Upload copies a local file to the remote host via SCP
+// Upload copies a local file to the remote host via SCP, or gzipped through
+// a plain session when the client has Compression set
 func (c *Client) Upload(localPath, remotePath string, progress func(written, total int64)) error {
+	if c.compress {
+		return c.uploadCompressed(localPath, remotePath, progress)
+	}
+
 	session, err := c.newSession()
 	if err != nil {
 		return err
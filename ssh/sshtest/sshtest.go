@@ -0,0 +1,150 @@
+// Package sshtest provides an in-process SSH server for testing code that
+// runs commands on Proxmox through an ssh.Client.
+package sshtest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/ssh"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// Handler answers one command with its stdout and exit code. It may be
+// called concurrently.
+type Handler func(cmd string) (stdout string, exitCode int)
+
+// NewServer starts an SSH server on localhost that answers every exec
+// request with run and forwards direct-tcpip channels, as a jump host does.
+// It accepts any password and returns the server's address; the server is
+// closed when the test ends.
+func NewServer(t testing.TB, run Handler) string {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := gossh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := &gossh.ServerConfig{
+		PasswordCallback: func(gossh.ConnMetadata, []byte) (*gossh.Permissions, error) { return nil, nil },
+	}
+	config.AddHostKey(signer)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serve(conn, config, run)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// NewClient starts a server with NewServer and returns a client connected
+// to it. Both are closed when the test ends.
+func NewClient(t testing.TB, run Handler) *ssh.Client {
+	t.Helper()
+	addr := NewServer(t, run)
+
+	client, err := ssh.NewClient(ssh.ClientOptions{
+		Host:          addr,
+		Password:      "test",
+		HostKeyPolicy: ssh.HostKeyIgnore,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// serve handles one client connection, running each session's command
+func serve(conn net.Conn, config *gossh.ServerConfig, run Handler) {
+	_, chans, reqs, err := gossh.NewServerConn(conn, config)
+	if err != nil {
+		return
+	}
+	go gossh.DiscardRequests(reqs)
+
+	for newCh := range chans {
+		if newCh.ChannelType() == "direct-tcpip" {
+			go forward(newCh)
+			continue
+		}
+		if newCh.ChannelType() != "session" {
+			newCh.Reject(gossh.UnknownChannelType, "session and direct-tcpip only")
+			continue
+		}
+		ch, chReqs, err := newCh.Accept()
+		if err != nil {
+			continue
+		}
+		go func() {
+			defer ch.Close()
+			for req := range chReqs {
+				if req.Type != "exec" || len(req.Payload) < 4 {
+					req.Reply(false, nil)
+					continue
+				}
+				req.Reply(true, nil)
+				stdout, code := run(string(req.Payload[4:]))
+				ch.Write([]byte(stdout))
+				status := make([]byte, 4)
+				binary.BigEndian.PutUint32(status, uint32(code))
+				ch.SendRequest("exit-status", false, status)
+				return
+			}
+		}()
+	}
+}
+
+// forward connects a direct-tcpip channel to the address it asks for
+func forward(newCh gossh.NewChannel) {
+	var target struct {
+		Host     string
+		Port     uint32
+		OrigHost string
+		OrigPort uint32
+	}
+	if err := gossh.Unmarshal(newCh.ExtraData(), &target); err != nil {
+		newCh.Reject(gossh.ConnectionFailed, err.Error())
+		return
+	}
+	conn, err := net.Dial("tcp", net.JoinHostPort(target.Host, strconv.Itoa(int(target.Port))))
+	if err != nil {
+		newCh.Reject(gossh.ConnectionFailed, err.Error())
+		return
+	}
+	ch, reqs, err := newCh.Accept()
+	if err != nil {
+		conn.Close()
+		return
+	}
+	go gossh.DiscardRequests(reqs)
+	go func() {
+		io.Copy(conn, ch)
+		conn.Close()
+	}()
+	io.Copy(ch, conn)
+	ch.Close()
+}
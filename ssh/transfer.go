@@ -0,0 +1,86 @@
+package ssh
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Ciphers lists the ciphers ClientOptions.Cipher may select, in the default
+// preference order. AES-GCM is fastest on CPUs with AES instructions;
+// ChaCha20-Poly1305 is usually faster on those without (older or ARM hosts).
+var Ciphers = []string{
+	"aes128-gcm@openssh.com",
+	"aes256-gcm@openssh.com",
+	"chacha20-poly1305@openssh.com",
+	"aes128-ctr",
+	"aes192-ctr",
+	"aes256-ctr",
+}
+
+// cipherPreference moves the preferred cipher to the front of the defaults,
+// so a server that doesn't offer it still negotiates one of the others
+func cipherPreference(preferred string) ([]string, error) {
+	found := false
+	ciphers := []string{preferred}
+	for _, c := range Ciphers {
+		if c == preferred {
+			found = true
+			continue
+		}
+		ciphers = append(ciphers, c)
+	}
+	if !found {
+		return nil, fmt.Errorf("unsupported SSH cipher %q (expected one of %s)", preferred, strings.Join(Ciphers, ", "))
+	}
+	return ciphers, nil
+}
+
+// uploadCompressed streams a local file gzipped at the fastest level and
+// unpacks it with gzip on the remote host. ISOs are mostly incompressible,
+// so this only pays off for sparse or text-heavy images over slow links.
+func (c *Client) uploadCompressed(localPath, remotePath string, progress func(written, total int64)) error {
+	session, err := c.newSession()
+	if err != nil {
+		return err
+	}
+	defer session.Close()
+
+	f, err := openFile(localPath)
+	if err != nil {
+		return fmt.Errorf("opening local file: %w", err)
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("getting file info: %w", err)
+	}
+
+	w, err := session.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("opening upload stream: %w", err)
+	}
+	copyErr := make(chan error, 1)
+	go func() {
+		defer w.Close()
+		gz, _ := gzip.NewWriterLevel(w, gzip.BestSpeed)
+		// Progress counts uncompressed bytes, so it tracks the file's size
+		pw := &progressWriter{w: gz, total: fi.Size(), callback: progress}
+		if _, err := io.Copy(pw, f); err != nil {
+			copyErr <- err
+			return
+		}
+		copyErr <- gz.Close()
+	}()
+
+	output, err := session.CombinedOutput("gzip -dc > " + ShellEscape(remotePath))
+	if err != nil {
+		return fmt.Errorf("compressed upload failed: %w (output: %s)", err, strings.TrimSpace(string(output)))
+	}
+	if err := <-copyErr; err != nil {
+		return fmt.Errorf("compressed upload failed: %w", err)
+	}
+	return nil
+}
@@ -8,6 +8,7 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"fmt"
 	"math/big"
 	"net"
 	"os"
@@ -40,9 +41,8 @@ func GenerateSelfSignedCert(certPath, keyPath string) error {
 		KeyUsage:              x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
 		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
 		BasicConstraintsValid: true,
-		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")},
-		DNSNames:              []string{"localhost"},
 	}
+	template.IPAddresses, template.DNSNames = certSANs()
 
 	// Create certificate
 	certDER, err := x509.CreateCertificate(rand.Reader, &template, &template, &privateKey.PublicKey, privateKey)
@@ -83,15 +83,66 @@ func GenerateSelfSignedCert(certPath, keyPath string) error {
 	return nil
 }
 
+// certSANs returns the IP and DNS subject alternative names for the self-signed cert:
+// loopback, the detected outbound IP, localhost and the machine hostname
+func certSANs() ([]net.IP, []string) {
+	ips := []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")}
+	if ip := net.ParseIP(getOutboundIP()); ip != nil && !ip.IsLoopback() {
+		ips = append(ips, ip)
+	}
+
+	dnsNames := []string{"localhost"}
+	if hostname, err := os.Hostname(); err == nil && hostname != "" && hostname != "localhost" {
+		dnsNames = append(dnsNames, hostname)
+	}
+
+	return ips, dnsNames
+}
+
+// selfSignedPaths returns the cert and key paths for the generated certificate
+func selfSignedPaths(configDir string) (string, string) {
+	return filepath.Join(configDir, "server.crt"), filepath.Join(configDir, "server.key")
+}
+
+// LoadCertFiles loads an operator-provided cert/key pair, failing if they don't
+// match or the certificate has expired
+func LoadCertFiles(certPath, keyPath string) (tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("loading TLS key pair: %w", err)
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("parsing TLS certificate: %w", err)
+	}
+	if time.Now().After(leaf.NotAfter) {
+		return tls.Certificate{}, fmt.Errorf("TLS certificate expired on %s", leaf.NotAfter.Format("2006-01-02"))
+	}
+	cert.Leaf = leaf
+
+	return cert, nil
+}
+
+// RegenerateCert replaces the self-signed cert in configDir with a fresh one
+func RegenerateCert(configDir string) (tls.Certificate, error) {
+	certPath, keyPath := selfSignedPaths(configDir)
+	if err := GenerateSelfSignedCert(certPath, keyPath); err != nil {
+		return tls.Certificate{}, fmt.Errorf("generating certificate: %w", err)
+	}
+	return tls.LoadX509KeyPair(certPath, keyPath)
+}
+
 // LoadOrGenerateCert loads existing cert or generates new one
 func LoadOrGenerateCert(configDir string) (tls.Certificate, error) {
-	certPath := filepath.Join(configDir, "server.crt")
-	keyPath := filepath.Join(configDir, "server.key")
+	certPath, keyPath := selfSignedPaths(configDir)
 
-	// Try to load existing
+	// Try to load existing, regenerating if it has expired
 	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
 	if err == nil {
-		return cert, nil
+		if leaf, perr := x509.ParseCertificate(cert.Certificate[0]); perr == nil && time.Now().Before(leaf.NotAfter) {
+			return cert, nil
+		}
 	}
 
 	// Generate new
@@ -6,6 +6,7 @@ import (
 	"io"
 	"log/slog"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -27,6 +28,7 @@ type ConsoleSession struct {
 	closeOnce sync.Once
 	wsConn    *websocket.Conn
 	pty       *ssh.PTYSession
+	logFile   *os.File // Recording of PTY output, nil when not logging
 	done      chan struct{}
 }
 
@@ -102,28 +104,37 @@ func (s *Server) handleConsoleSerial(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Pick a console tool that is known to work before opening the PTY.
+	tool, skipped, err := s.chooseConsoleTool(r.URL.Query().Get("tool"), vmid)
+	if err != nil {
+		slog.Warn("console serial: no usable console tool", "error", err, "vmid", vmid)
+		wsConn.WriteJSON(consoleMessage{
+			Type: "data",
+			Data: fmt.Sprintf("\r\n\x1b[31m%v\x1b[0m\r\n", err),
+		})
+		wsConn.WriteJSON(consoleMessage{Type: "error", Message: err.Error()})
+		wsConn.Close()
+		return
+	}
+	for _, reason := range skipped {
+		wsConn.WriteJSON(consoleMessage{
+			Type: "data",
+			Data: fmt.Sprintf("\x1b[33mSkipping: %s\x1b[0m\r\n", reason),
+		})
+	}
+
 	// Send initial status to the terminal
 	wsConn.WriteJSON(consoleMessage{
 		Type: "data",
-		Data: fmt.Sprintf("Connecting to VM %d serial console...\r\n", vmid),
+		Data: fmt.Sprintf("Connecting to VM %d serial console via %s...\r\n", vmid, tool),
 	})
 
-	// Try connecting directly to the QEMU serial socket first (most reliable),
-	// then fall back to qm terminal if the socket doesn't exist.
-	// On PVE 8.x, qm terminal uses a termproxy wrapper that doesn't pipe
-	// output through stdout, so direct socket connection is preferred.
-	socketPath := fmt.Sprintf("/var/run/qemu-server/%d.serial0", vmid)
-	command := fmt.Sprintf(
-		"if [ -S '%s' ]; then socat -,raw,echo=0 UNIX-CONNECT:'%s'; "+
-			"elif command -v miniterm >/dev/null 2>&1; then miniterm UNIX:'%s'; "+
-			"else qm terminal %d; fi",
-		socketPath, socketPath, socketPath, vmid,
-	)
+	command := consoleCommand(tool, vmid)
 
 	// Create PTY session
 	pty, err := ssh.NewPTYSession(s.sshClient, command, cols, rows)
 	if err != nil {
-		slog.Error("console serial: PTY creation failed", "error", err, "vmid", vmid, "command", command)
+		slog.Error("console serial: PTY creation failed", "error", err, "vmid", vmid, "tool", tool, "command", command)
 		wsConn.WriteJSON(consoleMessage{
 			Type: "data",
 			Data: fmt.Sprintf("\r\n\x1b[31mFailed to open terminal: %v\x1b[0m\r\n", err),
@@ -138,6 +149,25 @@ func (s *Server) handleConsoleSerial(w http.ResponseWriter, r *http.Request) {
 		Data: "Connected. Press Enter to activate console.\r\n",
 	})
 
+	// Tee PTY output to a recording file when enabled
+	var logFile *os.File
+	if s.consoleLogEnabled(r.URL.Query().Get("log")) {
+		logFile, err = s.openConsoleLog(vmid)
+		if err != nil {
+			slog.Warn("console serial: recording disabled", "error", err, "vmid", vmid)
+			wsConn.WriteJSON(consoleMessage{
+				Type: "data",
+				Data: fmt.Sprintf("\x1b[33mNot recording this session: %v\x1b[0m\r\n", err),
+			})
+		} else {
+			slog.Info("console serial: recording session", "vmid", vmid, "path", logFile.Name())
+			wsConn.WriteJSON(consoleMessage{
+				Type: "data",
+				Data: fmt.Sprintf("Recording to %s\r\n", logFile.Name()),
+			})
+		}
+	}
+
 	sessionID := fmt.Sprintf("serial-%d-%d", vmid, time.Now().UnixNano())
 	sess := &ConsoleSession{
 		ID:         sessionID,
@@ -147,6 +177,7 @@ func (s *Server) handleConsoleSerial(w http.ResponseWriter, r *http.Request) {
 		LastActive: time.Now(),
 		wsConn:     wsConn,
 		pty:        pty,
+		logFile:    logFile,
 		done:       make(chan struct{}),
 	}
 
@@ -159,6 +190,9 @@ func (s *Server) handleConsoleSerial(w http.ResponseWriter, r *http.Request) {
 		for {
 			n, err := pty.Read(buf)
 			if n > 0 {
+				if logFile != nil {
+					logFile.Write(buf[:n])
+				}
 				sess.mu.Lock()
 				sess.LastActive = time.Now()
 				writeErr := wsConn.WriteJSON(consoleMessage{Type: "data", Data: string(buf[:n])})
@@ -283,7 +317,17 @@ func (s *Server) handleConsoleTest(w http.ResponseWriter, r *http.Request) {
 	result, _ = s.sshClient.Run("command -v qm 2>&1")
 	addCheck(fmt.Sprintf("qm: %s", strings.TrimSpace(result.Stdout)))
 
-	// 7. Try a quick qm terminal test (1 second timeout)
+	// 7. Report which console tool the serial console would use
+	if tool, skipped, err := s.chooseConsoleTool(r.URL.Query().Get("tool"), vmid); err != nil {
+		addCheck(fmt.Sprintf("FAIL: %v", err))
+	} else {
+		for _, reason := range skipped {
+			addCheck("WARN: " + reason)
+		}
+		addCheck(fmt.Sprintf("Console command: %s", consoleCommand(tool, vmid)))
+	}
+
+	// 8. Try a quick qm terminal test (1 second timeout)
 	result, _ = s.sshClient.Run(fmt.Sprintf("timeout 2 qm terminal %d </dev/null 2>&1 || true", vmid))
 	addCheck(fmt.Sprintf("qm terminal test (2s): exit=%d stdout=%q stderr=%q",
 		result.ExitCode, strings.TrimSpace(result.Stdout), strings.TrimSpace(result.Stderr)))
@@ -350,6 +394,10 @@ func closeConsoleSession(sess *ConsoleSession) {
 			sess.pty.Close()
 		}
 
+		if sess.logFile != nil {
+			sess.logFile.Close()
+		}
+
 		// Remove from session map
 		consoleSessions.Delete(sess.ID)
 	})
@@ -380,7 +428,7 @@ func (s *Server) startSessionReaper() {
 	}()
 }
 
-// closeAllConsoleSessions closes all active console sessions. Called during server shutdown.
+// closeAllConsoleSessions closes all active console sessions. Called by Shutdown.
 func closeAllConsoleSessions() {
 	consoleSessions.Range(func(key, value interface{}) bool {
 		sess := value.(*ConsoleSession)
@@ -0,0 +1,72 @@
+package web
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/config"
+)
+
+// consoleLogEnabled reports whether a console session should be recorded. The
+// ?log= query param overrides the configured default.
+func (s *Server) consoleLogEnabled(param string) bool {
+	if enabled, err := strconv.ParseBool(param); err == nil {
+		return enabled
+	}
+	s.cfgMu.Lock()
+	defer s.cfgMu.Unlock()
+	return s.cfg.ConsoleLog
+}
+
+// openConsoleLog creates a timestamped recording file for a VM's serial
+// console, pruning recordings past the retention period first
+func (s *Server) openConsoleLog(vmid int) (*os.File, error) {
+	dir := config.ConsoleLogDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("creating console log directory: %w", err)
+	}
+
+	s.cfgMu.Lock()
+	days := s.cfg.ConsoleLogRetentionDays
+	s.cfgMu.Unlock()
+	if days > 0 {
+		pruneConsoleLogs(dir, time.Duration(days)*24*time.Hour)
+	}
+
+	name := fmt.Sprintf("vmid-%d-%s.log", vmid, time.Now().Format("20060102-150405"))
+	f, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("creating console log: %w", err)
+	}
+	return f, nil
+}
+
+// pruneConsoleLogs deletes recordings older than maxAge
+func pruneConsoleLogs(dir string, maxAge time.Duration) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), "vmid-") || !strings.HasSuffix(e.Name(), ".log") {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		path := filepath.Join(dir, e.Name())
+		if err := os.Remove(path); err != nil {
+			slog.Warn("console: failed to prune log", "path", path, "error", err)
+		} else {
+			slog.Info("console: pruned old log", "path", path)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/config"
+)
+
+// deployLogName matches the files handleDeploy writes, e.g.
+// deploy-2006-01-02_15-04-05.log; anything else is never served
+var deployLogName = regexp.MustCompile(`^deploy-[0-9_-]+\.log$`)
+
+// handleDeployLogs lists the deployment log files, newest first
+func (s *Server) handleDeployLogs(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	entries, err := os.ReadDir(config.DeployLogDir())
+	if err != nil && !os.IsNotExist(err) {
+		json.NewEncoder(w).Encode(DeployLogsResponse{APIResponse: APIResponse{Error: fmt.Sprintf("Reading log directory: %v", err)}})
+		return
+	}
+
+	logs := []DeployLogFile{}
+	for _, e := range entries {
+		if e.IsDir() || !deployLogName.MatchString(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		logs = append(logs, DeployLogFile{Name: e.Name(), Size: info.Size(), ModTime: info.ModTime()})
+	}
+	sort.Slice(logs, func(i, j int) bool { return logs[i].ModTime.After(logs[j].ModTime) })
+
+	json.NewEncoder(w).Encode(DeployLogsResponse{APIResponse: APIResponse{Success: true}, Logs: logs})
+}
+
+// handleDeployLog serves one deployment log as plain text. ?tail=N returns
+// only its last N lines.
+func (s *Server) handleDeployLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Only bare names of the expected form, so nothing outside the log
+	// directory can be reached
+	name := strings.TrimPrefix(r.URL.Path, "/api/deployments/logs/")
+	if !deployLogName.MatchString(name) {
+		http.Error(w, "Invalid log name", http.StatusBadRequest)
+		return
+	}
+
+	tail := 0
+	if v := r.URL.Query().Get("tail"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "tail must be a non-negative number of lines", http.StatusBadRequest)
+			return
+		}
+		tail = n
+	}
+
+	f, err := os.Open(filepath.Join(config.DeployLogDir(), name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			http.Error(w, "Log not found", http.StatusNotFound)
+		} else {
+			http.Error(w, "Failed to open log", http.StatusInternalServerError)
+		}
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if tail == 0 {
+		io.Copy(w, f)
+		return
+	}
+	data, err := tailLines(f, tail)
+	if err != nil {
+		http.Error(w, "Failed to read log", http.StatusInternalServerError)
+		return
+	}
+	w.Write(data)
+}
+
+// tailLines returns the last n lines of f, reading backwards from the end
+// in chunks so large logs aren't read whole
+func tailLines(f *os.File, n int) ([]byte, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	const chunkSize = 32 * 1024
+	var buf []byte
+	offset := info.Size()
+	for offset > 0 {
+		size := int64(chunkSize)
+		if size > offset {
+			size = offset
+		}
+		offset -= size
+		chunk := make([]byte, size)
+		if _, err := f.ReadAt(chunk, offset); err != nil && err != io.EOF {
+			return nil, err
+		}
+		buf = append(chunk, buf...)
+
+		// A trailing newline ends the last line rather than starting another
+		if strings.Count(strings.TrimSuffix(string(buf), "\n"), "\n") >= n {
+			break
+		}
+	}
+
+	content := strings.TrimSuffix(string(buf), "\n")
+	lines := strings.Split(content, "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	if content == "" {
+		return nil, nil
+	}
+	return []byte(strings.Join(lines, "\n") + "\n"), nil
+}
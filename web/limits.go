@@ -0,0 +1,114 @@
+package web
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Default connection timeouts. Writes get minutes because some API calls
+// (connect, source scans) do slow work before responding.
+const (
+	defaultReadHeaderTimeout = 10 * time.Second
+	defaultReadTimeout       = time.Minute
+	defaultWriteTimeout      = 5 * time.Minute
+	defaultIdleTimeout       = 2 * time.Minute
+)
+
+// ConnLimits bounds how long clients may hold connections to the web server
+// and how many they may open. Zero fields keep the defaults; MaxConns 0
+// means unlimited. Streaming endpoints (deploy progress SSE and the serial
+// console WebSocket) are exempt from the read and write timeouts.
+type ConnLimits struct {
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	MaxConns     int // Per listener; further clients wait until one closes
+}
+
+// SetConnLimits configures the HTTP and HTTPS servers' timeouts and connection cap
+func (s *Server) SetConnLimits(l ConnLimits) {
+	s.connLimits = l
+}
+
+// newHTTPServer builds an http.Server with the configured timeouts
+func (s *Server) newHTTPServer(addr string, handler http.Handler) *http.Server {
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: defaultReadHeaderTimeout,
+		ReadTimeout:       defaultReadTimeout,
+		WriteTimeout:      defaultWriteTimeout,
+		IdleTimeout:       defaultIdleTimeout,
+	}
+	if s.connLimits.ReadTimeout > 0 {
+		srv.ReadTimeout = s.connLimits.ReadTimeout
+		if srv.ReadTimeout < srv.ReadHeaderTimeout {
+			srv.ReadHeaderTimeout = srv.ReadTimeout
+		}
+	}
+	if s.connLimits.WriteTimeout > 0 {
+		srv.WriteTimeout = s.connLimits.WriteTimeout
+	}
+	if s.connLimits.IdleTimeout > 0 {
+		srv.IdleTimeout = s.connLimits.IdleTimeout
+	}
+	return srv
+}
+
+// listen opens a TCP listener, capped at MaxConns concurrent connections
+func (s *Server) listen(addr string) (net.Listener, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	if s.connLimits.MaxConns > 0 {
+		ln = newLimitListener(ln, s.connLimits.MaxConns)
+	}
+	return ln, nil
+}
+
+// streaming clears the server's read and write deadlines for a long-lived
+// response, so the timeouts don't cut an SSE stream or WebSocket mid-flow
+func streaming(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rc := http.NewResponseController(w)
+		rc.SetReadDeadline(time.Time{})
+		rc.SetWriteDeadline(time.Time{})
+		h(w, r)
+	}
+}
+
+// limitListener blocks Accept while max connections are open
+type limitListener struct {
+	net.Listener
+	sem chan struct{}
+}
+
+func newLimitListener(ln net.Listener, max int) *limitListener {
+	return &limitListener{Listener: ln, sem: make(chan struct{}, max)}
+}
+
+func (l *limitListener) Accept() (net.Conn, error) {
+	l.sem <- struct{}{}
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		<-l.sem
+		return nil, err
+	}
+	return &limitConn{Conn: conn, release: func() { <-l.sem }}, nil
+}
+
+// limitConn frees its listener slot once, however many times it is closed
+type limitConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *limitConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
@@ -0,0 +1,186 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/proxmox"
+)
+
+// Live deployment status polling. VM states come from one cluster-wide call
+// per poll; which VMs belong to the deployment, and guest agent IPs that
+// aren't known yet, are re-read less often.
+const (
+	liveStatusPoll        = 3 * time.Second
+	liveStatusMembersPoll = 30 * time.Second
+	liveStatusIPRetry     = 15 * time.Second
+)
+
+// handleDeploymentStatus returns the live power state and guest agent IP of
+// every VM in one or more deployments (?prefix=, repeatable). With ?stream=1
+// the states are streamed over SSE, with a new event whenever one changes;
+// one stream serves every deployment on a page, since browsers allow only a
+// few open connections per host.
+func (s *Server) handleDeploymentStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	prefixes := r.URL.Query()["prefix"]
+	if r.URL.Query().Get("stream") == "1" {
+		s.streamDeploymentStatus(w, r, prefixes)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if len(prefixes) == 0 {
+		json.NewEncoder(w).Encode(DeploymentStatusResponse{APIResponse: APIResponse{Error: "prefix is required"}})
+		return
+	}
+	if s.sshClient == nil || s.discoverer == nil {
+		json.NewEncoder(w).Encode(DeploymentStatusResponse{APIResponse: APIResponse{Error: "Not connected to Proxmox"}})
+		return
+	}
+
+	tracker := newLiveStatusTracker(proxmox.NewVMCreator(s.sshClient), s.discoverer, prefixes)
+	json.NewEncoder(w).Encode(tracker.response())
+}
+
+// streamDeploymentStatus sends deployments' live status as SSE events,
+// polling until the client disconnects
+func (s *Server) streamDeploymentStatus(w http.ResponseWriter, r *http.Request, prefixes []string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	if len(prefixes) == 0 {
+		http.Error(w, "prefix is required", http.StatusBadRequest)
+		return
+	}
+	if s.sshClient == nil || s.discoverer == nil {
+		http.Error(w, "Not connected to Proxmox", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetry.Milliseconds())
+
+	tracker := newLiveStatusTracker(proxmox.NewVMCreator(s.sshClient), s.discoverer, prefixes)
+	ticker := time.NewTicker(liveStatusPoll)
+	defer ticker.Stop()
+
+	var last []byte
+	for {
+		data, _ := json.Marshal(tracker.response())
+		if !bytes.Equal(data, last) {
+			writeSSEEvent(w, sseEvent{Data: string(data)})
+			last = data
+		} else {
+			fmt.Fprintf(w, ": keepalive\n\n")
+		}
+		flusher.Flush()
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.shutdown:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// liveStatusTracker polls the VMs of a set of deployments, remembering
+// membership and guest IPs between polls
+type liveStatusTracker struct {
+	creator    *proxmox.VMCreator
+	discoverer *proxmox.Discoverer
+	prefixes   []string
+	wanted     map[string]bool
+
+	members   []proxmox.VMInfo
+	membersAt time.Time
+	ips       map[int]string
+	ipAt      map[int]time.Time // Last guest agent query per VM
+	status    map[int]string    // Status seen by the previous poll
+}
+
+func newLiveStatusTracker(creator *proxmox.VMCreator, discoverer *proxmox.Discoverer, prefixes []string) *liveStatusTracker {
+	wanted := make(map[string]bool, len(prefixes))
+	for _, p := range prefixes {
+		wanted[p] = true
+	}
+	return &liveStatusTracker{
+		creator:    creator,
+		discoverer: discoverer,
+		prefixes:   prefixes,
+		wanted:     wanted,
+		ips:        make(map[int]string),
+		ipAt:       make(map[int]time.Time),
+		status:     make(map[int]string),
+	}
+}
+
+// response polls once and wraps the result for the API
+func (t *liveStatusTracker) response() DeploymentStatusResponse {
+	vms, err := t.poll()
+	if err != nil {
+		return DeploymentStatusResponse{APIResponse: APIResponse{Error: err.Error()}, Prefixes: t.prefixes}
+	}
+	return DeploymentStatusResponse{APIResponse: APIResponse{Success: true}, Prefixes: t.prefixes, VMs: vms}
+}
+
+// poll reads the current state of the deployments' VMs
+func (t *liveStatusTracker) poll() ([]VMLiveStatus, error) {
+	if t.members == nil || time.Since(t.membersAt) >= liveStatusMembersPoll {
+		vms, err := t.discoverer.FindVersaDeployments()
+		if err != nil {
+			return nil, fmt.Errorf("finding deployment VMs: %w", err)
+		}
+		members := []proxmox.VMInfo{}
+		for _, vm := range vms {
+			if t.wanted[extractDeployPrefix(vm)] {
+				members = append(members, vm)
+			}
+		}
+		sort.Slice(members, func(i, j int) bool { return members[i].VMID < members[j].VMID })
+		t.members, t.membersAt = members, time.Now()
+	}
+
+	states, err := t.creator.GetPowerStates()
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]VMLiveStatus, 0, len(t.members))
+	for _, vm := range t.members {
+		st, ok := states[vm.VMID]
+		if !ok {
+			continue // Destroyed since membership was read
+		}
+		entry := VMLiveStatus{VMID: vm.VMID, Name: vm.Name, Prefix: extractDeployPrefix(vm), Node: st.Node, Status: st.Status}
+
+		if st.Status == "running" {
+			// Query the agent when the VM (re)starts, and retry while it has no IP
+			justStarted := t.status[vm.VMID] != "running"
+			if justStarted || (t.ips[vm.VMID] == "" && time.Since(t.ipAt[vm.VMID]) >= liveStatusIPRetry) {
+				ip, _ := t.creator.GetGuestIP(st.Node, vm.VMID) // The agent is often not up yet
+				t.ips[vm.VMID], t.ipAt[vm.VMID] = ip, time.Now()
+			}
+			entry.IP = t.ips[vm.VMID]
+		} else {
+			delete(t.ips, vm.VMID)
+		}
+		t.status[vm.VMID] = st.Status
+		out = append(out, entry)
+	}
+	return out, nil
+}
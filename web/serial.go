@@ -0,0 +1,134 @@
+package web
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/mihailvovk/versa-proxmox-deployer/config"
+	"github.com/mihailvovk/versa-proxmox-deployer/ssh"
+)
+
+// Serial console tools. The default fallback chain is socat, then miniterm,
+// then qm terminal. socat and miniterm attach directly to the QEMU serial
+// socket, which is the most reliable path; qm terminal is the last resort
+// because on PVE 8.x its termproxy wrapper doesn't pipe output through stdout.
+const (
+	consoleToolSocat    = "socat"
+	consoleToolMiniterm = "miniterm"
+	consoleToolQM       = "qm"
+)
+
+var defaultConsoleChain = config.ConsoleTools
+
+// consoleProbe records which console tools exist on the host and whether the
+// VM's serial socket is present
+type consoleProbe struct {
+	Tools  map[string]bool
+	Socket bool
+}
+
+// serialSocketPath returns the QEMU serial0 socket path for a VM
+func serialSocketPath(vmid int) string {
+	return fmt.Sprintf("/var/run/qemu-server/%d.serial0", vmid)
+}
+
+// consoleChain returns the tool order to try, with the preferred tool (if
+// any) moved to the front of the default chain
+func consoleChain(preferred string) ([]string, error) {
+	if err := config.ValidateConsoleTool(preferred); err != nil {
+		return nil, err
+	}
+	preferred = strings.ToLower(strings.TrimSpace(preferred))
+	if preferred == "" || preferred == "auto" {
+		return defaultConsoleChain, nil
+	}
+
+	chain := []string{preferred}
+	for _, tool := range defaultConsoleChain {
+		if tool != preferred {
+			chain = append(chain, tool)
+		}
+	}
+	return chain, nil
+}
+
+// consoleCommand builds the shell command that attaches a tool to a VM's serial console
+func consoleCommand(tool string, vmid int) string {
+	socket := ssh.ShellEscape(serialSocketPath(vmid))
+	switch tool {
+	case consoleToolSocat:
+		return "socat -,raw,echo=0 UNIX-CONNECT:" + socket
+	case consoleToolMiniterm:
+		return "miniterm UNIX:" + socket
+	default:
+		return fmt.Sprintf("qm terminal %d", vmid)
+	}
+}
+
+// probeConsole checks tool availability and the serial socket in a single round trip
+func probeConsole(client *ssh.Client, vmid int) (*consoleProbe, error) {
+	cmd := fmt.Sprintf(
+		"for t in %s; do command -v $t >/dev/null 2>&1 && echo tool:$t; done; [ -S %s ] && echo socket; true",
+		strings.Join(defaultConsoleChain, " "), ssh.ShellEscape(serialSocketPath(vmid)),
+	)
+	result, err := client.Run(cmd)
+	if err != nil {
+		return nil, fmt.Errorf("probing console tools: %w", err)
+	}
+
+	probe := &consoleProbe{Tools: make(map[string]bool)}
+	for _, line := range strings.Split(result.Stdout, "\n") {
+		line = strings.TrimSpace(line)
+		if tool, ok := strings.CutPrefix(line, "tool:"); ok {
+			probe.Tools[tool] = true
+		} else if line == "socket" {
+			probe.Socket = true
+		}
+	}
+	return probe, nil
+}
+
+// chooseConsoleTool probes the host and picks a console tool for a VM. The
+// preferred tool (from the ?tool= query param) overrides the configured one.
+func (s *Server) chooseConsoleTool(preferred string, vmid int) (string, []string, error) {
+	if preferred == "" {
+		s.cfgMu.Lock()
+		preferred = s.cfg.ConsoleTool
+		s.cfgMu.Unlock()
+	}
+	chain, err := consoleChain(preferred)
+	if err != nil {
+		return "", nil, err
+	}
+	probe, err := probeConsole(s.sshClient, vmid)
+	if err != nil {
+		return "", nil, err
+	}
+	return selectConsoleTool(chain, probe, vmid)
+}
+
+// unavailable returns why a tool can't be used, or "" if it can
+func (p *consoleProbe) unavailable(tool string, vmid int) string {
+	if !p.Tools[tool] {
+		return fmt.Sprintf("%s is not installed on the host", tool)
+	}
+	if tool != consoleToolQM && !p.Socket {
+		return fmt.Sprintf("%s needs serial socket %s, which does not exist (is the VM running?)", tool, serialSocketPath(vmid))
+	}
+	return ""
+}
+
+// selectConsoleTool returns the first usable tool in the chain, plus the
+// reasons any earlier tools were skipped. If none is usable, the error names
+// every missing tool.
+func selectConsoleTool(chain []string, probe *consoleProbe, vmid int) (string, []string, error) {
+	var skipped []string
+	for _, tool := range chain {
+		reason := probe.unavailable(tool, vmid)
+		if reason == "" {
+			return tool, skipped, nil
+		}
+		skipped = append(skipped, reason)
+	}
+	return "", skipped, fmt.Errorf("no usable serial console tool: %s", strings.Join(skipped, "; "))
+}
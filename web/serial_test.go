@@ -0,0 +1,64 @@
+package web
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConsoleChain(t *testing.T) {
+	tests := []struct {
+		preferred string
+		want      []string
+		wantErr   bool
+	}{
+		{"", []string{"socat", "miniterm", "qm"}, false},
+		{"auto", []string{"socat", "miniterm", "qm"}, false},
+		{"socat", []string{"socat", "miniterm", "qm"}, false},
+		{"miniterm", []string{"miniterm", "socat", "qm"}, false},
+		{" QM ", []string{"qm", "socat", "miniterm"}, false},
+		{"screen", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := consoleChain(tt.preferred)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("consoleChain(%q) = %v, %v; want %v (error %v)", tt.preferred, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestSelectConsoleTool(t *testing.T) {
+	tools := func(names ...string) map[string]bool {
+		m := make(map[string]bool)
+		for _, n := range names {
+			m[n] = true
+		}
+		return m
+	}
+
+	tests := []struct {
+		name        string
+		preferred   string
+		probe       consoleProbe
+		want        string
+		wantSkipped int
+		wantErr     bool
+	}{
+		{"socat first", "", consoleProbe{Tools: tools("socat", "miniterm", "qm"), Socket: true}, "socat", 0, false},
+		{"miniterm without socat", "", consoleProbe{Tools: tools("miniterm", "qm"), Socket: true}, "miniterm", 1, false},
+		{"qm without the socket", "", consoleProbe{Tools: tools("socat", "miniterm", "qm")}, "qm", 2, false},
+		{"preferred tool wins", "qm", consoleProbe{Tools: tools("socat", "qm"), Socket: true}, "qm", 0, false},
+		{"preferred tool missing falls back", "miniterm", consoleProbe{Tools: tools("socat", "qm"), Socket: true}, "socat", 1, false},
+		{"nothing usable", "", consoleProbe{Tools: tools("socat")}, "", 3, true},
+	}
+	for _, tt := range tests {
+		chain, err := consoleChain(tt.preferred)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, skipped, err := selectConsoleTool(chain, &tt.probe, 101)
+		if got != tt.want || len(skipped) != tt.wantSkipped || (err != nil) != tt.wantErr {
+			t.Errorf("%s: got %q, skipped %q, error %v; want %q after %d skips (error %v)",
+				tt.name, got, skipped, err, tt.want, tt.wantSkipped, tt.wantErr)
+		}
+	}
+}
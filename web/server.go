@@ -1,9 +1,12 @@
 package web
 
 import (
+	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -13,6 +16,8 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -32,6 +37,7 @@ var staticFiles embed.FS
 
 // Server is the web UI server
 type Server struct {
+	cfgMu     sync.Mutex // guards cfg: handlers and background scans both change it
 	cfg       *config.Config
 	httpsPort int
 
@@ -41,14 +47,66 @@ type Server struct {
 	// Cached discovery results
 	mu             sync.RWMutex
 	discoveryState *DiscoveryState
+	lastScan       *sources.ISOCollection // most recent source scan
 
-	// SSE clients for deployment progress
+	// SSE clients for deployment progress, plus a bounded backlog of recent
+	// events replayed to clients that reconnect with Last-Event-ID
 	sseMu      sync.Mutex
-	sseClients map[chan string]struct{}
+	sseClients map[chan sseEvent]struct{}
+	sseEvents  []sseEvent
+	sseNextID  int64
 
 	// Deploy status tracking
 	deployMu     sync.RWMutex
 	deployStatus *DeployStatus
+	activeDeploy *deployer.Deployer // Running deployment, for cancelling its downloads
+
+	// TLS certificate, swappable at runtime via /api/cert/regenerate
+	tlsCertPath string // operator-provided cert (empty = self-signed)
+	tlsKeyPath  string
+	certMu      sync.RWMutex
+	cert        *tls.Certificate
+	tlsPolicy   TLSPolicy
+
+	hostKeyPolicy ssh.HostKeyPolicy // SSH host key verification for /api/connect
+	sshCipher     string            // Preferred SSH cipher for /api/connect
+	compressSSH   bool              // Gzip ISO uploads in transit
+
+	// Source scans are single-flight: callers arriving mid-scan share its result
+	scanMu         sync.Mutex
+	scan           *scanFlight
+	rescanInterval time.Duration // background rescan period (0 = off)
+	noStartupScan  bool          // wait for connect or an explicit rescan
+
+	connLimits ConnLimits // HTTP(S) server timeouts and connection cap
+
+	caBundle string // PEM CA file Proxmox-side downloads verify against
+
+	// Closed by Shutdown to end SSE streams; servers are the running
+	// HTTP and HTTPS servers it stops
+	shutdown     chan struct{}
+	shutdownOnce sync.Once
+	serversMu    sync.Mutex
+	servers      []*http.Server
+}
+
+// scanFlight is one in-progress source scan shared by concurrent callers
+type scanFlight struct {
+	done       chan struct{}
+	collection *sources.ISOCollection
+	err        error
+}
+
+// SSE replay settings
+const (
+	sseBacklogSize = 500             // events kept for Last-Event-ID replay
+	sseRetry       = 3 * time.Second // client reconnect delay
+)
+
+// sseEvent is one deploy progress message with its stream ID
+type sseEvent struct {
+	ID   int64
+	Data string
 }
 
 // DeployStatus tracks current deployment state
@@ -63,6 +121,9 @@ type DeployStatus struct {
 	} `json:"progress"`
 	Error    string `json:"error,omitempty"`
 	Complete bool   `json:"complete"`
+
+	// ISO downloads running as Proxmox tasks, which can be cancelled
+	DownloadTasks []deployer.DownloadTask `json:"downloadTasks,omitempty"`
 }
 
 // DiscoveryState holds all discovered data
@@ -84,10 +145,54 @@ func NewServer(cfg *config.Config, httpsPort int) *Server {
 	return &Server{
 		cfg:        cfg,
 		httpsPort:  httpsPort,
-		sseClients: make(map[chan string]struct{}),
+		sseClients: make(map[chan sseEvent]struct{}),
+		shutdown:   make(chan struct{}),
 	}
 }
 
+// SetTLSFiles configures an operator-provided certificate and key instead of
+// the generated self-signed pair
+func (s *Server) SetTLSFiles(certPath, keyPath string) {
+	s.tlsCertPath = certPath
+	s.tlsKeyPath = keyPath
+}
+
+// SetHostKeyPolicy sets how SSH host keys are verified when connecting to Proxmox
+func (s *Server) SetHostKeyPolicy(p ssh.HostKeyPolicy) {
+	s.hostKeyPolicy = p
+}
+
+// SetUploadTuning sets the preferred SSH cipher and whether ISO uploads are
+// gzipped in transit for connections made through /api/connect
+func (s *Server) SetUploadTuning(cipher string, compress bool) {
+	s.sshCipher = cipher
+	s.compressSSH = compress
+}
+
+// SetRescanInterval enables a periodic background rescan of image sources
+// so newly published ISOs show up without a restart (0 disables it)
+func (s *Server) SetRescanInterval(d time.Duration) {
+	s.rescanInterval = d
+}
+
+// DisableStartupScan skips the image source scan when the server starts, so
+// it is up at once and sources are only contacted on connect or rescan
+func (s *Server) DisableStartupScan() {
+	s.noStartupScan = true
+}
+
+// SetCABundle sets the CA bundle deployments pass to downloads on Proxmox
+func (s *Server) SetCABundle(path string) {
+	s.caBundle = path
+}
+
+// getCertificate serves the current certificate, picking up regenerations
+func (s *Server) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.certMu.RLock()
+	defer s.certMu.RUnlock()
+	return s.cert, nil
+}
+
 // getOutboundIP returns the preferred outbound IP of this machine
 func getOutboundIP() string {
 	conn, err := net.DialTimeout("udp", "8.8.8.8:80", 2*time.Second)
@@ -101,16 +206,33 @@ func getOutboundIP() string {
 
 // Start starts both HTTP and HTTPS servers
 func (s *Server) Start(httpPort int) error {
-	// Scan image sources on startup so images are ready before user connects
-	go s.scanAndUpdateImages()
+	// Scan image sources on startup so images are ready before user connects;
+	// otherwise the first connect or rescan does it
+	if !s.noStartupScan {
+		go s.scanAndUpdateImages()
+	}
 
 	// Start console session reaper for idle timeout cleanup
 	s.startSessionReaper()
 
-	cert, err := LoadOrGenerateCert(config.ConfigDir())
-	if err != nil {
-		return fmt.Errorf("failed to load/generate certificate: %w", err)
+	if s.rescanInterval > 0 {
+		s.startRescanTicker()
+	}
+
+	var cert tls.Certificate
+	var err error
+	if s.tlsCertPath != "" {
+		cert, err = LoadCertFiles(s.tlsCertPath, s.tlsKeyPath)
+		if err != nil {
+			return fmt.Errorf("invalid TLS certificate: %w", err)
+		}
+	} else {
+		cert, err = LoadOrGenerateCert(config.ConfigDir())
+		if err != nil {
+			return fmt.Errorf("failed to load/generate certificate: %w", err)
+		}
 	}
+	s.cert = &cert
 
 	mux := http.NewServeMux()
 
@@ -118,20 +240,34 @@ func (s *Server) Start(httpPort int) error {
 	mux.HandleFunc("/api/config", s.handleConfig)
 	mux.HandleFunc("/api/connect", s.handleConnect)
 	mux.HandleFunc("/api/discovery", s.handleDiscovery)
+	mux.HandleFunc("/api/discovery/refresh", s.handleDiscoveryRefresh)
 	mux.HandleFunc("/api/deploy", s.handleDeploy)
-	mux.HandleFunc("/api/deploy/progress", s.handleDeployProgress)
+	mux.HandleFunc("/api/deploy/progress", streaming(s.handleDeployProgress))
 	mux.HandleFunc("/api/deploy/status", s.handleDeployStatus)
+	mux.HandleFunc("/api/deploy/cancel-download", s.handleDeployCancelDownload)
 	mux.HandleFunc("/api/create-network", s.handleCreateNetwork)
 	mux.HandleFunc("/api/scan-sources", s.handleScanSources)
 	mux.HandleFunc("/api/sources", s.handleSources)
+	mux.HandleFunc("/api/components/availability", s.handleComponentsAvailability)
+	mux.HandleFunc("/api/images/verify", s.handleVerifyImages)
 	mux.HandleFunc("/api/upload-key", s.handleUploadKey)
 	mux.HandleFunc("/api/connection/status", s.handleConnectionStatus)
 	mux.HandleFunc("/api/deployments", s.handleDeployments)
+	mux.HandleFunc("/api/deployments/status", streaming(s.handleDeploymentStatus))
 	mux.HandleFunc("/api/deployments/stop", s.handleDeploymentsStop)
 	mux.HandleFunc("/api/deployments/delete", s.handleDeploymentsDelete)
+	mux.HandleFunc("/api/deployments/reclaim", s.handleDeploymentsReclaim)
+	mux.HandleFunc("/api/deployments/export", s.handleDeploymentsExport)
+	mux.HandleFunc("/api/deployments/logs", s.handleDeployLogs)
+	mux.HandleFunc("/api/deployments/logs/", s.handleDeployLog)
+	mux.HandleFunc("/api/vm/snapshot", s.handleVMSnapshot)
+	mux.HandleFunc("/api/vm/snapshot/rollback", s.handleVMSnapshotRollback)
+	mux.HandleFunc("/api/vm/pending-networks", s.handleVMPendingNetworks)
+	mux.HandleFunc("/api/vm/iso", s.handleVMISO)
+	mux.HandleFunc("/api/cert/regenerate", s.handleRegenCert)
 
 	// Console routes
-	mux.HandleFunc("/api/console/serial", s.handleConsoleSerial)
+	mux.HandleFunc("/api/console/serial", streaming(s.handleConsoleSerial))
 	mux.HandleFunc("/api/console/sessions", s.handleConsoleSessions)
 	mux.HandleFunc("/api/console/test", s.handleConsoleTest)
 
@@ -162,33 +298,36 @@ func (s *Server) Start(httpPort int) error {
 	fmt.Printf("╚════════════════════════════════════════════════════════════╝\n")
 	fmt.Printf("\n")
 
+	handler := s.securityHeaders(mux)
+
 	// Start HTTP server in background
+	httpServer := s.newHTTPServer(fmt.Sprintf("0.0.0.0:%d", httpPort), handler)
+	s.trackServer(httpServer)
 	go func() {
-		httpServer := &http.Server{
-			Addr:    fmt.Sprintf("0.0.0.0:%d", httpPort),
-			Handler: mux,
+		listener, err := s.listen(httpServer.Addr)
+		if err != nil {
+			slog.Error("http server failed", "error", err)
+			return
 		}
-		if err := httpServer.ListenAndServe(); err != nil {
+		if err := httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			slog.Error("http server failed", "error", err)
 		}
 	}()
 
-	// Start HTTPS server (blocks)
-	httpsServer := &http.Server{
-		Addr:    fmt.Sprintf("0.0.0.0:%d", s.httpsPort),
-		Handler: mux,
-		TLSConfig: &tls.Config{
-			Certificates: []tls.Certificate{cert},
-			MinVersion:   tls.VersionTLS12,
-		},
-	}
+	// Start HTTPS server (blocks until Shutdown)
+	httpsServer := s.newHTTPServer(fmt.Sprintf("0.0.0.0:%d", s.httpsPort), handler)
+	httpsServer.TLSConfig = s.tlsConfig()
+	s.trackServer(httpsServer)
 
-	listener, err := net.Listen("tcp", httpsServer.Addr)
+	listener, err := s.listen(httpsServer.Addr)
 	if err != nil {
 		return fmt.Errorf("HTTPS listen failed on port %d: %w", s.httpsPort, err)
 	}
 
-	return httpsServer.ServeTLS(listener, "", "")
+	if err := httpsServer.ServeTLS(listener, "", ""); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
 }
 
 // --- API Handlers ---
@@ -198,14 +337,18 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case "GET":
-		json.NewEncoder(w).Encode(ConfigResponse{
+		s.cfgMu.Lock()
+		resp := ConfigResponse{
 			LastProxmoxHost: s.cfg.LastProxmoxHost,
 			LastProxmoxUser: s.cfg.LastProxmoxUser,
 			LastStorage:     s.cfg.LastStorage,
 			LastSSHKeyPath:  s.cfg.LastSSHKeyPath,
-			ImageSources:    s.cfg.ImageSources,
+			ImageSources:    config.RedactSources(s.cfg.ImageSources),
+			MaxImageSources: s.cfg.SourceLimit(),
 			HasPassword:     s.cfg.LastProxmoxPassword != "",
-		})
+		}
+		s.cfgMu.Unlock()
+		json.NewEncoder(w).Encode(resp)
 
 	case "POST":
 		var updates map[string]interface{}
@@ -213,22 +356,24 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
-		if v, ok := updates["lastProxmoxHost"].(string); ok {
-			s.cfg.LastProxmoxHost = v
-		}
-		if v, ok := updates["lastProxmoxUser"].(string); ok {
-			s.cfg.LastProxmoxUser = v
-		}
-		if v, ok := updates["lastProxmoxPassword"].(string); ok {
-			s.cfg.LastProxmoxPassword = v
-		}
-		if v, ok := updates["lastStorage"].(string); ok {
-			s.cfg.LastStorage = v
-		}
-		if v, ok := updates["lastSSHKeyPath"].(string); ok {
-			s.cfg.LastSSHKeyPath = v
-		}
-		if err := s.cfg.Save(); err != nil {
+		err := s.updateConfig(func(cfg *config.Config) {
+			if v, ok := updates["lastProxmoxHost"].(string); ok {
+				cfg.LastProxmoxHost = v
+			}
+			if v, ok := updates["lastProxmoxUser"].(string); ok {
+				cfg.LastProxmoxUser = v
+			}
+			if v, ok := updates["lastProxmoxPassword"].(string); ok {
+				cfg.LastProxmoxPassword = v
+			}
+			if v, ok := updates["lastStorage"].(string); ok {
+				cfg.LastStorage = v
+			}
+			if v, ok := updates["lastSSHKeyPath"].(string); ok {
+				cfg.LastSSHKeyPath = v
+			}
+		})
+		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -282,11 +427,14 @@ func (s *Server) handleConnect(w http.ResponseWriter, r *http.Request) {
 
 	// Build SSH client options
 	opts := ssh.ClientOptions{
-		Host:         req.Host,
-		User:         req.User,
-		Timeout:      30 * time.Second,
-		HostKeyCheck: true,
-	}
+		Host:          req.Host,
+		User:          req.User,
+		Timeout:       30 * time.Second,
+		HostKeyPolicy: s.hostKeyPolicy,
+		Cipher:        s.sshCipher,
+		Compression:   s.compressSSH,
+	}
+	s.cfgMu.Lock()
 	if req.SSHKeyPath != "" {
 		opts.KeyPath = req.SSHKeyPath
 	} else if s.cfg.LastSSHKeyPath != "" {
@@ -298,6 +446,7 @@ func (s *Server) handleConnect(w http.ResponseWriter, r *http.Request) {
 		// Use saved password when user leaves the field empty
 		opts.Password = s.cfg.LastProxmoxPassword
 	}
+	s.cfgMu.Unlock()
 
 	client, err := ssh.NewClient(opts)
 	if err != nil {
@@ -319,15 +468,16 @@ func (s *Server) handleConnect(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Save connection info
-	s.cfg.LastProxmoxHost = req.Host
-	s.cfg.LastProxmoxUser = req.User
-	if req.SavePassword && req.Password != "" {
-		s.cfg.LastProxmoxPassword = req.Password
-	}
-	if req.SSHKeyPath != "" {
-		s.cfg.LastSSHKeyPath = req.SSHKeyPath
-	}
-	s.cfg.Save()
+	s.updateConfig(func(cfg *config.Config) {
+		cfg.LastProxmoxHost = req.Host
+		cfg.LastProxmoxUser = req.User
+		if req.SavePassword && req.Password != "" {
+			cfg.LastProxmoxPassword = req.Password
+		}
+		if req.SSHKeyPath != "" {
+			cfg.LastSSHKeyPath = req.SSHKeyPath
+		}
+	})
 
 	// Close any previous connection
 	if s.sshClient != nil {
@@ -369,29 +519,7 @@ func (s *Server) runParallelDiscovery() {
 	s.mu.Unlock()
 
 	// Scan image sources in background (can be slow)
-	go func() {
-		imageSources, err := sources.CreateSourcesFromConfig(s.cfg)
-		if err != nil {
-			return
-		}
-
-		collection, err := sources.ScanAllSources(imageSources)
-		if err != nil {
-			return
-		}
-
-		var allImages []sources.ISOFile
-		allImages = append(allImages, collection.Director...)
-		allImages = append(allImages, collection.Analytics...)
-		allImages = append(allImages, collection.FlexVNF...)
-		allImages = append(allImages, collection.Concerto...)
-
-		s.mu.Lock()
-		if s.discoveryState != nil {
-			s.discoveryState.Images = allImages
-		}
-		s.mu.Unlock()
-	}()
+	go s.scanAndUpdateImages()
 }
 
 func (s *Server) handleDiscovery(w http.ResponseWriter, r *http.Request) {
@@ -412,22 +540,222 @@ func (s *Server) handleDiscovery(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(state)
 }
 
+// Discovery sections that can be refreshed individually
+var discoverySections = map[string]bool{
+	"nodes":    true,
+	"storage":  true,
+	"networks": true,
+	"vms":      true,
+}
+
+// parseDiscoverySections parses a comma-separated section list (empty = all)
+func parseDiscoverySections(param string) ([]string, error) {
+	if strings.TrimSpace(param) == "" {
+		return []string{"nodes", "storage", "networks", "vms"}, nil
+	}
+
+	var sections []string
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(param, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || seen[name] {
+			continue
+		}
+		if !discoverySections[name] {
+			return nil, fmt.Errorf("unknown discovery section %q (valid: nodes, storage, networks, vms)", name)
+		}
+		seen[name] = true
+		sections = append(sections, name)
+	}
+	return sections, nil
+}
+
+// refreshDiscovery re-runs only the given discovery sections in parallel and
+// merges the results into the cached state, leaving other sections untouched.
+// Falls back to a full discovery if nothing has been discovered yet.
+func (s *Server) refreshDiscovery(sections []string) error {
+	s.mu.RLock()
+	hasState := s.discoveryState != nil && s.discoveryState.Error == ""
+	s.mu.RUnlock()
+
+	if !hasState {
+		s.runParallelDiscovery()
+		return nil
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		errs     []string
+		nodes    []proxmox.NodeInfo
+		storage  []proxmox.StorageInfo
+		networks []proxmox.NetworkInfo
+		vms      []proxmox.VMInfo
+	)
+
+	for _, section := range sections {
+		wg.Add(1)
+		go func(section string) {
+			defer wg.Done()
+			var err error
+			switch section {
+			case "nodes":
+				nodes, err = s.discoverer.GetNodes()
+			case "storage":
+				storage, err = s.discoverer.GetStorage()
+			case "networks":
+				networks, err = s.discoverer.GetNetworks()
+			case "vms":
+				vms, err = s.discoverer.GetVMs()
+			}
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Sprintf("%s: %v", section, err))
+				mu.Unlock()
+			}
+		}(section)
+	}
+	wg.Wait()
+
+	// Merge into a copy so readers holding the old pointer see a consistent snapshot
+	s.mu.Lock()
+	merged := *s.discoveryState
+	for _, section := range sections {
+		switch section {
+		case "nodes":
+			if nodes != nil {
+				merged.Nodes = nodes
+			}
+		case "storage":
+			if storage != nil {
+				merged.Storage = storage
+			}
+		case "networks":
+			if networks != nil {
+				merged.Networks = networks
+			}
+		case "vms":
+			if vms != nil {
+				merged.VMs = vms
+			}
+		}
+	}
+	s.discoveryState = &merged
+	s.mu.Unlock()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("refreshing discovery: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (s *Server) handleDiscoveryRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if s.discoverer == nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(APIResponse{Error: "Not connected to Proxmox"})
+		return
+	}
+
+	sections, err := parseDiscoverySections(r.URL.Query().Get("sections"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.refreshDiscovery(sections); err != nil {
+		slog.Warn("discovery refresh failed", "sections", sections, "error", err)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(APIResponse{Error: err.Error()})
+		return
+	}
+
+	s.mu.RLock()
+	state := s.discoveryState
+	s.mu.RUnlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(state)
+}
+
+// How long ensureBridgesExist waits for new bridges to come up, and how often it checks
+const (
+	bridgeSettleTimeout = 10 * time.Second
+	bridgeSettlePoll    = 500 * time.Millisecond
+)
+
+// bridgeState is a bridge's presence and state under /sys/class/net
+type bridgeState struct {
+	exists    bool
+	operstate string
+	adminUp   bool // IFF_UP
+}
+
+// ready reports whether the bridge exists and is up. A bridge without ports
+// has no carrier and reports operstate "down" even when administratively up.
+func (b bridgeState) ready() bool {
+	return b.exists && (b.adminUp || b.operstate == "up" || b.operstate == "unknown")
+}
+
+func (b bridgeState) String() string {
+	if !b.exists {
+		return "absent"
+	}
+	if b.adminUp {
+		return b.operstate + ", admin up"
+	}
+	return b.operstate + ", admin down"
+}
+
+// bridgeStates reads the state of each named bridge in one command. Names
+// must already be validated against validBridgeName.
+func (s *Server) bridgeStates(bridges []string) (map[string]bridgeState, error) {
+	quoted := make([]string, len(bridges))
+	for i, b := range bridges {
+		quoted[i] = ssh.ShellEscape(b)
+	}
+	cmd := fmt.Sprintf(`for b in %s; do if [ -d "/sys/class/net/$b" ]; then echo "$b $(cat "/sys/class/net/$b/operstate") $(cat "/sys/class/net/$b/flags")"; fi; done`,
+		strings.Join(quoted, " "))
+	r, err := s.sshClient.Run(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[string]bridgeState)
+	for _, line := range strings.Split(r.Stdout, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		flags, _ := strconv.ParseUint(strings.TrimPrefix(fields[2], "0x"), 16, 32)
+		states[fields[0]] = bridgeState{exists: true, operstate: fields[1], adminUp: flags&1 != 0}
+	}
+	return states, nil
+}
+
 // ensureBridgesExist checks all bridges referenced in the network config and creates
 // any that don't exist on Proxmox. Writes directly to /etc/network/interfaces and
-// brings bridges up with ifup. Verifies each step.
-func (s *Server) ensureBridgesExist(networks config.NetworkConfig) error {
+// brings bridges up with ifup. Verifies each step, giving new bridges
+// bridgeSettleTimeout to come up. With dryRun set it only returns the bridges
+// it would create.
+func (s *Server) ensureBridgesExist(networks config.NetworkConfig, dryRun bool) ([]string, error) {
 	// Collect all unique bridge names from the config
 	bridges := make(map[string]bool)
 	for _, b := range []string{
 		networks.NorthboundBridge,
 		networks.DirectorRouterBridge,
 		networks.ControllerRouterBridge,
+		networks.AnalyticsSouthboundBridge,
 		networks.AnalyticsClusterBridge,
 		networks.RouterHABridge,
 	} {
 		if b != "" {
 			if !validBridgeName.MatchString(b) {
-				return fmt.Errorf("invalid bridge name %q: must match vmbr[0-9]+", b)
+				return nil, fmt.Errorf("invalid bridge name %q: must match vmbr[0-9]+", b)
 			}
 			bridges[b] = true
 		}
@@ -435,21 +763,21 @@ func (s *Server) ensureBridgesExist(networks config.NetworkConfig) error {
 	for _, b := range networks.ControllerWANBridges {
 		if b != "" {
 			if !validBridgeName.MatchString(b) {
-				return fmt.Errorf("invalid bridge name %q: must match vmbr[0-9]+", b)
+				return nil, fmt.Errorf("invalid bridge name %q: must match vmbr[0-9]+", b)
 			}
 			bridges[b] = true
 		}
 	}
 
 	if len(bridges) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	// Check which bridges actually exist on the live system
 	existing := make(map[string]bool)
 	result, err := s.sshClient.Run("ls /sys/class/net/")
 	if err != nil {
-		return fmt.Errorf("listing network interfaces: %w", err)
+		return nil, fmt.Errorf("listing network interfaces: %w", err)
 	}
 	for _, name := range strings.Fields(result.Stdout) {
 		existing[strings.TrimSpace(name)] = true
@@ -473,11 +801,17 @@ func (s *Server) ensureBridgesExist(networks config.NetworkConfig) error {
 	}
 
 	if len(missing) == 0 {
-		return nil
+		return nil, nil
+	}
+	sort.Strings(missing)
+	if dryRun {
+		return missing, nil
 	}
 
 	slog.Info("creating bridges", "bridges", missing)
 
+	pveVersion := s.pveVersion()
+
 	// Append missing bridges to /etc/network/interfaces
 	for _, bridge := range missing {
 		if defined[bridge] {
@@ -488,56 +822,72 @@ func (s *Server) ensureBridgesExist(networks config.NetworkConfig) error {
 
 		slog.Info("adding bridge to interfaces", "bridge", bridge)
 
-		// Append bridge config block
+		// Append bridge config block (option syntax depends on ifupdown vs
+		// ifupdown2). Values go in as printf arguments, not the format string.
 		appendCmd := fmt.Sprintf(
-			`printf '\nauto %s\niface %s inet manual\n\tbridge-ports none\n\tbridge-stp off\n\tbridge-fd 0\n' >> /etc/network/interfaces`,
-			bridge, bridge,
+			`printf '\nauto %%s\niface %%s inet manual\n\t%%s none\n\t%%s off\n\t%%s 0\n' %s %s %s %s %s >> /etc/network/interfaces`,
+			ssh.ShellEscape(bridge), ssh.ShellEscape(bridge),
+			ssh.ShellEscape(pveVersion.BridgeOption("bridge-ports")),
+			ssh.ShellEscape(pveVersion.BridgeOption("bridge-stp")),
+			ssh.ShellEscape(pveVersion.BridgeOption("bridge-fd")),
 		)
 		r, err := s.sshClient.Run(appendCmd)
 		if err != nil {
-			return fmt.Errorf("writing bridge %s to interfaces file: %w", bridge, err)
+			return nil, fmt.Errorf("writing bridge %s to interfaces file: %w", bridge, err)
 		}
 		if r.ExitCode != 0 {
-			return fmt.Errorf("writing bridge %s failed (exit %d): %s", bridge, r.ExitCode, r.Stderr)
+			return nil, fmt.Errorf("writing bridge %s failed (exit %d): %s", bridge, r.ExitCode, r.Stderr)
 		}
 	}
 
 	// Bring up each missing bridge
 	for _, bridge := range missing {
 		slog.Info("bringing up bridge", "bridge", bridge)
-		r, err := s.sshClient.Run(fmt.Sprintf("ifup %s", bridge))
+		r, err := s.sshClient.Run("ifup " + ssh.ShellEscape(bridge))
 		if err != nil {
-			return fmt.Errorf("ifup %s: %w", bridge, err)
+			return nil, fmt.Errorf("ifup %s: %w", bridge, err)
 		}
 		if r.ExitCode != 0 {
 			// Try ifreload as fallback
 			slog.Warn("ifup failed, trying ifreload", "bridge", bridge)
 			r2, _ := s.sshClient.Run("ifreload -a")
 			if r2 != nil && r2.ExitCode != 0 {
-				return fmt.Errorf("bringing up bridge %s failed — ifup exit %d: %s, ifreload exit %d: %s",
+				return nil, fmt.Errorf("bringing up bridge %s failed — ifup exit %d: %s, ifreload exit %d: %s",
 					bridge, r.ExitCode, r.Stderr, r2.ExitCode, r2.Stderr)
 			}
 		}
 	}
 
-	// Verify every bridge now exists on the live system
-	r, err := s.sshClient.Run("ls /sys/class/net/")
-	if err != nil {
-		return fmt.Errorf("verifying bridges: %w", err)
-	}
-	nowExisting := make(map[string]bool)
-	for _, name := range strings.Fields(r.Stdout) {
-		nowExisting[strings.TrimSpace(name)] = true
+	// Interfaces can take a moment to appear after ifup/ifreload, so poll
+	// before declaring a bridge missing
+	deadline := time.Now().Add(bridgeSettleTimeout)
+	for {
+		states, err := s.bridgeStates(missing)
+		if err != nil {
+			return nil, fmt.Errorf("verifying bridges: %w", err)
+		}
+		var pending []string
+		for _, bridge := range missing {
+			if !states[bridge].ready() {
+				pending = append(pending, bridge)
+			}
+		}
+		if len(pending) == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			bridge := pending[0]
+			return nil, fmt.Errorf("bridge %s was configured but is not active %s after ifup (state: %s) — check /etc/network/interfaces on Proxmox host",
+				bridge, bridgeSettleTimeout, states[bridge])
+		}
+		time.Sleep(bridgeSettlePoll)
 	}
 	for _, bridge := range missing {
-		if !nowExisting[bridge] {
-			return fmt.Errorf("bridge %s was configured but is not active after ifup — check /etc/network/interfaces on Proxmox host", bridge)
-		}
 		slog.Info("bridge verified active", "bridge", bridge)
 	}
 
 	slog.Info("bridges created and verified", "bridges", missing)
-	return nil
+	return missing, nil
 }
 
 func (s *Server) handleDeploy(w http.ResponseWriter, r *http.Request) {
@@ -547,11 +897,25 @@ func (s *Server) handleDeploy(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		Prefix     string                   `json:"prefix"`
-		HAMode     bool                     `json:"haMode"`
-		Components []config.ComponentConfig `json:"components"`
-		Storage    string                   `json:"storage"`
-		Networks   config.NetworkConfig     `json:"networks"`
+		Prefix         string                   `json:"prefix"`
+		HAMode         bool                     `json:"haMode"`
+		Components     []config.ComponentConfig `json:"components"`
+		Storage        string                   `json:"storage"`
+		Operator       string                   `json:"operator"`
+		Ticket         string                   `json:"ticket"`
+		Environment    string                   `json:"environment"`
+		NoStart        bool                     `json:"noStart"`
+		Snapshot       bool                     `json:"snapshot"`
+		ManagementOnly bool                     `json:"managementOnly"`
+		OnFailure      string                   `json:"onFailure"`
+		// Run discovery and preflight checks only, without deploying
+		ValidateOnly bool                 `json:"validateOnly"`
+		Networks     config.NetworkConfig `json:"networks"`
+		// Stream the planned bridges, ISO transfers and VMs without creating anything
+		DryRun bool `json:"dryRun"`
+		// Spec overrides in specs-file form; they replace the CPU, RAM,
+		// disk and disk bus of the components they name
+		Specs map[config.ComponentType]config.VMSpec `json:"specs"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -566,13 +930,47 @@ func (s *Server) handleDeploy(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Auto-create any bridges that don't exist on Proxmox
-	if err := s.ensureBridgesExist(req.Networks); err != nil {
+	rollbackPolicy, err := config.ParseRollbackPolicy(req.OnFailure)
+	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(APIResponse{Error: fmt.Sprintf("Failed to create bridges: %v", err)})
+		json.NewEncoder(w).Encode(APIResponse{Error: err.Error()})
 		return
 	}
 
+	if len(req.Specs) > 0 {
+		specs, err := config.MergeVMSpecs(req.Specs)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(APIResponse{Error: fmt.Sprintf("Invalid specs: %v", err)})
+			return
+		}
+		for i, comp := range req.Components {
+			if _, ok := req.Specs[comp.Type]; !ok {
+				continue
+			}
+			spec := specs[comp.Type]
+			req.Components[i].CPU = spec.DefaultCPU
+			req.Components[i].RAMGB = spec.DefaultRAMGB
+			req.Components[i].DiskGB = spec.DefaultDiskGB
+			req.Components[i].DiskBus = spec.DiskBus
+			req.Components[i].BIOS = spec.BIOS
+			req.Components[i].Machine = spec.Machine
+		}
+	}
+
+	// Auto-create any bridges that don't exist on Proxmox. Validation alone
+	// changes nothing; missing bridges are reported as warnings instead.
+	var plannedBridges []string
+	if !req.ValidateOnly {
+		var err error
+		if plannedBridges, err = s.ensureBridgesExist(req.Networks, req.DryRun); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(APIResponse{Error: fmt.Sprintf("Failed to create bridges: %v", err)})
+			return
+		}
+	}
+
+	s.cfgMu.Lock()
 	deployCfg := config.NewDeploymentConfig()
 	deployCfg.ProxmoxHost = s.cfg.LastProxmoxHost
 	deployCfg.SSHUser = s.cfg.LastProxmoxUser
@@ -581,26 +979,57 @@ func (s *Server) handleDeploy(w http.ResponseWriter, r *http.Request) {
 	deployCfg.StoragePool = req.Storage
 	deployCfg.Networks = req.Networks
 	deployCfg.Components = req.Components
-
+	deployCfg.Operator = req.Operator
+	deployCfg.Ticket = req.Ticket
+	deployCfg.Environment = req.Environment
+	deployCfg.StartAfterCreate = !req.NoStart
+	deployCfg.SnapshotBeforeBoot = req.Snapshot
+	deployCfg.ManagementOnlyFirst = req.ManagementOnly
+	deployCfg.RollbackPolicy = rollbackPolicy
+	deployCfg.DescriptionTemplate = s.cfg.DescriptionTemplate
+	deployCfg.CABundle = s.caBundle
+	deployCfg.DownloadsPerSource = s.cfg.DownloadsPerSource
+	deployCfg.ChecksumSources = s.cfg.ChecksumSources
+	deployCfg.FeatureMinVersions = s.cfg.FeatureMinVersions
 	imageSources, _ := sources.CreateSourcesFromConfig(s.cfg)
-
-	dep := deployer.NewDeployer(s.sshClient, imageSources)
-	dep.SetConfig(deployCfg)
+	s.cfgMu.Unlock()
 
 	// Pass scanned images so deployer can download from sources
+	deployReq := deployer.DeployRequest{
+		Client:  s.sshClient,
+		Config:  deployCfg,
+		Sources: imageSources,
+		DryRun:  req.DryRun,
+	}
 	s.mu.Lock()
 	if s.discoveryState != nil {
-		dep.SetKnownImages(s.discoveryState.Images)
+		deployReq.KnownImages = s.discoveryState.Images
 	}
 	s.mu.Unlock()
 
+	if req.ValidateOnly {
+		w.Header().Set("Content-Type", "application/json")
+		dep, err := deployer.Prepare(r.Context(), deployReq)
+		if err != nil {
+			json.NewEncoder(w).Encode(APIResponse{Error: err.Error()})
+			return
+		}
+		report := dep.Preflight()
+		json.NewEncoder(w).Encode(ValidationResponse{
+			APIResponse: APIResponse{Success: report.OK()},
+			Report:      report,
+		})
+		return
+	}
+
 	// Init deploy status tracking
 	s.deployMu.Lock()
 	s.deployStatus = &DeployStatus{Active: true, Stage: "initializing"}
 	s.deployMu.Unlock()
+	s.resetSSEBacklog()
 
 	// Create deploy log file
-	logDir := filepath.Join(config.ConfigDir(), "logs")
+	logDir := config.DeployLogDir()
 	os.MkdirAll(logDir, 0755)
 	logPath := filepath.Join(logDir, fmt.Sprintf("deploy-%s.log", time.Now().Format("2006-01-02_15-04-05")))
 	logFile, logErr := os.Create(logPath)
@@ -616,7 +1045,7 @@ func (s *Server) handleDeploy(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	dep.OnLog = func(msg string) {
+	deployReq.OnLog = func(msg string) {
 		s.broadcastSSE(fmt.Sprintf(`{"type":"log","message":%q}`, msg))
 		writeLog(msg)
 		s.deployMu.Lock()
@@ -630,7 +1059,7 @@ func (s *Server) handleDeploy(w http.ResponseWriter, r *http.Request) {
 		}
 		s.deployMu.Unlock()
 	}
-	dep.OnProgress = func(stage string, current, total int) {
+	deployReq.OnProgress = func(stage string, current, total int) {
 		s.broadcastSSE(fmt.Sprintf(`{"type":"progress","stage":%q,"current":%d,"total":%d}`, stage, current, total))
 		s.deployMu.Lock()
 		if s.deployStatus != nil {
@@ -641,27 +1070,73 @@ func (s *Server) handleDeploy(w http.ResponseWriter, r *http.Request) {
 		s.deployMu.Unlock()
 	}
 
-	if _, err := dep.Discover(); err != nil {
-		writeLog(fmt.Sprintf("ERROR: Discovery failed: %v", err))
+	deployReq.OnTransfer = func(t deployer.TransferProgress) {
+		data, err := json.Marshal(t)
+		if err != nil {
+			slog.Warn("deploy: dropping transfer event", "error", err, "file", t.Filename)
+			return
+		}
+		s.broadcastSSETransient(fmt.Sprintf(`{"type":"transfer","transfer":%s}`, data))
+	}
+	deployReq.OnDownloadTasks = func(tasks []deployer.DownloadTask) {
+		data, _ := json.Marshal(tasks)
+		s.broadcastSSE(fmt.Sprintf(`{"type":"download_tasks","tasks":%s}`, data))
+		s.deployMu.Lock()
+		if s.deployStatus != nil {
+			s.deployStatus.DownloadTasks = tasks
+		}
+		s.deployMu.Unlock()
+	}
+
+	for _, bridge := range plannedBridges {
+		if req.DryRun {
+			deployReq.OnLog(fmt.Sprintf("Would create bridge %s", bridge))
+		} else {
+			deployReq.OnLog(fmt.Sprintf("Created bridge %s", bridge))
+		}
+	}
+
+	// Shutting the server down cancels the deployment's running Proxmox
+	// download tasks
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		select {
+		case <-s.shutdown:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	dep, err := deployer.Prepare(ctx, deployReq)
+	if err != nil {
+		cancel()
+		writeLog(fmt.Sprintf("ERROR: %v", err))
 		if logFile != nil {
 			logFile.Close()
 		}
 		s.deployMu.Lock()
 		s.deployStatus.Active = false
-		s.deployStatus.Error = fmt.Sprintf("Discovery failed: %v", err)
+		s.deployStatus.Error = err.Error()
 		s.deployMu.Unlock()
 
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(APIResponse{Error: fmt.Sprintf("Discovery failed: %v", err)})
+		json.NewEncoder(w).Encode(APIResponse{Error: err.Error()})
 		return
 	}
 
 	// Deploy asynchronously, send progress via SSE
+	s.deployMu.Lock()
+	s.activeDeploy = dep
+	s.deployMu.Unlock()
 	go func() {
 		defer func() {
+			cancel()
 			if logFile != nil {
 				logFile.Close()
 			}
+			s.deployMu.Lock()
+			s.activeDeploy = nil
+			s.deployMu.Unlock()
 		}()
 
 		result, err := dep.Deploy()
@@ -675,7 +1150,11 @@ func (s *Server) handleDeploy(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		writeLog("Deployment complete")
+		if result.Status == deployer.StatusPlanned {
+			writeLog("Dry run complete")
+		} else {
+			writeLog("Deployment complete")
+		}
 		resultJSON, _ := json.Marshal(result)
 		s.broadcastSSE(fmt.Sprintf(`{"type":"complete","result":%s}`, string(resultJSON)))
 
@@ -685,10 +1164,13 @@ func (s *Server) handleDeploy(w http.ResponseWriter, r *http.Request) {
 		s.deployStatus.Stage = "complete"
 		s.deployMu.Unlock()
 
+		if result.Status == deployer.StatusPlanned {
+			return
+		}
+
 		for _, vm := range result.VMs {
 			if vm.Component == config.ComponentDirector && vm.IP != "" {
-				s.cfg.DirectorIP = vm.IP
-				s.cfg.Save()
+				s.updateConfig(func(cfg *config.Config) { cfg.DirectorIP = vm.IP })
 				break
 			}
 		}
@@ -701,7 +1183,9 @@ func (s *Server) handleDeploy(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// handleDeployProgress serves SSE stream for deployment progress
+// handleDeployProgress serves SSE stream for deployment progress. A client
+// reconnecting with a Last-Event-ID header is first sent the buffered events
+// it missed.
 func (s *Server) handleDeployProgress(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -714,13 +1198,30 @@ func (s *Server) handleDeployProgress(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	lastID := int64(-1)
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		if id, err := strconv.ParseInt(v, 10, 64); err == nil {
+			lastID = id
+		}
+	}
+
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
 	w.Header().Set("Connection", "keep-alive")
 
-	ch := make(chan string, 64)
+	// Register and snapshot the backlog under one lock so no event is
+	// missed or sent twice between replay and the live stream
+	ch := make(chan sseEvent, 64)
 	s.sseMu.Lock()
 	s.sseClients[ch] = struct{}{}
+	var missed []sseEvent
+	if lastID >= 0 {
+		for _, ev := range s.sseEvents {
+			if ev.ID > lastID {
+				missed = append(missed, ev)
+			}
+		}
+	}
 	s.sseMu.Unlock()
 
 	defer func() {
@@ -729,6 +1230,15 @@ func (s *Server) handleDeployProgress(w http.ResponseWriter, r *http.Request) {
 		s.sseMu.Unlock()
 	}()
 
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetry.Milliseconds())
+	for _, ev := range missed {
+		writeSSEEvent(w, ev)
+	}
+	flusher.Flush()
+	if len(missed) > 0 {
+		slog.Debug("replayed SSE events", "count", len(missed), "last_event_id", lastID)
+	}
+
 	ctx := r.Context()
 	heartbeat := time.NewTicker(5 * time.Second)
 	defer heartbeat.Stop()
@@ -737,8 +1247,19 @@ func (s *Server) handleDeployProgress(w http.ResponseWriter, r *http.Request) {
 		select {
 		case <-ctx.Done():
 			return
-		case msg := <-ch:
-			fmt.Fprintf(w, "data: %s\n\n", msg)
+		case <-s.shutdown:
+			// Deliver what is queued, ending with the shutdown event
+			for {
+				select {
+				case ev := <-ch:
+					writeSSEEvent(w, ev)
+				default:
+					flusher.Flush()
+					return
+				}
+			}
+		case ev := <-ch:
+			writeSSEEvent(w, ev)
 			flusher.Flush()
 		case <-heartbeat.C:
 			// SSE comment as keepalive — prevents browser from thinking connection is dead
@@ -748,19 +1269,61 @@ func (s *Server) handleDeployProgress(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// broadcastSSE sends a message to all connected SSE clients
+// writeSSEEvent writes one SSE message with its id field. Transient events
+// (ID 0) carry no id so the client's Last-Event-ID is left alone.
+func writeSSEEvent(w io.Writer, ev sseEvent) {
+	if ev.ID == 0 {
+		fmt.Fprintf(w, "data: %s\n\n", ev.Data)
+		return
+	}
+	fmt.Fprintf(w, "id: %d\ndata: %s\n\n", ev.ID, ev.Data)
+}
+
+// broadcastSSE assigns the next event ID to msg, records it in the backlog
+// and sends it to all connected SSE clients
 func (s *Server) broadcastSSE(msg string) {
 	s.sseMu.Lock()
 	defer s.sseMu.Unlock()
 
+	s.sseNextID++
+	ev := sseEvent{ID: s.sseNextID, Data: msg}
+	if len(s.sseEvents) >= sseBacklogSize {
+		s.sseEvents = s.sseEvents[1:]
+	}
+	s.sseEvents = append(s.sseEvents, ev)
+
+	for ch := range s.sseClients {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// broadcastSSETransient sends msg to connected clients without an ID or a
+// backlog entry, for frequent updates superseded by the next one (transfer
+// progress) that would otherwise push logs out of the replay backlog
+func (s *Server) broadcastSSETransient(msg string) {
+	s.sseMu.Lock()
+	defer s.sseMu.Unlock()
+
+	ev := sseEvent{Data: msg}
 	for ch := range s.sseClients {
 		select {
-		case ch <- msg:
+		case ch <- ev:
 		default:
 		}
 	}
 }
 
+// resetSSEBacklog drops buffered events from a previous deployment. IDs keep
+// increasing so a stale Last-Event-ID never matches the new deployment.
+func (s *Server) resetSSEBacklog() {
+	s.sseMu.Lock()
+	s.sseEvents = nil
+	s.sseMu.Unlock()
+}
+
 func (s *Server) handleDeployStatus(w http.ResponseWriter, r *http.Request) {
 	s.deployMu.RLock()
 	status := s.deployStatus
@@ -774,7 +1337,40 @@ func (s *Server) handleDeployStatus(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(status)
 }
 
-func (s *Server) handleCreateNetwork(w http.ResponseWriter, r *http.Request) {
+// handleDeployCancelDownload stops a stuck ISO download task of the running
+// deployment; the deployment falls back to uploading the ISO over SSH
+func (s *Server) handleDeployCancelDownload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		UPID string `json:"upid"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UPID == "" {
+		json.NewEncoder(w).Encode(APIResponse{Error: "upid is required"})
+		return
+	}
+
+	s.deployMu.RLock()
+	dep := s.activeDeploy
+	s.deployMu.RUnlock()
+	if dep == nil {
+		json.NewEncoder(w).Encode(APIResponse{Error: "No deployment running"})
+		return
+	}
+
+	if err := dep.CancelDownload(req.UPID); err != nil {
+		json.NewEncoder(w).Encode(APIResponse{Error: err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(APIResponse{Success: true})
+}
+
+func (s *Server) handleCreateNetwork(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -795,6 +1391,12 @@ func (s *Server) handleCreateNetwork(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := validateCreateNetwork(req.Name, req.Node, req.Interface, req.Address, req.Gateway); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(APIResponse{Error: err.Error()})
+		return
+	}
+
 	if s.sshClient == nil {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(APIResponse{Error: "Not connected to Proxmox"})
@@ -832,43 +1434,53 @@ func (s *Server) handleCreateNetwork(w http.ResponseWriter, r *http.Request) {
 	// Apply network changes
 	s.sshClient.Run("pvesh set /nodes/" + ssh.ShellEscape(req.Node) + "/network")
 
-	go s.runParallelDiscovery()
+	// Only the bridge list changed; leave nodes, storage and VMs cached
+	if err := s.refreshDiscovery([]string{"networks"}); err != nil {
+		slog.Warn("network refresh after bridge creation failed", "error", err)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(APIResponse{Success: true})
 }
 
+// validateCreateNetwork checks a new bridge's fields against allowlists
+// before they reach pvesh
+func validateCreateNetwork(name, node, iface, address, gateway string) error {
+	if !validBridgeName.MatchString(name) {
+		return fmt.Errorf("invalid bridge name %q: must match vmbr[0-9]+", name)
+	}
+	if err := proxmox.ValidateNodeName(node); err != nil {
+		return err
+	}
+	if iface != "" {
+		if err := proxmox.ValidateInterfaceName(iface); err != nil {
+			return err
+		}
+	}
+	if address != "" {
+		if _, _, err := net.ParseCIDR(address); err != nil && net.ParseIP(address) == nil {
+			return fmt.Errorf("invalid address %q", address)
+		}
+	}
+	if gateway != "" && net.ParseIP(gateway) == nil {
+		return fmt.Errorf("invalid gateway %q", gateway)
+	}
+	return nil
+}
+
 func (s *Server) handleScanSources(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "POST" {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	imageSources, err := sources.CreateSourcesFromConfig(s.cfg)
-	if err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(ScanSourcesResponse{APIResponse: APIResponse{Error: err.Error()}})
-		return
-	}
-
-	collection, err := sources.ScanAllSources(imageSources)
+	collection, err := s.scanSources()
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(ScanSourcesResponse{APIResponse: APIResponse{Error: err.Error()}})
 		return
 	}
-
-	var allImages []sources.ISOFile
-	allImages = append(allImages, collection.Director...)
-	allImages = append(allImages, collection.Analytics...)
-	allImages = append(allImages, collection.FlexVNF...)
-	allImages = append(allImages, collection.Concerto...)
-
-	s.mu.Lock()
-	if s.discoveryState != nil {
-		s.discoveryState.Images = allImages
-	}
-	s.mu.Unlock()
+	allImages := collection.All()
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(ScanSourcesResponse{
@@ -884,19 +1496,18 @@ func (s *Server) handleSources(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case "GET":
 		// Return configured sources
-		json.NewEncoder(w).Encode(SourcesResponse{
-			APIResponse: APIResponse{Success: true},
-			Sources:     s.cfg.ImageSources,
-		})
+		json.NewEncoder(w).Encode(s.sourcesResponse(nil))
 
 	case "POST":
 		// Add a new source
 		var req struct {
-			URL      string `json:"url"`
-			Name     string `json:"name"`
-			Type     string `json:"type"`
-			SSHKey   string `json:"sshKey"`
-			Password string `json:"password"`
+			URL      string            `json:"url"`
+			Name     string            `json:"name"`
+			Type     string            `json:"type"`
+			SSHKey   string            `json:"sshKey"`
+			Password string            `json:"password"`
+			Username string            `json:"username"`
+			Headers  map[string]string `json:"headers"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			json.NewEncoder(w).Encode(SourcesResponse{APIResponse: APIResponse{Error: err.Error()}})
@@ -921,12 +1532,23 @@ func (s *Server) handleSources(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
+		// Refuse before the (possibly slow) connection test
+		s.cfgMu.Lock()
+		err := s.cfg.CanAddImageSource(req.URL)
+		s.cfgMu.Unlock()
+		if err != nil {
+			json.NewEncoder(w).Encode(s.sourcesResponse(err))
+			return
+		}
+
 		newSource := config.ImageSource{
 			URL:      req.URL,
 			Name:     req.Name,
 			Type:     req.Type,
 			SSHKey:   req.SSHKey,
 			Password: req.Password,
+			Username: req.Username,
+			Headers:  req.Headers,
 		}
 
 		// Validate by testing connection
@@ -941,20 +1563,21 @@ func (s *Server) handleSources(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if err := s.cfg.AddImageSource(newSource); err != nil {
-			json.NewEncoder(w).Encode(SourcesResponse{APIResponse: APIResponse{Error: err.Error()}})
+		s.cfgMu.Lock()
+		err = s.cfg.AddImageSource(newSource)
+		if err == nil {
+			s.cfg.Save()
+		}
+		s.cfgMu.Unlock()
+		if err != nil {
+			json.NewEncoder(w).Encode(s.sourcesResponse(err))
 			return
 		}
 
-		s.cfg.Save()
-
 		// Trigger a rescan in background
 		go s.scanAndUpdateImages()
 
-		json.NewEncoder(w).Encode(SourcesResponse{
-			APIResponse: APIResponse{Success: true},
-			Sources:     s.cfg.ImageSources,
-		})
+		json.NewEncoder(w).Encode(s.sourcesResponse(nil))
 
 	case "DELETE":
 		var req struct {
@@ -967,6 +1590,7 @@ func (s *Server) handleSources(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		s.cfgMu.Lock()
 		removed := false
 		// Try by index, but verify URL matches to prevent wrong deletion
 		if req.Index >= 0 && req.Index < len(s.cfg.ImageSources) {
@@ -989,51 +1613,218 @@ func (s *Server) handleSources(w http.ResponseWriter, r *http.Request) {
 				}
 			}
 		}
+		if removed {
+			s.cfg.Save()
+		}
+		remaining := config.RedactSources(s.cfg.ImageSources)
+		s.cfgMu.Unlock()
 		if !removed {
 			json.NewEncoder(w).Encode(SourcesResponse{
 				APIResponse: APIResponse{Success: false, Error: "Source not found"},
-				Sources:     s.cfg.ImageSources,
+				Sources:     remaining,
 			})
 			return
 		}
-		s.cfg.Save()
 
 		// Trigger a rescan in background
 		go s.scanAndUpdateImages()
 
-		json.NewEncoder(w).Encode(SourcesResponse{
-			APIResponse: APIResponse{Success: true},
-			Sources:     s.cfg.ImageSources,
-		})
+		json.NewEncoder(w).Encode(s.sourcesResponse(nil))
+
+	case "PATCH":
+		// Reorder sources; the first URL becomes the preferred download source
+		var req struct {
+			Order []string `json:"order"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			json.NewEncoder(w).Encode(SourcesResponse{APIResponse: APIResponse{Error: err.Error()}})
+			return
+		}
+
+		s.cfgMu.Lock()
+		err := s.cfg.ReorderImageSources(req.Order)
+		if err == nil {
+			s.cfg.Save()
+		}
+		current := config.RedactSources(s.cfg.ImageSources)
+		s.cfgMu.Unlock()
+		if err != nil {
+			json.NewEncoder(w).Encode(SourcesResponse{
+				APIResponse: APIResponse{Error: err.Error()},
+				Sources:     current,
+			})
+			return
+		}
+
+		// Rescan so merged ISOs pick up the new source preference
+		go s.scanAndUpdateImages()
+
+		json.NewEncoder(w).Encode(s.sourcesResponse(nil))
 
 	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
 }
 
-// scanAndUpdateImages scans all configured sources and updates discovery state
-func (s *Server) scanAndUpdateImages() {
-	imageSources, err := sources.CreateSourcesFromConfig(s.cfg)
+// sourcesResponse reports the configured sources and the source limit,
+// marking limit and duplicate refusals with a Code the UI can act on
+func (s *Server) sourcesResponse(err error) SourcesResponse {
+	s.cfgMu.Lock()
+	defer s.cfgMu.Unlock()
+	resp := SourcesResponse{
+		APIResponse: APIResponse{Success: err == nil},
+		Sources:     config.RedactSources(s.cfg.ImageSources),
+		Count:       len(s.cfg.ImageSources),
+		Limit:       s.cfg.SourceLimit(),
+	}
 	if err != nil {
-		return
+		resp.Error = err.Error()
+		switch {
+		case errors.Is(err, config.ErrSourceLimit):
+			resp.Code = "source_limit"
+		case errors.Is(err, config.ErrSourceExists):
+			resp.Code = "source_exists"
+		}
 	}
+	return resp
+}
 
-	collection, err := sources.ScanAllSources(imageSources)
+// updateConfig applies a change to the config and saves it, holding cfgMu
+// so concurrent handlers and scans don't interleave
+func (s *Server) updateConfig(change func(cfg *config.Config)) error {
+	s.cfgMu.Lock()
+	defer s.cfgMu.Unlock()
+	change(s.cfg)
+	return s.cfg.Save()
+}
+
+// storeScan records a source scan result, persists per-source scan status and
+// publishes its images to discovery state
+func (s *Server) storeScan(collection *sources.ISOCollection) []sources.ISOFile {
+	allImages := collection.All()
+
+	err := s.updateConfig(func(cfg *config.Config) {
+		sources.RecordScanResults(cfg, collection)
+	})
 	if err != nil {
-		return
+		slog.Warn("could not save source scan status", "error", err)
 	}
 
-	var allImages []sources.ISOFile
-	allImages = append(allImages, collection.Director...)
-	allImages = append(allImages, collection.Analytics...)
-	allImages = append(allImages, collection.FlexVNF...)
-	allImages = append(allImages, collection.Concerto...)
-
 	s.mu.Lock()
+	s.lastScan = collection
 	if s.discoveryState != nil {
 		s.discoveryState.Images = allImages
 	}
 	s.mu.Unlock()
+
+	return allImages
+}
+
+func (s *Server) handleComponentsAvailability(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mu.RLock()
+	collection := s.lastScan
+	s.mu.RUnlock()
+
+	resp := ComponentsAvailabilityResponse{APIResponse: APIResponse{Success: true}}
+	if collection != nil {
+		resp.Scanned = true
+		resp.Components = collection.Availability(config.AllComponents())
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// pveVersion returns the discovered Proxmox VE version, or the zero
+// (unknown) Version if discovery hasn't run
+func (s *Server) pveVersion() proxmox.Version {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.discoveryState == nil {
+		return proxmox.Version{}
+	}
+	v, _ := proxmox.ParseVersion(s.discoveryState.Version)
+	return v
+}
+
+// scanSources scans all configured sources and stores the result. A call
+// made while another scan is running waits for that scan instead of
+// starting a second one.
+func (s *Server) scanSources() (*sources.ISOCollection, error) {
+	s.scanMu.Lock()
+	if f := s.scan; f != nil {
+		s.scanMu.Unlock()
+		<-f.done
+		return f.collection, f.err
+	}
+	f := &scanFlight{done: make(chan struct{})}
+	s.scan = f
+	s.scanMu.Unlock()
+
+	defer func() {
+		s.scanMu.Lock()
+		s.scan = nil
+		s.scanMu.Unlock()
+		close(f.done)
+	}()
+
+	s.cfgMu.Lock()
+	imageSources, err := sources.CreateSourcesFromConfig(s.cfg)
+	s.cfgMu.Unlock()
+	if err != nil {
+		f.err = err
+		return nil, err
+	}
+	collection, err := sources.ScanAllSources(imageSources, sources.DefaultScanOptions)
+	if err != nil {
+		f.err = err
+		return nil, err
+	}
+	s.storeScan(collection)
+	f.collection = collection
+	return collection, nil
+}
+
+// scanAndUpdateImages scans all configured sources and updates discovery state
+func (s *Server) scanAndUpdateImages() {
+	s.scanSources()
+}
+
+// scanInFlight reports whether a source scan is running
+func (s *Server) scanInFlight() bool {
+	s.scanMu.Lock()
+	defer s.scanMu.Unlock()
+	return s.scan != nil
+}
+
+// startRescanTicker rescans image sources every rescanInterval, skipping a
+// tick while another scan is still running
+func (s *Server) startRescanTicker() {
+	go func() {
+		ticker := time.NewTicker(s.rescanInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-s.shutdown:
+				return
+			case <-ticker.C:
+			}
+			if s.scanInFlight() {
+				continue
+			}
+			if _, err := s.scanSources(); err != nil {
+				slog.Warn("background source rescan failed", "error", err)
+				continue
+			}
+			slog.Debug("background source rescan complete")
+		}
+	}()
 }
 
 func (s *Server) handleUploadKey(w http.ResponseWriter, r *http.Request) {
@@ -1074,8 +1865,7 @@ func (s *Server) handleUploadKey(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Save path in config
-	s.cfg.LastSSHKeyPath = keyPath
-	s.cfg.Save()
+	s.updateConfig(func(cfg *config.Config) { cfg.LastSSHKeyPath = keyPath })
 
 	json.NewEncoder(w).Encode(UploadKeyResponse{
 		APIResponse: APIResponse{Success: true},
@@ -1084,10 +1874,57 @@ func (s *Server) handleUploadKey(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (s *Server) handleRegenCert(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if s.tlsCertPath != "" {
+		json.NewEncoder(w).Encode(CertResponse{APIResponse: APIResponse{Error: "Server is using an operator-provided certificate (--tls-cert); replace the file and restart instead"}})
+		return
+	}
+
+	cert, err := RegenerateCert(config.ConfigDir())
+	if err != nil {
+		json.NewEncoder(w).Encode(CertResponse{APIResponse: APIResponse{Error: fmt.Sprintf("Failed to regenerate certificate: %v", err)}})
+		return
+	}
+
+	s.certMu.Lock()
+	s.cert = &cert
+	s.certMu.Unlock()
+
+	slog.Info("regenerated self-signed TLS certificate")
+	json.NewEncoder(w).Encode(certResponseFor(cert))
+}
+
+// certResponseFor describes a certificate for the API
+func certResponseFor(cert tls.Certificate) CertResponse {
+	resp := CertResponse{APIResponse: APIResponse{Success: true}}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return resp
+	}
+	resp.NotAfter = leaf.NotAfter.Format(time.RFC3339)
+	resp.DNSNames = leaf.DNSNames
+	for _, ip := range leaf.IPAddresses {
+		resp.IPAddresses = append(resp.IPAddresses, ip.String())
+	}
+	return resp
+}
+
 // DeploymentGroup represents a group of VMs from a single deployment
 type DeploymentGroup struct {
-	Prefix string           `json:"prefix"`
-	VMs    []proxmox.VMInfo `json:"vms"`
+	Prefix      string                     `json:"prefix"`
+	Environment string                     `json:"environment,omitempty"`
+	ToolVersion string                     `json:"toolVersion,omitempty"` // Deployer version that created the VMs
+	VMs         []proxmox.VMInfo           `json:"vms"`
+	Updates     []deployer.ComponentUpdate `json:"updates,omitempty"`
+	// Interfaces still to be added, by VMID (management-only deployments)
+	PendingNetworks map[int]int `json:"pendingNetworks,omitempty"`
 }
 
 func (s *Server) handleDeployments(w http.ResponseWriter, r *http.Request) {
@@ -1103,11 +1940,15 @@ func (s *Server) handleDeployments(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	versaVMs, err := s.discoverer.FindVersaDeployments()
+	// Optional ?env= limits the list to one environment's deployments
+	versaVMs, unverified, err := s.discoverer.FindVersaDeploymentsAndUnknown(r.URL.Query().Get("env"))
 	if err != nil {
 		json.NewEncoder(w).Encode(DeploymentsResponse{APIResponse: APIResponse{Error: fmt.Sprintf("Failed to find deployments: %v", err)}})
 		return
 	}
+	if len(unverified) > 0 {
+		slog.Warn("could not read tags of some VMs", "count", len(unverified))
+	}
 
 	// Group VMs by deployment prefix
 	groups := make(map[string]*DeploymentGroup)
@@ -1117,14 +1958,45 @@ func (s *Server) handleDeployments(w http.ResponseWriter, r *http.Request) {
 			prefix = "_unknown"
 		}
 		if groups[prefix] == nil {
-			groups[prefix] = &DeploymentGroup{Prefix: prefix}
+			groups[prefix] = &DeploymentGroup{Prefix: prefix, Environment: vm.Environment}
+		}
+		if groups[prefix].ToolVersion == "" {
+			groups[prefix].ToolVersion = vm.ToolVersion
 		}
 		groups[prefix].VMs = append(groups[prefix].VMs, vm)
 	}
 
+	// Compare tagged versions against the last source scan
+	s.mu.RLock()
+	collection := s.lastScan
+	s.mu.RUnlock()
+	for _, group := range groups {
+		group.Updates = deployer.CheckForUpdates(group.VMs, collection)
+	}
+
+	pending, err := deployer.ListPendingNetworks(s.sshClient.Host())
+	if err != nil {
+		slog.Warn("could not load pending interfaces", "error", err)
+	}
+	pendingCount := make(map[int]int, len(pending))
+	for _, p := range pending {
+		pendingCount[p.VMID] = len(p.Networks)
+	}
+	for _, group := range groups {
+		for _, vm := range group.VMs {
+			if n := pendingCount[vm.VMID]; n > 0 {
+				if group.PendingNetworks == nil {
+					group.PendingNetworks = make(map[int]int)
+				}
+				group.PendingNetworks[vm.VMID] = n
+			}
+		}
+	}
+
 	json.NewEncoder(w).Encode(DeploymentsResponse{
 		APIResponse: APIResponse{Success: true},
 		Deployments: groups,
+		Unverified:  unverified,
 	})
 }
 
@@ -1291,3 +2163,330 @@ func (s *Server) handleDeploymentsDelete(w http.ResponseWriter, r *http.Request)
 		Results:     results,
 	})
 }
+
+// handleDeploymentsReclaim re-tags a VM that lost its versa-deployer tag.
+// The request must repeat the VM's exact name as confirmation.
+func (s *Server) handleDeploymentsReclaim(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		VMID    int    `json:"vmid"`
+		Confirm string `json:"confirm"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		json.NewEncoder(w).Encode(ReclaimResponse{APIResponse: APIResponse{Error: fmt.Sprintf("Invalid request: %v", err)}})
+		return
+	}
+
+	if s.sshClient == nil {
+		json.NewEncoder(w).Encode(ReclaimResponse{APIResponse: APIResponse{Error: "Not connected to Proxmox"}})
+		return
+	}
+
+	result, err := deployer.ReclaimVM(s.sshClient, req.VMID, req.Confirm)
+	if err != nil {
+		json.NewEncoder(w).Encode(ReclaimResponse{APIResponse: APIResponse{Error: err.Error()}})
+		return
+	}
+
+	slog.Warn("VM reclaimed under deployer management", "vmid", result.VMID, "name", result.Name, "tags", result.Tags)
+	json.NewEncoder(w).Encode(ReclaimResponse{
+		APIResponse: APIResponse{Success: true},
+		Result:      result,
+	})
+}
+
+// handleVerifyImages re-checks the checksums of the given components' ISOs on
+// Proxmox storage without deploying. Components without an ISO are checked
+// against the image a deployment would pick.
+func (s *Server) handleVerifyImages(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		Components []config.ComponentConfig `json:"components"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		json.NewEncoder(w).Encode(VerifyImagesResponse{APIResponse: APIResponse{Error: fmt.Sprintf("Invalid request: %v", err)}})
+		return
+	}
+
+	if s.sshClient == nil {
+		json.NewEncoder(w).Encode(VerifyImagesResponse{APIResponse: APIResponse{Error: "Not connected to Proxmox"}})
+		return
+	}
+
+	deployCfg := config.NewDeploymentConfig()
+	deployCfg.Components = req.Components
+
+	s.cfgMu.Lock()
+	imageSources, _ := sources.CreateSourcesFromConfig(s.cfg)
+	s.cfgMu.Unlock()
+	var knownImages []sources.ISOFile
+	s.mu.Lock()
+	if s.discoveryState != nil {
+		knownImages = s.discoveryState.Images
+	}
+	s.mu.Unlock()
+
+	checks, err := deployer.VerifyImages(r.Context(), deployer.DeployRequest{
+		Client:      s.sshClient,
+		Config:      deployCfg,
+		Sources:     imageSources,
+		KnownImages: knownImages,
+	})
+	if err != nil {
+		json.NewEncoder(w).Encode(VerifyImagesResponse{APIResponse: APIResponse{Error: err.Error()}})
+		return
+	}
+
+	passed := true
+	for _, c := range checks {
+		if !c.Passed() && c.Status != deployer.ImageUnverified {
+			passed = false
+		}
+	}
+	json.NewEncoder(w).Encode(VerifyImagesResponse{
+		APIResponse: APIResponse{Success: passed},
+		Images:      checks,
+	})
+}
+
+// handleDeploymentsExport returns a hand-off bundle for deployed VMs as JSON
+// or, with ?format=markdown, as a downloadable markdown file
+func (s *Server) handleDeploymentsExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	prefix := r.URL.Query().Get("prefix")
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "markdown" {
+		http.Error(w, fmt.Sprintf("unknown format %q (expected json or markdown)", format), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if s.sshClient == nil {
+		json.NewEncoder(w).Encode(ExportResponse{APIResponse: APIResponse{Error: "Not connected to Proxmox"}})
+		return
+	}
+
+	s.cfgMu.Lock()
+	directorIP := s.cfg.DirectorIP
+	s.cfgMu.Unlock()
+	export, err := deployer.ExportDeployment(s.sshClient, prefix, directorIP)
+	if err != nil {
+		json.NewEncoder(w).Encode(ExportResponse{APIResponse: APIResponse{Error: err.Error()}})
+		return
+	}
+
+	if format == "markdown" {
+		name := "versa-deployment"
+		if prefix != "" {
+			name += "-" + prefix
+		}
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name+".md"))
+		io.WriteString(w, export.Markdown())
+		return
+	}
+
+	json.NewEncoder(w).Encode(ExportResponse{
+		APIResponse: APIResponse{Success: true},
+		Export:      export,
+	})
+}
+
+// handleVMSnapshot lists (GET ?vmid=) or creates (POST) snapshots of a
+// deployer-managed VM
+func (s *Server) handleVMSnapshot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		VMID int    `json:"vmid"`
+		Name string `json:"name"`
+	}
+	if r.Method == "GET" {
+		req.VMID, _ = strconv.Atoi(r.URL.Query().Get("vmid"))
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		json.NewEncoder(w).Encode(SnapshotsResponse{APIResponse: APIResponse{Error: fmt.Sprintf("Invalid request: %v", err)}})
+		return
+	}
+
+	if s.sshClient == nil || s.discoverer == nil {
+		json.NewEncoder(w).Encode(SnapshotsResponse{APIResponse: APIResponse{Error: "Not connected to Proxmox"}})
+		return
+	}
+	vm, err := s.discoverer.FindManagedVM(req.VMID)
+	if err != nil {
+		json.NewEncoder(w).Encode(SnapshotsResponse{APIResponse: APIResponse{Error: err.Error()}})
+		return
+	}
+
+	vmCreator := proxmox.NewVMCreator(s.sshClient)
+	if r.Method == "POST" {
+		if req.Name == "" {
+			req.Name = proxmox.CleanInstallSnapshot
+		}
+		if err := vmCreator.Snapshot(vm.VMID, req.Name); err != nil {
+			json.NewEncoder(w).Encode(SnapshotsResponse{APIResponse: APIResponse{Error: fmt.Sprintf("Snapshot failed: %v", err)}})
+			return
+		}
+		slog.Info("VM snapshot created", "vmid", vm.VMID, "name", vm.Name, "snapshot", req.Name)
+	}
+
+	snapshots, err := vmCreator.ListSnapshots(vm.VMID)
+	if err != nil {
+		json.NewEncoder(w).Encode(SnapshotsResponse{APIResponse: APIResponse{Error: err.Error()}})
+		return
+	}
+	json.NewEncoder(w).Encode(SnapshotsResponse{
+		APIResponse: APIResponse{Success: true},
+		Snapshots:   snapshots,
+	})
+}
+
+// handleVMSnapshotRollback reverts a deployer-managed VM to a snapshot
+func (s *Server) handleVMSnapshotRollback(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		VMID int    `json:"vmid"`
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		json.NewEncoder(w).Encode(APIResponse{Error: fmt.Sprintf("Invalid request: %v", err)})
+		return
+	}
+
+	if s.sshClient == nil || s.discoverer == nil {
+		json.NewEncoder(w).Encode(APIResponse{Error: "Not connected to Proxmox"})
+		return
+	}
+	vm, err := s.discoverer.FindManagedVM(req.VMID)
+	if err != nil {
+		json.NewEncoder(w).Encode(APIResponse{Error: err.Error()})
+		return
+	}
+
+	if err := proxmox.NewVMCreator(s.sshClient).RollbackSnapshot(vm.VMID, req.Name); err != nil {
+		json.NewEncoder(w).Encode(APIResponse{Error: fmt.Sprintf("Rollback failed: %v", err)})
+		return
+	}
+
+	slog.Warn("VM rolled back to snapshot", "vmid", vm.VMID, "name", vm.Name, "snapshot", req.Name)
+	json.NewEncoder(w).Encode(APIResponse{Success: true})
+}
+
+// handleVMISO attaches (POST {vmid, storage, filename, installer}) or
+// detaches (DELETE {vmid}) an ISO on a deployer-managed VM
+func (s *Server) handleVMISO(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" && r.Method != "DELETE" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	var req struct {
+		VMID      int    `json:"vmid"`
+		Storage   string `json:"storage"`
+		Filename  string `json:"filename"`
+		Installer bool   `json:"installer"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		json.NewEncoder(w).Encode(APIResponse{Error: fmt.Sprintf("Invalid request: %v", err)})
+		return
+	}
+
+	if s.sshClient == nil {
+		json.NewEncoder(w).Encode(APIResponse{Error: "Not connected to Proxmox"})
+		return
+	}
+
+	if r.Method == "DELETE" {
+		if err := deployer.DetachISO(s.sshClient, req.VMID); err != nil {
+			json.NewEncoder(w).Encode(APIResponse{Error: err.Error()})
+			return
+		}
+		slog.Info("ISO detached", "vmid", req.VMID)
+		json.NewEncoder(w).Encode(APIResponse{Success: true})
+		return
+	}
+
+	if req.Filename == "" {
+		json.NewEncoder(w).Encode(APIResponse{Error: "filename is required"})
+		return
+	}
+	storage, err := deployer.AttachISO(s.sshClient, req.VMID, req.Storage, req.Filename, req.Installer)
+	if err != nil {
+		json.NewEncoder(w).Encode(APIResponse{Error: err.Error()})
+		return
+	}
+	slog.Info("ISO attached", "vmid", req.VMID, "storage", storage, "iso", req.Filename, "installer", req.Installer)
+	json.NewEncoder(w).Encode(APIResponse{Success: true})
+}
+
+// handleVMPendingNetworks lists (GET) the VMs deployed management-only that
+// still await their remaining interfaces, or adds them to one VM (POST {vmid})
+func (s *Server) handleVMPendingNetworks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" && r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if s.sshClient == nil {
+		json.NewEncoder(w).Encode(PendingNetworksResponse{APIResponse: APIResponse{Error: "Not connected to Proxmox"}})
+		return
+	}
+
+	if r.Method == "POST" {
+		var req struct {
+			VMID int `json:"vmid"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			json.NewEncoder(w).Encode(PendingNetworksResponse{APIResponse: APIResponse{Error: fmt.Sprintf("Invalid request: %v", err)}})
+			return
+		}
+		added, err := deployer.AddRemainingNetworks(s.sshClient, req.VMID)
+		if err != nil {
+			json.NewEncoder(w).Encode(PendingNetworksResponse{APIResponse: APIResponse{Error: err.Error()}})
+			return
+		}
+		slog.Info("added remaining interfaces", "vmid", added.VMID, "name", added.Name, "count", len(added.Networks))
+	}
+
+	pending, err := deployer.ListPendingNetworks(s.sshClient.Host())
+	if err != nil {
+		json.NewEncoder(w).Encode(PendingNetworksResponse{APIResponse: APIResponse{Error: err.Error()}})
+		return
+	}
+	json.NewEncoder(w).Encode(PendingNetworksResponse{
+		APIResponse: APIResponse{Success: true},
+		Pending:     pending,
+	})
+}
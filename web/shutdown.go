@@ -0,0 +1,46 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Shutdown stops the web UI cleanly: it stops the background rescan, closes
+// every console session (and its PTY on the Proxmox host), tells SSE clients
+// the server is going away, gracefully shuts down the HTTP and HTTPS servers,
+// waiting for in-flight requests until ctx is done, and then closes the
+// Proxmox SSH connection
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.shutdownOnce.Do(func() {
+		// Sent before the streams are released so each client receives it
+		s.broadcastSSE(`{"type":"shutdown"}`)
+		close(s.shutdown)
+	})
+
+	closeAllConsoleSessions()
+
+	s.serversMu.Lock()
+	servers := s.servers
+	s.serversMu.Unlock()
+
+	var errs []error
+	for _, srv := range servers {
+		if err := srv.Shutdown(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// In-flight requests may still be using the connection until here
+	if s.sshClient != nil {
+		s.sshClient.Close()
+	}
+	return errors.Join(errs...)
+}
+
+// trackServer records an HTTP server for Shutdown
+func (s *Server) trackServer(srv *http.Server) {
+	s.serversMu.Lock()
+	s.servers = append(s.servers, srv)
+	s.serversMu.Unlock()
+}
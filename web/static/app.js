@@ -10,14 +10,21 @@ const state = {
     singleComponent: 'director',
     components: [],      // built from mode + discovery
     sseSource: null,
+    liveStatusSource: null, // EventSource for /api/deployments/status, covering every shown deployment
     imagesLoaded: false,
+    availability: null,  // compType -> {available, latestVersion}, null until a scan completes
     configSources: [],   // configured ImageSource entries
+    sourceLimit: 0,      // maximum number of image sources (0 = unknown)
     networkConfig: {
         northbound: '',
         directorRouter: '',
+        analyticsSouthbound: '', // empty = share the Southbound (Director-Router) link
         controllerRouter: '',
         controllerWANs: [],
-        extraInterfaces: {},     // compType -> [{label, bridge}]
+        controllerWANMTUs: [],   // MTU per controller WAN, parallel to controllerWANs
+        controllerWANVLANs: [],  // VLAN per controller WAN, parallel to controllerWANs (0 = untagged)
+        extraInterfaces: {},     // compType -> [{label, bridge, mtu}]
+        mtu: {},                 // field -> MTU for the fixed links (0/empty = Proxmox default)
         interfaceOrder: {},      // compType -> [id, id, ...] for reordering all interfaces
         autoCreate: new Set(),
     },
@@ -33,7 +40,7 @@ const INTERFACE_DEFS = {
     ],
     analytics: [
         { eth: 0, label: 'Management',     field: 'northbound',        required: true },
-        { eth: 1, label: 'Southbound',     field: 'directorRouter',    required: true },
+        { eth: 1, label: 'Southbound',     field: 'analyticsSouthbound', fallback: 'directorRouter', required: true },
         // Extra interfaces added dynamically via analyticsCluster
     ],
     controller: [
@@ -87,9 +94,13 @@ function saveState() {
         networkConfig: {
             northbound: state.networkConfig.northbound,
             directorRouter: state.networkConfig.directorRouter,
+            analyticsSouthbound: state.networkConfig.analyticsSouthbound,
             controllerRouter: state.networkConfig.controllerRouter,
             controllerWANs: state.networkConfig.controllerWANs,
+            controllerWANMTUs: state.networkConfig.controllerWANMTUs,
+            controllerWANVLANs: state.networkConfig.controllerWANVLANs,
             extraInterfaces: state.networkConfig.extraInterfaces,
+            mtu: state.networkConfig.mtu,
             interfaceOrder: state.networkConfig.interfaceOrder,
             autoCreate: Array.from(state.networkConfig.autoCreate),
         },
@@ -138,6 +149,8 @@ function setupEventListeners() {
     document.getElementById('create-network-btn').addEventListener('click', () => showNetworkModal());
     document.getElementById('create-network-form').addEventListener('submit', handleCreateNetwork);
     document.getElementById('deploy-btn').addEventListener('click', handleDeploy);
+    document.getElementById('validate-btn').addEventListener('click', handleValidate);
+    document.getElementById('dry-run-btn').addEventListener('click', () => handleDeploy(true));
     document.getElementById('add-source-btn').addEventListener('click', () => showSourceModal());
     document.getElementById('add-local-btn').addEventListener('click', () => showSourceModal('local'));
     document.getElementById('add-source-form').addEventListener('submit', handleAddSource);
@@ -148,6 +161,7 @@ function setupEventListeners() {
 
     // Deployments refresh
     document.getElementById('refresh-deployments-btn').addEventListener('click', loadDeployments);
+    document.getElementById('deployments-env').addEventListener('change', loadDeployments);
 
     // Auto-detect source type as user types
     document.getElementById('source-url').addEventListener('input', (e) => {
@@ -203,6 +217,7 @@ async function loadConfig() {
         if (cfg.lastProxmoxHost) document.getElementById('host').value = cfg.lastProxmoxHost;
         if (cfg.lastProxmoxUser) document.getElementById('user').value = cfg.lastProxmoxUser;
         if (cfg.imageSources) state.configSources = cfg.imageSources;
+        if (cfg.maxImageSources) state.sourceLimit = cfg.maxImageSources;
 
         // Show saved password status
         if (cfg.hasPassword) {
@@ -31,6 +31,7 @@ function rebuildComponents() {
             ram: DEFAULT_SPECS[type].ram,
             disk: DEFAULT_SPECS[type].disk,
             node: getBestNode(disc) || '',
+            storage: '',  // empty = deployment default storage
             iso: '',
         };
     });
@@ -56,9 +57,17 @@ function renderComponentsTable() {
         const isos = findISOsForComponent(comp.type);
         const hasISOs = isos.length > 0;
 
+        // Components with no image in any source can't be deployed
+        const avail = state.availability ? state.availability[comp.type] : null;
+        const noISO = avail && !avail.available;
+        if (noISO) comp.enabled = false;
+        const noISOBadge = noISO
+            ? ' <span class="net-badge" title="No image source provides an ISO for this component">No ISO</span>'
+            : '';
+
         tr.innerHTML = `
-            <td><input type="checkbox" data-idx="${idx}" class="comp-enable" ${comp.enabled ? 'checked' : ''}></td>
-            <td>${COMP_NAMES[comp.type] || comp.type}</td>
+            <td><input type="checkbox" data-idx="${idx}" class="comp-enable" ${comp.enabled ? 'checked' : ''} ${noISO ? 'disabled' : ''}></td>
+            <td>${COMP_NAMES[comp.type] || comp.type}${noISOBadge}</td>
             <td><input type="number" min="1" max="10" value="${comp.count}" data-idx="${idx}" class="comp-count"></td>
             <td><input type="number" min="1" max="64" value="${comp.cpu}" data-idx="${idx}" class="comp-cpu"></td>
             <td><input type="number" min="1" max="256" value="${comp.ram}" data-idx="${idx}" class="comp-ram"></td>
@@ -68,6 +77,12 @@ function renderComponentsTable() {
                     ${(disc.nodes || []).map(n => `<option value="${esc(n.Name)}" ${n.Name === comp.node ? 'selected' : ''}>${esc(n.Name)}</option>`).join('')}
                 </select>
             </td>
+            <td>
+                <select data-idx="${idx}" class="comp-storage">
+                    <option value="" ${!comp.storage ? 'selected' : ''}>Default</option>
+                    ${getImageStorage(disc).map(s => `<option value="${esc(s.Name)}" ${s.Name === comp.storage ? 'selected' : ''}>${esc(s.Name)} (${s.AvailableGB}GB)</option>`).join('')}
+                </select>
+            </td>
             <td>
                 <select data-idx="${idx}" class="comp-iso">
                     ${hasISOs
@@ -118,12 +133,30 @@ function renderComponentsTable() {
         state.components[+e.target.dataset.idx].node = e.target.value;
         saveState();
     }));
+    tbody.querySelectorAll('.comp-storage').forEach(el => el.addEventListener('change', (e) => {
+        state.components[+e.target.dataset.idx].storage = e.target.value;
+        saveState();
+    }));
     tbody.querySelectorAll('.comp-iso').forEach(el => el.addEventListener('change', (e) => {
         state.components[+e.target.dataset.idx].iso = e.target.value;
         saveState();
     }));
 }
 
+async function refreshAvailability() {
+    try {
+        const result = await api('GET', '/api/components/availability');
+        if (result.success && result.scanned) {
+            state.availability = {};
+            (result.components || []).forEach(a => { state.availability[a.component] = a; });
+        }
+    } catch (e) {
+        // Leave components selectable if availability can't be determined
+    }
+    renderComponentsTable();
+    updateSummary();
+}
+
 function findISOsForComponent(type) {
     if (!state.discovery || !state.discovery.images) return [];
     // FlexVNF ISO is used for controller, router, and flexvnf
@@ -136,6 +169,13 @@ function findISOsForComponent(type) {
     });
 }
 
+function getImageStorage(disc) {
+    // Active storages that can hold VM disks, most free space first
+    return (disc.storage || [])
+        .filter(s => s.Active && (!s.Content || s.Content.length === 0 || s.Content.includes('images')))
+        .sort((a, b) => b.AvailableGB - a.AvailableGB);
+}
+
 function getBestNode(disc) {
     if (!disc.nodes || disc.nodes.length === 0) return '';
     // Pick the online node with the most free RAM, then most free CPU
@@ -28,17 +28,17 @@ function updateSummary() {
     `;
 }
 
-async function handleDeploy() {
-    const btn = document.getElementById('deploy-btn');
-    const progressEl = document.getElementById('deploy-progress');
-    const resultEl = document.getElementById('deploy-result');
-
-    btn.disabled = true;
-    progressEl.classList.remove('hidden');
-    resultEl.classList.add('hidden');
-
+// Collect the deploy form into an /api/deploy request body
+function buildDeployPayload() {
     const prefix = document.getElementById('deploy-prefix').value.trim() || 'versa';
     const storage = document.getElementById('deploy-storage').value;
+    const operator = document.getElementById('deploy-operator').value.trim();
+    const ticket = document.getElementById('deploy-ticket').value.trim();
+    const environment = document.getElementById('deploy-environment').value.trim();
+    const noStart = document.getElementById('deploy-no-start').checked;
+    const snapshot = document.getElementById('deploy-snapshot').checked;
+    const managementOnly = document.getElementById('deploy-management-only').checked;
+    const onFailure = document.getElementById('deploy-on-failure').value;
     const isHA = state.mode === 'ha';
 
     // Build component configs
@@ -49,21 +49,82 @@ async function handleDeploy() {
         RAMGB: c.ram,
         DiskGB: c.disk,
         Node: c.node,
+        StoragePool: c.storage || '',
         ISOPath: c.iso,
         Version: '',
     }));
 
+    return {
+        prefix,
+        haMode: isHA,
+        components,
+        storage,
+        operator,
+        ticket,
+        environment,
+        noStart,
+        snapshot,
+        managementOnly,
+        onFailure,
+        networks: buildNetworkPayload(),
+    };
+}
+
+// Run preflight checks only and list every problem at once
+async function handleValidate() {
+    const btn = document.getElementById('validate-btn');
+    const resultEl = document.getElementById('deploy-result');
+    btn.disabled = true;
+
+    try {
+        const result = await api('POST', '/api/deploy', { ...buildDeployPayload(), validateOnly: true });
+        resultEl.classList.remove('hidden', 'success', 'error');
+        if (!result.report) {
+            resultEl.classList.add('error');
+            resultEl.innerHTML = `<strong>Validation Failed</strong><p>${esc(result.error || 'Unknown error')}</p>`;
+            return;
+        }
+
+        const report = result.report;
+        const errors = report.errors || [];
+        const warnings = report.warnings || [];
+        resultEl.classList.add(errors.length === 0 ? 'success' : 'error');
+        let html = errors.length === 0
+            ? '<strong>Validation Passed</strong>'
+            : `<strong>Validation Failed (${errors.length} problem${errors.length === 1 ? '' : 's'})</strong>`;
+        if (errors.length > 0) {
+            html += '<ul>' + errors.map(e => `<li>${esc(e)}</li>`).join('') + '</ul>';
+        }
+        if (warnings.length > 0) {
+            html += '<div style="margin-top:8px"><strong>Warnings</strong></div>';
+            html += '<ul>' + warnings.map(w => `<li>${esc(w)}</li>`).join('') + '</ul>';
+        }
+        resultEl.innerHTML = html;
+    } catch (err) {
+        resultEl.classList.remove('hidden', 'success');
+        resultEl.classList.add('error');
+        resultEl.innerHTML = `<strong>Validation Failed</strong><p>${esc(err.message)}</p>`;
+    } finally {
+        btn.disabled = false;
+    }
+}
+
+// With dryRun the server streams the planned bridges, ISO transfers and
+// qm create commands without changing anything
+async function handleDeploy(dryRun = false) {
+    const btn = document.getElementById('deploy-btn');
+    const progressEl = document.getElementById('deploy-progress');
+    const resultEl = document.getElementById('deploy-result');
+
+    btn.disabled = true;
+    progressEl.classList.remove('hidden');
+    resultEl.classList.add('hidden');
+
     // Start SSE listener
     startSSE();
 
     try {
-        const result = await api('POST', '/api/deploy', {
-            prefix,
-            haMode: isHA,
-            components,
-            storage,
-            networks: buildNetworkPayload(),
-        });
+        const result = await api('POST', '/api/deploy', { ...buildDeployPayload(), dryRun: dryRun === true });
 
         if (!result.success && result.error) {
             showDeployResult(false, result.error);
@@ -101,6 +162,8 @@ async function checkDeployStatus() {
             logEl.scrollTop = logEl.scrollHeight;
         }
 
+        renderDownloadTasks(status.downloadTasks);
+
         // Show current stage
         if (status.stage) {
             const pct = status.progress.total > 0
@@ -124,6 +187,8 @@ function startSSE() {
 
     const logEl = document.getElementById('progress-log');
     logEl.innerHTML = '';
+    document.getElementById('transfer-progress').classList.add('hidden');
+    renderDownloadTasks([]);
 
     state.sseSource = new EventSource('/api/deploy/progress');
 
@@ -157,6 +222,24 @@ function handleSSEMessage(data) {
             progressText.textContent = `${data.stage} (${data.current}/${data.total})`;
             break;
         }
+        case 'transfer':
+            renderTransfer(data.transfer);
+            break;
+
+        case 'download_tasks':
+            renderDownloadTasks(data.tasks);
+            break;
+
+        case 'shutdown': {
+            if (state.sseSource) state.sseSource.close();
+            const line = document.createElement('div');
+            line.className = 'log-line';
+            line.textContent = 'Deployer server stopped; progress updates have ended.';
+            logEl.appendChild(line);
+            logEl.scrollTop = logEl.scrollHeight;
+            break;
+        }
+
         case 'complete':
             if (state.sseSource) state.sseSource.close();
             showDeployResult(true, null, data.result);
@@ -171,21 +254,81 @@ function handleSSEMessage(data) {
     }
 }
 
+// Show byte-level progress of the current ISO download or upload, hiding
+// the bar once the file is done
+function renderTransfer(t) {
+    const el = document.getElementById('transfer-progress');
+    if (!t || t.total <= 0 || t.done >= t.total) {
+        el.classList.add('hidden');
+        return;
+    }
+    const pct = Math.round((t.done / t.total) * 100);
+    const verb = t.action === 'upload' ? 'Uploading' : 'Downloading';
+    const speed = t.bytesPerSec > 0 ? ` at ${formatSize(Math.round(t.bytesPerSec))}/s` : '';
+    document.getElementById('transfer-label').textContent =
+        `${verb} ${t.filename}: ${pct}% (${formatSize(t.done)} / ${formatSize(t.total)})${speed}`;
+    document.getElementById('transfer-fill').style.width = pct + '%';
+    el.classList.remove('hidden');
+}
+
+// List ISO downloads running as Proxmox tasks, each with a cancel button for
+// when a stalled mirror holds up the deploy
+function renderDownloadTasks(tasks) {
+    const el = document.getElementById('download-tasks');
+    if (!tasks || tasks.length === 0) {
+        el.classList.add('hidden');
+        el.innerHTML = '';
+        return;
+    }
+    el.innerHTML = tasks.map(t => `
+        <div class="download-task">
+            <span>Downloading ${esc(t.iso)} on ${esc(t.node)} (task ${esc(t.upid)})</span>
+            <button class="btn btn-small btn-danger" data-upid="${esc(t.upid)}"
+                title="Stop the Proxmox task and upload the ISO over SSH instead">Cancel download</button>
+        </div>`).join('');
+    el.querySelectorAll('button[data-upid]').forEach(btn => {
+        btn.addEventListener('click', () => cancelDownload(btn));
+    });
+    el.classList.remove('hidden');
+}
+
+async function cancelDownload(btn) {
+    btn.disabled = true;
+    try {
+        const result = await api('POST', '/api/deploy/cancel-download', { upid: btn.dataset.upid });
+        if (!result.success) {
+            throw new Error(result.error || 'Failed to cancel download');
+        }
+        btn.textContent = 'Cancelling...';
+    } catch (err) {
+        btn.disabled = false;
+        alert(err.message);
+    }
+}
+
 function showDeployResult(success, error, result) {
     const el = document.getElementById('deploy-result');
     el.classList.remove('hidden', 'success', 'error');
     el.classList.add(success ? 'success' : 'error');
 
     if (success && result) {
-        let html = '<strong>Deployment Complete</strong>';
+        let html = result.Status === 'planned'
+            ? '<strong>Dry Run Complete</strong><p>Nothing was changed; the log above lists every planned action.</p>'
+            : '<strong>Deployment Complete</strong>';
+        if (result.NotStarted && result.Status !== 'planned') {
+            html += '<p>VMs were created but not started. Start them from Proxmox once reviewed.</p>';
+        }
         if (result.VMs && result.VMs.length > 0) {
-            html += '<table><thead><tr><th>Name</th><th>VMID</th><th>Node</th><th>Status</th></tr></thead><tbody>';
+            html += '<table><thead><tr><th>Name</th><th>VMID</th><th>Node</th><th>Status</th><th>Networks</th></tr></thead><tbody>';
             result.VMs.forEach(vm => {
+                const nets = (vm.Interfaces || []).map(i =>
+                    `${i.slot}: ${i.bridge}${i.vlan ? '.' + i.vlan : ''}${i.purpose ? ' (' + i.purpose + ')' : ''}${i.pending ? ' [pending]' : ''}`);
                 html += `<tr>
                     <td>${esc(vm.Name)}</td>
                     <td>${vm.VMID}</td>
                     <td>${esc(vm.Node)}</td>
                     <td class="tag-online">${esc(vm.Status)}</td>
+                    <td>${nets.map(esc).join('<br>')}</td>
                 </tr>`;
             });
             html += '</tbody></table>';
@@ -233,8 +376,9 @@ async function handleCreateNetwork(e) {
         }
 
         closeModal();
-        // Re-poll discovery to pick up the new bridge
-        pollDiscovery();
+        // Only the bridge list changed; refresh networks without a full re-scan
+        await refreshDiscovery(['networks']);
+        renderNetworkConfig();
     } catch (err) {
         errEl.textContent = err.message;
         errEl.classList.remove('hidden');
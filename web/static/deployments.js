@@ -10,9 +10,12 @@ async function loadDeployments() {
     loadingEl.classList.remove('hidden');
     emptyEl.classList.add('hidden');
     listEl.innerHTML = '';
+    stopLiveStatus();
 
     try {
-        const result = await api('GET', '/api/deployments');
+        const env = document.getElementById('deployments-env').value.trim();
+        const path = env ? '/api/deployments?env=' + encodeURIComponent(env) : '/api/deployments';
+        const result = await api('GET', path);
         loadingEl.classList.add('hidden');
 
         if (!result.success) {
@@ -27,25 +30,70 @@ async function loadDeployments() {
         const allVMs = [];
         for (const prefix of prefixes) {
             const group = deployments[prefix];
+            const updates = {};
+            for (const u of (group.updates || [])) updates[u.vmid] = u;
+            const pending = group.pendingNetworks || {};
             for (const vm of (group.vms || [])) {
-                allVMs.push({ ...vm, prefix });
+                allVMs.push({ ...vm, prefix, update: updates[vm.VMID], pendingNetworks: pending[vm.VMID] || 0 });
             }
         }
 
+        // VMs whose tags couldn't be read may be ours; say so rather than hide them
+        const unverified = result.unverified || [];
+        if (unverified.length > 0) {
+            const names = unverified.map(vm => `${esc(vm.Name)} (${vm.VMID})`).join(', ');
+            listEl.innerHTML = `<div class="warning-msg">Could not read tags for ${unverified.length} VM${unverified.length === 1 ? '' : 's'}: ${names}. They may be deployer-managed; refresh to retry.</div>`;
+        }
+
         if (allVMs.length === 0) {
-            emptyEl.classList.remove('hidden');
+            if (unverified.length === 0) emptyEl.classList.remove('hidden');
             return;
         }
 
         allVMs.sort((a, b) => a.prefix.localeCompare(b.prefix) || a.VMID - b.VMID);
 
         renderDeploymentTable(listEl, allVMs);
+        watchLiveStatus(listEl, [...new Set(allVMs.map(vm => vm.prefix))]);
     } catch (err) {
         loadingEl.classList.add('hidden');
         listEl.innerHTML = `<div class="error-msg">Failed to load: ${esc(err.message)}</div>`;
     }
 }
 
+// Stream the shown deployments' live power state and update the status
+// badges in place, so boots and shutdowns show without a reload. One stream
+// covers every deployment: browsers allow only six connections per host.
+function watchLiveStatus(container, prefixes) {
+    const query = prefixes.filter(p => p !== '_unknown').map(p => 'prefix=' + encodeURIComponent(p));
+    if (query.length === 0) return;
+    const source = new EventSource('/api/deployments/status?stream=1&' + query.join('&'));
+    source.onmessage = (e) => {
+        let msg;
+        try { msg = JSON.parse(e.data); } catch { return; }
+        if (!msg.success) return;
+        for (const vm of (msg.vms || [])) {
+            const row = container.querySelector(`tr[data-vmid="${vm.vmid}"]`);
+            const badge = row && row.querySelector('.vm-status-badge');
+            if (!badge) continue;
+            badge.className = 'vm-status-badge ' + (vm.status === 'running' ? 'running' : 'stopped');
+            badge.textContent = vm.status;
+            let ipEl = row.querySelector('.vm-live-ip');
+            if (!ipEl) {
+                ipEl = document.createElement('span');
+                ipEl.className = 'vm-live-ip text-muted';
+                badge.after(ipEl);
+            }
+            ipEl.textContent = vm.ip ? ' ' + vm.ip : '';
+        }
+    };
+    state.liveStatusSource = source;
+}
+
+function stopLiveStatus() {
+    if (state.liveStatusSource) state.liveStatusSource.close();
+    state.liveStatusSource = null;
+}
+
 function renderDeploymentTable(container, allVMs) {
     const el = document.createElement('div');
     el.className = 'deployment-table-wrap';
@@ -55,6 +103,7 @@ function renderDeploymentTable(container, allVMs) {
         <label class="deploy-select-all-label"><input type="checkbox" class="deploy-select-all"> Select all</label>
         <button class="btn btn-small btn-warning deploy-action-stop" disabled>Stop Selected</button>
         <button class="btn btn-small btn-danger deploy-action-delete" disabled>Delete Selected</button>
+        <button class="btn btn-small deploy-action-export" title="Download console URLs, logins and next steps as markdown">Export</button>
         <span class="deploy-selection-count text-muted"></span>
     </div>`;
 
@@ -67,14 +116,14 @@ function renderDeploymentTable(container, allVMs) {
             <th>Deployment</th>
             <th>Component</th>
             <th>Status</th>
-            <th style="width:70px"></th>
+            <th style="width:260px"></th>
         </tr></thead>
         <tbody>`;
 
     allVMs.forEach(vm => {
         const statusClass = vm.Status === 'running' ? 'running' : 'stopped';
         // Extract component type from tags
-        const compTag = (vm.Tags || []).find(t => t.startsWith('versa-') && t !== 'versa-deployer' && !t.startsWith('versa-deploy-') && !t.startsWith('versa-ha-'));
+        const compTag = (vm.Tags || []).find(t => t.startsWith('versa-') && t !== 'versa-deployer' && !t.startsWith('versa-deploy-') && !t.startsWith('versa-ha-') && !t.startsWith('versa-version-') && !t.startsWith('versa-deployer-v'));
         const compType = compTag ? compTag.replace('versa-', '') : '';
 
         const isRunning = (vm.Status || '').toLowerCase() === 'running';
@@ -83,10 +132,13 @@ function renderDeploymentTable(container, allVMs) {
             <td><input type="checkbox" class="deploy-vm-check" value="${vm.VMID}"></td>
             <td class="deploy-vmid">${vm.VMID}</td>
             <td>${esc(vm.Name)}</td>
-            <td><span class="deployment-prefix-tag">${esc(vm.prefix)}</span></td>
-            <td>${esc(compType)}</td>
+            <td><span class="deployment-prefix-tag">${esc(vm.prefix)}</span>${vm.ToolVersion ? ` <span class="text-muted" title="Deployer version that created this VM">v${esc(vm.ToolVersion)}</span>` : ''}</td>
+            <td>${esc(compType)}${vm.Version ? ` <span class="text-muted">${esc(vm.Version)}</span>` : ''}${vm.update ? ` <span class="tag-yes" title="${esc(vm.update.latestIso)}">update: ${esc(vm.update.latestVersion)}</span>` : ''}</td>
             <td><span class="vm-status-badge ${statusClass}">${esc(vm.Status)}</span></td>
-            <td>${isRunning ? `<button class="btn-console" onclick="openConsole(${vm.VMID}, '${esc(vm.Name).replace(/'/g, "\\'")}')">Console</button>` : ''}</td>
+            <td class="deploy-vm-actions">${isRunning ? `<button class="btn-console" onclick="openConsole(${vm.VMID}, '${esc(vm.Name).replace(/'/g, "\\'")}')">Console</button>` : ''}
+                <button class="btn-snapshot deploy-vm-snapshot" title="Take a snapshot of this VM">Snapshot</button>
+                <button class="btn-snapshot deploy-vm-revert" title="Roll this VM back to a snapshot">Revert</button>${vm.pendingNetworks ? `
+                <button class="btn-snapshot deploy-vm-add-nics" title="Add the interfaces held back at deploy time">Add ${vm.pendingNetworks} NIC${vm.pendingNetworks > 1 ? 's' : ''}</button>` : ''}</td>
         </tr>`;
     });
 
@@ -101,6 +153,7 @@ function renderDeploymentTable(container, allVMs) {
     const checkboxes = el.querySelectorAll('.deploy-vm-check');
     const stopBtn = el.querySelector('.deploy-action-stop');
     const deleteBtn = el.querySelector('.deploy-action-delete');
+    const exportBtn = el.querySelector('.deploy-action-export');
     const countEl = el.querySelector('.deploy-selection-count');
 
     function getSelected() {
@@ -139,6 +192,36 @@ function renderDeploymentTable(container, allVMs) {
         });
     });
 
+    // Per-VM snapshot and revert
+    el.querySelectorAll('.deploy-vm-snapshot').forEach(btn => {
+        btn.addEventListener('click', () => {
+            const row = btn.closest('tr');
+            snapshotVM(parseInt(row.dataset.vmid), row.dataset.name, btn);
+        });
+    });
+    el.querySelectorAll('.deploy-vm-revert').forEach(btn => {
+        btn.addEventListener('click', () => {
+            const row = btn.closest('tr');
+            revertVM(parseInt(row.dataset.vmid), row.dataset.name, btn);
+        });
+    });
+
+    // Management-only VMs: attach the remaining interfaces
+    el.querySelectorAll('.deploy-vm-add-nics').forEach(btn => {
+        btn.addEventListener('click', () => {
+            const row = btn.closest('tr');
+            addRemainingNICs(parseInt(row.dataset.vmid), row.dataset.name, btn);
+        });
+    });
+
+    // Export the selected deployment (or all of them when the selection spans several)
+    exportBtn.addEventListener('click', () => {
+        const prefixes = [...new Set(getSelected().map(s => s.prefix))];
+        let url = '/api/deployments/export?format=markdown';
+        if (prefixes.length === 1) url += '&prefix=' + encodeURIComponent(prefixes[0]);
+        window.location.href = url;
+    });
+
     // Stop selected
     stopBtn.addEventListener('click', async () => {
         const selected = getSelected();
@@ -199,3 +282,79 @@ function renderDeploymentTable(container, allVMs) {
         }
     });
 }
+
+async function snapshotVM(vmid, name, btn) {
+    const snapName = prompt(`Snapshot name for ${name}:`, 'clean-install');
+    if (!snapName) return;
+
+    btn.disabled = true;
+    btn.textContent = 'Saving...';
+    try {
+        const result = await api('POST', '/api/vm/snapshot', { vmid, name: snapName });
+        if (!result.success) {
+            alert('Snapshot failed: ' + (result.error || 'Unknown error'));
+        }
+    } catch (err) {
+        alert('Snapshot failed: ' + err.message);
+    } finally {
+        btn.disabled = false;
+        btn.textContent = 'Snapshot';
+    }
+}
+
+// Roll a VM back to one of its snapshots, discarding everything since
+async function revertVM(vmid, name, btn) {
+    btn.disabled = true;
+    try {
+        const list = await api('GET', '/api/vm/snapshot?vmid=' + vmid);
+        if (!list.success) {
+            alert('Could not list snapshots: ' + (list.error || 'Unknown error'));
+            return;
+        }
+        const snapshots = (list.snapshots || []).map(s => s.name);
+        if (snapshots.length === 0) {
+            alert(`${name} has no snapshots.`);
+            return;
+        }
+
+        const latest = snapshots[snapshots.length - 1];
+        const snapName = prompt(`Roll ${name} back to which snapshot?\n\nAvailable: ${snapshots.join(', ')}`, latest);
+        if (!snapName) return;
+        if (!confirm(`Roll ${name} back to "${snapName}"? All changes since the snapshot will be lost.`)) return;
+
+        btn.textContent = 'Reverting...';
+        const result = await api('POST', '/api/vm/snapshot/rollback', { vmid, name: snapName });
+        if (!result.success) {
+            alert('Rollback failed: ' + (result.error || 'Unknown error'));
+            return;
+        }
+        loadDeployments();
+    } catch (err) {
+        alert('Rollback failed: ' + err.message);
+    } finally {
+        btn.disabled = false;
+        btn.textContent = 'Revert';
+    }
+}
+
+// Add the interfaces a management-only deployment held back
+async function addRemainingNICs(vmid, name, btn) {
+    if (!confirm(`Add the remaining interfaces to ${name}? Make sure management connectivity is verified first.`)) return;
+
+    btn.disabled = true;
+    btn.textContent = 'Adding...';
+    try {
+        const result = await api('POST', '/api/vm/pending-networks', { vmid });
+        if (!result.success) {
+            alert('Adding interfaces failed: ' + (result.error || 'Unknown error'));
+            btn.disabled = false;
+            btn.textContent = 'Add NICs';
+            return;
+        }
+        loadDeployments();
+    } catch (err) {
+        alert('Adding interfaces failed: ' + err.message);
+        btn.disabled = false;
+        btn.textContent = 'Add NICs';
+    }
+}
@@ -48,13 +48,14 @@ async function pollDiscovery() {
                 state.imagesLoaded = true;
                 state.discovery.images = disc.images;
                 renderImagesStatus();
-                renderComponentsTable(); // Re-render to populate ISO dropdowns
+                refreshAvailability(); // Re-render to populate ISO dropdowns
             }
 
             // If nodes loaded and we've waited enough for images, stop
             if (nodesLoaded && (state.imagesLoaded || i > 15)) {
                 if (!state.imagesLoaded) {
                     renderImagesStatus(); // Show "no images found" state
+                    refreshAvailability();
                 }
                 return;
             }
@@ -70,6 +71,20 @@ async function pollDiscovery() {
     }
 }
 
+// Re-run only the given discovery sections (nodes, storage, networks, vms)
+// and merge them into the cached discovery state
+async function refreshDiscovery(sections) {
+    const disc = await api('POST', '/api/discovery/refresh?sections=' + encodeURIComponent(sections.join(',')));
+    if (disc.error) throw new Error(disc.error);
+    if (!state.discovery) {
+        state.discovery = disc;
+        return;
+    }
+    for (const key of sections) {
+        if (disc[key]) state.discovery[key] = disc[key];
+    }
+}
+
 function renderDiscovery(disc) {
     // Version & cluster info
     document.getElementById('pve-version').textContent = `Proxmox VE ${disc.version}`;
@@ -103,6 +118,7 @@ function renderDiscovery(disc) {
         tr.innerHTML = `
             <td>${esc(s.Name)}</td>
             <td>${esc(s.Type)}</td>
+            <td>${esc((s.Content || []).join(', '))}</td>
             <td>${s.AvailableGB}GB</td>
             <td>${s.TotalGB}GB</td>`;
         storBody.appendChild(tr);
@@ -195,7 +211,10 @@ function renderImagesStatus() {
                 : '';
             html += `<td>${esc(iso.Version || iso.Filename)}</td>`;
             html += `<td>${size}</td>`;
-            html += `<td>${esc(iso.SourceName || '-')}</td>`;
+            const srcNames = (iso.Sources && iso.Sources.length > 0)
+                ? iso.Sources.map(ref => ref.Name).join(', ')
+                : (iso.SourceName || '-');
+            html += `<td>${esc(srcNames)}</td>`;
             html += `<td>${md5}</td>`;
             html += `</tr>`;
         });
@@ -221,7 +240,7 @@ async function pollForImages() {
                 if (state.discovery) state.discovery.images = disc.images;
                 state.imagesLoaded = true;
                 renderImagesStatus();
-                renderComponentsTable();
+                refreshAvailability();
                 return;
             }
         } catch (e) { /* retry */ }
@@ -50,7 +50,10 @@ function initNetworkConfig() {
     const neededFields = new Set();
     enabled.forEach(comp => {
         const defs = INTERFACE_DEFS[comp.type] || [];
-        defs.forEach(d => neededFields.add(d.field));
+        defs.forEach(d => {
+            neededFields.add(d.field);
+            if (d.fallback) neededFields.add(d.fallback);
+        });
     });
 
     // Southbound / Director-Router link — needed by director, analytics, router, concerto
@@ -60,6 +63,11 @@ function initNetworkConfig() {
         state.networkConfig.directorRouter = '';
     }
 
+    // Analytics southbound defaults to sharing the Southbound link
+    if (!enabledTypes.includes('analytics')) {
+        state.networkConfig.analyticsSouthbound = '';
+    }
+
     // Controller-Router link — needed by controller, router
     if (neededFields.has('controllerRouter')) {
         state.networkConfig.controllerRouter = proposeBridge();
@@ -200,6 +208,11 @@ function renderTopologyDiagram() {
         tiers.push({ kind: 'bus', label: 'Rtr ' + iface.label, bridge: iface.bridge, shared: true, ownerType: 'router' });
     });
 
+    // Dedicated Analytics southbound
+    if (nc.analyticsSouthbound && enabledTypes.includes('analytics')) {
+        tiers.push({ kind: 'bus', label: 'Ana Southbound', bridge: nc.analyticsSouthbound, shared: true, ownerType: 'analytics' });
+    }
+
     // Director/Analytics/Concerto extra interfaces
     ['director', 'analytics', 'concerto'].forEach(ct => {
         const exts = (nc.extraInterfaces || {})[ct] || [];
@@ -494,7 +507,7 @@ function renderBridgeAssignments() {
 
     if (nc.directorRouter) {
         const users = ['Director', 'Analytics', 'Router', 'Concerto'].filter(n =>
-            enabledTypes.includes(n.toLowerCase())
+            enabledTypes.includes(n.toLowerCase()) && !(n === 'Analytics' && nc.analyticsSouthbound)
         );
         rows.push({
             label: 'Southbound Link',
@@ -505,6 +518,16 @@ function renderBridgeAssignments() {
         });
     }
 
+    if (enabledTypes.includes('analytics')) {
+        rows.push({
+            label: 'Analytics Southbound',
+            field: 'analyticsSouthbound',
+            value: nc.analyticsSouthbound,
+            desc: nc.analyticsSouthbound ? 'Analytics' : 'Analytics (none = share Southbound Link)',
+            optional: true,
+        });
+    }
+
     if (nc.controllerRouter) {
         rows.push({
             label: 'Controller-Router Link',
@@ -587,9 +610,12 @@ function renderBridgeAssignments() {
             moveButtons += '</span>';
         }
 
+        const mtu = getNetworkFieldMTU(r.field);
         html += `<div class="network-row">
             <label>${esc(r.label)}</label>
             ${buildBridgeDropdown(r.value, r.field, { allowNone: r.optional })}
+            <input type="number" class="net-mtu" data-field="${esc(r.field)}" value="${mtu || ''}" min="1" max="65520" placeholder="MTU" title="Interface MTU (empty = default, 1 = inherit bridge MTU)">
+            ${r.wanIndex !== undefined ? `<input type="number" class="net-vlan" data-wan="${r.wanIndex}" value="${getWANVLAN(r.wanIndex) || ''}" min="0" max="4094" placeholder="VLAN" title="VLAN tag for this WAN (empty = untagged)">` : ''}
             ${badge}
             ${moveButtons}
             ${removeBtn}
@@ -616,6 +642,36 @@ function renderBridgeAssignments() {
         });
     });
 
+    // Bind MTU inputs
+    container.querySelectorAll('.net-mtu').forEach(input => {
+        input.addEventListener('change', () => {
+            const mtu = parseInt(input.value) || 0;
+            if (mtu !== 0 && mtu !== 1 && (mtu < 576 || mtu > 65520)) {
+                input.classList.add('input-error');
+                input.title = 'MTU must be 1 (inherit bridge) or 576-65520';
+                return;
+            }
+            input.classList.remove('input-error');
+            setNetworkFieldMTU(input.dataset.field, mtu);
+            saveState();
+        });
+    });
+
+    // Bind WAN VLAN inputs
+    container.querySelectorAll('.net-vlan').forEach(input => {
+        input.addEventListener('change', () => {
+            const vlan = parseInt(input.value) || 0;
+            if (vlan < 0 || vlan > 4094) {
+                input.classList.add('input-error');
+                input.title = 'VLAN must be 1-4094 (empty = untagged)';
+                return;
+            }
+            input.classList.remove('input-error');
+            setWANVLAN(parseInt(input.dataset.wan), vlan);
+            saveState();
+        });
+    });
+
     // Add WAN button
     const addWanBtn = container.querySelector('#add-wan-btn');
     if (addWanBtn) {
@@ -634,6 +690,8 @@ function renderBridgeAssignments() {
             if (field.startsWith('controllerWAN_')) {
                 const idx = parseInt(field.split('_')[1]);
                 state.networkConfig.controllerWANs.splice(idx, 1);
+                (state.networkConfig.controllerWANMTUs || []).splice(idx, 1);
+                (state.networkConfig.controllerWANVLANs || []).splice(idx, 1);
             } else if (field.startsWith('extra_')) {
                 const parts = field.split('_');
                 const compType = parts[1];
@@ -655,9 +713,13 @@ function renderBridgeAssignments() {
             if (field.startsWith('controllerWAN_')) {
                 const idx = parseInt(field.split('_')[1]);
                 const arr = state.networkConfig.controllerWANs;
+                const mtus = state.networkConfig.controllerWANMTUs = state.networkConfig.controllerWANMTUs || [];
+                const vlans = state.networkConfig.controllerWANVLANs = state.networkConfig.controllerWANVLANs || [];
                 const swapIdx = dir === 'up' ? idx - 1 : idx + 1;
                 if (swapIdx >= 0 && swapIdx < arr.length) {
                     [arr[idx], arr[swapIdx]] = [arr[swapIdx], arr[idx]];
+                    [mtus[idx], mtus[swapIdx]] = [mtus[swapIdx], mtus[idx]];
+                    [vlans[idx], vlans[swapIdx]] = [vlans[swapIdx], vlans[idx]];
                 }
             } else if (field.startsWith('extra_')) {
                 const parts = field.split('_');
@@ -889,7 +951,7 @@ function resolveInterfaces(compType) {
     const all = [];
 
     baseDefs.forEach((def, i) => {
-        const bridge = nc[def.field] || '';
+        const bridge = nc[def.field] || (def.fallback ? nc[def.fallback] : '') || '';
         if (!def.required && !bridge) return;
         all.push({
             id: `base:${i}`,
@@ -951,6 +1013,69 @@ function resolveInterfaces(compType) {
     }));
 }
 
+function getNetworkFieldMTU(field) {
+    const nc = state.networkConfig;
+    if (field.startsWith('controllerWAN_')) {
+        return (nc.controllerWANMTUs || [])[parseInt(field.split('_')[1])] || 0;
+    }
+    if (field.startsWith('extra_')) {
+        const parts = field.split('_');
+        const arr = (nc.extraInterfaces || {})[parts[1]] || [];
+        const iface = arr[parseInt(parts[2])];
+        return iface ? iface.mtu || 0 : 0;
+    }
+    return (nc.mtu || {})[field] || 0;
+}
+
+function setNetworkFieldMTU(field, mtu) {
+    const nc = state.networkConfig;
+    if (field.startsWith('controllerWAN_')) {
+        if (!nc.controllerWANMTUs) nc.controllerWANMTUs = [];
+        nc.controllerWANMTUs[parseInt(field.split('_')[1])] = mtu;
+    } else if (field.startsWith('extra_')) {
+        const parts = field.split('_');
+        const arr = (nc.extraInterfaces || {})[parts[1]] || [];
+        const iface = arr[parseInt(parts[2])];
+        if (iface) iface.mtu = mtu;
+    } else {
+        if (!nc.mtu) nc.mtu = {};
+        nc.mtu[field] = mtu;
+    }
+}
+
+function getWANVLAN(idx) {
+    return (state.networkConfig.controllerWANVLANs || [])[idx] || 0;
+}
+
+function setWANVLAN(idx, vlan) {
+    const nc = state.networkConfig;
+    if (!nc.controllerWANVLANs) nc.controllerWANVLANs = [];
+    nc.controllerWANVLANs[idx] = vlan;
+}
+
+// buildMTUPayload maps the per-row MTUs to the network purposes the backend
+// keys them by. A shared link sets the MTU of every interface attached to it.
+function buildMTUPayload() {
+    const nc = state.networkConfig;
+    const out = {};
+    const put = (purposes, mtu) => {
+        if (mtu) purposes.forEach(p => { out[p] = mtu; });
+    };
+
+    put(['northbound'], getNetworkFieldMTU('northbound'));
+    put(nc.analyticsSouthbound
+        ? ['director-router', 'concerto-south']
+        : ['director-router', 'concerto-south', 'analytics-south'], getNetworkFieldMTU('directorRouter'));
+    if (nc.analyticsSouthbound) put(['analytics-south'], getNetworkFieldMTU('analyticsSouthbound'));
+    put(['controller-router'], getNetworkFieldMTU('controllerRouter'));
+    nc.controllerWANs.forEach((_, i) => {
+        put(i === 0 ? ['controller-wan-1', 'flexvnf-wan'] : [`controller-wan-${i + 1}`], getNetworkFieldMTU(`controllerWAN_${i}`));
+    });
+    put(['analytics-cluster'], getNetworkFieldMTU('extra_analytics_0'));
+    put(['router-ha'], getNetworkFieldMTU('extra_router_0'));
+    return out;
+}
+
 function buildNetworkPayload() {
     const nc = state.networkConfig;
     const extras = nc.extraInterfaces || {};
@@ -961,10 +1086,14 @@ function buildNetworkPayload() {
     return {
         NorthboundBridge: nc.northbound,
         DirectorRouterBridge: nc.directorRouter,
+        AnalyticsSouthboundBridge: nc.analyticsSouthbound || '',
         ControllerRouterBridge: nc.controllerRouter,
         ControllerWANBridges: nc.controllerWANs.length > 0 ? nc.controllerWANs : [],
+        // One VLAN per WAN bridge, so none is left untagged by a short list
+        ControllerWANVLANs: nc.controllerWANs.map((_, i) => getWANVLAN(i)),
         AnalyticsClusterBridge: analyticsExtras.length > 0 ? analyticsExtras[0].bridge : '',
         RouterHABridge: routerExtras.length > 0 ? routerExtras[0].bridge : '',
         InterfaceOrder: nc.interfaceOrder || {},
+        MTU: buildMTUPayload(),
     };
 }
@@ -10,12 +10,14 @@ async function renderSourcesList() {
         if (result.success && result.sources) {
             state.configSources = result.sources;
         }
+        if (result.limit) state.sourceLimit = result.limit;
     } catch (e) {
         // Use local state
     }
 
     const container = document.getElementById('sources-list');
     const srcs = state.configSources;
+    renderSourceLimit();
 
     if (!srcs || srcs.length === 0) {
         container.innerHTML = '<div class="source-item"><span class="source-url">No sources configured — add a source above</span></div>';
@@ -27,29 +29,104 @@ async function renderSourcesList() {
         const item = document.createElement('div');
         item.className = 'source-item';
         item.innerHTML = `
-            <span class="source-type">${esc(src.Type || 'auto')}</span>
-            <span class="source-name">${esc(src.Name || '')}</span>
-            <span class="source-url" title="${esc(src.URL)}">${esc(src.URL)}</span>
+            <span class="source-type">${esc(src.type || 'auto')}</span>
+            <span class="source-name">${esc(src.name || '')}</span>
+            <span class="source-url" title="${esc(src.url)}">${esc(src.url)}</span>
         `;
+        item.appendChild(makeSourceHealthBadge(src.scan));
         const removeBtn = document.createElement('button');
         removeBtn.className = 'btn-remove';
         removeBtn.textContent = 'Remove';
         removeBtn.addEventListener('click', async () => {
-            const label = src.Name || src.URL;
+            const label = src.name || src.url;
             if (!confirm('Remove source: ' + label + '?')) return;
             removeBtn.disabled = true;
             removeBtn.textContent = 'Removing...';
-            const result = await api('DELETE', '/api/sources', { url: src.URL, index: idx });
+            const result = await api('DELETE', '/api/sources', { url: src.url, index: idx });
             if (result.sources) {
                 state.configSources = result.sources;
             }
             renderSourcesList();
         });
+        if (srcs.length > 1) {
+            item.appendChild(makeSourceMoveButton('\u2191', 'Prefer this source', idx, -1));
+            item.appendChild(makeSourceMoveButton('\u2193', 'Prefer this source less', idx, 1));
+        }
         item.appendChild(removeBtn);
         container.appendChild(item);
     });
 }
 
+// Show "n/limit sources used" and disable adding once the limit is reached
+function renderSourceLimit() {
+    const count = (state.configSources || []).length;
+    const full = state.sourceLimit > 0 && count >= state.sourceLimit;
+    const countEl = document.getElementById('sources-count');
+    countEl.textContent = state.sourceLimit ? `${count}/${state.sourceLimit} sources used` : '';
+    countEl.classList.toggle('full', full);
+    ['add-source-btn', 'add-local-btn'].forEach(id => {
+        const btn = document.getElementById(id);
+        btn.disabled = full;
+        btn.title = full ? 'Source limit reached \u2014 remove a source or raise max_image_sources' : '';
+    });
+}
+
+// Green when the last scan succeeded, red when it failed, grey if never scanned
+function makeSourceHealthBadge(scan) {
+    const badge = document.createElement('span');
+    badge.className = 'source-health';
+    if (!scan) {
+        badge.classList.add('unknown');
+        badge.textContent = 'not scanned';
+        return badge;
+    }
+    if (scan.last_error) {
+        badge.classList.add('failing');
+        badge.textContent = 'failing';
+        badge.title = scan.last_error + (scan.last_success ? '\nLast good scan ' + timeAgo(scan.last_success) : '');
+        return badge;
+    }
+    badge.classList.add('ok');
+    badge.textContent = `${scan.iso_count} ISOs \u00b7 ${timeAgo(scan.last_success)}`;
+    badge.title = 'Last scanned ' + new Date(scan.last_success).toLocaleString();
+    return badge;
+}
+
+function timeAgo(iso) {
+    const secs = Math.max(0, Math.round((Date.now() - new Date(iso).getTime()) / 1000));
+    if (secs < 60) return 'just now';
+    if (secs < 3600) return Math.floor(secs / 60) + 'm ago';
+    if (secs < 86400) return Math.floor(secs / 3600) + 'h ago';
+    return Math.floor(secs / 86400) + 'd ago';
+}
+
+// Sources are tried for downloads top to bottom; moving one changes its priority
+function makeSourceMoveButton(label, title, idx, delta) {
+    const btn = document.createElement('button');
+    btn.className = 'btn-move';
+    btn.textContent = label;
+    btn.title = title;
+    const target = idx + delta;
+    btn.disabled = target < 0 || target >= state.configSources.length;
+    btn.addEventListener('click', () => moveSource(idx, target));
+    return btn;
+}
+
+async function moveSource(from, to) {
+    const order = state.configSources.map(src => src.url);
+    const [moved] = order.splice(from, 1);
+    order.splice(to, 0, moved);
+
+    const result = await api('PATCH', '/api/sources', { order });
+    if (result.sources) {
+        state.configSources = result.sources;
+    }
+    if (!result.success) {
+        alert('Failed to reorder sources: ' + (result.error || 'unknown error'));
+    }
+    renderSourcesList();
+}
+
 function showSourceModal(mode) {
     document.getElementById('add-source-modal').classList.remove('hidden');
     document.getElementById('add-source-error').classList.add('hidden');
@@ -91,6 +168,14 @@ async function handleAddSource(e) {
 
     try {
         const result = await api('POST', '/api/sources', { url, name });
+        if (result.limit) state.sourceLimit = result.limit;
+        if (result.code === 'source_limit') {
+            renderSourceLimit();
+            throw new Error(`Source limit reached (${result.count}/${result.limit}). Remove a source first, or raise max_image_sources in config.json.`);
+        }
+        if (result.code === 'source_exists') {
+            throw new Error('This source is already configured.');
+        }
         if (!result.success) {
             throw new Error(result.error || 'Failed to add source');
         }
@@ -136,7 +221,7 @@ async function handleRescanSources() {
             }
             state.imagesLoaded = true;
             renderImagesStatus();
-            renderComponentsTable();
+            refreshAvailability();
         } else {
             document.getElementById('images-status').classList.remove('loading');
             document.getElementById('images-status').textContent = result.error || 'Scan failed';
@@ -147,5 +232,6 @@ async function handleRescanSources() {
     } finally {
         btn.disabled = false;
         btn.textContent = 'Rescan';
+        renderSourcesList();
     }
 }
@@ -1,7 +1,11 @@
 package web
 
 import (
+	"time"
+
 	"github.com/mihailvovk/versa-proxmox-deployer/config"
+	"github.com/mihailvovk/versa-proxmox-deployer/deployer"
+	"github.com/mihailvovk/versa-proxmox-deployer/proxmox"
 	"github.com/mihailvovk/versa-proxmox-deployer/sources"
 )
 
@@ -18,6 +22,7 @@ type ConfigResponse struct {
 	LastStorage     string               `json:"lastStorage"`
 	LastSSHKeyPath  string               `json:"lastSSHKeyPath"`
 	ImageSources    []config.ImageSource `json:"imageSources"`
+	MaxImageSources int                  `json:"maxImageSources"`
 	HasPassword     bool                 `json:"hasPassword"`
 }
 
@@ -40,10 +45,22 @@ type ScanSourcesResponse struct {
 	Sources []sources.SourceSummary `json:"sources,omitempty"`
 }
 
+// ComponentsAvailabilityResponse is the response for GET /api/components/availability.
+type ComponentsAvailabilityResponse struct {
+	APIResponse
+	Scanned    bool                            `json:"scanned"`
+	Components []sources.ComponentAvailability `json:"components,omitempty"`
+}
+
 // SourcesResponse is the response for GET/POST/DELETE /api/sources.
+// Count and Limit let the UI show how many more sources can be added; Code
+// is "source_limit" or "source_exists" when an add was refused for that.
 type SourcesResponse struct {
 	APIResponse
+	Code    string               `json:"code,omitempty"`
 	Sources []config.ImageSource `json:"sources,omitempty"`
+	Count   int                  `json:"count"`
+	Limit   int                  `json:"limit"`
 }
 
 // UploadKeyResponse is the response for POST /api/upload-key.
@@ -53,10 +70,37 @@ type UploadKeyResponse struct {
 	KeyName string `json:"keyName,omitempty"`
 }
 
+// CertResponse is the response for POST /api/cert/regenerate.
+type CertResponse struct {
+	APIResponse
+	NotAfter    string   `json:"notAfter,omitempty"`
+	DNSNames    []string `json:"dnsNames,omitempty"`
+	IPAddresses []string `json:"ipAddresses,omitempty"`
+}
+
 // DeploymentsResponse is the response for GET /api/deployments.
 type DeploymentsResponse struct {
 	APIResponse
 	Deployments map[string]*DeploymentGroup `json:"deployments,omitempty"`
+	// VMs whose tags could not be read; they may be deployer-managed
+	Unverified []proxmox.VMInfo `json:"unverified,omitempty"`
+}
+
+// DeploymentStatusResponse is the response (or SSE event) for GET /api/deployments/status.
+type DeploymentStatusResponse struct {
+	APIResponse
+	Prefixes []string       `json:"prefixes"`
+	VMs      []VMLiveStatus `json:"vms"`
+}
+
+// VMLiveStatus is one VM's live power state and guest agent IP.
+type VMLiveStatus struct {
+	VMID   int    `json:"vmid"`
+	Name   string `json:"name"`
+	Prefix string `json:"prefix"`
+	Node   string `json:"node"`
+	Status string `json:"status"`
+	IP     string `json:"ip,omitempty"` // First IPv4 reported by the guest agent
 }
 
 // VMActionResponse is the response for POST /api/deployments/stop and /api/deployments/delete.
@@ -65,6 +109,55 @@ type VMActionResponse struct {
 	Results []VMActionResult `json:"results,omitempty"`
 }
 
+// ValidationResponse is the response for POST /api/deploy with validateOnly set.
+type ValidationResponse struct {
+	APIResponse
+	Report *deployer.ValidationReport `json:"report"`
+}
+
+// ReclaimResponse is the response for POST /api/deployments/reclaim.
+type ReclaimResponse struct {
+	APIResponse
+	Result *deployer.ReclaimResult `json:"result,omitempty"`
+}
+
+// VerifyImagesResponse is the response for POST /api/images/verify.
+type VerifyImagesResponse struct {
+	APIResponse
+	Images []deployer.ImageCheck `json:"images,omitempty"`
+}
+
+// DeployLogFile describes one deployment log file.
+type DeployLogFile struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// DeployLogsResponse is the response for GET /api/deployments/logs.
+type DeployLogsResponse struct {
+	APIResponse
+	Logs []DeployLogFile `json:"logs"`
+}
+
+// ExportResponse is the response for GET /api/deployments/export.
+type ExportResponse struct {
+	APIResponse
+	Export *deployer.DeploymentExport `json:"export,omitempty"`
+}
+
+// SnapshotsResponse is the response for /api/vm/snapshot.
+type SnapshotsResponse struct {
+	APIResponse
+	Snapshots []proxmox.SnapshotInfo `json:"snapshots,omitempty"`
+}
+
+// PendingNetworksResponse is the response for /api/vm/pending-networks.
+type PendingNetworksResponse struct {
+	APIResponse
+	Pending []deployer.PendingNetworks `json:"pending"`
+}
+
 // VMActionResult holds the result of a per-VM action (stop, delete).
 type VMActionResult struct {
 	VMID    int    `json:"vmid"`